@@ -15,29 +15,23 @@
 package authz
 
 import (
+	"fmt"
 	"strings"
 
-	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
 	"github.com/casdoor/casdoor/conf"
 	"github.com/casdoor/casdoor/object"
 	"github.com/casdoor/casdoor/util"
 	stringadapter "github.com/qiangmzsx/string-adapter/v2"
+	"github.com/xorm-io/xorm"
+	"github.com/xorm-io/xorm/migrate"
 )
 
-var Enforcer *casbin.Enforcer
-
-func InitApi() {
-	e, err := object.GetInitializedEnforcer(util.GetId("built-in", "api-enforcer-built-in"))
-	if err != nil {
-		panic(err)
-	}
-
-	Enforcer = e.Enforcer
-	Enforcer.ClearPolicy()
-
-	// if len(Enforcer.GetPolicy()) == 0 {
-	if true {
-		ruleText := `
+// defaultApiPolicyVersions are the default API policies, a version lists the policies added since
+// the previous one. The policies of a version are added once, the missing ones only, so that the
+// policies the administrators removed before are not added back by the next versions.
+var defaultApiPolicyVersions = []string{
+	`
 p, built-in, *, *, *, *, *
 p, app, *, *, *, *, *
 p, *, *, POST, /api/signup, *, *
@@ -95,64 +89,137 @@ p, *, *, GET, /api/get-provider, *, *
 p, *, *, GET, /api/get-organization-names, *, *
 p, *, *, GET, /api/get-ldap-server-names, *, *
 p, *, !anonymous, POST, /api/add-user-id-provider, *, *
-`
+`,
+	`
+p, *, !anonymous, GET, /api/get-device-auth, *, *
+p, *, !anonymous, POST, /api/approve-device-auth, *, *
+p, *, !anonymous, POST, /api/grant-consent, *, *
+p, *, !anonymous, GET, /api/get-consents, *, *
+p, *, !anonymous, POST, /api/revoke-consent, *, *
+p, *, *, *, /api/saml/slo, *, *
+p, *, *, *, /scim, *, *
+`,
+	// more versions add here in chronological order...
+}
 
-		sa := stringadapter.NewAdapter(ruleText)
-		// load all rules from string adapter to enforcer's memory
-		err := sa.LoadPolicy(Enforcer.GetModel())
-		if err != nil {
-			panic(err)
-		}
+// getDefaultApiRules returns the rules of the policies in the text, in the model of the enforcer
+func getDefaultApiRules(m model.Model, ruleText string) ([][]string, error) {
+	m = m.Copy()
+	m.ClearPolicy()
+	sa := stringadapter.NewAdapter(ruleText)
+	err := sa.LoadPolicy(m)
+	if err != nil {
+		return nil, err
+	}
 
-		// save all rules from enforcer's memory to Xorm adapter (DB)
-		// same as:
-		// a.SavePolicy(Enforcer.GetModel())
-		err = Enforcer.SavePolicy()
-		if err != nil {
-			panic(err)
-		}
+	return m.GetPolicy("p", "p"), nil
+}
+
+// getDefaultApiPolicyMigrations returns the migrations adding the default API policies of each
+// version to the enforcer
+func getDefaultApiPolicyMigrations(e *object.Enforcer) []*migrate.Migration {
+	migrations := []*migrate.Migration{}
+	for i, ruleText := range defaultApiPolicyVersions {
+		ruleText := ruleText
+		migrations = append(migrations, &migrate.Migration{
+			ID: fmt.Sprintf("ApiPolicyVersion%d -- Add the default API policies of version %d", i+1, i+1),
+			Migrate: func(engine *xorm.Engine) error {
+				rules, err := getDefaultApiRules(e.GetModel(), ruleText)
+				if err != nil {
+					return err
+				}
+
+				for _, rule := range rules {
+					// the policy is saved to the database by the adapter, an existing one is skipped
+					_, err = e.AddPolicy(rule)
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		})
+	}
+	return migrations
+}
+
+func InitApi() {
+	e, err := object.GetInitializedEnforcer(apiEnforcerId)
+	if err != nil {
+		panic(err)
+	}
+
+	err = object.DoMigrations(getDefaultApiPolicyMigrations(e))
+	if err != nil {
+		panic(err)
 	}
+
+	setApiEnforcer(e.Enforcer)
 }
 
 func IsAllowed(subOwner string, subName string, method string, urlPath string, objOwner string, objName string, id string) bool {
+	decision, err := Explain(subOwner, subName, method, urlPath, objOwner, objName, id)
+	if err != nil {
+		panic(err)
+	}
+
+	return decision.IsAllowed
+}
+
+// Explain returns whether IsAllowed grants the request and why, the policies of the request are
+// enforced for the user and then for each of their roles and groups
+func Explain(subOwner string, subName string, method string, urlPath string, objOwner string, objName string, id string) (*Decision, error) {
 	if conf.IsDemoMode() {
 		if !isAllowedInDemoMode(subOwner, subName, method, urlPath, objOwner, objName) {
-			return false
+			return &Decision{Reason: DecisionDemoMode}, nil
 		}
 	}
 
 	user, err := object.GetUser(util.GetId(subOwner, subName))
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	if subOwner == "app" {
-		return true
+		return &Decision{IsAllowed: true, Reason: DecisionApplication}, nil
 	}
 
 	if id != "" {
 		objIdOwner, _ := util.GetOwnerAndNameFromIdNoCheck(id)
 		if subOwner != "built-in" && objIdOwner != objOwner {
-			return false
+			return &Decision{Reason: DecisionOtherOrganization}, nil
 		}
 	}
 
 	if user != nil {
 		if user.IsDeleted {
-			return false
+			return &Decision{Reason: DecisionDeletedUser}, nil
 		}
 
 		if user.IsAdmin && (subOwner == objOwner || (objOwner == "admin")) {
-			return true
+			return &Decision{IsAllowed: true, Reason: DecisionAdmin}, nil
 		}
 	}
 
-	res, err := Enforcer.Enforce(subOwner, subName, method, urlPath, objOwner, objName)
+	e, hasReferences := getApiEnforcer()
+	decision, err := enforceApiPolicy(e, subOwner, []string{subName}, method, urlPath, objOwner, objName)
+	if err != nil || decision.IsAllowed || user == nil || !hasReferences {
+		return decision, err
+	}
+
+	// the roles and the groups are only queried when a policy references them
+	subjects, err := getApiSubjects(user)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	decision, err = enforceApiPolicy(e, subOwner, subjects, method, urlPath, objOwner, objName)
+	if err != nil {
+		return nil, err
 	}
 
-	return res
+	decision.Subjects = append([]string{subName}, decision.Subjects...)
+	return decision, nil
 }
 
 func isAllowedInDemoMode(subOwner string, subName string, method string, urlPath string, objOwner string, objName string) bool {
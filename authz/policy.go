@@ -0,0 +1,262 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casbin/casbin/v2"
+	"github.com/casdoor/casdoor/object"
+)
+
+const (
+	apiEnforcerId           = "built-in/api-enforcer-built-in"
+	apiPolicyReloadInterval = time.Minute
+
+	// ApiSubjectRolePrefix and ApiSubjectGroupPrefix reference the users of a role or a group as
+	// the subject of a policy, like role:built-in/support
+	ApiSubjectRolePrefix  = "role:"
+	ApiSubjectGroupPrefix = "group:"
+)
+
+const (
+	DecisionDemoMode          = "the request is not allowed in the demo mode"
+	DecisionApplication       = "the subject is an application"
+	DecisionOtherOrganization = "the object id belongs to another organization"
+	DecisionDeletedUser       = "the user is deleted"
+	DecisionAdmin             = "the user is an administrator of the organization of the object"
+	DecisionSelf              = "the user requests their own object"
+	DecisionPolicy            = "a policy allows the request"
+	DecisionNoPolicy          = "no policy allows the request"
+)
+
+// Enforcer enforces the API policies. It is replaced when the policies are reloaded and never
+// modified, the policies are edited in the database.
+var Enforcer *casbin.Enforcer
+
+var (
+	enforcerMutex    sync.RWMutex
+	hasApiReferences bool
+)
+
+// ApiPolicy is a policy of the API enforcer, it allows the subject of the organization to send
+// requests with the method to the URL path for the objects. The subject is a user name, * for
+// everyone, !anonymous for the signed-in users, or a role or a group with its prefix.
+type ApiPolicy struct {
+	Owner       string `json:"owner"`
+	Subject     string `json:"subject"`
+	Method      string `json:"method"`
+	UrlPath     string `json:"urlPath"`
+	ObjectOwner string `json:"objectOwner"`
+	ObjectName  string `json:"objectName"`
+}
+
+// Decision is the result of IsAllowed for a request, with the policy which allowed it if any
+type Decision struct {
+	IsAllowed bool     `json:"isAllowed"`
+	Reason    string   `json:"reason"`
+	Subjects  []string `json:"subjects"`
+	Subject   string   `json:"subject"`
+	Policy    []string `json:"policy"`
+}
+
+func newApiPolicy(rule []string) *ApiPolicy {
+	values := make([]string, 6)
+	copy(values, rule)
+	return &ApiPolicy{
+		Owner:       values[0],
+		Subject:     values[1],
+		Method:      values[2],
+		UrlPath:     values[3],
+		ObjectOwner: values[4],
+		ObjectName:  values[5],
+	}
+}
+
+func (policy *ApiPolicy) toRule() []string {
+	return []string{policy.Owner, policy.Subject, policy.Method, policy.UrlPath, policy.ObjectOwner, policy.ObjectName}
+}
+
+func isApiReference(subject string) bool {
+	return strings.HasPrefix(subject, ApiSubjectRolePrefix) || strings.HasPrefix(subject, ApiSubjectGroupPrefix)
+}
+
+func getApiEnforcer() (*casbin.Enforcer, bool) {
+	enforcerMutex.RLock()
+	defer enforcerMutex.RUnlock()
+
+	return Enforcer, hasApiReferences
+}
+
+func setApiEnforcer(e *casbin.Enforcer) {
+	hasReferences := false
+	for _, rule := range e.GetPolicy() {
+		if len(rule) > 1 && isApiReference(rule[1]) {
+			hasReferences = true
+			break
+		}
+	}
+
+	enforcerMutex.Lock()
+	defer enforcerMutex.Unlock()
+
+	Enforcer = e
+	hasApiReferences = hasReferences
+}
+
+// reloadApiEnforcer loads the policies of the database into a new enforcer
+func reloadApiEnforcer() error {
+	e, err := object.GetInitializedEnforcer(apiEnforcerId)
+	if err != nil {
+		return err
+	}
+
+	setApiEnforcer(e.Enforcer)
+	return nil
+}
+
+// RunApiPolicyReloadJob reloads the API policies in the background, to apply the ones edited by
+// the other instances or with the enforcer of the policies
+func RunApiPolicyReloadJob() {
+	for {
+		time.Sleep(apiPolicyReloadInterval)
+
+		err := reloadApiEnforcer()
+		if err != nil {
+			logs.Warning("failed to reload the API policies: %s", err.Error())
+		}
+	}
+}
+
+// getApiSubjects returns the subjects of the roles and the groups of the user
+func getApiSubjects(user *object.User) ([]string, error) {
+	roleIds, err := object.GetRoleIdsByUser(user.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	groupIds, err := object.GetGroupIdsByUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	subjects := []string{}
+	for _, roleId := range roleIds {
+		subjects = append(subjects, ApiSubjectRolePrefix+roleId)
+	}
+	for _, groupId := range groupIds {
+		subjects = append(subjects, ApiSubjectGroupPrefix+groupId)
+	}
+	return subjects, nil
+}
+
+// enforceApiPolicy enforces the request for each subject until a policy allows it
+func enforceApiPolicy(e *casbin.Enforcer, subOwner string, subjects []string, method string, urlPath string, objOwner string, objName string) (*Decision, error) {
+	for _, subject := range subjects {
+		res, explain, err := e.EnforceEx(subOwner, subject, method, urlPath, objOwner, objName)
+		if err != nil {
+			return nil, err
+		}
+
+		if res {
+			decision := &Decision{IsAllowed: true, Reason: DecisionPolicy, Subjects: subjects, Subject: subject, Policy: explain}
+			// the matcher allows the own objects of a user whatever the policy
+			if subOwner == objOwner && subject == objName {
+				decision.Reason, decision.Policy = DecisionSelf, nil
+			}
+			return decision, nil
+		}
+	}
+
+	return &Decision{Reason: DecisionNoPolicy, Subjects: subjects}, nil
+}
+
+// CheckApiPolicy returns an error if the policy is incomplete, or cannot be managed by the
+// administrators of the organization. They only manage the policies of their users on their
+// objects, an empty organization is a global administrator.
+func CheckApiPolicy(policy *ApiPolicy, organization string) error {
+	for _, value := range policy.toRule() {
+		if value == "" {
+			return fmt.Errorf("the API policy: %s should not have empty values, use * for any value", strings.Join(policy.toRule(), ", "))
+		}
+	}
+
+	if isApiReference(policy.Subject) {
+		id := strings.TrimPrefix(strings.TrimPrefix(policy.Subject, ApiSubjectRolePrefix), ApiSubjectGroupPrefix)
+		tokens := strings.Split(id, "/")
+		if len(tokens) != 2 || tokens[0] == "" || tokens[1] == "" {
+			return fmt.Errorf("the subject: %s of the API policy should be like role:<owner>/<name> or group:<owner>/<name>", policy.Subject)
+		}
+
+		if organization != "" && tokens[0] != organization {
+			return fmt.Errorf("the subject: %s of the API policy should belong to the organization: %s", policy.Subject, organization)
+		}
+	}
+
+	if organization != "" && (policy.Owner != organization || policy.ObjectOwner != organization) {
+		return fmt.Errorf("the owner and the object owner of the API policy should be the organization: %s", organization)
+	}
+
+	return nil
+}
+
+// GetApiPolicies returns the API policies of the organization, or all of them for an empty one
+func GetApiPolicies(organization string) []*ApiPolicy {
+	e, _ := getApiEnforcer()
+
+	var rules [][]string
+	if organization == "" {
+		rules = e.GetPolicy()
+	} else {
+		rules = e.GetFilteredPolicy(0, organization)
+	}
+
+	policies := []*ApiPolicy{}
+	for _, rule := range rules {
+		policies = append(policies, newApiPolicy(rule))
+	}
+	return policies
+}
+
+func AddApiPolicy(policy *ApiPolicy) (bool, error) {
+	affected, err := object.AddPolicy(apiEnforcerId, "p", policy.toRule())
+	if err != nil || !affected {
+		return affected, err
+	}
+
+	return true, reloadApiEnforcer()
+}
+
+func UpdateApiPolicy(oldPolicy *ApiPolicy, policy *ApiPolicy) (bool, error) {
+	affected, err := object.UpdatePolicy(apiEnforcerId, "p", oldPolicy.toRule(), policy.toRule())
+	if err != nil || !affected {
+		return affected, err
+	}
+
+	return true, reloadApiEnforcer()
+}
+
+func RemoveApiPolicy(policy *ApiPolicy) (bool, error) {
+	affected, err := object.RemovePolicy(apiEnforcerId, "p", policy.toRule())
+	if err != nil || !affected {
+		return affected, err
+	}
+
+	return true, reloadApiEnforcer()
+}
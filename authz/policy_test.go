@@ -0,0 +1,126 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// testApiModelText is the model of the built-in API enforcer
+const testApiModelText = `[request_definition]
+r = subOwner, subName, method, urlPath, objOwner, objName
+
+[policy_definition]
+p = subOwner, subName, method, urlPath, objOwner, objName
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = (r.subOwner == p.subOwner || p.subOwner == "*") && \
+    (r.subName == p.subName || p.subName == "*" || r.subName != "anonymous" && p.subName == "!anonymous") && \
+    (r.method == p.method || p.method == "*") && \
+    (r.urlPath == p.urlPath || p.urlPath == "*") && \
+    (r.objOwner == p.objOwner || p.objOwner == "*") && \
+    (r.objName == p.objName || p.objName == "*") || \
+    (r.subOwner == r.objOwner && r.subName == r.objName)`
+
+func TestCheckApiPolicy(t *testing.T) {
+	policy := &ApiPolicy{Owner: "org", Subject: "role:org/support", Method: "GET", UrlPath: "/api/get-users", ObjectOwner: "org", ObjectName: "*"}
+	assert.Nil(t, CheckApiPolicy(policy, "org"))
+	assert.Nil(t, CheckApiPolicy(policy, ""))
+	assert.NotNil(t, CheckApiPolicy(policy, "other"))
+
+	policy.Subject = "group:other/support"
+	assert.NotNil(t, CheckApiPolicy(policy, "org"))
+	assert.Nil(t, CheckApiPolicy(policy, ""))
+
+	policy.Subject = "role:support"
+	assert.NotNil(t, CheckApiPolicy(policy, ""))
+
+	// the administrators of an organization cannot grant access to the objects of the others
+	policy.Subject, policy.ObjectOwner = "*", "*"
+	assert.NotNil(t, CheckApiPolicy(policy, "org"))
+
+	policy.ObjectName = ""
+	assert.NotNil(t, CheckApiPolicy(policy, ""))
+}
+
+func TestEnforceApiPolicy(t *testing.T) {
+	m, err := model.NewModelFromString(testApiModelText)
+	assert.Nil(t, err)
+	e, err := casbin.NewEnforcer(m)
+	assert.Nil(t, err)
+
+	_, err = e.AddPolicies([][]string{
+		{"*", "!anonymous", "GET", "/api/get-account", "*", "*"},
+		{"org", "role:org/support", "GET", "/api/get-users", "org", "*"},
+	})
+	assert.Nil(t, err)
+
+	decision, err := enforceApiPolicy(e, "org", []string{"alice"}, "GET", "/api/get-users", "org", "")
+	assert.Nil(t, err)
+	assert.False(t, decision.IsAllowed)
+	assert.Equal(t, DecisionNoPolicy, decision.Reason)
+
+	decision, err = enforceApiPolicy(e, "org", []string{"group:org/staff", "role:org/support"}, "GET", "/api/get-users", "org", "")
+	assert.Nil(t, err)
+	assert.True(t, decision.IsAllowed)
+	assert.Equal(t, DecisionPolicy, decision.Reason)
+	assert.Equal(t, "role:org/support", decision.Subject)
+	assert.Equal(t, []string{"org", "role:org/support", "GET", "/api/get-users", "org", "*"}, decision.Policy)
+
+	decision, err = enforceApiPolicy(e, "other", []string{"role:org/support"}, "GET", "/api/get-users", "org", "")
+	assert.Nil(t, err)
+	assert.False(t, decision.IsAllowed)
+
+	decision, err = enforceApiPolicy(e, "org", []string{"alice"}, "POST", "/api/update-user", "org", "alice")
+	assert.Nil(t, err)
+	assert.True(t, decision.IsAllowed)
+	assert.Equal(t, DecisionSelf, decision.Reason)
+	assert.Nil(t, decision.Policy)
+}
+
+func TestDefaultApiPolicyVersions(t *testing.T) {
+	m, err := model.NewModelFromString(testApiModelText)
+	assert.Nil(t, err)
+
+	// a policy is added by one version only, the next ones would add it back once removed
+	ruleVersions := map[string]int{}
+	for i, ruleText := range defaultApiPolicyVersions {
+		rules, err := getDefaultApiRules(m, ruleText)
+		assert.Nil(t, err)
+		assert.NotEmpty(t, rules)
+
+		for _, rule := range rules {
+			key := strings.Join(rule, ", ")
+			version, ok := ruleVersions[key]
+			assert.False(t, ok, "the policy: %s of version %d is already added by version %d", key, i+1, version)
+			ruleVersions[key] = i + 1
+		}
+	}
+
+	assert.Empty(t, m.GetPolicy("p", "p"))
+	assert.Equal(t, 2, ruleVersions["*, !anonymous, GET, /api/get-device-auth, *, *"])
+	assert.Equal(t, 2, ruleVersions["*, *, *, /scim, *, *"])
+}
@@ -41,6 +41,9 @@ type Response struct {
 	Name   string      `json:"name"`
 	Data   interface{} `json:"data"`
 	Data2  interface{} `json:"data2"`
+
+	// FrontChannelLogoutUrls are the URLs that the browser loads after /api/logout to sign out of the applications
+	FrontChannelLogoutUrls []string `json:"frontChannelLogoutUrls,omitempty"`
 }
 
 type Captcha struct {
@@ -297,6 +300,7 @@ func (c *ApiController) Signup() {
 // @Tag Login API
 // @Description logout the current user
 // @Param   id_token_hint   query        string  false        "id_token_hint"
+// @Param   client_id   query        string  false        "client_id"
 // @Param   post_logout_redirect_uri    query    string  false     "post_logout_redirect_uri"
 // @Param   state     query    string  false     "state"
 // @Success 200 {object} controllers.Response The Response object
@@ -304,6 +308,7 @@ func (c *ApiController) Signup() {
 func (c *ApiController) Logout() {
 	// https://openid.net/specs/openid-connect-rpinitiated-1_0-final.html
 	accessToken := c.Input().Get("id_token_hint")
+	clientId := c.Input().Get("client_id")
 	redirectUri := c.Input().Get("post_logout_redirect_uri")
 	state := c.Input().Get("state")
 
@@ -320,6 +325,9 @@ func (c *ApiController) Logout() {
 			return
 		}
 
+		sid := c.getSid(user)
+		application := c.GetSessionApplication()
+
 		c.ClearUserSession()
 		owner, username := util.GetOwnerAndNameFromId(user)
 		_, err := object.DeleteSessionId(util.GetSessionId(owner, username, object.CasdoorApplication), c.Ctx.Input.CruSession.SessionID())
@@ -330,16 +338,25 @@ func (c *ApiController) Logout() {
 			return
 		}
 
+		frontChannelLogoutUrls, err := object.LogoutSession(user, sid, c.Ctx.Request.Host)
+		if err != nil {
+			record.AddReason(fmt.Sprintf("Logout error: %s", err.Error()))
+
+			c.ResponseError(err.Error())
+			return
+		}
+
 		util.LogInfo(c.Ctx, "API: [%s] logged out", user)
 
-		application := c.GetSessionApplication()
+		resp := &Response{Status: "ok", Data: user, FrontChannelLogoutUrls: frontChannelLogoutUrls}
 		if application == nil || application.Name == "app-built-in" || application.HomepageUrl == "" {
 			record.AddReason("Logout error: application mismatch")
-
-			c.ResponseOk(user)
-			return
+		} else {
+			resp.Data2 = application.HomepageUrl
 		}
-		c.ResponseOk(user, application.HomepageUrl)
+
+		c.Data["json"] = resp
+		c.ServeJSON()
 		return
 	} else {
 		// "post_logout_redirect_uri" has been made optional, see: https://github.com/casdoor/casdoor/issues/2151
@@ -376,8 +393,29 @@ func (c *ApiController) Logout() {
 			return
 		}
 
+		if clientId != "" && clientId != application.ClientId {
+			record.AddReason(fmt.Sprintf("Logout error: wrong client_id: %s", clientId))
+
+			c.ResponseError(c.T("token:Invalid client_id"))
+			return
+		}
+
+		if redirectUri != "" && !application.IsRedirectUriValid(redirectUri) {
+			record.AddReason(fmt.Sprintf("Logout error: wrong redirect URI: %s", redirectUri))
+
+			c.ResponseError(fmt.Sprintf(c.T("token:Redirect URI: %s doesn't exist in the allowed Redirect URI list"), redirectUri))
+			return
+		}
+
+		// the sid of the ID token identifies the session to end even if the browser is no longer signed in
+		tokenUserId := util.GetId(token.Organization, token.User)
+		sid := object.GetTokenSid(accessToken)
+		if sid == "" && user == tokenUserId {
+			sid = c.getSid(user)
+		}
+
 		if user == "" {
-			user = util.GetId(token.Organization, token.User)
+			user = tokenUserId
 		}
 
 		c.ClearUserSession()
@@ -392,20 +430,26 @@ func (c *ApiController) Logout() {
 			return
 		}
 
+		frontChannelLogoutUrls, err := object.LogoutSession(tokenUserId, sid, c.Ctx.Request.Host)
+		if err != nil {
+			record.AddReason(fmt.Sprintf("Logout error: %s", err.Error()))
+
+			c.ResponseError(err.Error())
+			return
+		}
+
 		util.LogInfo(c.Ctx, "API: [%s] logged out", user)
 
-		if redirectUri == "" {
+		if redirectUri != "" {
+			redirectUri = fmt.Sprintf("%s?state=%s", strings.TrimRight(redirectUri, "/"), state)
+		}
+
+		if len(frontChannelLogoutUrls) != 0 {
+			c.renderFrontChannelLogout(frontChannelLogoutUrls, redirectUri)
+		} else if redirectUri == "" {
 			c.ResponseOk()
-			return
 		} else {
-			if application.IsRedirectUriValid(redirectUri) {
-				c.Ctx.Redirect(http.StatusFound, fmt.Sprintf("%s?state=%s", strings.TrimRight(redirectUri, "/"), state))
-			} else {
-				record.AddReason(fmt.Sprintf("Logout error: wrong redirect URI: %s", redirectUri))
-
-				c.ResponseError(fmt.Sprintf(c.T("token:Redirect URI: %s doesn't exist in the allowed Redirect URI list"), redirectUri))
-				return
-			}
+			c.Ctx.Redirect(http.StatusFound, redirectUri)
 		}
 	}
 }
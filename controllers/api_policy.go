@@ -0,0 +1,224 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+
+	"github.com/casdoor/casdoor/authz"
+	"github.com/casdoor/casdoor/util"
+)
+
+// apiPolicyUpdate is the body of UpdateApiPolicy, the owner lets the API filter authorize the
+// administrators of the organization
+type apiPolicyUpdate struct {
+	Owner     string           `json:"owner"`
+	OldPolicy *authz.ApiPolicy `json:"oldPolicy"`
+	Policy    *authz.ApiPolicy `json:"policy"`
+}
+
+// getApiPolicyOrganization returns the organization whose API policies are managed, empty for all
+// of them. A global admin can manage any organization and an organization admin only their own.
+func (c *ApiController) getApiPolicyOrganization(owner string) (string, bool) {
+	if c.IsGlobalAdmin() {
+		return owner, true
+	}
+
+	user, ok := c.RequireSignedInUser()
+	if !ok {
+		c.ResponseUnauthorized(c.T("auth:Unauthorized operation"))
+		return "", false
+	}
+
+	if !user.IsAdmin {
+		c.ResponseForbidden(c.T("auth:Forbidden operation"))
+		return "", false
+	}
+
+	if owner != "" && owner != user.Owner {
+		c.ResponseForbidden(c.T("auth:Unable to manage the API policies of other organization without global administrator role"))
+		return "", false
+	}
+
+	return user.Owner, true
+}
+
+// GetApiPolicies
+// @Title GetApiPolicies
+// @Tag API Policy API
+// @Description get the API policies of an organization, all of them for a global admin without owner
+// @Param   owner     query    string  false       "The organization"
+// @Success 200 {array} authz.ApiPolicy The Response object
+// @router /get-api-policies [get]
+func (c *ApiController) GetApiPolicies() {
+	organization, ok := c.getApiPolicyOrganization(c.Input().Get("owner"))
+	if !ok {
+		return
+	}
+
+	c.ResponseOk(authz.GetApiPolicies(organization))
+}
+
+// AddApiPolicy
+// @Title AddApiPolicy
+// @Tag API Policy API
+// @Description add an API policy, the subject can be a role or a group like role:built-in/support
+// @Param   body    body   authz.ApiPolicy  true        "The policy"
+// @Success 200 {object} controllers.Response The Response object
+// @router /add-api-policy [post]
+func (c *ApiController) AddApiPolicy() {
+	var policy authz.ApiPolicy
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &policy)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	organization, ok := c.getApiPolicyOrganization(policy.Owner)
+	if !ok {
+		return
+	}
+
+	err = authz.CheckApiPolicy(&policy, organization)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(authz.AddApiPolicy(&policy))
+	c.ServeJSON()
+}
+
+// UpdateApiPolicy
+// @Title UpdateApiPolicy
+// @Tag API Policy API
+// @Description replace an API policy
+// @Param   body    body   controllers.apiPolicyUpdate  true        "The owner, the old policy and the policy"
+// @Success 200 {object} controllers.Response The Response object
+// @router /update-api-policy [post]
+func (c *ApiController) UpdateApiPolicy() {
+	var update apiPolicyUpdate
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &update)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if update.OldPolicy == nil || update.Policy == nil {
+		c.ResponseError(c.T("general:Missing parameter"))
+		return
+	}
+
+	organization, ok := c.getApiPolicyOrganization(update.Owner)
+	if !ok {
+		return
+	}
+
+	for _, policy := range []*authz.ApiPolicy{update.OldPolicy, update.Policy} {
+		err = authz.CheckApiPolicy(policy, organization)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+	}
+
+	c.Data["json"] = wrapActionResponse(authz.UpdateApiPolicy(update.OldPolicy, update.Policy))
+	c.ServeJSON()
+}
+
+// RemoveApiPolicy
+// @Title RemoveApiPolicy
+// @Tag API Policy API
+// @Description remove an API policy
+// @Param   body    body   authz.ApiPolicy  true        "The policy"
+// @Success 200 {object} controllers.Response The Response object
+// @router /remove-api-policy [post]
+func (c *ApiController) RemoveApiPolicy() {
+	var policy authz.ApiPolicy
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &policy)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	organization, ok := c.getApiPolicyOrganization(policy.Owner)
+	if !ok {
+		return
+	}
+
+	err = authz.CheckApiPolicy(&policy, organization)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(authz.RemoveApiPolicy(&policy))
+	c.ServeJSON()
+}
+
+// ExplainApiPolicy
+// @Title ExplainApiPolicy
+// @Tag API Policy API
+// @Description dry-run a request against the API policies and explain why it is allowed or denied
+// @Param   owner     query    string  false       "The organization of the subject"
+// @Param   subject     query    string  true        "The id of the user, like built-in/alice, or anonymous"
+// @Param   method     query    string  true        "The method of the request, like GET"
+// @Param   urlPath     query    string  true        "The URL path of the request, like /api/get-users"
+// @Param   objectOwner     query    string  false       "The owner of the object of the request"
+// @Param   objectName     query    string  false       "The name of the object of the request"
+// @Param   objectId     query    string  false       "The id query parameter of the request"
+// @Success 200 {object} authz.Decision The Response object
+// @router /explain-api-policy [get]
+func (c *ApiController) ExplainApiPolicy() {
+	subject := c.Input().Get("subject")
+	method := c.Input().Get("method")
+	urlPath := c.Input().Get("urlPath")
+	objectOwner := c.Input().Get("objectOwner")
+	objectName := c.Input().Get("objectName")
+	objectId := c.Input().Get("objectId")
+
+	if subject == "" || method == "" || urlPath == "" {
+		c.ResponseError(c.T("general:Missing parameter"))
+		return
+	}
+
+	subOwner, subName := "anonymous", "anonymous"
+	if subject != "anonymous" {
+		var err error
+		subOwner, subName, err = util.GetOwnerAndNameFromIdWithError(subject)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+	}
+
+	organization, ok := c.getApiPolicyOrganization(c.Input().Get("owner"))
+	if !ok {
+		return
+	}
+
+	if organization != "" && subOwner != organization && subOwner != "anonymous" {
+		c.ResponseForbidden(c.T("auth:Unable to manage the API policies of other organization without global administrator role"))
+		return
+	}
+
+	decision, err := authz.Explain(subOwner, subName, method, urlPath, objectOwner, objectName, objectId)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(decision)
+}
@@ -220,6 +220,18 @@ func (c *ApiController) UpdateApplication() {
 		return
 	}
 
+	err = object.CheckScopes(application.Scopes)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	err = object.CheckSamlSettings(&application)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
 	c.Data["json"] = wrapActionResponse(object.UpdateApplication(goCtx, id, &application))
 	c.ServeJSON()
 }
@@ -239,6 +251,18 @@ func (c *ApiController) AddApplication() {
 		return
 	}
 
+	err = object.CheckScopes(application.Scopes)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	err = object.CheckSamlSettings(&application)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
 	count, err := object.GetApplicationCount("", "", "")
 	if err != nil {
 		c.ResponseError(err.Error())
@@ -0,0 +1,176 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/beego/beego/utils/pagination"
+	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
+)
+
+// getAuditOrganization returns the organization whose audit log is requested, a global admin can
+// request any organization and an organization admin only their own
+func (c *ApiController) getAuditOrganization(organization string) (string, bool) {
+	if c.IsGlobalAdmin() {
+		if organization == "" {
+			c.ResponseError(c.T("general:Missing parameter"))
+			return "", false
+		}
+		return organization, true
+	}
+
+	user, ok := c.RequireSignedInUser()
+	if !ok {
+		c.ResponseUnauthorized(c.T("auth:Unauthorized operation"))
+		return "", false
+	}
+
+	if !user.IsAdmin {
+		c.ResponseForbidden(c.T("auth:Forbidden operation"))
+		return "", false
+	}
+
+	if organization != "" && organization != user.Owner {
+		c.ResponseForbidden(c.T("auth:Unable to access the audit log of other organization without global administrator role"))
+		return "", false
+	}
+
+	return user.Owner, true
+}
+
+// GetAuditEntries
+// @Title GetAuditEntries
+// @Tag Audit API
+// @Description get the audit log entries of an organization, the latest first
+// @Param   organization     query    string  false       "The organization, the one of the user by default"
+// @Param   pageSize     query    string  false       "The size of each page"
+// @Param   p     query    string  false       "The number of the page"
+// @Success 200 {array} object.AuditEntry The Response object
+// @router /get-audit-entries [get]
+func (c *ApiController) GetAuditEntries() {
+	pageSize := c.Input().Get("pageSize")
+	pageNumber := c.Input().Get("p")
+
+	organization, ok := c.getAuditOrganization(c.Input().Get("organization"))
+	if !ok {
+		return
+	}
+
+	if pageSize == "" || pageNumber == "" {
+		entries, err := object.GetAuditEntries(organization, -1, -1)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		c.ResponseOk(entries)
+	} else {
+		limit := util.ParseInt(pageSize)
+		count, err := object.GetAuditEntryCount(organization)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		paginator := pagination.SetPaginator(c.Ctx, limit, count)
+		entries, err := object.GetAuditEntries(organization, paginator.Offset(), limit)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		c.ResponseOk(entries, paginator.Nums())
+	}
+}
+
+// VerifyAuditChain
+// @Title VerifyAuditChain
+// @Tag Audit API
+// @Description verify the hash chain of the audit log of an organization and report its gaps and breaks
+// @Param   organization     query    string  false       "The organization, the one of the user by default"
+// @Success 200 {object} object.AuditVerification The Response object
+// @router /verify-audit-chain [get]
+func (c *ApiController) VerifyAuditChain() {
+	organization, ok := c.getAuditOrganization(c.Input().Get("organization"))
+	if !ok {
+		return
+	}
+
+	verification, err := object.VerifyAuditChain(organization)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(verification)
+}
+
+// PurgeAuditEntries
+// @Title PurgeAuditEntries
+// @Tag Audit API
+// @Description delete the audit log entries and the records of an organization created before a time
+// @Param   organization     query    string  false       "The organization, the one of the user by default"
+// @Param   before     query    string  true        "The time in the RFC 3339 format, like 2024-01-01T00:00:00Z"
+// @Success 200 {object} controllers.Response The Response object
+// @router /purge-audit-entries [post]
+func (c *ApiController) PurgeAuditEntries() {
+	before := c.Input().Get("before")
+
+	organization, ok := c.getAuditOrganization(c.Input().Get("organization"))
+	if !ok {
+		return
+	}
+
+	beforeTime, err := time.Parse(time.RFC3339, before)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	affected, err := object.PurgeAuditEntries(organization, beforeTime.UTC().Format(time.RFC3339))
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(affected)
+}
+
+// ExportAuditEntries
+// @Title ExportAuditEntries
+// @Tag Audit API
+// @Description export the audit log entries of an organization as JSON lines in the order of the chain
+// @Param   organization     query    string  false       "The organization, the one of the user by default"
+// @Success 200 {string} string "The entries as JSON lines"
+// @router /export-audit-entries [get]
+func (c *ApiController) ExportAuditEntries() {
+	organization, ok := c.getAuditOrganization(c.Input().Get("organization"))
+	if !ok {
+		return
+	}
+
+	c.Ctx.Output.Header("Content-Type", "application/x-ndjson")
+	c.Ctx.Output.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"audit-%s.jsonl\"", organization))
+
+	// the response has started, an error can only end it early
+	err := object.WriteAuditEntries(organization, c.Ctx.ResponseWriter)
+	if err != nil {
+		logs.Error("failed to export the audit entries of the organization: %s: %s", organization, err.Error())
+	}
+}
@@ -134,25 +134,44 @@ func (c *ApiController) HandleLoggedIn(application *object.Application, user *ob
 		util.LogInfo(c.Ctx, "API: [%s] signed in", userId)
 		resp = &Response{Status: "ok", Msg: "", Data: userId}
 	} else if form.Type == ResponseTypeCode {
-		clientId := c.Input().Get("clientId")
-		responseType := c.Input().Get("responseType")
-		redirectUri := c.Input().Get("redirectUri")
-		scope := c.Input().Get("scope")
-		state := c.Input().Get("state")
-		nonce := c.Input().Get("nonce")
-		challengeMethod := c.Input().Get("code_challenge_method")
-		codeChallenge := c.Input().Get("code_challenge")
+		authorizeRequest, msg, err := c.getAuthorizeRequest()
+		if err != nil {
+			c.ResponseError(err.Error(), nil)
+			return
+		}
+		if msg != "" {
+			c.ResponseError(msg)
+			return
+		}
+
+		consentResp, err := c.getConsentResponse(user, authorizeRequest, form)
+		if err != nil {
+			c.ResponseError(err.Error(), nil)
+			return
+		}
+		if consentResp != nil {
+			return consentResp
+		}
 
+		challengeMethod := authorizeRequest.CodeChallengeMethod
 		if challengeMethod != "S256" && challengeMethod != "null" && challengeMethod != "" {
 			c.ResponseError(c.T("auth:Challenge method should be S256"))
 			return
 		}
-		code, err := object.GetOAuthCode(userId, clientId, responseType, redirectUri, scope, state, nonce, codeChallenge, c.Ctx.Request.Host, sid, c.GetAcceptLanguage())
+		code, err := object.GetOAuthCode(userId, authorizeRequest.ClientId, authorizeRequest.ResponseType, authorizeRequest.RedirectUri, authorizeRequest.Scope, authorizeRequest.State, authorizeRequest.Nonce, authorizeRequest.CodeChallenge, c.Ctx.Request.Host, sid, c.GetAcceptLanguage())
 		if err != nil {
 			c.ResponseError(err.Error(), nil)
 			return
 		}
 
+		if code.Code != "" {
+			err = object.DeletePushedAuthRequest(c.Input().Get("requestUri"))
+			if err != nil {
+				c.ResponseError(err.Error(), nil)
+				return
+			}
+		}
+
 		resp = codeToResponse(code)
 
 		if application.EnableSigninSession || application.HasPromptPage() {
@@ -163,12 +182,30 @@ func (c *ApiController) HandleLoggedIn(application *object.Application, user *ob
 		if !object.IsGrantTypeValid(form.Type, application.GrantTypes) {
 			resp = &Response{Status: "error", Msg: fmt.Sprintf("error: grant_type: %s is not supported in this application", form.Type), Data: ""}
 		} else {
-			scope := c.Input().Get("scope")
-			token, _ := object.GetTokenByUser(application, user, scope, c.Ctx.Request.Host, sid)
+			authorizeRequest, msg, err := c.getAuthorizeRequest()
+			if err != nil {
+				c.ResponseError(err.Error(), nil)
+				return
+			}
+			if msg != "" {
+				c.ResponseError(msg)
+				return
+			}
+
+			consentResp, err := c.getConsentResponse(user, authorizeRequest, form)
+			if err != nil {
+				c.ResponseError(err.Error(), nil)
+				return
+			}
+			if consentResp != nil {
+				return consentResp
+			}
+
+			token, _ := object.GetTokenByUser(application, user, authorizeRequest.Scope, c.Ctx.Request.Host, sid)
 			resp = tokenToResponse(token)
 		}
 	} else if form.Type == ResponseTypeSaml { // saml flow
-		res, redirectUrl, method, err := object.GetSamlResponse(application, user, form.SamlRequest, c.Ctx.Request.Host)
+		res, redirectUrl, method, err := object.GetSamlResponse(application, user, form.SamlRequest, form.RelayState, form.SamlQuery, c.Ctx.Request.Host, sid)
 		if err != nil {
 			c.ResponseError(err.Error(), nil)
 			return
@@ -184,7 +221,7 @@ func (c *ApiController) HandleLoggedIn(application *object.Application, user *ob
 		service := c.Input().Get("service")
 		resp = wrapErrorResponse(nil)
 		if service != "" {
-			st, err := object.GenerateCasToken(userId, service)
+			st, err := object.GenerateCasToken(userId, service, sid)
 			if err != nil {
 				resp = wrapErrorResponse(err)
 			} else {
@@ -216,6 +253,8 @@ func (c *ApiController) HandleLoggedIn(application *object.Application, user *ob
 			c.ResponseError(err.Error(), nil)
 			return
 		}
+
+		object.EmitLoginSucceededEvent(user, application, form.Type)
 	}
 
 	return resp
@@ -233,23 +272,28 @@ func (c *ApiController) HandleLoggedIn(application *object.Application, user *ob
 // @Success 200 {object}  Response The Response object
 // @router /get-app-login [get]
 func (c *ApiController) GetApplicationLogin() {
-	clientId := c.Input().Get("clientId")
-	responseType := c.Input().Get("responseType")
 	redirectUri := c.Input().Get("redirectUri")
-	scope := c.Input().Get("scope")
-	state := c.Input().Get("state")
 	id := c.Input().Get("id")
 	loginType := c.Input().Get("type")
 
 	var application *object.Application
+	var authorizeRequest *object.AuthorizeRequest
 	var msg string
 	var err error
 	if loginType == "code" {
-		msg, application, err = object.CheckOAuthLogin(clientId, responseType, redirectUri, scope, state, c.GetAcceptLanguage())
+		authorizeRequest, msg, err = c.getAuthorizeRequest()
 		if err != nil {
 			c.ResponseInternalServerError("internal server error")
 			return
 		}
+
+		if msg == "" {
+			msg, application, err = object.CheckOAuthLogin(authorizeRequest.ClientId, authorizeRequest.ResponseType, authorizeRequest.RedirectUri, authorizeRequest.Scope, authorizeRequest.State, c.GetAcceptLanguage())
+			if err != nil {
+				c.ResponseInternalServerError("internal server error")
+				return
+			}
+		}
 	} else if loginType == "cas" {
 		application, err = object.GetApplication(id)
 		if err != nil {
@@ -271,11 +315,32 @@ func (c *ApiController) GetApplicationLogin() {
 	application = object.GetMaskedApplication(application, "")
 	if msg != "" {
 		c.ResponseError(msg, application)
+	} else if authorizeRequest != nil {
+		// the frontend continues the flow with the parameters of the request object or the pushed request
+		c.ResponseOk(application, authorizeRequest)
 	} else {
 		c.ResponseOk(application)
 	}
 }
 
+// getAuthorizeRequest returns the parameters of the authorization request that the frontend
+// passes to /api/get-app-login and /api/login, resolving its request or requestUri parameter
+func (c *ApiController) getAuthorizeRequest() (*object.AuthorizeRequest, string, error) {
+	authorizeRequest := &object.AuthorizeRequest{
+		ClientId:            c.Input().Get("clientId"),
+		ResponseType:        c.Input().Get("responseType"),
+		RedirectUri:         c.Input().Get("redirectUri"),
+		Scope:               c.Input().Get("scope"),
+		State:               c.Input().Get("state"),
+		Nonce:               c.Input().Get("nonce"),
+		CodeChallengeMethod: c.Input().Get("code_challenge_method"),
+		CodeChallenge:       c.Input().Get("code_challenge"),
+		Prompt:              c.Input().Get("prompt"),
+	}
+
+	return object.ResolveAuthorizeRequest(authorizeRequest, c.Input().Get("request"), c.Input().Get("requestUri"), c.Ctx.Request.Host, c.GetAcceptLanguage())
+}
+
 func setHttpClient(idProvider idp.IdProvider, providerInfo idp.ProviderInfo) error {
 	if isProxyProviderType(providerInfo.Type) {
 		idProvider.SetHttpClient(proxy.ProxyHttpClient)
@@ -48,7 +48,12 @@ func (c *RootController) CasValidate() {
 		c.Ctx.Output.Body([]byte("no\n"))
 		return
 	}
-	if ok, response, issuedService, _ := object.GetCasTokenByTicket(ticket); ok {
+	ok, response, issuedService, _, err := object.GetCasTokenByTicket(ticket)
+	if err != nil {
+		c.Ctx.Output.Body([]byte("no\n"))
+		return
+	}
+	if ok {
 		// check whether service is the one for which we previously issued token
 		if issuedService == service {
 			c.Ctx.Output.Body([]byte(fmt.Sprintf("yes\n%s\n", response.User)))
@@ -98,7 +103,11 @@ func (c *RootController) CasP3ProxyValidate() {
 		c.sendCasAuthenticationResponseErr(InvalidRequest, "service and ticket must exist", format)
 		return
 	}
-	ok, response, issuedService, userId := object.GetCasTokenByTicket(ticket)
+	ok, response, issuedService, userId, err := object.GetCasTokenByTicket(ticket)
+	if err != nil {
+		c.sendCasAuthenticationResponseErr(InternalError, err.Error(), format)
+		return
+	}
 	// find the token
 	if ok {
 		// check whether service is the one for which we previously issued token
@@ -117,7 +126,11 @@ func (c *RootController) CasP3ProxyValidate() {
 
 	if pgtUrl != "" && serviceResponse.Failure == nil {
 		// that means we are in proxy web flow
-		pgt := object.StoreCasTokenForPgt(serviceResponse.Success, service, userId)
+		pgt, err := object.StoreCasTokenForPgt(serviceResponse.Success, service, userId)
+		if err != nil {
+			c.sendCasAuthenticationResponseErr(InternalError, err.Error(), format)
+			return
+		}
 		pgtiou := serviceResponse.Success.ProxyGrantingTicket
 		// todo: check whether it is https
 		pgtUrlObj, err := url.Parse(pgtUrl)
@@ -169,7 +182,11 @@ func (c *RootController) CasProxy() {
 		return
 	}
 
-	ok, authenticationSuccess, issuedService, userId := object.GetCasTokenByPgt(pgt)
+	ok, authenticationSuccess, issuedService, userId, err := object.GetCasTokenByPgt(pgt)
+	if err != nil {
+		c.sendCasProxyResponseErr(InternalError, err.Error(), format)
+		return
+	}
 	if !ok {
 		c.sendCasProxyResponseErr(UnauthorizedService, "service not authorized", format)
 		return
@@ -180,7 +197,11 @@ func (c *RootController) CasProxy() {
 		newAuthenticationSuccess.Proxies = &object.CasProxies{}
 	}
 	newAuthenticationSuccess.Proxies.Proxies = append(newAuthenticationSuccess.Proxies.Proxies, issuedService)
-	proxyTicket := object.StoreCasTokenForProxyTicket(&newAuthenticationSuccess, targetService, userId)
+	proxyTicket, err := object.StoreCasTokenForProxyTicket(&newAuthenticationSuccess, targetService, userId)
+	if err != nil {
+		c.sendCasProxyResponseErr(InternalError, err.Error(), format)
+		return
+	}
 
 	serviceResponse := object.CasServiceResponse{
 		Xmlns: "http://www.yale.edu/tp/cas",
@@ -0,0 +1,169 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/casdoor/casdoor/object"
+)
+
+func (c *ApiController) getBearerToken() string {
+	header := c.Ctx.Request.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return ""
+	}
+
+	return strings.TrimPrefix(header, "Bearer ")
+}
+
+// getClientMetadata parses the client metadata of the request body, it responds with
+// invalid_client_metadata and returns false if the body is malformed
+func (c *ApiController) getClientMetadata() (*object.ClientMetadata, bool) {
+	var metadata object.ClientMetadata
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &metadata)
+	if err != nil {
+		c.serveClientRegistrationResponse(&object.TokenError{
+			Error:            object.InvalidClientMetadata,
+			ErrorDescription: err.Error(),
+		}, http.StatusOK)
+		return nil, false
+	}
+
+	return &metadata, true
+}
+
+// serveClientRegistrationResponse responds with the given status on success, or with the
+// status of the error defined by RFC 7591 section 3.2.2 and RFC 7592 section 2
+func (c *ApiController) serveClientRegistrationResponse(res interface{}, status int) {
+	if tokenError, ok := res.(*object.TokenError); ok {
+		if tokenError.Error == object.InvalidToken {
+			status = http.StatusUnauthorized
+			c.Ctx.Output.Header("WWW-Authenticate", "Bearer error=\"invalid_token\"")
+		} else {
+			status = http.StatusBadRequest
+		}
+	}
+
+	c.Ctx.Output.Header("Cache-Control", "no-store")
+	c.Ctx.Output.SetStatus(status)
+	c.Data["json"] = res
+	c.ServeJSON()
+}
+
+// RegisterClient
+// @Title RegisterClient
+// @Tag Client Registration API
+// @Description register a client with an initial access token of its organization (RFC 7591)
+// @Param   Authorization  header   string  true  "Bearer <initial access token>"
+// @Param   body    body   object.ClientMetadata  true        "The metadata of the client"
+// @Success 201 {object} object.ClientInformation The Response object
+// @router /login/oauth/register [post]
+func (c *ApiController) RegisterClient() {
+	metadata, ok := c.getClientMetadata()
+	if !ok {
+		return
+	}
+
+	res, err := object.RegisterClient(c.getBearerToken(), metadata, c.Ctx.Request.Host)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.serveClientRegistrationResponse(res, http.StatusCreated)
+}
+
+// GetRegisteredClient
+// @Title GetRegisteredClient
+// @Tag Client Registration API
+// @Description read the configuration of a client registered dynamically (RFC 7592)
+// @Param   Authorization  header   string  true  "Bearer <registration access token>"
+// @Param   client_id     query    string  true        "The client_id of the client"
+// @Success 200 {object} object.ClientInformation The Response object
+// @router /login/oauth/register [get]
+func (c *ApiController) GetRegisteredClient() {
+	res, err := object.GetRegisteredClient(c.Input().Get("client_id"), c.getBearerToken(), c.Ctx.Request.Host)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.serveClientRegistrationResponse(res, http.StatusOK)
+}
+
+// UpdateRegisteredClient
+// @Title UpdateRegisteredClient
+// @Tag Client Registration API
+// @Description replace the metadata of a client registered dynamically (RFC 7592)
+// @Param   Authorization  header   string  true  "Bearer <registration access token>"
+// @Param   client_id     query    string  true        "The client_id of the client"
+// @Param   body    body   object.ClientInformation  true        "The metadata of the client"
+// @Success 200 {object} object.ClientInformation The Response object
+// @router /login/oauth/register [put]
+func (c *ApiController) UpdateRegisteredClient() {
+	clientId := c.Input().Get("client_id")
+
+	var clientInformation object.ClientInformation
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &clientInformation)
+	if err != nil {
+		c.serveClientRegistrationResponse(&object.TokenError{
+			Error:            object.InvalidClientMetadata,
+			ErrorDescription: err.Error(),
+		}, http.StatusOK)
+		return
+	}
+
+	if clientInformation.ClientId != clientId {
+		c.serveClientRegistrationResponse(&object.TokenError{
+			Error:            object.InvalidClientMetadata,
+			ErrorDescription: "the client_id of the request body does not match the client",
+		}, http.StatusOK)
+		return
+	}
+
+	res, err := object.UpdateRegisteredClient(clientId, c.getBearerToken(), clientInformation.ClientSecret, &clientInformation.ClientMetadata, c.Ctx.Request.Host)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.serveClientRegistrationResponse(res, http.StatusOK)
+}
+
+// DeleteRegisteredClient
+// @Title DeleteRegisteredClient
+// @Tag Client Registration API
+// @Description delete a client registered dynamically (RFC 7592)
+// @Param   Authorization  header   string  true  "Bearer <registration access token>"
+// @Param   client_id     query    string  true        "The client_id of the client"
+// @Success 204 The client has been deleted
+// @router /login/oauth/register [delete]
+func (c *ApiController) DeleteRegisteredClient() {
+	tokenError, err := object.DeleteRegisteredClient(c.Input().Get("client_id"), c.getBearerToken())
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if tokenError != nil {
+		c.serveClientRegistrationResponse(tokenError, http.StatusOK)
+		return
+	}
+
+	c.Ctx.Output.SetStatus(http.StatusNoContent)
+}
@@ -0,0 +1,182 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/casdoor/casdoor/form"
+	"github.com/casdoor/casdoor/object"
+)
+
+// consentGrantedKey marks a request in which the user has just given consent, so that
+// prompt=consent does not ask the user again
+const consentGrantedKey = "consentGranted"
+
+// getConsentResponse returns the response asking the user for consent before the client of
+// the authorization request is authorized, or nil if the user does not need to be asked
+func (c *ApiController) getConsentResponse(user *object.User, authorizeRequest *object.AuthorizeRequest, form *form.AuthForm) (*Response, error) {
+	if granted, ok := c.Ctx.Input.GetData(consentGrantedKey).(bool); ok && granted {
+		return nil, nil
+	}
+
+	application, err := object.GetApplicationByClientId(authorizeRequest.ClientId)
+	if err != nil {
+		return nil, err
+	}
+
+	if application == nil {
+		return nil, nil
+	}
+
+	consentRequest, err := object.GetConsentRequest(application, user, authorizeRequest.Scope, authorizeRequest.Prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	if consentRequest == nil {
+		return nil, nil
+	}
+
+	// The consent page needs the user to be signed in
+	c.SetSessionUsername(user.GetId())
+	if !form.AutoSignin {
+		c.setExpireForSession()
+	}
+
+	return &Response{Status: "ok", Msg: "", Data: object.NextConsent, Data2: consentRequest}, nil
+}
+
+// GrantConsent
+// @Title GrantConsent
+// @Tag Consent API
+// @Description grant the scopes of the authorization request to its client as the signed-in user, and continue the authorization
+// @Param   clientId    query    string  true        "client id"
+// @Param   responseType    query    string  true        "response type"
+// @Param   redirectUri    query    string  true        "redirect uri"
+// @Param   scope    query    string  true        "scope"
+// @Param   state    query    string  true        "state"
+// @Param   body    body   form.AuthForm  true        "The login form"
+// @Success 200 {object} controllers.Response The Response object
+// @router /grant-consent [post]
+func (c *ApiController) GrantConsent() {
+	user, ok := c.RequireSignedInUser()
+	if !ok {
+		return
+	}
+
+	var authForm form.AuthForm
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &authForm)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	authorizeRequest, msg, err := c.getAuthorizeRequest()
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+	if msg != "" {
+		c.ResponseError(msg)
+		return
+	}
+
+	application, err := object.GetApplicationByClientId(authorizeRequest.ClientId)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if application == nil {
+		c.ResponseError(c.T("token:Invalid client_id"))
+		return
+	}
+
+	err = object.GrantConsent(application, user, authorizeRequest.Scope)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Ctx.Input.SetData(consentGrantedKey, true)
+	resp := c.HandleLoggedIn(application, user, &authForm)
+	c.Data["json"] = resp
+	c.ServeJSON()
+}
+
+// GetConsents
+// @Title GetConsents
+// @Tag Consent API
+// @Description get the consents the signed-in user has given to applications
+// @Success 200 {array} object.Consent The Response object
+// @router /get-consents [get]
+func (c *ApiController) GetConsents() {
+	user, ok := c.RequireSignedInUser()
+	if !ok {
+		return
+	}
+
+	consents, err := object.GetConsents(user.Owner, user.Name)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(consents)
+}
+
+// RevokeConsent
+// @Title RevokeConsent
+// @Tag Consent API
+// @Description revoke a consent of the signed-in user, the tokens issued to the application for the user are invalidated
+// @Param   body    body   object.Consent  true        "The consent"
+// @Success 200 {object} controllers.Response The Response object
+// @router /revoke-consent [post]
+func (c *ApiController) RevokeConsent() {
+	user, ok := c.RequireSignedInUser()
+	if !ok {
+		return
+	}
+
+	var consent object.Consent
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &consent)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	existingConsent, err := object.GetConsent(consent.GetId())
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if existingConsent == nil {
+		c.ResponseError(fmt.Sprintf(c.T("general:The consent: %s does not exist"), consent.GetId()))
+		return
+	}
+
+	isOwnConsent := existingConsent.Owner == user.Owner && existingConsent.User == user.Name
+	isOrganizationAdmin := user.IsAdmin && existingConsent.Owner == user.Owner
+	if !isOwnConsent && !isOrganizationAdmin && !c.IsGlobalAdmin() {
+		c.ResponseError(c.T("auth:Unauthorized operation"))
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.RevokeConsent(existingConsent))
+	c.ServeJSON()
+}
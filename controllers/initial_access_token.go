@@ -0,0 +1,145 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+
+	"github.com/beego/beego/utils/pagination"
+	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
+)
+
+// GetInitialAccessTokens
+// @Title GetInitialAccessTokens
+// @Tag Initial Access Token API
+// @Description get initial access tokens
+// @Param   owner     query    string  true        "The organization of the initial access tokens"
+// @Success 200 {array} object.InitialAccessToken The Response object
+// @router /get-initial-access-tokens [get]
+func (c *ApiController) GetInitialAccessTokens() {
+	owner := c.Input().Get("owner")
+	limit := c.Input().Get("pageSize")
+	page := c.Input().Get("p")
+	field := c.Input().Get("field")
+	value := c.Input().Get("value")
+	sortField := c.Input().Get("sortField")
+	sortOrder := c.Input().Get("sortOrder")
+
+	if limit == "" || page == "" {
+		initialAccessTokens, err := object.GetInitialAccessTokens(owner)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		c.ResponseOk(initialAccessTokens)
+	} else {
+		limit := util.ParseInt(limit)
+		count, err := object.GetInitialAccessTokenCount(owner, field, value)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		paginator := pagination.SetPaginator(c.Ctx, limit, count)
+		initialAccessTokens, err := object.GetPaginationInitialAccessTokens(owner, paginator.Offset(), limit, field, value, sortField, sortOrder)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		c.ResponseOk(initialAccessTokens, paginator.Nums())
+	}
+}
+
+// GetInitialAccessToken
+// @Title GetInitialAccessToken
+// @Tag Initial Access Token API
+// @Description get initial access token
+// @Param   id     query    string  true        "The id ( owner/name ) of the initial access token"
+// @Success 200 {object} object.InitialAccessToken The Response object
+// @router /get-initial-access-token [get]
+func (c *ApiController) GetInitialAccessToken() {
+	id := c.Input().Get("id")
+
+	initialAccessToken, err := object.GetInitialAccessToken(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(initialAccessToken)
+}
+
+// UpdateInitialAccessToken
+// @Title UpdateInitialAccessToken
+// @Tag Initial Access Token API
+// @Description update initial access token
+// @Param   id     query    string  true        "The id ( owner/name ) of the initial access token"
+// @Param   body    body   object.InitialAccessToken  true        "The details of the initial access token"
+// @Success 200 {object} controllers.Response The Response object
+// @router /update-initial-access-token [post]
+func (c *ApiController) UpdateInitialAccessToken() {
+	id := c.Input().Get("id")
+
+	var initialAccessToken object.InitialAccessToken
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &initialAccessToken)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.UpdateInitialAccessToken(id, &initialAccessToken))
+	c.ServeJSON()
+}
+
+// AddInitialAccessToken
+// @Title AddInitialAccessToken
+// @Tag Initial Access Token API
+// @Description add initial access token, the token is generated if it is empty
+// @Param   body    body   object.InitialAccessToken  true        "The details of the initial access token"
+// @Success 200 {object} controllers.Response The Response object
+// @router /add-initial-access-token [post]
+func (c *ApiController) AddInitialAccessToken() {
+	var initialAccessToken object.InitialAccessToken
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &initialAccessToken)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.AddInitialAccessToken(&initialAccessToken))
+	c.ServeJSON()
+}
+
+// DeleteInitialAccessToken
+// @Title DeleteInitialAccessToken
+// @Tag Initial Access Token API
+// @Description delete initial access token
+// @Param   body    body   object.InitialAccessToken  true        "The details of the initial access token"
+// @Success 200 {object} controllers.Response The Response object
+// @router /delete-initial-access-token [post]
+func (c *ApiController) DeleteInitialAccessToken() {
+	var initialAccessToken object.InitialAccessToken
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &initialAccessToken)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.DeleteInitialAccessToken(&initialAccessToken))
+	c.ServeJSON()
+}
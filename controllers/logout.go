@@ -0,0 +1,101 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/casdoor/casdoor/object"
+)
+
+// frontChannelLogoutTemplate loads the front-channel logout URLs of the applications in hidden
+// iframes, see OpenID Connect Front-Channel Logout 1.0, and then continues to the redirect URL, or
+// posts the SAML LogoutResponse form.
+var frontChannelLogoutTemplate = template.Must(template.New("logout").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}}</title>
+</head>
+<body>
+  <p>{{.Title}}</p>
+  {{range .Urls}}<iframe src="{{.}}" style="display: none;"></iframe>
+  {{end}}
+  {{with .Form}}<form id="logout-form" method="post" action="{{.Url}}">
+    <input type="hidden" name="SAMLResponse" value="{{.SamlResponse}}">
+    {{if .RelayState}}<input type="hidden" name="RelayState" value="{{.RelayState}}">{{end}}
+  </form>
+  {{end}}
+  <script>
+    var redirectUrl = {{.RedirectUrl}};
+    var pending = {{len .Urls}};
+    var finished = false;
+    var finish = function() {
+      if (finished) {
+        return;
+      }
+      finished = true;
+
+      var form = document.getElementById("logout-form");
+      if (form !== null) {
+        form.submit();
+      } else if (redirectUrl !== "") {
+        window.location.replace(redirectUrl);
+      }
+    };
+    Array.prototype.forEach.call(document.getElementsByTagName("iframe"), function(iframe) {
+      iframe.onload = iframe.onerror = function() {
+        pending -= 1;
+        if (pending === 0) {
+          finish();
+        }
+      };
+    });
+    if (pending === 0) {
+      finish();
+    }
+    setTimeout(finish, 5000);
+  </script>
+</body>
+</html>
+`))
+
+func (c *ApiController) renderFrontChannelLogout(urls []string, redirectUrl string) {
+	c.renderLogoutPage(urls, redirectUrl, nil)
+}
+
+// renderLogoutPage loads the front-channel logout URLs and then posts the SAML LogoutResponse if
+// form is not nil, or else redirects to the redirect URL
+func (c *ApiController) renderLogoutPage(urls []string, redirectUrl string, form *object.SamlLogoutResponse) {
+	var buf bytes.Buffer
+	err := frontChannelLogoutTemplate.Execute(&buf, map[string]interface{}{
+		"Title":       c.T("general:You have been signed out"),
+		"Urls":        urls,
+		"RedirectUrl": redirectUrl,
+		"Form":        form,
+	})
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Ctx.Output.Header("Content-Type", "text/html; charset=utf-8")
+	err = c.Ctx.Output.Body(buf.Bytes())
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+}
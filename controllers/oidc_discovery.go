@@ -24,7 +24,33 @@ import "github.com/casdoor/casdoor/object"
 // @router /.well-known/openid-configuration [get]
 func (c *RootController) GetOidcDiscovery() {
 	host := c.Ctx.Request.Host
-	c.Data["json"] = object.GetOidcDiscovery(host)
+	oidcDiscovery, err := object.GetOidcDiscovery(host, "")
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = oidcDiscovery
+	c.ServeJSON()
+}
+
+// GetOidcDiscoveryByApplication
+// @Title GetOidcDiscoveryByApplication
+// @Tag OIDC API
+// @Description Get Oidc Discovery of an application, with the scopes and claims defined by the application
+// @Param   application     path    string  true        "The name of the application"
+// @Success 200 {object} object.OidcDiscovery
+// @router /.well-known/:application/openid-configuration [get]
+func (c *RootController) GetOidcDiscoveryByApplication() {
+	host := c.Ctx.Request.Host
+	application := c.Ctx.Input.Param(":application")
+	oidcDiscovery, err := object.GetOidcDiscovery(host, application)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = oidcDiscovery
 	c.ServeJSON()
 }
 
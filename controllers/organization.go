@@ -123,6 +123,18 @@ func (c *ApiController) UpdateOrganization() {
 		return
 	}
 
+	err = object.CheckLdapSchema(organization.LdapObjectClasses, organization.LdapAttributes)
+	if err != nil {
+		c.ResponseBadRequest(err.Error())
+		return
+	}
+
+	err = object.CheckRadiusReplyRules(organization.RadiusReplyRules)
+	if err != nil {
+		c.ResponseBadRequest(err.Error())
+		return
+	}
+
 	c.Data["json"] = wrapActionResponse(object.UpdateOrganization(c.Ctx.Request.Context(), id, &organization, c.GetAcceptLanguage()))
 	c.ServeJSON()
 }
@@ -145,6 +157,18 @@ func (c *ApiController) AddOrganization() {
 		return
 	}
 
+	err = object.CheckLdapSchema(organization.LdapObjectClasses, organization.LdapAttributes)
+	if err != nil {
+		c.ResponseBadRequest(err.Error())
+		return
+	}
+
+	err = object.CheckRadiusReplyRules(organization.RadiusReplyRules)
+	if err != nil {
+		c.ResponseBadRequest(err.Error())
+		return
+	}
+
 	count, err := object.GetOrganizationCount("", "", "")
 	if err != nil {
 		c.ResponseInternalServerError(err.Error())
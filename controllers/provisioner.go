@@ -0,0 +1,242 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/beego/beego/utils/pagination"
+	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
+)
+
+// GetProvisioners
+// @Title GetProvisioners
+// @Tag Provisioner API
+// @Description get provisioners
+// @Param   owner     query    string  true        "The owner of provisioners"
+// @Param   organization     query    string  false        "The organization of provisioners"
+// @Success 200 {array} object.Provisioner The Response object
+// @router /get-provisioners [get]
+func (c *ApiController) GetProvisioners() {
+	owner := c.Input().Get("owner")
+	limit := c.Input().Get("pageSize")
+	page := c.Input().Get("p")
+	field := c.Input().Get("field")
+	value := c.Input().Get("value")
+	sortField := c.Input().Get("sortField")
+	sortOrder := c.Input().Get("sortOrder")
+	organization := c.Input().Get("organization")
+
+	if limit == "" || page == "" {
+		provisioners, err := object.GetProvisioners(owner, organization)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		c.ResponseOk(object.GetMaskedProvisioners(provisioners))
+	} else {
+		limit := util.ParseInt(limit)
+		count, err := object.GetProvisionerCount(owner, organization, field, value)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		paginator := pagination.SetPaginator(c.Ctx, limit, count)
+		provisioners, err := object.GetPaginationProvisioners(owner, organization, paginator.Offset(), limit, field, value, sortField, sortOrder)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		c.ResponseOk(object.GetMaskedProvisioners(provisioners), paginator.Nums())
+	}
+}
+
+// GetProvisioner
+// @Title GetProvisioner
+// @Tag Provisioner API
+// @Description get provisioner
+// @Param   id     query    string  true        "The id ( owner/name ) of the provisioner"
+// @Success 200 {object} object.Provisioner The Response object
+// @router /get-provisioner [get]
+func (c *ApiController) GetProvisioner() {
+	id := c.Input().Get("id")
+
+	provisioner, err := object.GetProvisioner(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(object.GetMaskedProvisioner(provisioner))
+}
+
+// UpdateProvisioner
+// @Title UpdateProvisioner
+// @Tag Provisioner API
+// @Description update provisioner
+// @Param   id     query    string  true        "The id ( owner/name ) of the provisioner"
+// @Param   body    body   object.Provisioner  true        "The details of the provisioner"
+// @Success 200 {object} controllers.Response The Response object
+// @router /update-provisioner [post]
+func (c *ApiController) UpdateProvisioner() {
+	id := c.Input().Get("id")
+
+	var provisioner object.Provisioner
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &provisioner)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.UpdateProvisioner(id, &provisioner))
+	c.ServeJSON()
+}
+
+// AddProvisioner
+// @Title AddProvisioner
+// @Tag Provisioner API
+// @Description add provisioner
+// @Param   body    body   object.Provisioner  true        "The details of the provisioner"
+// @Success 200 {object} controllers.Response The Response object
+// @router /add-provisioner [post]
+func (c *ApiController) AddProvisioner() {
+	var provisioner object.Provisioner
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &provisioner)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.AddProvisioner(&provisioner))
+	c.ServeJSON()
+}
+
+// DeleteProvisioner
+// @Title DeleteProvisioner
+// @Tag Provisioner API
+// @Description delete provisioner, with its queue
+// @Param   body    body   object.Provisioner  true        "The details of the provisioner"
+// @Success 200 {object} controllers.Response The Response object
+// @router /delete-provisioner [post]
+func (c *ApiController) DeleteProvisioner() {
+	var provisioner object.Provisioner
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &provisioner)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.DeleteProvisioner(&provisioner))
+	c.ServeJSON()
+}
+
+func (c *ApiController) getProvisioner(id string) *object.Provisioner {
+	provisioner, err := object.GetProvisioner(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return nil
+	}
+
+	if provisioner == nil {
+		c.ResponseError(fmt.Sprintf(c.T("general:The provisioner: %s does not exist"), id))
+		return nil
+	}
+
+	return provisioner
+}
+
+// GetProvisionerStatus
+// @Title GetProvisionerStatus
+// @Tag Provisioner API
+// @Description get the status of the queue and of the last runs of a provisioner
+// @Param   id     query    string  true        "The id ( owner/name ) of the provisioner"
+// @Success 200 {object} object.ProvisionerStatus The Response object
+// @router /get-provisioner-status [get]
+func (c *ApiController) GetProvisionerStatus() {
+	provisioner := c.getProvisioner(c.Input().Get("id"))
+	if provisioner == nil {
+		return
+	}
+
+	status, err := object.GetProvisionerStatus(provisioner)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(status)
+}
+
+// GetProvisioningTasks
+// @Title GetProvisioningTasks
+// @Tag Provisioner API
+// @Description get the queued changes of a provisioner
+// @Param   id     query    string  true        "The id ( owner/name ) of the provisioner"
+// @Param   state     query    string  false        "The state of the changes: Pending or Failed"
+// @Success 200 {array} object.ProvisioningTask The Response object
+// @router /get-provisioning-tasks [get]
+func (c *ApiController) GetProvisioningTasks() {
+	provisioner := c.getProvisioner(c.Input().Get("id"))
+	if provisioner == nil {
+		return
+	}
+
+	tasks, err := object.GetProvisioningTasks(provisioner.GetId(), c.Input().Get("state"))
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(tasks)
+}
+
+// RetryProvisioningTasks
+// @Title RetryProvisioningTasks
+// @Tag Provisioner API
+// @Description put the failed changes of a provisioner back in its queue
+// @Param   id     query    string  true        "The id ( owner/name ) of the provisioner"
+// @Success 200 {object} controllers.Response The Response object
+// @router /retry-provisioning-tasks [post]
+func (c *ApiController) RetryProvisioningTasks() {
+	provisioner := c.getProvisioner(c.Input().Get("id"))
+	if provisioner == nil {
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.RetryProvisioningTasks(provisioner.GetId()))
+	c.ServeJSON()
+}
+
+// SyncProvisioner
+// @Title SyncProvisioner
+// @Tag Provisioner API
+// @Description add all the users and groups of the organization of a provisioner to its queue
+// @Param   id     query    string  true        "The id ( owner/name ) of the provisioner"
+// @Success 200 {object} controllers.Response The Response object
+// @router /sync-provisioner [post]
+func (c *ApiController) SyncProvisioner() {
+	provisioner := c.getProvisioner(c.Input().Get("id"))
+	if provisioner == nil {
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.SyncProvisioner(provisioner))
+	c.ServeJSON()
+}
@@ -0,0 +1,224 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/beego/beego/utils/pagination"
+	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
+)
+
+// GetRadiusClients
+// @Title GetRadiusClients
+// @Tag RADIUS Client API
+// @Description get RADIUS clients
+// @Param   owner     query    string  true        "The organization of the RADIUS clients"
+// @Success 200 {array} object.RadiusClient The Response object
+// @router /get-radius-clients [get]
+func (c *ApiController) GetRadiusClients() {
+	owner := c.Input().Get("owner")
+	limit := c.Input().Get("pageSize")
+	page := c.Input().Get("p")
+	field := c.Input().Get("field")
+	value := c.Input().Get("value")
+	sortField := c.Input().Get("sortField")
+	sortOrder := c.Input().Get("sortOrder")
+
+	if limit == "" || page == "" {
+		radiusClients, err := object.GetRadiusClients(owner)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		c.ResponseOk(radiusClients)
+	} else {
+		limit := util.ParseInt(limit)
+		count, err := object.GetRadiusClientCount(owner, field, value)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		paginator := pagination.SetPaginator(c.Ctx, limit, count)
+		radiusClients, err := object.GetPaginationRadiusClients(owner, paginator.Offset(), limit, field, value, sortField, sortOrder)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		c.ResponseOk(radiusClients, paginator.Nums())
+	}
+}
+
+// GetRadiusClient
+// @Title GetRadiusClient
+// @Tag RADIUS Client API
+// @Description get RADIUS client
+// @Param   id     query    string  true        "The id ( owner/name ) of the RADIUS client"
+// @Success 200 {object} object.RadiusClient The Response object
+// @router /get-radius-client [get]
+func (c *ApiController) GetRadiusClient() {
+	id := c.Input().Get("id")
+
+	radiusClient, err := object.GetRadiusClient(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(radiusClient)
+}
+
+// checkRadiusClientAddress checks that only a global admin registers an address range, a range of
+// another organization would take over the requests of its network access servers
+func (c *ApiController) checkRadiusClientAddress(radiusClient *object.RadiusClient) bool {
+	if strings.Contains(radiusClient.Address, "/") && !c.IsGlobalAdmin() {
+		c.ResponseForbidden(c.T("auth:Unable to register an address range of RADIUS client without global administrator role"))
+		return false
+	}
+
+	return true
+}
+
+// UpdateRadiusClient
+// @Title UpdateRadiusClient
+// @Tag RADIUS Client API
+// @Description update RADIUS client
+// @Param   id     query    string  true        "The id ( owner/name ) of the RADIUS client"
+// @Param   body    body   object.RadiusClient  true        "The details of the RADIUS client"
+// @Success 200 {object} controllers.Response The Response object
+// @router /update-radius-client [post]
+func (c *ApiController) UpdateRadiusClient() {
+	id := c.Input().Get("id")
+
+	var radiusClient object.RadiusClient
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &radiusClient)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if !c.checkRadiusClientAddress(&radiusClient) {
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.UpdateRadiusClient(id, &radiusClient))
+	c.ServeJSON()
+}
+
+// AddRadiusClient
+// @Title AddRadiusClient
+// @Tag RADIUS Client API
+// @Description add RADIUS client, the secret is generated if it is empty
+// @Param   body    body   object.RadiusClient  true        "The details of the RADIUS client"
+// @Success 200 {object} controllers.Response The Response object
+// @router /add-radius-client [post]
+func (c *ApiController) AddRadiusClient() {
+	var radiusClient object.RadiusClient
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &radiusClient)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if !c.checkRadiusClientAddress(&radiusClient) {
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.AddRadiusClient(&radiusClient))
+	c.ServeJSON()
+}
+
+// DeleteRadiusClient
+// @Title DeleteRadiusClient
+// @Tag RADIUS Client API
+// @Description delete RADIUS client
+// @Param   body    body   object.RadiusClient  true        "The details of the RADIUS client"
+// @Success 200 {object} controllers.Response The Response object
+// @router /delete-radius-client [post]
+func (c *ApiController) DeleteRadiusClient() {
+	var radiusClient object.RadiusClient
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &radiusClient)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.DeleteRadiusClient(&radiusClient))
+	c.ServeJSON()
+}
+
+type radiusReplyRulesTest struct {
+	Owner string                    `json:"owner"`
+	Name  string                    `json:"name"`
+	Rules []*object.RadiusReplyRule `json:"rules"`
+}
+
+// TestRadiusReplyRules
+// @Title TestRadiusReplyRules
+// @Tag RADIUS Client API
+// @Description get the attributes added to the Access-Accept of a user by RADIUS reply rules, the rules of the organization are used if no rules are given
+// @Param   body    body   controllers.radiusReplyRulesTest  true        "The owner and the name of the user, and the rules to test"
+// @Success 200 {array} object.RadiusReplyAttribute The Response object
+// @router /test-radius-reply-rules [post]
+func (c *ApiController) TestRadiusReplyRules() {
+	var test radiusReplyRulesTest
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &test)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	user, err := object.GetUser(util.GetId(test.Owner, test.Name))
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+	if user == nil {
+		c.ResponseError(fmt.Sprintf(c.T("general:The user: %s doesn't exist"), util.GetId(test.Owner, test.Name)))
+		return
+	}
+
+	rules := test.Rules
+	if rules == nil {
+		organization, err := object.GetOrganizationByUser(user)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+		if organization != nil {
+			rules = organization.RadiusReplyRules
+		}
+	}
+
+	err = object.CheckRadiusReplyRules(rules)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	attributes, err := object.GetRadiusReplyAttributes(user, rules)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(attributes)
+}
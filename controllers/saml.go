@@ -17,10 +17,12 @@ package controllers
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/beego/beego/logs"
 	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
 )
 
 func (c *ApiController) GetSamlMeta() {
@@ -41,6 +43,99 @@ func (c *ApiController) GetSamlMeta() {
 	c.ServeXML()
 }
 
+// SamlSingleLogout
+// @Title SamlSingleLogout
+// @Tag Login API
+// @Description the SAML single logout service of the application, for the HTTP-Redirect and HTTP-POST bindings
+// @Param   application     query    string  true        "The id ( owner/name ) of the application"
+// @Param   SAMLRequest     query    string  false       "The LogoutRequest of the SP"
+// @Param   SAMLResponse    query    string  false       "The LogoutResponse of the SP"
+// @Param   RelayState      query    string  false       "RelayState"
+// @Param   SigAlg          query    string  false       "SigAlg"
+// @Param   Signature       query    string  false       "Signature"
+// @Success 200 {object} controllers.Response The Response object
+// @router /saml/slo [get,post]
+func (c *ApiController) SamlSingleLogout() {
+	id := c.Input().Get("application")
+	samlRequest := c.Input().Get("SAMLRequest")
+	relayState := c.Input().Get("RelayState")
+
+	application, err := object.GetApplication(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if application == nil {
+		c.ResponseError(fmt.Sprintf(c.T("saml:Application %s not found"), id))
+		return
+	}
+
+	// the SPs respond to the LogoutRequests of the logout started by the IdP
+	if samlRequest == "" {
+		c.ResponseOk()
+		return
+	}
+
+	logoutRequest, err := object.ParseSamlLogoutRequest(application, samlRequest, c.Ctx.Request.URL.RawQuery)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	frontChannelLogoutUrls := []string{}
+	user := c.GetSessionUsername()
+	if user != "" {
+		sid := c.getSid(user)
+		isSession, err := object.IsSamlLogoutRequestOfSession(application, logoutRequest, user, sid)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		if isSession {
+			owner, username := util.GetOwnerAndNameFromId(user)
+			sessionId := c.Ctx.Input.CruSession.SessionID()
+
+			// the SP has already ended its session, so it is not sent a LogoutRequest
+			_, err = object.DeleteSessionId(util.GetSessionId(owner, username, application.Name), sessionId)
+			if err != nil {
+				c.ResponseError(err.Error())
+				return
+			}
+
+			c.ClearUserSession()
+			_, err = object.DeleteSessionId(util.GetSessionId(owner, username, object.CasdoorApplication), sessionId)
+			if err != nil {
+				c.ResponseError(err.Error())
+				return
+			}
+
+			frontChannelLogoutUrls, err = object.LogoutSession(user, sid, c.Ctx.Request.Host)
+			if err != nil {
+				c.ResponseError(err.Error())
+				return
+			}
+
+			util.LogInfo(c.Ctx, "API: [%s] logged out by the SAML SP of the application %s", user, application.GetId())
+		}
+	}
+
+	logoutResponse, err := object.GetSamlLogoutResponse(application, logoutRequest, relayState, c.Ctx.Request.Host)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if logoutResponse.Method == "POST" {
+		c.renderLogoutPage(frontChannelLogoutUrls, "", logoutResponse)
+	} else if len(frontChannelLogoutUrls) != 0 {
+		c.renderFrontChannelLogout(frontChannelLogoutUrls, logoutResponse.Url)
+	} else {
+		c.Ctx.Redirect(http.StatusFound, logoutResponse.Url)
+	}
+}
+
 // GetProviderSamlMetadata
 // @Title GetProviderSamlMetadata
 // @Tag Provider API
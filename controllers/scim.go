@@ -0,0 +1,572 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
+)
+
+func (c *RootController) scimResponse(status int, data interface{}) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		c.scimResponseError(err)
+		return
+	}
+
+	c.Data["json"] = data
+	c.Ctx.Output.Header("Content-Type", "application/scim+json; charset=utf-8")
+	c.Ctx.Output.SetStatus(status)
+	err = c.Ctx.Output.Body(body)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (c *RootController) scimResponseError(err error) {
+	var scimError *object.ScimError
+	if !errors.As(err, &scimError) {
+		scimError = object.NewScimError(http.StatusInternalServerError, "", err.Error())
+	}
+
+	c.scimResponse(util.ParseInt(scimError.Status), scimError)
+}
+
+// getScimApplication authenticates the SCIM client by an access token of the application
+// ("Authorization: Bearer <token>") or by its client credentials ("Authorization: Basic ...")
+func (c *RootController) getScimApplication() (*object.Application, error) {
+	header := c.Ctx.Request.Header.Get("Authorization")
+	if strings.HasPrefix(header, "Bearer ") {
+		token, err := object.GetTokenByAccessToken(strings.TrimPrefix(header, "Bearer "))
+		if err != nil {
+			return nil, err
+		}
+		if token == nil || util.IsTokenExpired(token.CreatedTime, token.ExpiresIn) {
+			return nil, nil
+		}
+
+		// only the tokens of the client credentials grant represent the application itself
+		if token.GrantType != object.ClientCredentialsGrantType || token.User != token.Application {
+			return nil, nil
+		}
+
+		application, err := object.GetApplication(util.GetId(token.Owner, token.Application))
+		if err != nil || application == nil || application.Organization != token.Organization {
+			return nil, err
+		}
+
+		return application, nil
+	}
+
+	clientId, clientSecret, ok := c.Ctx.Request.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+
+	application, err := object.GetApplicationByClientId(clientId)
+	if err != nil {
+		return nil, err
+	}
+	if application == nil || subtle.ConstantTimeCompare([]byte(application.ClientSecret), []byte(clientSecret)) != 1 {
+		return nil, nil
+	}
+
+	return application, nil
+}
+
+// requireScimClient returns the organization of the request if the client is allowed to provision it
+func (c *RootController) requireScimClient() (string, bool) {
+	organization := c.Ctx.Input.Param(":organization")
+
+	application, err := c.getScimApplication()
+	if err != nil {
+		c.scimResponseError(err)
+		return "", false
+	}
+
+	if application == nil {
+		c.Ctx.Output.Header("WWW-Authenticate", "Bearer realm=\"SCIM\"")
+		c.scimResponseError(object.NewScimError(http.StatusUnauthorized, "", "the access token or client credentials are invalid"))
+		return "", false
+	}
+
+	if application.Organization != organization {
+		c.scimResponseError(object.NewScimError(http.StatusForbidden, "", fmt.Sprintf("the application: %s is not allowed to provision the organization: %s", application.Name, organization)))
+		return "", false
+	}
+
+	return organization, true
+}
+
+// getScimListParams returns the filter, the start index and the count of a list request, a SCIM
+// invalidValue error is sent for a start index or a count which is not an integer
+func (c *RootController) getScimListParams() (string, int, int, bool) {
+	filter := c.Input().Get("filter")
+
+	startIndex, ok := c.getScimIntParam("startIndex", 1)
+	if !ok {
+		return "", 0, 0, false
+	}
+
+	count, ok := c.getScimIntParam("count", object.ScimMaxResults)
+	if !ok {
+		return "", 0, 0, false
+	}
+
+	return filter, startIndex, count, true
+}
+
+func (c *RootController) getScimIntParam(key string, defaultValue int) (int, bool) {
+	s := c.Input().Get(key)
+	if s == "" {
+		return defaultValue, true
+	}
+
+	value, err := strconv.Atoi(s)
+	if err != nil {
+		c.scimResponseError(object.NewScimError(http.StatusBadRequest, "invalidValue", fmt.Sprintf("%s: %s is not an integer", key, s)))
+		return 0, false
+	}
+	return value, true
+}
+
+func (c *RootController) parseScimBody(v interface{}) bool {
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, v)
+	if err != nil {
+		c.scimResponseError(object.NewScimError(http.StatusBadRequest, "invalidSyntax", err.Error()))
+		return false
+	}
+	return true
+}
+
+// GetScimUsers
+// @Title GetScimUsers
+// @Tag SCIM API
+// @Description list the users of the organization, see RFC 7644
+// @Param   organization     path    string  true        "The organization"
+// @Param   filter     query    string  false        "The SCIM filter, e.g. userName eq \"alice\""
+// @Param   startIndex     query    int  false        "The 1-based index of the first result"
+// @Param   count     query    int  false        "The maximum number of results"
+// @Success 200 {object} object.ScimListResponse The Response object
+// @router /scim/v2/:organization/Users [get]
+func (c *RootController) GetScimUsers() {
+	organization, ok := c.requireScimClient()
+	if !ok {
+		return
+	}
+
+	filter, startIndex, count, ok := c.getScimListParams()
+	if !ok {
+		return
+	}
+
+	res, err := object.GetScimUsers(organization, filter, startIndex, count, c.Ctx.Request.Host)
+	if err != nil {
+		c.scimResponseError(err)
+		return
+	}
+
+	c.scimResponse(http.StatusOK, res)
+}
+
+// GetScimUser
+// @Title GetScimUser
+// @Tag SCIM API
+// @Description get a user of the organization
+// @Param   organization     path    string  true        "The organization"
+// @Param   id     path    string  true        "The id of the user"
+// @Success 200 {object} object.ScimUser The Response object
+// @router /scim/v2/:organization/Users/:id [get]
+func (c *RootController) GetScimUser() {
+	organization, ok := c.requireScimClient()
+	if !ok {
+		return
+	}
+
+	user, err := object.GetScimUser(organization, c.Ctx.Input.Param(":id"), c.Ctx.Request.Host)
+	if err != nil {
+		c.scimResponseError(err)
+		return
+	}
+
+	c.scimResponse(http.StatusOK, user)
+}
+
+// AddScimUser
+// @Title AddScimUser
+// @Tag SCIM API
+// @Description add a user to the organization
+// @Param   organization     path    string  true        "The organization"
+// @Param   body    body   object.ScimUser  true        "The user"
+// @Success 201 {object} object.ScimUser The Response object
+// @router /scim/v2/:organization/Users [post]
+func (c *RootController) AddScimUser() {
+	organization, ok := c.requireScimClient()
+	if !ok {
+		return
+	}
+
+	var scimUser object.ScimUser
+	if !c.parseScimBody(&scimUser) {
+		return
+	}
+
+	user, err := object.AddScimUser(organization, &scimUser, c.Ctx.Request.Host)
+	if err != nil {
+		c.scimResponseError(err)
+		return
+	}
+
+	c.scimResponse(http.StatusCreated, user)
+}
+
+// UpdateScimUser
+// @Title UpdateScimUser
+// @Tag SCIM API
+// @Description replace a user of the organization
+// @Param   organization     path    string  true        "The organization"
+// @Param   id     path    string  true        "The id of the user"
+// @Param   body    body   object.ScimUser  true        "The user"
+// @Success 200 {object} object.ScimUser The Response object
+// @router /scim/v2/:organization/Users/:id [put]
+func (c *RootController) UpdateScimUser() {
+	organization, ok := c.requireScimClient()
+	if !ok {
+		return
+	}
+
+	var scimUser object.ScimUser
+	if !c.parseScimBody(&scimUser) {
+		return
+	}
+
+	user, err := object.UpdateScimUser(organization, c.Ctx.Input.Param(":id"), &scimUser, c.Ctx.Request.Host)
+	if err != nil {
+		c.scimResponseError(err)
+		return
+	}
+
+	c.scimResponse(http.StatusOK, user)
+}
+
+// PatchScimUser
+// @Title PatchScimUser
+// @Tag SCIM API
+// @Description modify a user of the organization with PATCH operations
+// @Param   organization     path    string  true        "The organization"
+// @Param   id     path    string  true        "The id of the user"
+// @Param   body    body   object.ScimPatchRequest  true        "The PATCH operations"
+// @Success 200 {object} object.ScimUser The Response object
+// @router /scim/v2/:organization/Users/:id [patch]
+func (c *RootController) PatchScimUser() {
+	organization, ok := c.requireScimClient()
+	if !ok {
+		return
+	}
+
+	var patch object.ScimPatchRequest
+	if !c.parseScimBody(&patch) {
+		return
+	}
+
+	user, err := object.PatchScimUser(organization, c.Ctx.Input.Param(":id"), &patch, c.Ctx.Request.Host)
+	if err != nil {
+		c.scimResponseError(err)
+		return
+	}
+
+	c.scimResponse(http.StatusOK, user)
+}
+
+// DeleteScimUser
+// @Title DeleteScimUser
+// @Tag SCIM API
+// @Description delete a user of the organization
+// @Param   organization     path    string  true        "The organization"
+// @Param   id     path    string  true        "The id of the user"
+// @Success 204
+// @router /scim/v2/:organization/Users/:id [delete]
+func (c *RootController) DeleteScimUser() {
+	organization, ok := c.requireScimClient()
+	if !ok {
+		return
+	}
+
+	err := object.DeleteScimUser(organization, c.Ctx.Input.Param(":id"))
+	if err != nil {
+		c.scimResponseError(err)
+		return
+	}
+
+	c.Ctx.Output.SetStatus(http.StatusNoContent)
+}
+
+// GetScimGroups
+// @Title GetScimGroups
+// @Tag SCIM API
+// @Description list the groups of the organization
+// @Param   organization     path    string  true        "The organization"
+// @Param   filter     query    string  false        "The SCIM filter, e.g. displayName eq \"admins\""
+// @Param   startIndex     query    int  false        "The 1-based index of the first result"
+// @Param   count     query    int  false        "The maximum number of results"
+// @Success 200 {object} object.ScimListResponse The Response object
+// @router /scim/v2/:organization/Groups [get]
+func (c *RootController) GetScimGroups() {
+	organization, ok := c.requireScimClient()
+	if !ok {
+		return
+	}
+
+	filter, startIndex, count, ok := c.getScimListParams()
+	if !ok {
+		return
+	}
+
+	res, err := object.GetScimGroups(organization, filter, startIndex, count, c.Ctx.Request.Host)
+	if err != nil {
+		c.scimResponseError(err)
+		return
+	}
+
+	c.scimResponse(http.StatusOK, res)
+}
+
+// GetScimGroup
+// @Title GetScimGroup
+// @Tag SCIM API
+// @Description get a group of the organization
+// @Param   organization     path    string  true        "The organization"
+// @Param   id     path    string  true        "The id of the group"
+// @Success 200 {object} object.ScimGroup The Response object
+// @router /scim/v2/:organization/Groups/:id [get]
+func (c *RootController) GetScimGroup() {
+	organization, ok := c.requireScimClient()
+	if !ok {
+		return
+	}
+
+	group, err := object.GetScimGroup(organization, c.Ctx.Input.Param(":id"), c.Ctx.Request.Host)
+	if err != nil {
+		c.scimResponseError(err)
+		return
+	}
+
+	c.scimResponse(http.StatusOK, group)
+}
+
+// AddScimGroup
+// @Title AddScimGroup
+// @Tag SCIM API
+// @Description add a group to the organization
+// @Param   organization     path    string  true        "The organization"
+// @Param   body    body   object.ScimGroup  true        "The group"
+// @Success 201 {object} object.ScimGroup The Response object
+// @router /scim/v2/:organization/Groups [post]
+func (c *RootController) AddScimGroup() {
+	organization, ok := c.requireScimClient()
+	if !ok {
+		return
+	}
+
+	var scimGroup object.ScimGroup
+	if !c.parseScimBody(&scimGroup) {
+		return
+	}
+
+	group, err := object.AddScimGroup(organization, &scimGroup, c.Ctx.Request.Host)
+	if err != nil {
+		c.scimResponseError(err)
+		return
+	}
+
+	c.scimResponse(http.StatusCreated, group)
+}
+
+// UpdateScimGroup
+// @Title UpdateScimGroup
+// @Tag SCIM API
+// @Description replace a group of the organization
+// @Param   organization     path    string  true        "The organization"
+// @Param   id     path    string  true        "The id of the group"
+// @Param   body    body   object.ScimGroup  true        "The group"
+// @Success 200 {object} object.ScimGroup The Response object
+// @router /scim/v2/:organization/Groups/:id [put]
+func (c *RootController) UpdateScimGroup() {
+	organization, ok := c.requireScimClient()
+	if !ok {
+		return
+	}
+
+	var scimGroup object.ScimGroup
+	if !c.parseScimBody(&scimGroup) {
+		return
+	}
+
+	group, err := object.UpdateScimGroup(organization, c.Ctx.Input.Param(":id"), &scimGroup, c.Ctx.Request.Host)
+	if err != nil {
+		c.scimResponseError(err)
+		return
+	}
+
+	c.scimResponse(http.StatusOK, group)
+}
+
+// PatchScimGroup
+// @Title PatchScimGroup
+// @Tag SCIM API
+// @Description modify a group of the organization with PATCH operations
+// @Param   organization     path    string  true        "The organization"
+// @Param   id     path    string  true        "The id of the group"
+// @Param   body    body   object.ScimPatchRequest  true        "The PATCH operations"
+// @Success 200 {object} object.ScimGroup The Response object
+// @router /scim/v2/:organization/Groups/:id [patch]
+func (c *RootController) PatchScimGroup() {
+	organization, ok := c.requireScimClient()
+	if !ok {
+		return
+	}
+
+	var patch object.ScimPatchRequest
+	if !c.parseScimBody(&patch) {
+		return
+	}
+
+	group, err := object.PatchScimGroup(organization, c.Ctx.Input.Param(":id"), &patch, c.Ctx.Request.Host)
+	if err != nil {
+		c.scimResponseError(err)
+		return
+	}
+
+	c.scimResponse(http.StatusOK, group)
+}
+
+// DeleteScimGroup
+// @Title DeleteScimGroup
+// @Tag SCIM API
+// @Description delete a group of the organization
+// @Param   organization     path    string  true        "The organization"
+// @Param   id     path    string  true        "The id of the group"
+// @Success 204
+// @router /scim/v2/:organization/Groups/:id [delete]
+func (c *RootController) DeleteScimGroup() {
+	organization, ok := c.requireScimClient()
+	if !ok {
+		return
+	}
+
+	err := object.DeleteScimGroup(organization, c.Ctx.Input.Param(":id"))
+	if err != nil {
+		c.scimResponseError(err)
+		return
+	}
+
+	c.Ctx.Output.SetStatus(http.StatusNoContent)
+}
+
+// GetScimServiceProviderConfig
+// @Title GetScimServiceProviderConfig
+// @Tag SCIM API
+// @Description get the SCIM features supported by the server
+// @Param   organization     path    string  true        "The organization"
+// @Success 200 {object} object.ScimServiceProviderConfig The Response object
+// @router /scim/v2/:organization/ServiceProviderConfig [get]
+func (c *RootController) GetScimServiceProviderConfig() {
+	organization, ok := c.requireScimClient()
+	if !ok {
+		return
+	}
+
+	config, err := object.GetScimServiceProviderConfig(organization, c.Ctx.Request.Host)
+	if err != nil {
+		c.scimResponseError(err)
+		return
+	}
+
+	c.scimResponse(http.StatusOK, config)
+}
+
+// GetScimResourceTypes
+// @Title GetScimResourceTypes
+// @Tag SCIM API
+// @Description get the SCIM resource types, or one of them when id is given
+// @Param   organization     path    string  true        "The organization"
+// @Success 200 {object} object.ScimListResponse The Response object
+// @router /scim/v2/:organization/ResourceTypes [get]
+func (c *RootController) GetScimResourceTypes() {
+	organization, ok := c.requireScimClient()
+	if !ok {
+		return
+	}
+
+	if id := c.Ctx.Input.Param(":id"); id != "" {
+		resourceType, err := object.GetScimResourceType(organization, id, c.Ctx.Request.Host)
+		if err != nil {
+			c.scimResponseError(err)
+			return
+		}
+
+		c.scimResponse(http.StatusOK, resourceType)
+		return
+	}
+
+	resourceTypes, err := object.GetScimResourceTypes(organization, c.Ctx.Request.Host)
+	if err != nil {
+		c.scimResponseError(err)
+		return
+	}
+
+	c.scimResponse(http.StatusOK, resourceTypes)
+}
+
+// GetScimSchemas
+// @Title GetScimSchemas
+// @Tag SCIM API
+// @Description get the SCIM schemas, or one of them when id is given
+// @Param   organization     path    string  true        "The organization"
+// @Success 200 {object} object.ScimListResponse The Response object
+// @router /scim/v2/:organization/Schemas [get]
+func (c *RootController) GetScimSchemas() {
+	organization, ok := c.requireScimClient()
+	if !ok {
+		return
+	}
+
+	if id := c.Ctx.Input.Param(":id"); id != "" {
+		schema, err := object.GetScimSchema(organization, id, c.Ctx.Request.Host)
+		if err != nil {
+			c.scimResponseError(err)
+			return
+		}
+
+		c.scimResponse(http.StatusOK, schema)
+		return
+	}
+
+	schemas, err := object.GetScimSchemas(organization, c.Ctx.Request.Host)
+	if err != nil {
+		c.scimResponseError(err)
+		return
+	}
+
+	c.scimResponse(http.StatusOK, schemas)
+}
@@ -16,6 +16,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/beego/beego/utils/pagination"
 	"github.com/casdoor/casdoor/object"
@@ -168,3 +169,32 @@ func (c *ApiController) RunSyncer() {
 
 	c.ResponseOk()
 }
+
+// DryRunSyncer
+// @Title DryRunSyncer
+// @Tag Syncer API
+// @Description get the report of the users the syncer would create, update and delete, without syncing them
+// @Param   id     query    string  true        "The id ( owner/name ) of the syncer"
+// @Success 200 {object} object.SyncerReport The Response object
+// @router /dry-run-syncer [get]
+func (c *ApiController) DryRunSyncer() {
+	id := c.Input().Get("id")
+	syncer, err := object.GetSyncer(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if syncer == nil {
+		c.ResponseError(fmt.Sprintf(c.T("general:The syncer: %s does not exist"), id))
+		return
+	}
+
+	report, err := object.DryRunSyncer(syncer)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(report)
+}
@@ -15,6 +15,7 @@
 package controllers
 
 import (
+	"github.com/casdoor/casdoor/sync"
 	"github.com/casdoor/casdoor/util"
 )
 
@@ -50,6 +51,21 @@ func (c *ApiController) GetVersionInfo() {
 	c.ResponseOk(versionInfo)
 }
 
+// GetReplicationStatus
+// @Title GetReplicationStatus
+// @Tag System API
+// @Description get the status of the database replication, null if it is disabled
+// @Success 200 {object} sync.ReplicationStatus The Response object
+// @router /get-replication-status [get]
+func (c *ApiController) GetReplicationStatus() {
+	_, ok := c.RequireAdmin()
+	if !ok {
+		return
+	}
+
+	c.ResponseOk(sync.GetReplicationStatus())
+}
+
 // Health
 // @Title Health
 // @Tag System API
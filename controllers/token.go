@@ -16,6 +16,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"net/http"
 
 	"github.com/beego/beego/utils/pagination"
 	"github.com/casdoor/casdoor/object"
@@ -169,6 +170,13 @@ func (c *ApiController) GetOAuthToken() {
 	password := c.Input().Get("password")
 	tag := c.Input().Get("tag")
 	avatar := c.Input().Get("avatar")
+	exchangeParams := &object.TokenExchangeParams{
+		SubjectToken:     c.Input().Get("subject_token"),
+		SubjectTokenType: c.Input().Get("subject_token_type"),
+		ActorToken:       c.Input().Get("actor_token"),
+		ActorTokenType:   c.Input().Get("actor_token_type"),
+		Audience:         c.Input().Get("audience"),
+	}
 
 	if clientId == "" && clientSecret == "" {
 		clientId, clientSecret, _ = c.Ctx.Request.BasicAuth()
@@ -188,10 +196,33 @@ func (c *ApiController) GetOAuthToken() {
 			password = tokenRequest.Password
 			tag = tokenRequest.Tag
 			avatar = tokenRequest.Avatar
+			if grantType == object.DeviceCodeGrantType {
+				code = tokenRequest.DeviceCode
+			}
+			exchangeParams = &object.TokenExchangeParams{
+				SubjectToken:     tokenRequest.SubjectToken,
+				SubjectTokenType: tokenRequest.SubjectTokenType,
+				ActorToken:       tokenRequest.ActorToken,
+				ActorTokenType:   tokenRequest.ActorTokenType,
+				Audience:         tokenRequest.Audience,
+			}
+		}
+	}
+	if grantType == object.DeviceCodeGrantType && code == "" {
+		code = c.Input().Get("device_code")
+	}
+	if grantType == object.JwtBearerGrantType && code == "" {
+		code = c.Input().Get("assertion")
+	}
+	if c.Input().Get("client_assertion") != "" {
+		var ok bool
+		clientId, clientSecret, ok = c.authenticateClientAssertion(clientId)
+		if !ok {
+			return
 		}
 	}
 	host := c.Ctx.Request.Host
-	oAuthtoken, err := object.GetOAuthToken(grantType, clientId, clientSecret, code, verifier, scope, username, password, host, refreshToken, tag, avatar, c.GetAcceptLanguage())
+	oAuthtoken, err := object.GetOAuthToken(grantType, clientId, clientSecret, code, verifier, scope, username, password, host, refreshToken, tag, avatar, exchangeParams, c.GetAcceptLanguage())
 	if err != nil {
 		c.ResponseError(err.Error())
 		return
@@ -202,6 +233,30 @@ func (c *ApiController) GetOAuthToken() {
 	c.ServeJSON()
 }
 
+// authenticateClientAssertion authenticates the client by the client_assertion parameter with
+// the private_key_jwt or client_secret_jwt method (RFC 7523). On success the client ID and secret
+// of the application are returned, so that the request is handled as if the client had
+// presented its secret. Otherwise the error has been responded and ok is false.
+func (c *ApiController) authenticateClientAssertion(clientId string) (string, string, bool) {
+	clientAssertionType := c.Input().Get("client_assertion_type")
+	clientAssertion := c.Input().Get("client_assertion")
+
+	application, tokenError, err := object.AuthenticateClientAssertion(clientId, clientAssertionType, clientAssertion, c.Ctx.Request.Host)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return "", "", false
+	}
+
+	if tokenError != nil {
+		c.Data["json"] = tokenError
+		c.SetTokenErrorHttpStatus()
+		c.ServeJSON()
+		return "", "", false
+	}
+
+	return application.ClientId, application.ClientSecret, true
+}
+
 // RefreshToken
 // @Title RefreshToken
 // @Tag Token API
@@ -263,7 +318,12 @@ func (c *ApiController) RefreshToken() {
 func (c *ApiController) IntrospectToken() {
 	tokenValue := c.Input().Get("token")
 	clientId, clientSecret, ok := c.Ctx.Request.BasicAuth()
-	if !ok {
+	if !ok && c.Input().Get("client_assertion") != "" {
+		clientId, clientSecret, ok = c.authenticateClientAssertion(c.Input().Get("client_id"))
+		if !ok {
+			return
+		}
+	} else if !ok {
 		clientId = c.Input().Get("client_id")
 		clientSecret = c.Input().Get("client_secret")
 		if clientId == "" || clientSecret == "" {
@@ -303,9 +363,13 @@ func (c *ApiController) IntrospectToken() {
 	}
 	jwtToken, err := object.ParseJwtTokenByApplication(tokenValue, application)
 	if err != nil || jwtToken.Valid() != nil {
-		// and token revoked case. but we not implement
-		// TODO: 2022-03-03 add token revoked check, when we implemented the Token Revocation(rfc7009) Specs.
-		// refs: https://tools.ietf.org/html/rfc7009
+		c.Data["json"] = &object.IntrospectionResponse{Active: false}
+		c.ServeJSON()
+		return
+	}
+
+	// access tokens revoked by /api/login/oauth/revoke or logout are expired in the database
+	if token.AccessToken == tokenValue && util.IsTokenExpired(token.CreatedTime, token.ExpiresIn) {
 		c.Data["json"] = &object.IntrospectionResponse{Active: false}
 		c.ServeJSON()
 		return
@@ -327,3 +391,229 @@ func (c *ApiController) IntrospectToken() {
 	}
 	c.ServeJSON()
 }
+
+// RevokeToken
+// @Title RevokeToken
+// @Tag Token API
+// @Description revoke an access token or a refresh token, see RFC 7009. Revoking a refresh token
+// also revokes the access token issued with it. The client is authenticated by Basic Authorization
+// or by the client_id and client_secret parameters, the public clients send their client_id only.
+// @Param   token     formData    string  true        "access_token's value or refresh_token's value"
+// @Param   token_type_hint     formData    string  false        "the token type access_token or refresh_token"
+// @Success 200 The token has been revoked or was invalid already
+// @Success 400 {object} object.TokenError The Response object
+// @Success 401 {object} object.TokenError The Response object
+// @router /login/oauth/revoke [post]
+func (c *ApiController) RevokeToken() {
+	tokenValue := c.Input().Get("token")
+	tokenTypeHint := c.Input().Get("token_type_hint")
+
+	clientId, clientSecret, ok := c.Ctx.Request.BasicAuth()
+	if !ok {
+		clientId = c.Input().Get("client_id")
+		clientSecret = c.Input().Get("client_secret")
+	}
+	if c.Input().Get("client_assertion") != "" {
+		clientId, clientSecret, ok = c.authenticateClientAssertion(clientId)
+		if !ok {
+			return
+		}
+	}
+
+	application, err := object.GetApplicationByClientId(clientId)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	// the client secret can be empty for the public clients, like at the refresh token grant, but if
+	// it is provided, it must be accurate. The token must have been issued to the client anyway.
+	if application == nil || (clientSecret != "" && application.ClientSecret != clientSecret) {
+		c.Data["json"] = &object.TokenError{
+			Error:            object.InvalidClient,
+			ErrorDescription: c.T("token:Invalid application or wrong clientSecret"),
+		}
+		c.SetTokenErrorHttpStatus()
+		c.ServeJSON()
+		return
+	}
+
+	tokenError, err := object.RevokeToken(application, tokenValue, tokenTypeHint)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if tokenError != nil {
+		c.Data["json"] = tokenError
+		c.SetTokenErrorHttpStatus()
+		c.ServeJSON()
+		return
+	}
+
+	c.Ctx.Output.SetStatus(http.StatusOK)
+}
+
+// PushAuthorizationRequest
+// @Title PushAuthorizationRequest
+// @Tag Token API
+// @Description push the parameters of an authorization request (RFC 9126), either as form parameters
+// or as a signed request object, and get the request_uri to pass to the authorization endpoint
+// together with the client_id. The client is authenticated like at the token endpoint.
+// @Param   client_id     formData    string  true        "OAuth client id"
+// @Param   client_secret     formData    string  false        "OAuth client secret"
+// @Param   request     formData    string  false        "The request object (RFC 9101)"
+// @Success 201 {object} object.PushedAuthResponse The Response object
+// @Success 400 {object} object.TokenError The Response object
+// @Success 401 {object} object.TokenError The Response object
+// @router /login/oauth/par [post]
+func (c *ApiController) PushAuthorizationRequest() {
+	clientId, clientSecret, ok := c.Ctx.Request.BasicAuth()
+	if !ok {
+		clientId = c.Input().Get("client_id")
+		clientSecret = c.Input().Get("client_secret")
+	}
+	if c.Input().Get("client_assertion") != "" {
+		clientId, clientSecret, ok = c.authenticateClientAssertion(clientId)
+		if !ok {
+			return
+		}
+	}
+
+	application, err := object.GetApplicationByClientId(clientId)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if application == nil || clientSecret == "" || application.ClientSecret != clientSecret {
+		c.Data["json"] = &object.TokenError{
+			Error:            object.InvalidClient,
+			ErrorDescription: c.T("token:Invalid application or wrong clientSecret"),
+		}
+		c.SetTokenErrorHttpStatus()
+		c.ServeJSON()
+		return
+	}
+
+	if c.Input().Get("request_uri") != "" {
+		c.Data["json"] = &object.TokenError{
+			Error:            object.InvalidRequest,
+			ErrorDescription: "request_uri is not allowed in a pushed authorization request",
+		}
+		c.SetTokenErrorHttpStatus()
+		c.ServeJSON()
+		return
+	}
+
+	authorizeRequest := object.NewAuthorizeRequest(application.ClientId, c.Input().Get)
+	res, err := object.PushAuthorizeRequest(application, authorizeRequest, c.Input().Get("request"), c.Ctx.Request.Host, c.GetAcceptLanguage())
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = res
+	if _, ok := res.(*object.PushedAuthResponse); ok {
+		c.Ctx.Output.SetStatus(http.StatusCreated)
+	}
+	c.SetTokenErrorHttpStatus()
+	c.ServeJSON()
+}
+
+// DeviceAuthorization
+// @Title DeviceAuthorization
+// @Tag Token API
+// @Description start the OAuth 2.0 Device Authorization Grant (RFC 8628), the device then polls
+// /api/login/oauth/access_token with grant_type=urn:ietf:params:oauth:grant-type:device_code
+// @Param   client_id     formData    string  true        "OAuth client id"
+// @Param   client_secret     formData    string  false        "OAuth client secret"
+// @Param   scope     formData    string  false        "OAuth scope"
+// @Success 200 {object} object.DeviceAuthResponse The Response object
+// @Success 400 {object} object.TokenError The Response object
+// @Success 401 {object} object.TokenError The Response object
+// @router /login/oauth/device_authorization [post]
+func (c *ApiController) DeviceAuthorization() {
+	clientId := c.Input().Get("client_id")
+	clientSecret := c.Input().Get("client_secret")
+	scope := c.Input().Get("scope")
+
+	if clientId == "" && clientSecret == "" {
+		clientId, clientSecret, _ = c.Ctx.Request.BasicAuth()
+	}
+
+	deviceAuth, err := object.GetDeviceAuthorization(clientId, clientSecret, scope, c.Ctx.Request.Host)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.Data["json"] = deviceAuth
+	c.SetTokenErrorHttpStatus()
+	c.ServeJSON()
+}
+
+// GetDeviceAuth
+// @Title GetDeviceAuth
+// @Tag Token API
+// @Description get the pending device authorization by the user code shown on the device
+// @Param   userCode     query    string  true        "The user code"
+// @Success 200 {object} object.DeviceAuth The Response object
+// @router /get-device-auth [get]
+func (c *ApiController) GetDeviceAuth() {
+	user, ok := c.RequireSignedInUser()
+	if !ok {
+		return
+	}
+
+	deviceAuth, err := object.GetDeviceAuthByUserCode(c.Input().Get("userCode"))
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if deviceAuth == nil {
+		c.ResponseError(c.T("token:The user code is invalid or has expired"))
+		return
+	}
+
+	application, err := object.GetApplication(util.GetId(deviceAuth.Owner, deviceAuth.Application))
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	deviceAuth.DeviceCode = ""
+	c.ResponseOk(deviceAuth, object.GetMaskedApplication(application, user.GetId()))
+}
+
+// ApproveDeviceAuth
+// @Title ApproveDeviceAuth
+// @Tag Token API
+// @Description approve or deny the device authorization identified by the user code as the signed-in user
+// @Param   userCode     query    string  true        "The user code"
+// @Param   approved     query    bool  true        "Whether the user approves the device"
+// @Success 200 {object} controllers.Response The Response object
+// @router /approve-device-auth [post]
+func (c *ApiController) ApproveDeviceAuth() {
+	user, ok := c.RequireSignedInUser()
+	if !ok {
+		return
+	}
+
+	userCode := c.Input().Get("userCode")
+	approved := c.Input().Get("approved") == "true"
+
+	msg, err := object.ApproveDeviceAuth(userCode, user, approved, c.GetAcceptLanguage())
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	if msg != "" {
+		c.ResponseError(msg)
+		return
+	}
+
+	c.ResponseOk()
+}
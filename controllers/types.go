@@ -26,4 +26,11 @@ type TokenRequest struct {
 	Tag          string `json:"tag"`
 	Avatar       string `json:"avatar"`
 	RefreshToken string `json:"refresh_token"`
+	DeviceCode   string `json:"device_code"`
+
+	SubjectToken     string `json:"subject_token"`
+	SubjectTokenType string `json:"subject_token_type"`
+	ActorToken       string `json:"actor_token"`
+	ActorTokenType   string `json:"actor_token_type"`
+	Audience         string `json:"audience"`
 }
@@ -15,190 +15,217 @@
 package controllers
 
 import (
-	"github.com/beego/beego/logs"
+	"encoding/json"
+	"fmt"
+
 	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
 )
 
-// AddApiToken
-// @Title AddApiToken
-// @Tag Api Token API
-// @Description add api token
-// @Param owner query string true "The owner of token"
-// @Success 200 {object} object.UserApiToken The Response object
-// @Failure 403 Unauthorized operation
-// @Failure 500 Internal Server Error
-// @router /add-api-token [post]
-func (c *ApiController) AddApiToken() {
-	owner := c.Input().Get("owner")
-	if !c.IsGlobalAdmin() && owner == "" {
-		c.ResponseForbidden(c.T("auth:Unauthorized operation"))
-		return
-	}
+// apiTokenForm is the body of the API token requests, the owner and the name are the ones of the
+// user of the token so the API filter authorizes the user and the administrators of the organization
+type apiTokenForm struct {
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+	Token string `json:"token"`
+
+	DisplayName   string   `json:"displayName"`
+	Routes        []string `json:"routes"`
+	Organizations []string `json:"organizations"`
+	IpAllowList   []string `json:"ipAllowList"`
+	ExpireTime    string   `json:"expireTime"`
+}
 
-	user, err := object.GetUser(owner)
-	if err != nil {
-		logs.Error("get user: %s", err.Error())
+// apiTokenUserForm is the body of GetUserByApiToken
+type apiTokenUserForm struct {
+	ApiToken string `json:"apiToken"`
+}
 
-		c.ResponseInternalServerError("Internal server error")
-		return
+// getApiTokenUser returns the user whose tokens are managed, a user manages their own tokens and
+// an admin the ones of the users of their organization
+func (c *ApiController) getApiTokenUser(userId string) (*object.User, bool) {
+	currentUser, ok := c.RequireSignedInUser()
+	if !ok {
+		return nil, false
 	}
 
-	tokenUser := object.MakeUserForToken(user)
-
-	affected, err := object.AddUser(tokenUser)
+	user, err := object.GetUser(userId)
 	if err != nil {
-		logs.Error("token creation: %s", err.Error())
+		c.ResponseError(err.Error())
+		return nil, false
+	}
 
-		c.ResponseInternalServerError("Token creation error")
-		return
+	if user == nil {
+		c.ResponseError(fmt.Sprintf(c.T("general:The user: %s doesn't exist"), userId))
+		return nil, false
 	}
-	if !affected {
-		logs.Error("token creation: record not affected")
 
-		c.ResponseInternalServerError("Token creation error")
-		return
+	if !currentUser.IsGlobalAdmin() && !(currentUser.IsAdmin && currentUser.Owner == user.Owner) && currentUser.GetId() != user.GetId() {
+		c.ResponseForbidden(c.T("auth:Forbidden operation"))
+		return nil, false
 	}
 
-	c.ResponseOk(object.MakeUserApiToken(tokenUser))
+	return user, true
 }
 
-// DeleteApiToken
-// @Title DeleteApiToken
-// @Tag Api Token API
-// @Description delete api token
-// @Param owner query string true "The owner of token"
-// @Param api_token string true "The user api token"
-// @Success 200 Ok
-// @Failure 403 Unauthorized operation
-// @Failure 422 Unprocessable entity
-// @Failure 500 Internal Server Error
-// @router /delete-api-token [post]
-func (c *ApiController) DeleteApiToken() {
-	owner := c.Input().Get("owner")
-	if !c.IsGlobalAdmin() && owner == "" {
-		c.ResponseForbidden(c.T("auth:Unauthorized operation"))
-		return
+// getApiTokenOfForm returns the user and the token of the form
+func (c *ApiController) getApiTokenOfForm() (*object.User, *object.ApiToken, bool) {
+	var form apiTokenForm
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &form)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return nil, nil, false
 	}
 
-	token := c.Input().Get("api_token")
-	if token == "" {
-		c.ResponseUnprocessableEntity("token not provided")
-		return
+	user, ok := c.getApiTokenUser(util.GetId(form.Owner, form.Name))
+	if !ok {
+		return nil, nil, false
 	}
 
-	user, err := object.GetUser(owner)
+	tokenId := util.GetId(user.Owner, form.Token)
+	token, err := object.GetApiToken(tokenId)
 	if err != nil {
-		logs.Error("get user: %s", err.Error())
+		c.ResponseError(err.Error())
+		return nil, nil, false
+	}
 
-		c.ResponseInternalServerError("Internal server error")
-		return
+	if token == nil || token.User != user.Name {
+		c.ResponseError(fmt.Sprintf(c.T("general:The API token: %s does not exist"), tokenId))
+		return nil, nil, false
 	}
 
-	affected, err := object.DeleteApiToken(user, token)
-	if err != nil {
-		logs.Error("delete api token: %s", err.Error())
+	return user, token, true
+}
 
-		c.ResponseInternalServerError("delete token error")
+// GetApiTokens
+// @Title GetApiTokens
+// @Tag Api Token API
+// @Description get the API tokens of a user, without their secrets
+// @Param   id     query    string  true        "The id ( owner/name ) of the user"
+// @Success 200 {array} object.ApiToken The Response object
+// @router /get-api-tokens [get]
+func (c *ApiController) GetApiTokens() {
+	user, ok := c.getApiTokenUser(c.Input().Get("id"))
+	if !ok {
 		return
 	}
-	if !affected {
-		logs.Error("delete api token: does not affected")
 
-		c.ResponseInternalServerError("token does not deleted")
+	tokens, err := object.GetApiTokens(user.Owner, user.Name)
+	if err != nil {
+		c.ResponseError(err.Error())
 		return
 	}
 
-	c.ResponseOk()
+	c.ResponseOk(tokens)
 }
 
-// RecreateApiToken
-// @Title RecreateApiToken
+// AddApiToken
+// @Title AddApiToken
 // @Tag Api Token API
-// @Description recreate api token
-// @Param owner query string true "The owner of token"
-// @Param api_token string true "The user api token"
+// @Description add an API token to a user, restricted to routes like /api/get-users or /api/get-*, organizations and IP addresses or CIDR ranges, with an optional expire time in the RFC 3339 format. The secret of the token is only returned once
+// @Param   body    body   controllers.apiTokenForm  true        "The owner and the name of the user, and the scope of the token"
 // @Success 200 {object} object.UserApiToken The Response object
-// @Failure 403 Unauthorized operation
-// @Failure 422 Unprocessable entity
-// @Failure 500 Internal Server Error
-// @router /recreate-api-token [post]
-func (c *ApiController) RecreateApiToken() {
-	owner := c.Input().Get("owner")
-	if !c.IsGlobalAdmin() && owner == "" {
-		c.ResponseForbidden(c.T("auth:Unauthorized operation"))
+// @router /add-api-token [post]
+func (c *ApiController) AddApiToken() {
+	var form apiTokenForm
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &form)
+	if err != nil {
+		c.ResponseError(err.Error())
 		return
 	}
 
-	token := c.Input().Get("api_token")
-	if token == "" {
-		c.ResponseUnprocessableEntity("token not provided")
+	user, ok := c.getApiTokenUser(util.GetId(form.Owner, form.Name))
+	if !ok {
 		return
 	}
 
-	apiTokenUser, err := object.GetApiKeyUser(token)
-	if err != nil {
-		logs.Error("get api token: %s", err.Error())
-
-		c.ResponseInternalServerError("token not provided")
-		return
+	token := &object.ApiToken{
+		DisplayName:   form.DisplayName,
+		Routes:        form.Routes,
+		Organizations: form.Organizations,
+		IpAllowList:   form.IpAllowList,
+		ExpireTime:    form.ExpireTime,
 	}
-	if apiTokenUser == nil {
-		logs.Info("api token user not found")
 
-		c.ResponseInternalServerError("api token not found")
+	userApiToken, err := object.AddApiToken(user, token)
+	if err != nil {
+		c.ResponseError(err.Error())
 		return
 	}
 
-	tokenOwner, err := object.GetUser(owner)
-	if err != nil {
-		logs.Error("get user: %s", err.Error())
+	c.ResponseOk(userApiToken)
+}
 
-		c.ResponseInternalServerError("Internal server error")
+// DeleteApiToken
+// @Title DeleteApiToken
+// @Tag Api Token API
+// @Description delete an API token of a user
+// @Param   body    body   controllers.apiTokenForm  true        "The owner and the name of the user, and the name of the token"
+// @Success 200 {object} controllers.Response The Response object
+// @router /delete-api-token [post]
+func (c *ApiController) DeleteApiToken() {
+	_, token, ok := c.getApiTokenOfForm()
+	if !ok {
 		return
 	}
 
-	if apiTokenUser.Tag != object.MakeTokenUserTag(tokenOwner) {
-		logs.Error("token owner mismatch")
+	c.Data["json"] = wrapActionResponse(object.DeleteApiToken(token))
+	c.ServeJSON()
+}
 
-		c.ResponseUnprocessableEntity("owner mismatch")
+// RecreateApiToken
+// @Title RecreateApiToken
+// @Tag Api Token API
+// @Description replace the secret of an API token of a user, its scope is kept. The secret of the token is only returned once
+// @Param   body    body   controllers.apiTokenForm  true        "The owner and the name of the user, and the name of the token"
+// @Success 200 {object} object.UserApiToken The Response object
+// @router /recreate-api-token [post]
+func (c *ApiController) RecreateApiToken() {
+	_, token, ok := c.getApiTokenOfForm()
+	if !ok {
 		return
 	}
 
-	err = object.RecreateApiToken(tokenOwner, apiTokenUser)
+	userApiToken, err := object.RecreateApiToken(token)
 	if err != nil {
-		logs.Error("recreate api token: %s", err.Error())
-
-		c.ResponseInternalServerError("Token not affected")
+		c.ResponseError(err.Error())
 		return
 	}
 
-	c.ResponseOk(object.MakeUserApiToken(apiTokenUser))
+	c.ResponseOk(userApiToken)
 }
 
 // GetUserByApiToken
 // @Title GetUserByApiToken
 // @Tag Api Token API
-// @Description get user by API token
-// @Param api_token string true "The user api token"
+// @Description get the user of a valid API token, nil for an invalid or expired token. The token is sent in the body so that it is not logged with the URL.
+// @Param   body    body   controllers.apiTokenUserForm  true        "The API token"
 // @Success 200 {object} object.User The Response object
-// @Failure 403 Unauthorized operation
-// @Failure 422 Unprocessable entity
-// @Failure 500 Internal Server Error
 // @router /get-user-by-api-token [post]
 func (c *ApiController) GetUserByApiToken() {
-	token := c.Input().Get("api_token")
+	var form apiTokenUserForm
+	err := json.Unmarshal(c.Ctx.Input.RequestBody, &form)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	token := form.ApiToken
 	if token == "" {
-		c.ResponseUnprocessableEntity("token not provided")
+		c.ResponseError(c.T("general:Missing parameter"))
 		return
 	}
 
-	owner, err := object.GetApiKeyOwner(token)
+	user, err := object.GetApiTokenUser(token)
 	if err != nil {
-		logs.Error("get api key owner : %s", err.Error())
+		c.ResponseError(err.Error())
+		return
+	}
 
-		c.ResponseInternalServerError("Internal server error")
+	if user != nil && !c.IsAdminOrSelf(user) {
+		c.ResponseForbidden(c.T("auth:Forbidden operation"))
 		return
 	}
 
-	c.ResponseOk(owner)
+	c.ResponseOk(user)
 }
@@ -16,6 +16,8 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
+	"strconv"
 
 	"github.com/beego/beego/utils/pagination"
 	"github.com/casdoor/casdoor/object"
@@ -146,3 +148,150 @@ func (c *ApiController) DeleteWebhook() {
 	c.Data["json"] = wrapActionResponse(object.DeleteWebhook(&webhook))
 	c.ServeJSON()
 }
+
+func (c *ApiController) getWebhook(id string) *object.Webhook {
+	webhook, err := object.GetWebhook(id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return nil
+	}
+
+	if webhook == nil {
+		c.ResponseError(fmt.Sprintf(c.T("general:The webhook: %s does not exist"), id))
+		return nil
+	}
+
+	return webhook
+}
+
+// getWebhookDelivery returns the delivery of the webhook, so that the request is authorized
+// against the organization of the webhook of the delivery
+func (c *ApiController) getWebhookDelivery(webhookId string, id string) *object.WebhookDelivery {
+	webhook := c.getWebhook(webhookId)
+	if webhook == nil {
+		return nil
+	}
+
+	deliveryId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return nil
+	}
+
+	delivery, err := object.GetWebhookDelivery(deliveryId)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return nil
+	}
+
+	if delivery == nil || delivery.Webhook != webhook.GetId() {
+		c.ResponseError(fmt.Sprintf(c.T("general:The webhook delivery: %s does not exist"), id))
+		return nil
+	}
+
+	return delivery
+}
+
+// GetWebhookDeliveries
+// @Title GetWebhookDeliveries
+// @Tag Webhook API
+// @Description get the deliveries of the outbox of a webhook, the latest first
+// @Param   id     query    string  true        "The id ( owner/name ) of the webhook"
+// @Param   state     query    string  false        "The state of the deliveries: Pending, Succeeded or Dead"
+// @Success 200 {array} object.WebhookDelivery The Response object
+// @router /get-webhook-deliveries [get]
+func (c *ApiController) GetWebhookDeliveries() {
+	limit := c.Input().Get("pageSize")
+	page := c.Input().Get("p")
+	state := c.Input().Get("state")
+
+	webhook := c.getWebhook(c.Input().Get("id"))
+	if webhook == nil {
+		return
+	}
+
+	if limit == "" || page == "" {
+		deliveries, err := object.GetWebhookDeliveries(webhook.GetId(), state, -1, -1)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		c.ResponseOk(deliveries)
+	} else {
+		limit := util.ParseInt(limit)
+		count, err := object.GetWebhookDeliveryCount(webhook.GetId(), state)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		paginator := pagination.SetPaginator(c.Ctx, limit, count)
+
+		deliveries, err := object.GetWebhookDeliveries(webhook.GetId(), state, paginator.Offset(), limit)
+		if err != nil {
+			c.ResponseError(err.Error())
+			return
+		}
+
+		c.ResponseOk(deliveries, paginator.Nums())
+	}
+}
+
+// GetWebhookAttempts
+// @Title GetWebhookAttempts
+// @Tag Webhook API
+// @Description get the log of the requests sent for a webhook delivery
+// @Param   id     query    string  true        "The id ( owner/name ) of the webhook"
+// @Param   deliveryId     query    string  true        "The id of the delivery"
+// @Success 200 {array} object.WebhookAttempt The Response object
+// @router /get-webhook-attempts [get]
+func (c *ApiController) GetWebhookAttempts() {
+	delivery := c.getWebhookDelivery(c.Input().Get("id"), c.Input().Get("deliveryId"))
+	if delivery == nil {
+		return
+	}
+
+	attempts, err := object.GetWebhookAttempts(delivery.Id)
+	if err != nil {
+		c.ResponseError(err.Error())
+		return
+	}
+
+	c.ResponseOk(attempts)
+}
+
+// ReplayWebhookDelivery
+// @Title ReplayWebhookDelivery
+// @Tag Webhook API
+// @Description put a webhook delivery back in the outbox to send it again
+// @Param   id     query    string  true        "The id ( owner/name ) of the webhook"
+// @Param   deliveryId     query    string  true        "The id of the delivery"
+// @Success 200 {object} controllers.Response The Response object
+// @router /replay-webhook-delivery [post]
+func (c *ApiController) ReplayWebhookDelivery() {
+	delivery := c.getWebhookDelivery(c.Input().Get("id"), c.Input().Get("deliveryId"))
+	if delivery == nil {
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.ReplayWebhookDelivery(delivery.Id))
+	c.ServeJSON()
+}
+
+// ReplayWebhookDeliveries
+// @Title ReplayWebhookDeliveries
+// @Tag Webhook API
+// @Description put the dead deliveries of a webhook back in the outbox
+// @Param   id     query    string  true        "The id ( owner/name ) of the webhook"
+// @Success 200 {object} controllers.Response The Response object
+// @router /replay-webhook-deliveries [post]
+func (c *ApiController) ReplayWebhookDeliveries() {
+	webhook := c.getWebhook(c.Input().Get("id"))
+	if webhook == nil {
+		return
+	}
+
+	c.Data["json"] = wrapActionResponse(object.ReplayWebhookDeliveries(webhook.GetId()))
+	c.ServeJSON()
+}
@@ -49,6 +49,7 @@ type AuthForm struct {
 	RelayState   string `json:"relayState"`
 	SamlRequest  string `json:"samlRequest"`
 	SamlResponse string `json:"samlResponse"`
+	SamlQuery    string `json:"samlQuery"`
 
 	CaptchaType  string `json:"captchaType"`
 	CaptchaToken string `json:"captchaToken"`
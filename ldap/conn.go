@@ -0,0 +1,76 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ldap
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sync"
+
+	ldap "github.com/forestmgy/ldapserver"
+)
+
+// ldapConn is a client connection whose writes are serialized. The server writes every response
+// in a single write, so the responses it writes and the ones written by searchResponseWriter are
+// never interleaved.
+type ldapConn struct {
+	net.Conn
+	lock sync.Mutex
+}
+
+func (c *ldapConn) Write(b []byte) (int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.Conn.Write(b)
+}
+
+func (c *ldapConn) isTls() bool {
+	_, ok := c.Conn.(*tls.Conn)
+	return ok
+}
+
+// ldapListener wraps the accepted connections into ldapConn
+type ldapListener struct {
+	net.Listener
+}
+
+func (l *ldapListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &ldapConn{Conn: conn}, nil
+}
+
+// withLdapListener is the option of the servers wrapping their listener, the TLS listener of
+// LDAPS is wrapped as well so that the writes are serialized above TLS
+func withLdapListener(tlsConfig *tls.Config) func(s *ldap.Server) {
+	return func(s *ldap.Server) {
+		if tlsConfig != nil {
+			s.Listener = tls.NewListener(s.Listener, tlsConfig)
+		}
+		s.Listener = &ldapListener{Listener: s.Listener}
+	}
+}
+
+// getLdapConn returns the current connection of the client, which is replaced on StartTLS
+func getLdapConn(m *ldap.Message) (*ldapConn, error) {
+	conn, ok := m.Client.GetConn().(*ldapConn)
+	if !ok {
+		return nil, fmt.Errorf("the LDAP connection: %T is not serialized", m.Client.GetConn())
+	}
+	return conn, nil
+}
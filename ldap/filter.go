@@ -0,0 +1,191 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ldap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	ldap "github.com/forestmgy/ldapserver"
+	"github.com/lor00x/goldap/message"
+)
+
+// ldapEntry is an entry whose attributes are indexed by their lower case names, the filters
+// are evaluated against it in memory
+type ldapEntry struct {
+	dn         string
+	attributes map[string][]string
+	// chainAttributes are the values of the attributes including nested memberships, matched
+	// by the matching rule in chain
+	chainAttributes map[string][]string
+}
+
+func (entry *ldapEntry) getValues(attribute string, inChain bool) []string {
+	attribute = strings.ToLower(attribute)
+	if inChain {
+		if values, ok := entry.chainAttributes[attribute]; ok {
+			return values
+		}
+	}
+	return entry.attributes[attribute]
+}
+
+func (entry *ldapEntry) toSearchResultEntry(attributes []string) message.SearchResultEntry {
+	e := ldap.NewSearchResultEntry(entry.dn)
+	for _, attribute := range attributes {
+		values := []message.AttributeValue{}
+		for _, value := range entry.getValues(attribute, false) {
+			values = append(values, message.AttributeValue(value))
+		}
+		if len(values) != 0 {
+			e.AddAttribute(message.AttributeDescription(attribute), values...)
+		}
+	}
+	return e
+}
+
+// compareValues compares the values as integers if both are integers, and as case-insensitive
+// strings otherwise
+func compareValues(a string, b string) int {
+	x, errX := strconv.ParseInt(a, 10, 64)
+	y, errY := strconv.ParseInt(b, 10, 64)
+	if errX == nil && errY == nil {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+func matchAnyValue(values []string, match func(value string) bool) bool {
+	for _, value := range values {
+		if match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchEquality(value string, assertion string) bool {
+	// DNs are compared regardless of the spaces between their RDNs
+	return strings.EqualFold(value, assertion) || normalizeDn(value) == normalizeDn(assertion)
+}
+
+func matchSubstrings(value string, substrings []message.Substring) bool {
+	value = strings.ToLower(value)
+	for _, substring := range substrings {
+		switch s := substring.(type) {
+		case message.SubstringInitial:
+			prefix := strings.ToLower(string(s))
+			if !strings.HasPrefix(value, prefix) {
+				return false
+			}
+			value = value[len(prefix):]
+		case message.SubstringAny:
+			part := strings.ToLower(string(s))
+			i := strings.Index(value, part)
+			if i == -1 {
+				return false
+			}
+			value = value[i+len(part):]
+		case message.SubstringFinal:
+			if !strings.HasSuffix(value, strings.ToLower(string(s))) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchFilter evaluates the LDAP filter against the entry
+func matchFilter(filter interface{}, entry *ldapEntry) (bool, error) {
+	switch f := filter.(type) {
+	case message.FilterAnd:
+		for _, v := range f {
+			ok, err := matchFilter(v, entry)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case message.FilterOr:
+		for _, v := range f {
+			ok, err := matchFilter(v, entry)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case message.FilterNot:
+		ok, err := matchFilter(f.Filter, entry)
+		return !ok, err
+	case message.FilterEqualityMatch:
+		assertion := string(f.AssertionValue())
+		return matchAnyValue(entry.getValues(string(f.AttributeDesc()), false), func(value string) bool {
+			return matchEquality(value, assertion)
+		}), nil
+	case message.FilterApproxMatch:
+		assertion := string(f.AssertionValue())
+		return matchAnyValue(entry.getValues(string(f.AttributeDesc()), false), func(value string) bool {
+			return matchEquality(value, assertion)
+		}), nil
+	case message.FilterPresent:
+		return len(entry.getValues(string(f), false)) != 0, nil
+	case message.FilterGreaterOrEqual:
+		assertion := string(f.AssertionValue())
+		return matchAnyValue(entry.getValues(string(f.AttributeDesc()), false), func(value string) bool {
+			return compareValues(value, assertion) >= 0
+		}), nil
+	case message.FilterLessOrEqual:
+		assertion := string(f.AssertionValue())
+		return matchAnyValue(entry.getValues(string(f.AttributeDesc()), false), func(value string) bool {
+			return compareValues(value, assertion) <= 0
+		}), nil
+	case message.FilterSubstrings:
+		return matchAnyValue(entry.getValues(string(f.Type_()), false), func(value string) bool {
+			return matchSubstrings(value, f.Substrings())
+		}), nil
+	case message.FilterExtensibleMatch:
+		attribute, rule, assertion := getExtensibleMatch(f)
+		if rule != "" && rule != matchingRuleInChain {
+			return false, fmt.Errorf("LDAP matching rule %s not supported", rule)
+		}
+		return matchAnyValue(entry.getValues(attribute, rule == matchingRuleInChain), func(value string) bool {
+			return matchEquality(value, assertion)
+		}), nil
+	default:
+		return false, fmt.Errorf("LDAP filter operation %#v not supported", f)
+	}
+}
+
+// getExtensibleMatch returns the attribute, the matching rule and the value of an extensible
+// match filter like (memberOf:1.2.840.113556.1.4.1941:=cn=admins,ou=groups,ou=built-in,dc=example,dc=com)
+func getExtensibleMatch(f message.FilterExtensibleMatch) (string, string, string) {
+	attribute, rule := "", ""
+	if f.Type_() != nil {
+		attribute = string(*f.Type_())
+	}
+	if f.MatchingRule() != nil {
+		rule = string(*f.MatchingRule())
+	}
+	return attribute, rule, string(f.MatchValue())
+}
@@ -0,0 +1,346 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ldap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
+)
+
+// Groups and roles of an organization are published under the ou=groups branch of the organization:
+//
+//	cn=<group>,ou=groups,ou=<organization>,dc=example,dc=com
+//	cn=<role>,ou=roles,ou=groups,ou=<organization>,dc=example,dc=com
+const (
+	groupsOu = "groups"
+	rolesOu  = "roles"
+
+	// matchingRuleInChain is LDAP_MATCHING_RULE_IN_CHAIN of Active Directory, the filter
+	// (memberOf:1.2.840.113556.1.4.1941:=<group DN>) also matches the members of nested groups
+	matchingRuleInChain = "1.2.840.113556.1.4.1941"
+)
+
+var groupObjectClasses = []string{"top", "groupOfNames", "posixGroup"}
+
+// groupAttributes are the attributes of group entries, indexed by their lower case names
+var groupAttributes = map[string]string{
+	"objectclass": "objectClass",
+	"cn":          "cn",
+	"description": "description",
+	"gidnumber":   "gidNumber",
+	"member":      "member",
+	"memberuid":   "memberUid",
+	"memberof":    "memberOf",
+}
+
+type ldapGroup struct {
+	dn          string
+	name        string
+	description string
+	gidNumber   uint32
+	users       []*object.User
+	members     []*ldapGroup
+	memberOf    []*ldapGroup
+}
+
+// groupDirectory holds the groups and roles of an organization together with their members
+type groupDirectory struct {
+	orgDn        string
+	groups       []*ldapGroup
+	userMemberOf map[string][]*ldapGroup
+}
+
+func splitDn(dn string) []string {
+	rdns := []string{}
+	for _, rdn := range strings.Split(dn, ",") {
+		rdn = strings.TrimSpace(rdn)
+		if rdn != "" {
+			rdns = append(rdns, rdn)
+		}
+	}
+	return rdns
+}
+
+func normalizeDn(dn string) string {
+	return strings.ToLower(strings.Join(splitDn(dn), ","))
+}
+
+// isDnUnder returns whether the DN is the base DN or one of its descendants
+func isDnUnder(dn string, baseDn string) bool {
+	dn, baseDn = normalizeDn(dn), normalizeDn(baseDn)
+	return dn == baseDn || strings.HasSuffix(dn, ","+baseDn)
+}
+
+// getOrgDn returns the DN of the organization in the DN, with the organization replaced by
+// org if it is not empty, e.g. "ou=built-in,dc=example,dc=com" for
+// "cn=admins,ou=groups,ou=built-in,dc=example,dc=com"
+func getOrgDn(dn string, org string) string {
+	rdns := splitDn(dn)
+	for i := len(rdns) - 1; i >= 0; i-- {
+		if strings.HasPrefix(strings.ToLower(rdns[i]), "ou=") {
+			if org != "" {
+				rdns[i] = "ou=" + org
+			}
+			return strings.Join(rdns[i:], ",")
+		}
+	}
+	return dn
+}
+
+// getOrgName returns the organization of an organization DN like "ou=built-in,dc=example,dc=com"
+func getOrgName(orgDn string) string {
+	rdns := splitDn(orgDn)
+	if len(rdns) == 0 || !strings.HasPrefix(strings.ToLower(rdns[0]), "ou=") {
+		return ""
+	}
+	return rdns[0][len("ou="):]
+}
+
+// isGroupDn returns whether the DN is in the ou=groups branch of an organization
+func isGroupDn(dn string) bool {
+	rdns := splitDn(dn)
+	for i, rdn := range rdns {
+		if strings.EqualFold(rdn, "ou="+groupsOu) && i < len(rdns)-1 {
+			return true
+		}
+	}
+	return false
+}
+
+func getUserDn(user *object.User, orgDn string) string {
+	return fmt.Sprintf("uid=%s,cn=%s,%s", user.Id, user.Name, orgDn)
+}
+
+func addGroupMember(group *ldapGroup, member *ldapGroup) {
+	group.members = append(group.members, member)
+	member.memberOf = append(member.memberOf, group)
+}
+
+func newGroupDirectory(org string, orgDn string) (*groupDirectory, error) {
+	groups, err := object.GetGroups(org)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := object.GetRoles(org)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := object.GetUsers(org)
+	if err != nil {
+		return nil, err
+	}
+
+	directory := &groupDirectory{
+		orgDn:        orgDn,
+		groups:       []*ldapGroup{},
+		userMemberOf: map[string][]*ldapGroup{},
+	}
+
+	groupMap := map[string]*ldapGroup{}
+	for _, group := range groups {
+		ldapGroup := &ldapGroup{
+			dn:          fmt.Sprintf("cn=%s,ou=%s,%s", group.Name, groupsOu, orgDn),
+			name:        group.Name,
+			description: group.DisplayName,
+			gidNumber:   hash(group.GetId()),
+		}
+		groupMap[group.GetId()] = ldapGroup
+		directory.groups = append(directory.groups, ldapGroup)
+	}
+
+	roleMap := map[string]*ldapGroup{}
+	for _, role := range roles {
+		ldapGroup := &ldapGroup{
+			dn:          fmt.Sprintf("cn=%s,ou=%s,ou=%s,%s", role.Name, rolesOu, groupsOu, orgDn),
+			name:        role.Name,
+			description: role.Description,
+			// roles and groups can have the same name
+			gidNumber: hash("role:" + role.GetId()),
+		}
+		roleMap[role.GetId()] = ldapGroup
+		directory.groups = append(directory.groups, ldapGroup)
+	}
+
+	for _, group := range groups {
+		if group.Owner == group.ParentId {
+			continue
+		}
+
+		if parent, ok := groupMap[util.GetId(group.Owner, group.ParentId)]; ok {
+			addGroupMember(parent, groupMap[group.GetId()])
+		}
+	}
+
+	userMap := map[string]*object.User{}
+	for _, user := range users {
+		userMap[user.GetId()] = user
+		for _, groupId := range user.Groups {
+			if group, ok := groupMap[groupId]; ok {
+				group.users = append(group.users, user)
+				directory.userMemberOf[user.Name] = append(directory.userMemberOf[user.Name], group)
+			}
+		}
+	}
+
+	// the members of the groups and of the sub roles of a role are members of the role as well
+	for _, role := range roles {
+		ldapRole := roleMap[role.GetId()]
+		for _, groupId := range role.Groups {
+			if group, ok := groupMap[groupId]; ok {
+				addGroupMember(ldapRole, group)
+			}
+		}
+		for _, roleId := range role.Roles {
+			if subRole, ok := roleMap[roleId]; ok {
+				addGroupMember(ldapRole, subRole)
+			}
+		}
+		for _, userId := range role.Users {
+			if user, ok := userMap[userId]; ok {
+				ldapRole.users = append(ldapRole.users, user)
+				directory.userMemberOf[user.Name] = append(directory.userMemberOf[user.Name], ldapRole)
+			}
+		}
+	}
+
+	return directory, nil
+}
+
+func (directory *groupDirectory) getGroupByDn(dn string) *ldapGroup {
+	dn = normalizeDn(dn)
+	for _, group := range directory.groups {
+		if normalizeDn(group.dn) == dn {
+			return group
+		}
+	}
+	return nil
+}
+
+// getNestedGroups returns the groups and the groups nested in them
+func getNestedGroups(groups []*ldapGroup, next func(group *ldapGroup) []*ldapGroup) []*ldapGroup {
+	res := []*ldapGroup{}
+	visited := map[*ldapGroup]bool{}
+	queue := append([]*ldapGroup{}, groups...)
+	for len(queue) > 0 {
+		group := queue[0]
+		queue = queue[1:]
+		if visited[group] {
+			continue
+		}
+
+		visited[group] = true
+		res = append(res, group)
+		queue = append(queue, next(group)...)
+	}
+	return res
+}
+
+// getNestedUsers returns the members of the group and of the groups nested in it
+func (group *ldapGroup) getNestedUsers() []*object.User {
+	res := []*object.User{}
+	visited := map[*object.User]bool{}
+	for _, g := range getNestedGroups([]*ldapGroup{group}, func(g *ldapGroup) []*ldapGroup { return g.members }) {
+		for _, user := range g.users {
+			if !visited[user] {
+				visited[user] = true
+				res = append(res, user)
+			}
+		}
+	}
+	return res
+}
+
+// getUserMemberOf returns the DNs of the groups the user is a member of, with nested set to
+// true the groups containing these groups are returned as well
+func (directory *groupDirectory) getUserMemberOf(user *object.User, nested bool) []string {
+	groups := directory.userMemberOf[user.Name]
+	if nested {
+		groups = getNestedGroups(groups, func(g *ldapGroup) []*ldapGroup { return g.memberOf })
+	}
+
+	res := []string{}
+	for _, group := range groups {
+		res = append(res, group.dn)
+	}
+	return res
+}
+
+func (directory *groupDirectory) getGroupEntry(group *ldapGroup) *ldapEntry {
+	members := []string{}
+	memberUids := []string{}
+	for _, user := range group.users {
+		members = append(members, getUserDn(user, directory.orgDn))
+		memberUids = append(memberUids, user.Name)
+	}
+	for _, member := range group.members {
+		members = append(members, member.dn)
+	}
+
+	memberOf := []string{}
+	for _, parent := range group.memberOf {
+		memberOf = append(memberOf, parent.dn)
+	}
+
+	nestedMembers := []string{}
+	for _, user := range group.getNestedUsers() {
+		nestedMembers = append(nestedMembers, getUserDn(user, directory.orgDn))
+	}
+	for _, member := range getNestedGroups(group.members, func(g *ldapGroup) []*ldapGroup { return g.members }) {
+		nestedMembers = append(nestedMembers, member.dn)
+	}
+
+	nestedMemberOf := []string{}
+	for _, parent := range getNestedGroups(group.memberOf, func(g *ldapGroup) []*ldapGroup { return g.memberOf }) {
+		nestedMemberOf = append(nestedMemberOf, parent.dn)
+	}
+
+	return &ldapEntry{
+		dn: group.dn,
+		attributes: map[string][]string{
+			"objectclass": groupObjectClasses,
+			"cn":          {group.name},
+			"description": {group.description},
+			"gidnumber":   {fmt.Sprintf("%v", group.gidNumber)},
+			"member":      members,
+			"memberuid":   memberUids,
+			"memberof":    memberOf,
+		},
+		chainAttributes: map[string][]string{
+			"member":   nestedMembers,
+			"memberof": nestedMemberOf,
+		},
+	}
+}
+
+// getGroupDirectory returns the directory of the organization, the directories are cached
+// for the duration of a search
+func getGroupDirectory(directories map[string]*groupDirectory, org string, orgDn string) (*groupDirectory, error) {
+	if directory, ok := directories[org]; ok {
+		return directory, nil
+	}
+
+	directory, err := newGroupDirectory(org, orgDn)
+	if err != nil {
+		return nil, err
+	}
+
+	directories[org] = directory
+	return directory, nil
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ldap
+
+import (
+	"testing"
+
+	"github.com/casdoor/casdoor/object"
+	"github.com/lor00x/goldap/message"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupDn(t *testing.T) {
+	groupDn := "cn=admins, ou=groups, ou=built-in, dc=example, dc=com"
+
+	assert.Equal(t, "ou=built-in,dc=example,dc=com", getOrgDn(groupDn, ""))
+	assert.Equal(t, "ou=org,dc=example,dc=com", getOrgDn(groupDn, "org"))
+	assert.Equal(t, "built-in", getOrgName(getOrgDn(groupDn, "")))
+	assert.True(t, isGroupDn(groupDn))
+	assert.False(t, isGroupDn("ou=built-in,dc=example,dc=com"))
+	assert.True(t, isDnUnder(groupDn, "ou=groups,ou=built-in,dc=example,dc=com"))
+	assert.False(t, isDnUnder(groupDn, "ou=roles,ou=groups,ou=built-in,dc=example,dc=com"))
+}
+
+func TestNestedGroups(t *testing.T) {
+	alice := &object.User{Name: "alice"}
+	bob := &object.User{Name: "bob"}
+	engineering := &ldapGroup{dn: "cn=engineering,ou=groups,ou=org,dc=example,dc=com", users: []*object.User{alice}}
+	backend := &ldapGroup{dn: "cn=backend,ou=groups,ou=org,dc=example,dc=com", users: []*object.User{bob}}
+	addGroupMember(engineering, backend)
+	directory := &groupDirectory{
+		orgDn:        "ou=org,dc=example,dc=com",
+		groups:       []*ldapGroup{engineering, backend},
+		userMemberOf: map[string][]*ldapGroup{"alice": {engineering}, "bob": {backend}},
+	}
+
+	assert.Equal(t, []*object.User{alice, bob}, engineering.getNestedUsers())
+	assert.Equal(t, []string{backend.dn}, directory.getUserMemberOf(bob, false))
+	assert.Equal(t, []string{backend.dn, engineering.dn}, directory.getUserMemberOf(bob, true))
+
+	entry := directory.getGroupEntry(engineering)
+	assert.Len(t, entry.getValues("member", false), 2)
+	assert.Len(t, entry.getValues("member", true), 3)
+}
+
+func TestMatchSubstrings(t *testing.T) {
+	substrings := []message.Substring{message.SubstringInitial("ad"), message.SubstringAny("mi"), message.SubstringFinal("s")}
+	assert.True(t, matchSubstrings("Admins", substrings))
+	assert.False(t, matchSubstrings("Administrator", substrings))
+}
@@ -0,0 +1,488 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ldap
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
+	ldap "github.com/forestmgy/ldapserver"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	ldapv3 "github.com/go-ldap/ldap/v3"
+	"github.com/lor00x/goldap/message"
+)
+
+// The scopes of a search request, see RFC 4511 section 4.5.1.2
+const (
+	scopeBaseObject   = 0
+	scopeSingleLevel  = 1
+	scopeWholeSubtree = 2
+)
+
+var (
+	containerObjectClasses = []string{"top", "organizationalUnit"}
+)
+
+var containerAttributes = map[string]string{
+	"objectclass": "objectClass",
+	"ou":          "ou",
+}
+
+var rootDseAttributes = map[string]string{
+	"objectclass":          "objectClass",
+	"supportedldapversion": "supportedLDAPVersion",
+	"supportedcontrol":     "supportedControl",
+	"supportedextension":   "supportedExtension",
+}
+
+// searchEntry is an entry a search can return, it is returned if it is in the scope of the
+// search and matches its filter
+type searchEntry struct {
+	*ldapEntry
+	// scopeDns are the DNs the scope is evaluated against, an entry can be found under several DNs
+	scopeDns          []string
+	searchResultEntry func() message.SearchResultEntry
+}
+
+func (entry *searchEntry) getSearchResultEntry() message.SearchResultEntry {
+	return entry.searchResultEntry()
+}
+
+func getParentDn(dn string) string {
+	rdns := splitDn(dn)
+	if len(rdns) == 0 {
+		return ""
+	}
+	return strings.Join(rdns[1:], ",")
+}
+
+func (entry *searchEntry) isInScope(baseDn string, scope int) bool {
+	for _, dn := range entry.scopeDns {
+		switch scope {
+		case scopeBaseObject:
+			if normalizeDn(dn) == normalizeDn(baseDn) {
+				return true
+			}
+		case scopeSingleLevel:
+			if normalizeDn(getParentDn(dn)) == normalizeDn(baseDn) {
+				return true
+			}
+		default:
+			if isDnUnder(dn, baseDn) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getRequestedAttributes returns the names of the requested attributes among the attributes,
+// which are indexed by their lower case names
+func getRequestedAttributes(r message.SearchRequest, attributes map[string]string) []string {
+	res := []string{}
+	isAll := len(r.Attributes()) == 0
+	for _, attr := range r.Attributes() {
+		if string(attr) == "*" {
+			isAll = true
+			break
+		}
+
+		if name, ok := attributes[strings.ToLower(string(attr))]; ok {
+			res = append(res, name)
+		}
+	}
+
+	if isAll {
+		res = []string{}
+		for _, name := range attributes {
+			res = append(res, name)
+		}
+		sort.Strings(res)
+	}
+	return res
+}
+
+func isAttributeRequested(r message.SearchRequest, attribute string) bool {
+	for _, attr := range r.Attributes() {
+		if string(attr) == "*" || strings.EqualFold(string(attr), attribute) {
+			return true
+		}
+	}
+	return false
+}
+
+func isRootDseSearch(r message.SearchRequest) bool {
+	return string(r.BaseObject()) == "" && int(r.Scope()) == scopeBaseObject
+}
+
+func getRootDseEntry() *ldapEntry {
+	extensions := []string{}
+	if ldapTlsConfig != nil {
+		extensions = append(extensions, string(ldap.NoticeOfStartTLS))
+	}
+
+	return &ldapEntry{
+		dn: "",
+		attributes: map[string][]string{
+			"objectclass":          {"top"},
+			"supportedldapversion": {"3"},
+			"supportedcontrol":     {ldapv3.ControlTypePaging},
+			"supportedextension":   extensions,
+		},
+	}
+}
+
+func newContainerEntry(r message.SearchRequest, dn string) *searchEntry {
+	entry := &ldapEntry{
+		dn: dn,
+		attributes: map[string][]string{
+			"objectclass": containerObjectClasses,
+			"ou":          {splitDn(dn)[0][len("ou="):]},
+		},
+	}
+
+	return &searchEntry{
+		ldapEntry: entry,
+		scopeDns:  []string{dn},
+		searchResultEntry: func() message.SearchResultEntry {
+			return entry.toSearchResultEntry(getRequestedAttributes(r, containerAttributes))
+		},
+	}
+}
+
+func newUserEntry(r message.SearchRequest, user *object.User, orgDn string, organization *object.Organization, directory *groupDirectory) *searchEntry {
+	dn := getUserDn(user, orgDn)
+	objectClasses, schema := object.GetLdapSchema(organization)
+
+	entry := &ldapEntry{
+		dn: dn,
+		attributes: map[string][]string{
+			"objectclass": objectClasses,
+		},
+		chainAttributes: map[string][]string{},
+	}
+
+	// the names of the attributes of the entry, indexed by their lower case names
+	attributeNames := map[string]string{"objectclass": "objectClass"}
+	for _, attribute := range schema {
+		name := strings.ToLower(attribute.Name)
+		if _, ok := attributeNames[name]; !ok {
+			attributeNames[name] = attribute.Name
+		}
+
+		for _, value := range attribute.GetValues(user) {
+			if !util.InSlice(entry.attributes[name], value) {
+				entry.attributes[name] = append(entry.attributes[name], value)
+			}
+		}
+	}
+
+	if directory != nil {
+		attributeNames["memberof"] = "memberOf"
+		entry.attributes["memberof"] = directory.getUserMemberOf(user, false)
+		entry.chainAttributes["memberof"] = directory.getUserMemberOf(user, true)
+	}
+
+	searchResultEntry := func() message.SearchResultEntry {
+		e := entry.toSearchResultEntry(getRequestedAttributes(r, attributeNames))
+
+		// the password is never searchable, and only returned when it is explicitly requested
+		for _, attr := range r.Attributes() {
+			if strings.EqualFold(string(attr), "userPassword") {
+				e.AddAttribute("userPassword", message.AttributeValue(getUserPasswordWithType(user, organization)))
+				break
+			}
+		}
+		return e
+	}
+
+	return &searchEntry{
+		ldapEntry: entry,
+		// the user can be read by its bind DN as well
+		scopeDns:          []string{dn, fmt.Sprintf("cn=%s,%s", user.Name, orgDn)},
+		searchResultEntry: searchResultEntry,
+	}
+}
+
+// getSearchOrganization returns the organization of the users, the organizations are cached
+// for the duration of a search
+func getSearchOrganization(organizations map[string]*object.Organization, name string) (*object.Organization, error) {
+	if organization, ok := organizations[name]; ok {
+		return organization, nil
+	}
+
+	organization, err := object.GetOrganization(util.GetId("admin", name))
+	if err != nil {
+		return nil, err
+	}
+
+	organizations[name] = organization
+	return organization, nil
+}
+
+func newGroupEntry(r message.SearchRequest, directory *groupDirectory, group *ldapGroup) *searchEntry {
+	entry := directory.getGroupEntry(group)
+	return &searchEntry{
+		ldapEntry: entry,
+		scopeDns:  []string{group.dn},
+		searchResultEntry: func() message.SearchResultEntry {
+			return entry.toSearchResultEntry(getRequestedAttributes(r, groupAttributes))
+		},
+	}
+}
+
+// getSearchUsers returns the users of the organization the bound user can read: all of them
+// for the administrators, and only the bound user otherwise
+func getSearchUsers(m *ldap.Message, org string) ([]*object.User, error) {
+	if m.Client.IsOrgAdmin {
+		if org == "*" {
+			return object.GetGlobalUsersWithFilter(nil)
+		}
+		return object.GetUsersWithFilter(org, nil)
+	}
+
+	user, err := object.GetUser(util.GetId(m.Client.OrgName, m.Client.UserName))
+	if err != nil || user == nil {
+		return []*object.User{}, err
+	}
+	return []*object.User{user}, nil
+}
+
+// getSearchEntries returns the entries under the organization of the base DN of the search
+func getSearchEntries(m *ldap.Message) ([]*searchEntry, int) {
+	r := m.GetSearchRequest()
+	baseDn := string(r.BaseObject())
+	orgDn := getOrgDn(baseDn, "")
+	org := getOrgName(orgDn)
+	if org == "" {
+		return nil, ldap.LDAPResultInvalidDNSyntax
+	}
+
+	if org == "*" && !m.Client.IsGlobalAdmin || org != "*" && org != m.Client.OrgName && !m.Client.IsGlobalAdmin {
+		return nil, ldap.LDAPResultInsufficientAccessRights
+	}
+
+	entries := []*searchEntry{}
+	directories := map[string]*groupDirectory{}
+	organizations := map[string]*object.Organization{}
+	filter := strings.ToLower(r.FilterString())
+	needsMemberOf := isAttributeRequested(r, "memberOf") || strings.Contains(filter, "memberof")
+
+	if !isGroupDn(baseDn) {
+		users, err := getSearchUsers(m, org)
+		if err != nil {
+			log.Printf("getSearchUsers() error: %s", err.Error())
+			return nil, ldap.LDAPResultOperationsError
+		}
+
+		for _, user := range users {
+			userOrgDn := getOrgDn(baseDn, user.Owner)
+
+			var directory *groupDirectory
+			if needsMemberOf {
+				directory, err = getGroupDirectory(directories, user.Owner, userOrgDn)
+				if err != nil {
+					log.Printf("getGroupDirectory() error: %s", err.Error())
+					return nil, ldap.LDAPResultOperationsError
+				}
+			}
+
+			organization, err := getSearchOrganization(organizations, user.Owner)
+			if err != nil {
+				log.Printf("getSearchOrganization() error: %s", err.Error())
+				return nil, ldap.LDAPResultOperationsError
+			}
+
+			entry := newUserEntry(r, user, userOrgDn, organization, directory)
+			if org == "*" {
+				// the users of all the organizations are under ou=*
+				entry.scopeDns = append(entry.scopeDns, getUserDn(user, orgDn), fmt.Sprintf("cn=%s,%s", user.Name, orgDn))
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	if org == "*" {
+		return entries, ldap.LDAPResultSuccess
+	}
+
+	groupsDn := fmt.Sprintf("ou=%s,%s", groupsOu, orgDn)
+	rolesDn := fmt.Sprintf("ou=%s,%s", rolesOu, groupsDn)
+	for _, dn := range []string{orgDn, groupsDn, rolesDn} {
+		entries = append(entries, newContainerEntry(r, dn))
+	}
+
+	if !isDnUnder(baseDn, groupsDn) && (!isDnUnder(groupsDn, baseDn) || int(r.Scope()) != scopeWholeSubtree) {
+		return entries, ldap.LDAPResultSuccess
+	}
+
+	directory, err := getGroupDirectory(directories, org, orgDn)
+	if err != nil {
+		log.Printf("getGroupDirectory() error: %s", err.Error())
+		return nil, ldap.LDAPResultOperationsError
+	}
+
+	// users who are not administrators only see the groups they are members of
+	visibleGroups := map[string]bool{}
+	if !m.Client.IsOrgAdmin {
+		user, err := object.GetUser(util.GetId(m.Client.OrgName, m.Client.UserName))
+		if err != nil {
+			log.Printf("GetUser() error: %s", err.Error())
+			return nil, ldap.LDAPResultOperationsError
+		}
+		if user != nil {
+			for _, dn := range directory.getUserMemberOf(user, true) {
+				visibleGroups[dn] = true
+			}
+		}
+	}
+
+	for _, group := range directory.groups {
+		if m.Client.IsOrgAdmin || visibleGroups[group.dn] {
+			entries = append(entries, newGroupEntry(r, directory, group))
+		}
+	}
+
+	return entries, ldap.LDAPResultSuccess
+}
+
+// pagingControl is the Simple Paged Results control of a search (RFC 2696), the cookie is the
+// offset of the next page in the results
+type pagingControl struct {
+	size   int
+	offset int
+}
+
+func getPagingControl(m *ldap.Message) (*pagingControl, error) {
+	controls := m.Controls()
+	if controls == nil {
+		return nil, nil
+	}
+
+	for _, control := range *controls {
+		if string(control.ControlType()) != ldapv3.ControlTypePaging {
+			continue
+		}
+
+		if control.ControlValue() == nil {
+			return nil, fmt.Errorf("the paged results control has no value")
+		}
+
+		packet, err := ber.DecodePacketErr([]byte(*control.ControlValue()))
+		if err != nil {
+			return nil, err
+		}
+		if len(packet.Children) != 2 {
+			return nil, fmt.Errorf("the paged results control is invalid")
+		}
+
+		size, ok := packet.Children[0].Value.(int64)
+		if !ok || size < 0 {
+			return nil, fmt.Errorf("the size of the paged results control is invalid")
+		}
+
+		offset := 0
+		cookie := packet.Children[1].Data.Bytes()
+		if len(cookie) != 0 {
+			offset, err = strconv.Atoi(string(cookie))
+			if err != nil || offset < 0 {
+				return nil, fmt.Errorf("the cookie of the paged results control is invalid")
+			}
+		}
+
+		return &pagingControl{size: int(size), offset: offset}, nil
+	}
+
+	return nil, nil
+}
+
+// getPage returns the current page of the results, and the control of the response whose cookie
+// is empty at the last page
+func (paging *pagingControl) getPage(results []*searchEntry) ([]*searchEntry, *ldapv3.ControlPaging) {
+	control := ldapv3.NewControlPaging(uint32(len(results)))
+
+	// a page size of 0 abandons the paged search
+	if paging.size == 0 || paging.offset >= len(results) {
+		return []*searchEntry{}, control
+	}
+
+	end := paging.offset + paging.size
+	if end >= len(results) {
+		return results[paging.offset:], control
+	}
+
+	control.SetCookie([]byte(strconv.Itoa(end)))
+	return results[paging.offset:end], control
+}
+
+// searchResponseWriter writes the responses of a search. The response writer of the server
+// cannot attach controls to a response, so all the responses of a paged search are written to
+// the serialized connection of the client, after the TLS upgrade if any, see ldapConn.
+type searchResponseWriter struct {
+	w      ldap.ResponseWriter
+	m      *ldap.Message
+	direct bool
+}
+
+func (sw *searchResponseWriter) write(op message.ProtocolOp, controls ...ldapv3.Control) error {
+	if !sw.direct {
+		sw.w.Write(op)
+		return nil
+	}
+
+	data, err := encodeMessage(op, int(sw.m.MessageID()), controls)
+	if err != nil {
+		return err
+	}
+
+	conn, err := getLdapConn(sw.m)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Write(data)
+	return err
+}
+
+func encodeMessage(op message.ProtocolOp, messageId int, controls []ldapv3.Control) ([]byte, error) {
+	msg := message.NewLDAPMessageWithProtocolOp(op)
+	msg.SetMessageID(messageId)
+	data, err := msg.Write()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(controls) == 0 {
+		return data.Bytes(), nil
+	}
+
+	packet, err := ber.DecodePacketErr(data.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	controlsPacket := ber.Encode(ber.ClassContext, ber.TypeConstructed, 0, nil, "Controls")
+	for _, control := range controls {
+		controlsPacket.AppendChild(control.Encode())
+	}
+	packet.AppendChild(controlsPacket)
+
+	return packet.Bytes(), nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ldap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchScope(t *testing.T) {
+	entry := &searchEntry{scopeDns: []string{"uid=1234,cn=alice,ou=org,dc=example,dc=com", "cn=alice,ou=org,dc=example,dc=com"}}
+
+	assert.True(t, entry.isInScope("cn=alice, ou=org, dc=example, dc=com", scopeBaseObject))
+	assert.True(t, entry.isInScope("ou=org,dc=example,dc=com", scopeSingleLevel))
+	assert.False(t, entry.isInScope("dc=example,dc=com", scopeSingleLevel))
+	assert.True(t, entry.isInScope("dc=example,dc=com", scopeWholeSubtree))
+	assert.False(t, entry.isInScope("ou=other,dc=example,dc=com", scopeWholeSubtree))
+}
+
+func TestPaging(t *testing.T) {
+	results := []*searchEntry{{}, {}, {}, {}, {}}
+
+	page, control := (&pagingControl{size: 2}).getPage(results)
+	assert.Len(t, page, 2)
+	assert.Equal(t, "2", string(control.Cookie))
+
+	page, control = (&pagingControl{size: 2, offset: 4}).getPage(results)
+	assert.Len(t, page, 1)
+	assert.Empty(t, control.Cookie)
+	assert.Equal(t, uint32(5), control.PagingSize)
+
+	page, _ = (&pagingControl{size: 0, offset: 2}).getPage(results)
+	assert.Empty(t, page)
+}
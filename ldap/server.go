@@ -15,6 +15,7 @@
 package ldap
 
 import (
+	"crypto/tls"
 	"fmt"
 	"hash/fnv"
 	"log"
@@ -22,28 +23,120 @@ import (
 	"github.com/casdoor/casdoor/conf"
 	"github.com/casdoor/casdoor/object"
 	ldap "github.com/forestmgy/ldapserver"
-	"github.com/lor00x/goldap/message"
+	ldapv3 "github.com/go-ldap/ldap/v3"
 )
 
+// ldapTlsConfig is the TLS config of LDAPS and StartTLS, nil if no certificate is configured
+var ldapTlsConfig *tls.Config
+
 func StartLdapServer() {
 	ldapServerPort := conf.GetConfigString("ldapServerPort")
-	if ldapServerPort == "" || ldapServerPort == "0" {
-		return
+	ldapsServerPort := conf.GetConfigString("ldapsServerPort")
+
+	tlsConfig, err := getLdapTlsConfig()
+	if err != nil {
+		log.Printf("StartLdapServer() failed to load the certificate, err = %s", err.Error())
 	}
+	ldapTlsConfig = tlsConfig
 
-	server := ldap.NewServer()
 	routes := ldap.NewRouteMux()
-
 	routes.Bind(handleBind)
 	routes.Search(handleSearch).Label(" SEARCH****")
+	if tlsConfig != nil {
+		routes.Extended(func(w ldap.ResponseWriter, m *ldap.Message) {
+			handleStartTls(w, m, tlsConfig)
+		}).RequestName(ldap.NoticeOfStartTLS).Label("StartTLS")
+	}
+
+	if tlsConfig != nil && ldapsServerPort != "" && ldapsServerPort != "0" {
+		go func() {
+			server := ldap.NewServer()
+			server.Handle(routes)
+			err := server.ListenAndServe("0.0.0.0:"+ldapsServerPort, withLdapListener(tlsConfig))
+			if err != nil {
+				log.Printf("StartLdapServer() failed to serve LDAPS, err = %s", err.Error())
+			}
+		}()
+	}
 
+	if ldapServerPort == "" || ldapServerPort == "0" {
+		return
+	}
+
+	server := ldap.NewServer()
 	server.Handle(routes)
-	err := server.ListenAndServe("0.0.0.0:" + ldapServerPort)
+	err = server.ListenAndServe("0.0.0.0:"+ldapServerPort, withLdapListener(nil))
 	if err != nil {
 		log.Printf("StartLdapServer() failed, err = %s", err.Error())
 	}
 }
 
+// getLdapTlsConfig returns the TLS config of LDAPS and StartTLS, or nil if no certificate is
+// configured. The certificate is read on every handshake so that it can be renewed in place.
+func getLdapTlsConfig() (*tls.Config, error) {
+	certId := conf.GetConfigString("ldapsCertId")
+	if certId == "" {
+		return nil, nil
+	}
+
+	getCertificate := func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		cert, err := object.GetCert(certId)
+		if err != nil {
+			return nil, err
+		}
+		if cert == nil {
+			return nil, fmt.Errorf("the cert: %s does not exist", certId)
+		}
+
+		certificate, err := tls.X509KeyPair([]byte(cert.Certificate), []byte(cert.PrivateKey))
+		if err != nil {
+			return nil, err
+		}
+		return &certificate, nil
+	}
+
+	// fail early on a misconfigured certificate
+	_, err := getCertificate(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: getCertificate,
+	}, nil
+}
+
+func handleStartTls(w ldap.ResponseWriter, m *ldap.Message, tlsConfig *tls.Config) {
+	res := ldap.NewExtendedResponse(ldap.LDAPResultSuccess)
+	res.SetResponseName(ldap.NoticeOfStartTLS)
+	conn, err := getLdapConn(m)
+	if err != nil {
+		res.SetResultCode(ldap.LDAPResultOperationsError)
+		res.SetDiagnosticMessage(err.Error())
+		w.Write(res)
+		return
+	}
+
+	if conn.isTls() {
+		res.SetResultCode(ldap.LDAPResultOperationsError)
+		res.SetDiagnosticMessage("TLS is already established")
+		w.Write(res)
+		return
+	}
+
+	w.Write(res)
+
+	tlsConn := tls.Server(conn, tlsConfig)
+	err = tlsConn.Handshake()
+	if err != nil {
+		log.Printf("StartTLS handshake error: %s", err.Error())
+		return
+	}
+
+	m.Client.SetConn(&ldapConn{Conn: tlsConn})
+}
+
 func handleBind(w ldap.ResponseWriter, m *ldap.Message) {
 	r := m.GetBindRequest()
 	res := ldap.NewBindResponse(ldap.LDAPResultSuccess)
@@ -86,18 +179,38 @@ func handleBind(w ldap.ResponseWriter, m *ldap.Message) {
 
 func handleSearch(w ldap.ResponseWriter, m *ldap.Message) {
 	res := ldap.NewSearchResultDoneResponse(ldap.LDAPResultSuccess)
+	r := m.GetSearchRequest()
+
+	// the root DSE can be read before binding, e.g. to discover the support of StartTLS
+	if isRootDseSearch(r) {
+		w.Write(getRootDseEntry().toSearchResultEntry(getRequestedAttributes(r, rootDseAttributes)))
+		w.Write(res)
+		return
+	}
+
 	if !m.Client.IsAuthenticated {
 		res.SetResultCode(ldap.LDAPResultUnwillingToPerform)
 		w.Write(res)
 		return
 	}
 
-	r := m.GetSearchRequest()
-	if r.FilterString() == "(objectClass=*)" {
+	paging, err := getPagingControl(m)
+	if err != nil {
+		res.SetResultCode(ldap.LDAPResultProtocolError)
+		res.SetDiagnosticMessage(err.Error())
 		w.Write(res)
 		return
 	}
 
+	sw := &searchResponseWriter{w: w, m: m, direct: paging != nil}
+
+	entries, code := getSearchEntries(m)
+	if code != ldap.LDAPResultSuccess {
+		res.SetResultCode(code)
+		sw.write(res)
+		return
+	}
+
 	// Handle Stop Signal (server stop / client disconnected / Abandoned request....)
 	select {
 	case <-m.Done:
@@ -106,39 +219,53 @@ func handleSearch(w ldap.ResponseWriter, m *ldap.Message) {
 	default:
 	}
 
-	users, code := GetFilteredUsers(m)
-	if code != ldap.LDAPResultSuccess {
-		res.SetResultCode(code)
-		w.Write(res)
-		return
+	results := []*searchEntry{}
+	for _, entry := range entries {
+		if !entry.isInScope(string(r.BaseObject()), int(r.Scope())) {
+			continue
+		}
+
+		ok, err := matchFilter(r.Filter(), entry.ldapEntry)
+		if err != nil {
+			res.SetResultCode(ldap.LDAPResultUnwillingToPerform)
+			res.SetDiagnosticMessage(err.Error())
+			sw.write(res)
+			return
+		}
+
+		if ok {
+			results = append(results, entry)
+		}
 	}
 
-	for _, user := range users {
-		dn := fmt.Sprintf("uid=%s,cn=%s,%s", user.Id, user.Name, string(r.BaseObject()))
-		e := ldap.NewSearchResultEntry(dn)
-		uidNumberStr := fmt.Sprintf("%v", hash(user.Name))
-		e.AddAttribute("uidNumber", message.AttributeValue(uidNumberStr))
-		e.AddAttribute("gidNumber", message.AttributeValue(uidNumberStr))
-		e.AddAttribute("homeDirectory", message.AttributeValue("/home/"+user.Name))
-		e.AddAttribute("cn", message.AttributeValue(user.Name))
-		e.AddAttribute("uid", message.AttributeValue(user.Id))
-		attrs := r.Attributes()
-		for _, attr := range attrs {
-			if string(attr) == "*" {
-				attrs = AdditionalLdapAttributes
-				break
-			}
+	sizeLimit := int(r.SizeLimit())
+	if sizeLimit > 0 && len(results) > sizeLimit {
+		results = results[:sizeLimit]
+		res.SetResultCode(ldap.LDAPResultSizeLimitExceeded)
+	}
+
+	var control *ldapv3.ControlPaging
+	if paging != nil {
+		results, control = paging.getPage(results)
+		if len(control.Cookie) != 0 {
+			// the size limit is only exceeded at the last page
+			res.SetResultCode(ldap.LDAPResultSuccess)
 		}
-		for _, attr := range attrs {
-			e.AddAttribute(message.AttributeDescription(attr), getAttribute(string(attr), user))
-			if string(attr) == "cn" {
-				e.AddAttribute(message.AttributeDescription(attr), getAttribute("title", user))
-			}
+	}
+
+	for _, entry := range results {
+		err = sw.write(entry.getSearchResultEntry())
+		if err != nil {
+			log.Printf("handleSearch() failed to write the entry: %s", err.Error())
+			return
 		}
+	}
 
-		w.Write(e)
+	if control != nil {
+		sw.write(res, control)
+	} else {
+		sw.write(res)
 	}
-	w.Write(res)
 }
 
 func hash(s string) uint32 {
@@ -29,6 +29,7 @@ import (
 	"github.com/casdoor/casdoor/radius"
 	"github.com/casdoor/casdoor/repository"
 	"github.com/casdoor/casdoor/routers"
+	"github.com/casdoor/casdoor/sync"
 	"github.com/casdoor/casdoor/txmanager"
 	"github.com/casdoor/casdoor/util"
 )
@@ -51,6 +52,12 @@ func main() {
 	object.InitUserManager()
 
 	util.SafeGoroutine(func() { object.RunSyncUsersJob() })
+	util.SafeGoroutine(func() { object.RunProvisioningJob() })
+	util.SafeGoroutine(func() { object.RunWebhookJob() })
+	util.SafeGoroutine(func() { object.RunAuditRetentionJob() })
+	util.SafeGoroutine(func() { object.RunAuditExportJob() })
+	util.SafeGoroutine(func() { authz.RunApiPolicyReloadJob() })
+	util.SafeGoroutine(func() { sync.StartReplication() })
 
 	// beego.DelStaticPath("/static")
 	// beego.SetStaticPath("/static", "web/build/static")
@@ -78,16 +78,25 @@ type Application struct {
 	SigninMethods          []*SigninMethod `xorm:"varchar(2000)" json:"signinMethods"`
 	SignupItems            []*SignupItem   `xorm:"varchar(1000)" json:"signupItems"`
 	GrantTypes             []string        `xorm:"varchar(1000)" json:"grantTypes"`
+	TokenExchangeAllowList []string        `xorm:"varchar(1000)" json:"tokenExchangeAllowList"`
 	OrganizationObj        *Organization   `xorm:"-" json:"organizationObj"`
 	CertPublicKey          string          `xorm:"-" json:"certPublicKey"`
 	Tags                   []string        `xorm:"mediumtext" json:"tags"`
 	InvitationCodes        []string        `xorm:"varchar(200)" json:"invitationCodes"`
 	IsPublic               bool            `xorm:"bool" json:"isPublic"`
 	SamlAttributes         []*SamlItem     `xorm:"varchar(1000)" json:"samlAttributes"`
+	SamlSpMetadata         string          `xorm:"mediumtext" json:"samlSpMetadata"`
+	SamlNameIdFormat       string          `xorm:"varchar(100)" json:"samlNameIdFormat"`
+	SamlSigningMode        string          `xorm:"varchar(100)" json:"samlSigningMode"`
+	SamlSignatureAlgorithm string          `xorm:"varchar(100)" json:"samlSignatureAlgorithm"`
+
+	EnableSamlAssertionEncryption bool `json:"enableSamlAssertionEncryption"`
 
 	ClientId             string     `xorm:"varchar(100)" json:"clientId"`
 	ClientSecret         string     `xorm:"varchar(100)" json:"clientSecret"`
 	RedirectUris         []string   `xorm:"varchar(1000)" json:"redirectUris"`
+	Jwks                 string     `xorm:"mediumtext" json:"jwks"`
+	JwksUri              string     `xorm:"varchar(200)" json:"jwksUri"`
 	TokenFormat          string     `xorm:"varchar(100)" json:"tokenFormat"`
 	TokenFields          []string   `xorm:"varchar(1000)" json:"tokenFields"`
 	ExpireInHours        int        `json:"expireInHours"`
@@ -109,6 +118,12 @@ type Application struct {
 
 	FailedSigninLimit      int `json:"failedSigninLimit"`
 	FailedSigninFrozenTime int `json:"failedSigninFrozenTime"`
+
+	RequirePushedAuthorizationRequests bool         `json:"requirePushedAuthorizationRequests"`
+	RequireConsent                     bool         `json:"requireConsent"`
+	FrontChannelLogoutUri              string       `xorm:"varchar(200)" json:"frontChannelLogoutUri"`
+	BackChannelLogoutUri               string       `xorm:"varchar(200)" json:"backChannelLogoutUri"`
+	Scopes                             []*ScopeItem `xorm:"mediumtext" json:"scopes"`
 }
 
 func GetApplicationCount(owner, field, value string) (int64, error) {
@@ -395,6 +410,9 @@ func GetMaskedApplication(application *Application, userId string) *Application
 	if application.TokenFields == nil {
 		application.TokenFields = []string{}
 	}
+	if application.Scopes == nil {
+		application.Scopes = []*ScopeItem{}
+	}
 
 	if application.FailedSigninLimit == 0 {
 		application.FailedSigninLimit = 5
@@ -0,0 +1,416 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/util"
+)
+
+const (
+	AuditIssueGap      = "Gap"
+	AuditIssueBreak    = "Break"
+	AuditIssueTampered = "Tampered"
+)
+
+const (
+	auditBatchSize         = 1000
+	auditMaxAppendAttempts = 5
+	auditMaxIssues         = 1000
+	auditRetentionInterval = time.Hour
+)
+
+var auditMutex sync.Mutex
+
+// AuditEntry is an entry of the audit log of an organization, added for each record. The entries
+// of an organization are chained by their sequence: the hash of an entry is the hex SHA-256 of the
+// JSON of its hashed fields, see auditEntryContent, which include the hash of the previous entry.
+// Modifying, inserting or deleting an entry breaks the chain, so it can be verified offline from
+// an export of the entries.
+type AuditEntry struct {
+	Id           int64  `xorm:"pk autoincr" json:"id"`
+	Organization string `xorm:"varchar(100) notnull unique(organization_sequence)" json:"organization"`
+	Sequence     int64  `xorm:"notnull unique(organization_sequence)" json:"sequence"`
+	CreatedTime  string `xorm:"varchar(100) index" json:"createdTime"`
+
+	Record     int    `json:"record"`
+	User       string `xorm:"varchar(100)" json:"user"`
+	ClientIp   string `xorm:"varchar(100)" json:"clientIp"`
+	Method     string `xorm:"varchar(100)" json:"method"`
+	RequestUri string `xorm:"varchar(1000)" json:"requestUri"`
+	Action     string `xorm:"varchar(1000)" json:"action"`
+	StatusCode string `xorm:"varchar(5)" json:"statusCode"`
+	ApiToken   string `xorm:"varchar(200)" json:"apiToken"`
+	Object     string `xorm:"mediumtext" json:"object"`
+
+	PrevHash string `xorm:"varchar(64)" json:"prevHash"`
+	Hash     string `xorm:"varchar(64)" json:"hash"`
+}
+
+// auditEntryContent is the hashed fields of an entry, in the order of their JSON
+type auditEntryContent struct {
+	Organization string `json:"organization"`
+	Sequence     int64  `json:"sequence"`
+	CreatedTime  string `json:"createdTime"`
+	Record       int    `json:"record"`
+	User         string `json:"user"`
+	ClientIp     string `json:"clientIp"`
+	Method       string `json:"method"`
+	RequestUri   string `json:"requestUri"`
+	Action       string `json:"action"`
+	StatusCode   string `json:"statusCode"`
+	ApiToken     string `json:"apiToken,omitempty"`
+	Object       string `json:"object"`
+	PrevHash     string `json:"prevHash"`
+}
+
+// AuditCheckpoint is the last purged entry of the audit log of an organization, from which the
+// chain of the remaining entries starts
+type AuditCheckpoint struct {
+	Organization string `xorm:"varchar(100) notnull pk" json:"organization"`
+	Sequence     int64  `json:"sequence"`
+	Hash         string `xorm:"varchar(64)" json:"hash"`
+	PurgedCount  int64  `json:"purgedCount"`
+	UpdatedTime  string `xorm:"varchar(100)" json:"updatedTime"`
+}
+
+type AuditIssue struct {
+	Sequence int64  `json:"sequence"`
+	Type     string `json:"type"`
+	Message  string `json:"message"`
+}
+
+type AuditVerification struct {
+	Organization  string        `json:"organization"`
+	EntryCount    int64         `json:"entryCount"`
+	FirstSequence int64         `json:"firstSequence"`
+	LastSequence  int64         `json:"lastSequence"`
+	LastHash      string        `json:"lastHash"`
+	IsValid       bool          `json:"isValid"`
+	Issues        []*AuditIssue `json:"issues"`
+	VerifiedTime  string        `json:"verifiedTime"`
+}
+
+func (entry *AuditEntry) computeHash() string {
+	data, _ := json.Marshal(&auditEntryContent{
+		Organization: entry.Organization,
+		Sequence:     entry.Sequence,
+		CreatedTime:  entry.CreatedTime,
+		Record:       entry.Record,
+		User:         entry.User,
+		ClientIp:     entry.ClientIp,
+		Method:       entry.Method,
+		RequestUri:   entry.RequestUri,
+		Action:       entry.Action,
+		StatusCode:   entry.StatusCode,
+		ApiToken:     entry.ApiToken,
+		Object:       entry.Object,
+		PrevHash:     entry.PrevHash,
+	})
+
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func getAuditCheckpoint(organization string) (*AuditCheckpoint, error) {
+	checkpoint := AuditCheckpoint{Organization: organization}
+	existed, err := ormer.Engine.Get(&checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if existed {
+		return &checkpoint, nil
+	} else {
+		return nil, nil
+	}
+}
+
+// getAuditChainHead returns the sequence and the hash of the last entry of the organization
+func getAuditChainHead(organization string) (int64, string, error) {
+	entries := []*AuditEntry{}
+	err := ormer.Engine.Where("organization = ?", organization).Desc("sequence").Limit(1).Find(&entries)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if len(entries) != 0 {
+		return entries[0].Sequence, entries[0].Hash, nil
+	}
+
+	checkpoint, err := getAuditCheckpoint(organization)
+	if err != nil || checkpoint == nil {
+		return 0, "", err
+	}
+	return checkpoint.Sequence, checkpoint.Hash, nil
+}
+
+// addAuditEntry appends the record to the audit log of its organization. The sequence is unique
+// per organization, so the append is retried when another instance appended the same one first.
+func addAuditEntry(record *Record) error {
+	auditMutex.Lock()
+	defer auditMutex.Unlock()
+
+	var err error
+	for i := 0; i < auditMaxAppendAttempts; i++ {
+		var sequence int64
+		var prevHash string
+		sequence, prevHash, err = getAuditChainHead(record.Organization)
+		if err != nil {
+			return err
+		}
+
+		entry := &AuditEntry{
+			Organization: record.Organization,
+			Sequence:     sequence + 1,
+			CreatedTime:  record.CreatedTime,
+			Record:       record.Id,
+			User:         record.User,
+			ClientIp:     record.ClientIp,
+			Method:       record.Method,
+			RequestUri:   record.RequestUri,
+			Action:       record.Action,
+			StatusCode:   record.StatusCode,
+			ApiToken:     record.ApiToken,
+			Object:       record.Object,
+			PrevHash:     prevHash,
+		}
+		entry.Hash = entry.computeHash()
+
+		_, err = ormer.Engine.Insert(entry)
+		if err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+func GetAuditEntryCount(organization string) (int64, error) {
+	return ormer.Engine.Count(&AuditEntry{Organization: organization})
+}
+
+func GetAuditEntries(organization string, offset, limit int) ([]*AuditEntry, error) {
+	entries := []*AuditEntry{}
+	session := ormer.Engine.Where("organization = ?", organization).Desc("sequence")
+	if offset != -1 && limit != -1 {
+		session = session.Limit(limit, offset)
+	}
+	err := session.Find(&entries)
+	if err != nil {
+		return entries, err
+	}
+
+	return entries, nil
+}
+
+// walkAuditEntries calls f with the entries of the organization in the order of the chain
+func walkAuditEntries(organization string, f func(entries []*AuditEntry) error) error {
+	var sequence int64
+	for {
+		entries := []*AuditEntry{}
+		err := ormer.Engine.Where("organization = ? and sequence > ?", organization, sequence).
+			Asc("sequence").Limit(auditBatchSize).Find(&entries)
+		if err != nil {
+			return err
+		}
+
+		if len(entries) == 0 {
+			return nil
+		}
+
+		err = f(entries)
+		if err != nil {
+			return err
+		}
+
+		sequence = entries[len(entries)-1].Sequence
+	}
+}
+
+// WriteAuditEntries writes the entries of the organization in the order of the chain as JSON
+// lines, the export can be verified offline from the prevHash of its first entry
+func WriteAuditEntries(organization string, w io.Writer) error {
+	return walkAuditEntries(organization, func(entries []*AuditEntry) error {
+		for _, entry := range entries {
+			_, err := io.WriteString(w, util.StructToJson(entry)+"\n")
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// auditVerifier checks the entries of a chain given in order, from the sequence and the hash of
+// the last purged entry
+type auditVerifier struct {
+	sequence int64
+	prevHash string
+	count    int64
+	issues   []*AuditIssue
+}
+
+func (v *auditVerifier) addIssue(sequence int64, issueType string, message string) {
+	if len(v.issues) < auditMaxIssues {
+		v.issues = append(v.issues, &AuditIssue{Sequence: sequence, Type: issueType, Message: message})
+	}
+}
+
+func (v *auditVerifier) verify(entry *AuditEntry) {
+	v.count++
+	if entry.Hash != entry.computeHash() {
+		v.addIssue(entry.Sequence, AuditIssueTampered, "the hash of the entry does not match its content")
+	}
+
+	if entry.Sequence != v.sequence+1 {
+		// the link of the entry after a gap cannot be checked
+		v.addIssue(entry.Sequence, AuditIssueGap, fmt.Sprintf("the entries from %d to %d are missing", v.sequence+1, entry.Sequence-1))
+	} else if entry.PrevHash != v.prevHash {
+		v.addIssue(entry.Sequence, AuditIssueBreak, "the previous hash of the entry does not match the hash of the previous entry")
+	}
+
+	v.sequence = entry.Sequence
+	v.prevHash = entry.Hash
+}
+
+// VerifyAuditChain checks that the entries of the audit log of the organization are complete and
+// unmodified since the last purge. Deleting the last entries cannot be told from the chain alone,
+// the last sequence and hash are compared to the ones of an export for that.
+func VerifyAuditChain(organization string) (*AuditVerification, error) {
+	verifier := &auditVerifier{}
+	checkpoint, err := getAuditCheckpoint(organization)
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint != nil {
+		verifier.sequence, verifier.prevHash = checkpoint.Sequence, checkpoint.Hash
+	}
+
+	verification := &AuditVerification{Organization: organization, FirstSequence: verifier.sequence + 1}
+	err = walkAuditEntries(organization, func(entries []*AuditEntry) error {
+		for _, entry := range entries {
+			verifier.verify(entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	verification.EntryCount = verifier.count
+	verification.LastSequence = verifier.sequence
+	verification.LastHash = verifier.prevHash
+	verification.Issues = verifier.issues
+	if verification.Issues == nil {
+		verification.Issues = []*AuditIssue{}
+	}
+	verification.IsValid = len(verification.Issues) == 0
+	verification.VerifiedTime = util.GetCurrentTime()
+	return verification, nil
+}
+
+// PurgeAuditEntries deletes the entries and the records of the organization created before the
+// time. The chain stays verifiable as the last purged entry is kept as the checkpoint.
+func PurgeAuditEntries(organization string, before string) (int64, error) {
+	auditMutex.Lock()
+	defer auditMutex.Unlock()
+
+	// the entries are purged from the start of the chain only
+	entries := []*AuditEntry{}
+	err := ormer.Engine.Where("organization = ? and created_time < ?", organization, before).
+		Desc("sequence").Limit(1).Find(&entries)
+	if err != nil || len(entries) == 0 {
+		return 0, err
+	}
+	last := entries[0]
+
+	checkpoint, err := getAuditCheckpoint(organization)
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := ormer.Engine.Where("organization = ? and sequence <= ?", organization, last.Sequence).Delete(&AuditEntry{})
+	if err != nil {
+		return 0, err
+	}
+
+	if checkpoint == nil {
+		checkpoint = &AuditCheckpoint{Organization: organization}
+		_, err = ormer.Engine.Insert(checkpoint)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	checkpoint.Sequence = last.Sequence
+	checkpoint.Hash = last.Hash
+	checkpoint.PurgedCount += affected
+	checkpoint.UpdatedTime = util.GetCurrentTime()
+	_, err = ormer.Engine.ID(organization).AllCols().Update(checkpoint)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = ormer.Engine.Where("organization = ? and created_time < ?", organization, before).Delete(&Record{})
+	if err != nil {
+		return 0, err
+	}
+
+	return affected, nil
+}
+
+// purgeExpiredAuditEntries applies the audit retention of the organizations
+func purgeExpiredAuditEntries() error {
+	organizations := []*Organization{}
+	err := ormer.Engine.Where("audit_retention_days > 0").Find(&organizations)
+	if err != nil {
+		return err
+	}
+
+	for _, organization := range organizations {
+		before := time.Now().UTC().AddDate(0, 0, -organization.AuditRetentionDays).Format(time.RFC3339)
+		affected, err := PurgeAuditEntries(organization.Name, before)
+		if err != nil {
+			return err
+		}
+
+		if affected != 0 {
+			logs.Info("purged %d audit entries of the organization: %s created before %s", affected, organization.Name, before)
+		}
+	}
+
+	return nil
+}
+
+// RunAuditRetentionJob purges the expired audit entries in the background
+func RunAuditRetentionJob() {
+	for {
+		err := purgeExpiredAuditEntries()
+		if err != nil {
+			logs.Warning("failed to purge the expired audit entries: %s", err.Error())
+		}
+
+		time.Sleep(auditRetentionInterval)
+	}
+}
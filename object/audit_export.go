@@ -0,0 +1,436 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/conf"
+	"github.com/casdoor/casdoor/util"
+)
+
+const (
+	auditExportInterval   = 5 * time.Second
+	auditExportBatchSize  = 500
+	auditExportTimeout    = 10 * time.Second
+	auditFileMaxSize      = 100
+	auditFileMaxBackups   = 10
+	auditSyslogFacility   = 10 // authpriv
+	auditSyslogAppName    = "casdoor"
+	auditSyslogMessageId  = "audit"
+	auditOtlpServiceName  = "casdoor"
+	auditOtlpScopeName    = "casdoor/audit"
+	auditOtlpSeverityInfo = 9
+	auditOtlpSeverityWarn = 13
+)
+
+// AuditExportCursor is the last entry sent to an exporter of an instance
+type AuditExportCursor struct {
+	Name        string `xorm:"varchar(200) notnull pk" json:"name"`
+	LastId      int64  `json:"lastId"`
+	UpdatedTime string `xorm:"varchar(100)" json:"updatedTime"`
+}
+
+// auditExporter streams the audit entries of all the organizations, in the order they were added,
+// to an external log storage
+type auditExporter interface {
+	export(entries []*AuditEntry) error
+	close()
+}
+
+// auditSyslogExporter sends the entries to a syslog server in the RFC 5424 format, over UDP or
+// over TCP with the octet counting framing of RFC 6587
+type auditSyslogExporter struct {
+	network  string
+	address  string
+	hostname string
+	conn     net.Conn
+}
+
+// auditFileExporter appends the entries as JSON lines to a file, which is rotated when it exceeds
+// the max size, in MB
+type auditFileExporter struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// auditOtlpExporter sends the entries to an OpenTelemetry collector as logs with OTLP/HTTP in the
+// JSON encoding, like http://localhost:4318/v1/logs
+type auditOtlpExporter struct {
+	endpoint string
+	client   *http.Client
+}
+
+func getAuditHostname() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		return "-"
+	}
+	return hostname
+}
+
+func getAuditSeverity(entry *AuditEntry) (int, bool) {
+	statusCode, _ := strconv.Atoi(entry.StatusCode)
+	isWarning := statusCode >= 400
+	return statusCode, isWarning
+}
+
+func newAuditSyslogExporter(address string) (*auditSyslogExporter, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "udp" && u.Scheme != "tcp" {
+		return nil, fmt.Errorf("the syslog address: %s should start with udp:// or tcp://", address)
+	}
+
+	return &auditSyslogExporter{network: u.Scheme, address: u.Host, hostname: getAuditHostname()}, nil
+}
+
+// formatAuditSyslogMessage returns the entry as a RFC 5424 message, with the JSON of the entry as
+// the message so it can be verified from the logs
+func formatAuditSyslogMessage(entry *AuditEntry, hostname string) string {
+	severity := 6 // informational
+	if _, isWarning := getAuditSeverity(entry); isWarning {
+		severity = 4 // warning
+	}
+
+	timestamp := entry.CreatedTime
+	if timestamp == "" {
+		timestamp = "-"
+	}
+
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s - %s", auditSyslogFacility*8+severity, timestamp, hostname,
+		auditSyslogAppName, os.Getpid(), auditSyslogMessageId, util.StructToJson(entry))
+}
+
+func (e *auditSyslogExporter) export(entries []*AuditEntry) error {
+	if e.conn == nil {
+		conn, err := net.DialTimeout(e.network, e.address, auditExportTimeout)
+		if err != nil {
+			return err
+		}
+		e.conn = conn
+	}
+
+	for _, entry := range entries {
+		message := formatAuditSyslogMessage(entry, e.hostname)
+		if e.network == "tcp" {
+			message = fmt.Sprintf("%d %s", len(message), message)
+		}
+
+		e.conn.SetWriteDeadline(time.Now().Add(auditExportTimeout))
+		_, err := e.conn.Write([]byte(message))
+		if err != nil {
+			e.close()
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *auditSyslogExporter) close() {
+	if e.conn != nil {
+		e.conn.Close()
+		e.conn = nil
+	}
+}
+
+func newAuditFileExporter(path string, maxSize int64, maxBackups int) *auditFileExporter {
+	if maxSize <= 0 {
+		maxSize = auditFileMaxSize
+	}
+	if maxBackups <= 0 {
+		maxBackups = auditFileMaxBackups
+	}
+
+	return &auditFileExporter{path: path, maxSize: maxSize * 1024 * 1024, maxBackups: maxBackups}
+}
+
+func (e *auditFileExporter) open() error {
+	err := os.MkdirAll(filepath.Dir(e.path), 0o750)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(e.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	e.file = file
+	e.size = info.Size()
+	return nil
+}
+
+// rotate renames the file with the current time and removes the oldest backups
+func (e *auditFileExporter) rotate() error {
+	e.close()
+
+	ext := filepath.Ext(e.path)
+	backup := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(e.path, ext), time.Now().UTC().Format("20060102T150405.000000000"), ext)
+	err := os.Rename(e.path, backup)
+	if err != nil {
+		return err
+	}
+
+	backups, err := filepath.Glob(strings.TrimSuffix(e.path, ext) + "-*" + ext)
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(backups)
+	for len(backups) > e.maxBackups {
+		err = os.Remove(backups[0])
+		if err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+
+	return e.open()
+}
+
+func (e *auditFileExporter) export(entries []*AuditEntry) error {
+	if e.file == nil {
+		err := e.open()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range entries {
+		line := []byte(util.StructToJson(entry) + "\n")
+		if e.size != 0 && e.size+int64(len(line)) > e.maxSize {
+			err := e.rotate()
+			if err != nil {
+				return err
+			}
+		}
+
+		n, err := e.file.Write(line)
+		e.size += int64(n)
+		if err != nil {
+			e.close()
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *auditFileExporter) close() {
+	if e.file != nil {
+		e.file.Close()
+		e.file = nil
+	}
+}
+
+func newAuditOtlpExporter(endpoint string) *auditOtlpExporter {
+	return &auditOtlpExporter{endpoint: endpoint, client: &http.Client{Timeout: auditExportTimeout}}
+}
+
+func getOtlpAttribute(key string, value string) map[string]interface{} {
+	return map[string]interface{}{"key": key, "value": map[string]interface{}{"stringValue": value}}
+}
+
+// getAuditOtlpRequest returns the OTLP ExportLogsServiceRequest of the entries, the body of a log
+// is the JSON of the entry so it can be verified from the logs
+func getAuditOtlpRequest(entries []*AuditEntry, hostname string) map[string]interface{} {
+	logRecords := []interface{}{}
+	for _, entry := range entries {
+		timeUnixNano := ""
+		if createdTime, err := time.Parse(time.RFC3339, entry.CreatedTime); err == nil {
+			timeUnixNano = strconv.FormatInt(createdTime.UnixNano(), 10)
+		}
+
+		severityNumber, severityText := auditOtlpSeverityInfo, "INFO"
+		if _, isWarning := getAuditSeverity(entry); isWarning {
+			severityNumber, severityText = auditOtlpSeverityWarn, "WARN"
+		}
+
+		logRecords = append(logRecords, map[string]interface{}{
+			"timeUnixNano":   timeUnixNano,
+			"severityNumber": severityNumber,
+			"severityText":   severityText,
+			"body":           map[string]interface{}{"stringValue": util.StructToJson(entry)},
+			"attributes": []interface{}{
+				getOtlpAttribute("casdoor.organization", entry.Organization),
+				getOtlpAttribute("casdoor.audit.sequence", strconv.FormatInt(entry.Sequence, 10)),
+				getOtlpAttribute("casdoor.audit.hash", entry.Hash),
+				getOtlpAttribute("enduser.id", entry.User),
+				getOtlpAttribute("client.address", entry.ClientIp),
+				getOtlpAttribute("http.request.method", entry.Method),
+				getOtlpAttribute("http.response.status_code", entry.StatusCode),
+				getOtlpAttribute("url.path", entry.Action),
+			},
+		})
+	}
+
+	return map[string]interface{}{
+		"resourceLogs": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": []interface{}{
+						getOtlpAttribute("service.name", auditOtlpServiceName),
+						getOtlpAttribute("host.name", hostname),
+					},
+				},
+				"scopeLogs": []interface{}{
+					map[string]interface{}{
+						"scope":      map[string]interface{}{"name": auditOtlpScopeName},
+						"logRecords": logRecords,
+					},
+				},
+			},
+		},
+	}
+}
+
+func (e *auditOtlpExporter) export(entries []*AuditEntry) error {
+	body := util.StructToJson(getAuditOtlpRequest(entries, getAuditHostname()))
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewBufferString(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("the OpenTelemetry collector returned the status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (e *auditOtlpExporter) close() {}
+
+// getAuditExporters returns the exporters of the config by their names
+func getAuditExporters() (map[string]auditExporter, error) {
+	exporters := map[string]auditExporter{}
+
+	if address := conf.GetConfigString("auditSyslogAddress"); address != "" {
+		exporter, err := newAuditSyslogExporter(address)
+		if err != nil {
+			return nil, err
+		}
+		exporters["syslog"] = exporter
+	}
+
+	if path := conf.GetConfigString("auditLogFile"); path != "" {
+		maxSize, _ := conf.GetConfigInt64("auditLogFileMaxSize")
+		maxBackups, _ := conf.GetConfigInt64("auditLogFileMaxBackups")
+		exporters["file"] = newAuditFileExporter(path, maxSize, int(maxBackups))
+	}
+
+	if endpoint := conf.GetConfigString("auditOtlpEndpoint"); endpoint != "" {
+		exporters["otlp"] = newAuditOtlpExporter(endpoint)
+	}
+
+	return exporters, nil
+}
+
+func getAuditExportCursor(name string) (*AuditExportCursor, error) {
+	cursor := AuditExportCursor{Name: name}
+	existed, err := ormer.Engine.Get(&cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if !existed {
+		cursor.UpdatedTime = util.GetCurrentTime()
+		_, err = ormer.Engine.Insert(&cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &cursor, nil
+}
+
+// exportAuditEntries sends the entries added since the cursor to the exporter, it returns false
+// once they have all been sent
+func exportAuditEntries(exporter auditExporter, cursor *AuditExportCursor) (bool, error) {
+	entries := []*AuditEntry{}
+	err := ormer.Engine.Where("id > ?", cursor.LastId).Asc("id").Limit(auditExportBatchSize).Find(&entries)
+	if err != nil || len(entries) == 0 {
+		return false, err
+	}
+
+	err = exporter.export(entries)
+	if err != nil {
+		return false, err
+	}
+
+	cursor.LastId = entries[len(entries)-1].Id
+	cursor.UpdatedTime = util.GetCurrentTime()
+	_, err = ormer.Engine.ID(cursor.Name).AllCols().Update(cursor)
+	if err != nil {
+		return false, err
+	}
+
+	return len(entries) == auditExportBatchSize, nil
+}
+
+// RunAuditExportJob streams the audit entries to the syslog server, the JSON lines file and the
+// OpenTelemetry collector of the config in the background. The exporters of each instance have
+// their own cursor, so the entries are sent at least once by every instance.
+func RunAuditExportJob() {
+	exporters, err := getAuditExporters()
+	if err != nil {
+		logs.Error("failed to start the audit export: %s", err.Error())
+		return
+	}
+
+	if len(exporters) == 0 {
+		return
+	}
+
+	hostname := getAuditHostname()
+	for {
+		for name, exporter := range exporters {
+			cursor, err := getAuditExportCursor(fmt.Sprintf("%s/%s", hostname, name))
+			for more := err == nil; more; {
+				more, err = exportAuditEntries(exporter, cursor)
+			}
+
+			if err != nil {
+				logs.Warning("failed to export the audit entries to %s: %s", name, err.Error())
+			}
+		}
+
+		time.Sleep(auditExportInterval)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestAuditChain(count int) []*AuditEntry {
+	entries := []*AuditEntry{}
+	prevHash := ""
+	for i := 1; i <= count; i++ {
+		entry := &AuditEntry{
+			Organization: "built-in",
+			Sequence:     int64(i),
+			CreatedTime:  "2024-01-01T00:00:00Z",
+			User:         "alice",
+			Method:       "POST",
+			Action:       "update-user",
+			StatusCode:   "200",
+			PrevHash:     prevHash,
+		}
+		entry.Hash = entry.computeHash()
+		prevHash = entry.Hash
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func verifyTestAuditChain(entries []*AuditEntry) []*AuditIssue {
+	verifier := &auditVerifier{}
+	for _, entry := range entries {
+		verifier.verify(entry)
+	}
+	return verifier.issues
+}
+
+func TestAuditVerifier(t *testing.T) {
+	assert.Empty(t, verifyTestAuditChain(getTestAuditChain(5)))
+
+	entries := getTestAuditChain(5)
+	entries[2].Action = "delete-user"
+	issues := verifyTestAuditChain(entries)
+	assert.Len(t, issues, 1)
+	assert.Equal(t, AuditIssueTampered, issues[0].Type)
+	assert.Equal(t, int64(3), issues[0].Sequence)
+
+	// rehashing a modified entry breaks the link of the next one
+	entries[2].Hash = entries[2].computeHash()
+	issues = verifyTestAuditChain(entries)
+	assert.Len(t, issues, 1)
+	assert.Equal(t, AuditIssueBreak, issues[0].Type)
+	assert.Equal(t, int64(4), issues[0].Sequence)
+
+	entries = getTestAuditChain(5)
+	issues = verifyTestAuditChain(append(entries[:1], entries[3:]...))
+	assert.Len(t, issues, 1)
+	assert.Equal(t, AuditIssueGap, issues[0].Type)
+	assert.Equal(t, "the entries from 2 to 3 are missing", issues[0].Message)
+
+	// a purged chain starts from the checkpoint
+	entries = getTestAuditChain(5)
+	verifier := &auditVerifier{sequence: 2, prevHash: entries[1].Hash}
+	for _, entry := range entries[2:] {
+		verifier.verify(entry)
+	}
+	assert.Empty(t, verifier.issues)
+	assert.Equal(t, int64(3), verifier.count)
+}
+
+func TestFormatAuditSyslogMessage(t *testing.T) {
+	entry := getTestAuditChain(1)[0]
+	message := formatAuditSyslogMessage(entry, "host")
+	prefix := fmt.Sprintf("<86>1 2024-01-01T00:00:00Z host casdoor %d audit - ", os.Getpid())
+	assert.True(t, strings.HasPrefix(message, prefix))
+
+	var values map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(strings.TrimPrefix(message, prefix)), &values))
+	assert.Equal(t, entry.Hash, values["hash"])
+
+	entry.StatusCode = "403"
+	assert.True(t, strings.HasPrefix(formatAuditSyslogMessage(entry, "host"), "<84>1 "))
+}
+
+func TestAuditFileExporter(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	exporter := newAuditFileExporter(path, 1, 2)
+	// rotate after about two entries
+	exporter.maxSize = 600
+	defer exporter.close()
+
+	entries := getTestAuditChain(10)
+	for _, entry := range entries {
+		assert.Nil(t, exporter.export([]*AuditEntry{entry}))
+	}
+
+	backups, err := filepath.Glob(filepath.Join(dir, "audit-*.log"))
+	assert.Nil(t, err)
+	assert.Len(t, backups, 2)
+
+	file, err := os.Open(path)
+	assert.Nil(t, err)
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry AuditEntry
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &entry))
+		lines++
+	}
+	assert.NotZero(t, lines)
+	assert.Less(t, lines, len(entries))
+}
+
+func TestGetAuditOtlpRequest(t *testing.T) {
+	entry := getTestAuditChain(1)[0]
+
+	var request map[string]interface{}
+	data, err := json.Marshal(getAuditOtlpRequest([]*AuditEntry{entry}, "host"))
+	assert.Nil(t, err)
+	assert.Nil(t, json.Unmarshal(data, &request))
+
+	resourceLog := request["resourceLogs"].([]interface{})[0].(map[string]interface{})
+	scopeLog := resourceLog["scopeLogs"].([]interface{})[0].(map[string]interface{})
+	logRecord := scopeLog["logRecords"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "1704067200000000000", logRecord["timeUnixNano"])
+	assert.Equal(t, "INFO", logRecord["severityText"])
+
+	var values map[string]interface{}
+	body := logRecord["body"].(map[string]interface{})["stringValue"].(string)
+	assert.Nil(t, json.Unmarshal([]byte(body), &values))
+	assert.Equal(t, entry.Hash, values["hash"])
+}
@@ -0,0 +1,265 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/casdoor/casdoor/i18n"
+	"github.com/casdoor/casdoor/util"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	RequestUriPrefix = "urn:ietf:params:oauth:request_uri:"
+
+	InvalidRequestObject = "invalid_request_object"
+
+	pushedAuthRequestExpireInSeconds = 300
+)
+
+// AuthorizeRequest holds the parameters of an authorization request, which are either
+// passed in the query, signed in a request object (RFC 9101) or pushed in advance (RFC 9126).
+type AuthorizeRequest struct {
+	ClientId            string `json:"clientId"`
+	ResponseType        string `json:"responseType"`
+	RedirectUri         string `json:"redirectUri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	Nonce               string `json:"nonce"`
+	CodeChallengeMethod string `json:"codeChallengeMethod"`
+	CodeChallenge       string `json:"codeChallenge"`
+	Prompt              string `json:"prompt"`
+}
+
+// PushedAuthRequest is an authorization request pushed to /api/login/oauth/par, it is
+// referenced by its request_uri until the authorization code is issued or it expires.
+type PushedAuthRequest struct {
+	Owner       string `xorm:"varchar(100) notnull pk" json:"owner"`
+	Name        string `xorm:"varchar(100) notnull pk" json:"name"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+
+	Application string            `xorm:"varchar(100)" json:"application"`
+	ClientId    string            `xorm:"varchar(100)" json:"clientId"`
+	RequestUri  string            `xorm:"varchar(200) index" json:"requestUri"`
+	Params      map[string]string `xorm:"mediumtext" json:"params"`
+	ExpireIn    int64             `json:"expireIn"`
+}
+
+type PushedAuthResponse struct {
+	RequestUri string `json:"request_uri"`
+	ExpiresIn  int    `json:"expires_in"`
+}
+
+// NewAuthorizeRequest builds an authorization request from the parameters returned by get,
+// which are looked up by their names in the OAuth specification.
+func NewAuthorizeRequest(clientId string, get func(key string) string) *AuthorizeRequest {
+	return &AuthorizeRequest{
+		ClientId:            clientId,
+		ResponseType:        get("response_type"),
+		RedirectUri:         get("redirect_uri"),
+		Scope:               get("scope"),
+		State:               get("state"),
+		Nonce:               get("nonce"),
+		CodeChallengeMethod: get("code_challenge_method"),
+		CodeChallenge:       get("code_challenge"),
+		Prompt:              get("prompt"),
+	}
+}
+
+func (request *AuthorizeRequest) toParams() map[string]string {
+	return map[string]string{
+		"response_type":         request.ResponseType,
+		"redirect_uri":          request.RedirectUri,
+		"scope":                 request.Scope,
+		"state":                 request.State,
+		"nonce":                 request.Nonce,
+		"code_challenge_method": request.CodeChallengeMethod,
+		"code_challenge":        request.CodeChallenge,
+		"prompt":                request.Prompt,
+	}
+}
+
+// parseRequestObject verifies a request object against the keys of the application, the
+// same keys that authenticate the application with JWT assertions, see RFC 9101 section 6.
+func parseRequestObject(application *Application, requestObject string, host string) (*AuthorizeRequest, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(requestObject, claims, getAssertionKeyFunc(application))
+	if err != nil {
+		return nil, err
+	}
+
+	get := func(key string) string {
+		value, _ := claims[key].(string)
+		return value
+	}
+
+	if get("iss") != application.ClientId {
+		return nil, fmt.Errorf("the iss of the request object is invalid")
+	}
+
+	if clientId := get("client_id"); clientId != "" && clientId != application.ClientId {
+		return nil, fmt.Errorf("the client_id of the request object is invalid")
+	}
+
+	if _, ok := claims["aud"]; ok {
+		isAudienceValid := false
+		for _, audience := range getAssertionAudiences(host) {
+			if claims.VerifyAudience(audience, true) {
+				isAudienceValid = true
+				break
+			}
+		}
+		if !isAudienceValid {
+			return nil, fmt.Errorf("the aud of the request object is invalid")
+		}
+	}
+
+	return NewAuthorizeRequest(application.ClientId, get), nil
+}
+
+func getPushedAuthRequest(requestUri string) (*PushedAuthRequest, error) {
+	if !strings.HasPrefix(requestUri, RequestUriPrefix) {
+		return nil, nil
+	}
+
+	pushedAuthRequest := PushedAuthRequest{RequestUri: requestUri}
+	existed, err := ormer.Engine.Get(&pushedAuthRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	if !existed || pushedAuthRequest.ExpireIn < time.Now().Unix() {
+		return nil, nil
+	}
+
+	return &pushedAuthRequest, nil
+}
+
+// DeletePushedAuthRequest makes a request_uri unusable once the authorization code has been issued for it
+func DeletePushedAuthRequest(requestUri string) error {
+	if requestUri == "" {
+		return nil
+	}
+
+	_, err := ormer.Engine.Where("request_uri = ?", requestUri).Delete(&PushedAuthRequest{})
+	return err
+}
+
+// ResolveAuthorizeRequest returns the effective parameters of an authorization request. When
+// a request_uri or a request object is given, the parameters of the query are ignored except
+// client_id. If the application requires pushed authorization requests, only a request_uri is
+// accepted. A non-empty message is returned if the request is rejected.
+func ResolveAuthorizeRequest(request *AuthorizeRequest, requestObject string, requestUri string, host string, lang string) (*AuthorizeRequest, string, error) {
+	application, err := GetApplicationByClientId(request.ClientId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if application == nil {
+		return nil, i18n.Translate(lang, "token:Invalid client_id"), nil
+	}
+
+	if requestUri != "" {
+		pushedAuthRequest, err := getPushedAuthRequest(requestUri)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if pushedAuthRequest == nil || pushedAuthRequest.ClientId != request.ClientId {
+			return nil, i18n.Translate(lang, "token:The request_uri is invalid or has expired"), nil
+		}
+
+		return NewAuthorizeRequest(request.ClientId, func(key string) string {
+			return pushedAuthRequest.Params[key]
+		}), "", nil
+	}
+
+	if application.RequirePushedAuthorizationRequests {
+		return nil, fmt.Sprintf(i18n.Translate(lang, "token:The application: %s requires pushed authorization requests"), application.Name), nil
+	}
+
+	if requestObject != "" {
+		resolvedRequest, err := parseRequestObject(application, requestObject, host)
+		if err != nil {
+			return nil, fmt.Sprintf(i18n.Translate(lang, "token:The request object is invalid: %s"), err.Error()), nil
+		}
+
+		return resolvedRequest, "", nil
+	}
+
+	return request, "", nil
+}
+
+// PushAuthorizeRequest stores an authorization request of an authenticated client and returns
+// the request_uri referencing it, see RFC 9126 section 2
+func PushAuthorizeRequest(application *Application, request *AuthorizeRequest, requestObject string, host string, lang string) (interface{}, error) {
+	if requestObject != "" {
+		var err error
+		request, err = parseRequestObject(application, requestObject, host)
+		if err != nil {
+			return &TokenError{
+				Error:            InvalidRequestObject,
+				ErrorDescription: err.Error(),
+			}, nil
+		}
+	}
+
+	msg, _, err := CheckOAuthLogin(application.ClientId, request.ResponseType, request.RedirectUri, request.Scope, request.State, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg != "" {
+		return &TokenError{
+			Error:            InvalidRequest,
+			ErrorDescription: msg,
+		}, nil
+	}
+
+	if request.CodeChallengeMethod != "" && request.CodeChallengeMethod != "S256" {
+		return &TokenError{
+			Error:            InvalidRequest,
+			ErrorDescription: "code_challenge_method should be S256",
+		}, nil
+	}
+
+	_, err = ormer.Engine.Where("expire_in < ?", time.Now().Unix()).Delete(&PushedAuthRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	pushedAuthRequest := &PushedAuthRequest{
+		Owner:       application.Owner,
+		Name:        util.GenerateId(),
+		CreatedTime: util.GetCurrentTime(),
+		Application: application.Name,
+		ClientId:    application.ClientId,
+		RequestUri:  RequestUriPrefix + util.GenerateClientSecret(),
+		Params:      request.toParams(),
+		ExpireIn:    time.Now().Add(pushedAuthRequestExpireInSeconds * time.Second).Unix(),
+	}
+	_, err = ormer.Engine.Insert(pushedAuthRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PushedAuthResponse{
+		RequestUri: pushedAuthRequest.RequestUri,
+		ExpiresIn:  pushedAuthRequestExpireInSeconds,
+	}, nil
+}
@@ -0,0 +1,224 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/casdoor/casdoor/conf"
+	"github.com/casdoor/casdoor/util"
+)
+
+const (
+	casServiceTicketExpireIn        = 5 * time.Minute
+	casProxyGrantingTicketExpireIn  = 2 * time.Hour
+	casSingleLogoutRetention        = 30 * 24 * time.Hour
+	casTicketStoreRedis             = "redis"
+	casExpiredTicketsPurgeInterval  = time.Minute
+	casExpiredTicketsPurgeBatchSize = 1000
+)
+
+// CasTicket is a service ticket (ST), a proxy ticket (PT) or a proxy-granting ticket (PGT) issued
+// by the CAS server. Service and proxy tickets can be validated only once before they expire.
+// The service tickets of a browser session are kept after they are used until the retention
+// time, so that the services can be sent a single logout request when the session ends.
+type CasTicket struct {
+	Name          string `xorm:"varchar(100) notnull pk" json:"name"`
+	CreatedTime   string `xorm:"varchar(100)" json:"createdTime"`
+	Service       string `xorm:"varchar(1000)" json:"service"`
+	UserId        string `xorm:"varchar(100)" json:"userId"`
+	Sid           string `xorm:"varchar(100) index" json:"sid"`
+	Response      string `xorm:"mediumtext" json:"response"`
+	ExpireTime    int64  `json:"expireTime"`
+	RetentionTime int64  `xorm:"index" json:"retentionTime"`
+	IsUsed        bool   `json:"isUsed"`
+}
+
+// casTicketStore stores the tickets where all the replicas of the server can validate them
+type casTicketStore interface {
+	add(ticket *CasTicket) error
+	// get returns the ticket, or nil if it does not exist
+	get(name string) (*CasTicket, error)
+	// use marks the ticket as used, and returns nil if it does not exist or has already been used
+	use(name string) (*CasTicket, error)
+	// takeBySid deletes and returns the service tickets issued within the browser session
+	takeBySid(sid string) ([]*CasTicket, error)
+}
+
+var (
+	casTickets     casTicketStore
+	casTicketsOnce sync.Once
+)
+
+func getCasTicketStore() casTicketStore {
+	casTicketsOnce.Do(func() {
+		switch conf.GetConfigString("casTicketStore") {
+		case casTicketStoreRedis:
+			casTickets = newRedisCasTicketStore(conf.GetConfigString("redisEndpoint"))
+		default:
+			casTickets = &databaseCasTicketStore{}
+		}
+	})
+	return casTickets
+}
+
+func newCasTicket(prefix string, token *CasAuthenticationSuccess, service string, userId string, sid string, expireIn time.Duration) (*CasTicket, error) {
+	response, err := json.Marshal(token)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	ticket := &CasTicket{
+		Name:          fmt.Sprintf("%s-%s", prefix, util.GenerateId()),
+		CreatedTime:   util.GetCurrentTime(),
+		Service:       service,
+		UserId:        userId,
+		Sid:           sid,
+		Response:      string(response),
+		ExpireTime:    now.Add(expireIn).Unix(),
+		RetentionTime: now.Add(expireIn).Unix(),
+	}
+	if sid != "" {
+		ticket.RetentionTime = now.Add(casSingleLogoutRetention).Unix()
+	}
+	return ticket, nil
+}
+
+func (ticket *CasTicket) isExpired() bool {
+	return ticket.ExpireTime < time.Now().Unix()
+}
+
+func (ticket *CasTicket) getResponse() (*CasAuthenticationSuccess, error) {
+	var response CasAuthenticationSuccess
+	err := json.Unmarshal([]byte(ticket.Response), &response)
+	if err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func addCasTicket(prefix string, token *CasAuthenticationSuccess, service string, userId string, sid string, expireIn time.Duration) (string, error) {
+	ticket, err := newCasTicket(prefix, token, service, userId, sid, expireIn)
+	if err != nil {
+		return "", err
+	}
+
+	err = getCasTicketStore().add(ticket)
+	if err != nil {
+		return "", err
+	}
+	return ticket.Name, nil
+}
+
+// getCasTicket returns the ticket if it exists and has not expired, a single-use ticket is
+// marked as used
+func getCasTicket(name string, isSingleUse bool) (*CasTicket, error) {
+	var ticket *CasTicket
+	var err error
+	if isSingleUse {
+		ticket, err = getCasTicketStore().use(name)
+	} else {
+		ticket, err = getCasTicketStore().get(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if ticket == nil || ticket.isExpired() {
+		return nil, nil
+	}
+	return ticket, nil
+}
+
+type databaseCasTicketStore struct {
+	lastPurgeTime time.Time
+	lock          sync.Mutex
+}
+
+// purge deletes the tickets whose retention time has passed, at most once per interval
+func (store *databaseCasTicketStore) purge() error {
+	store.lock.Lock()
+	if time.Since(store.lastPurgeTime) < casExpiredTicketsPurgeInterval {
+		store.lock.Unlock()
+		return nil
+	}
+	store.lastPurgeTime = time.Now()
+	store.lock.Unlock()
+
+	_, err := ormer.Engine.Where("retention_time < ?", time.Now().Unix()).Limit(casExpiredTicketsPurgeBatchSize).Delete(&CasTicket{})
+	return err
+}
+
+func (store *databaseCasTicketStore) add(ticket *CasTicket) error {
+	err := store.purge()
+	if err != nil {
+		return err
+	}
+
+	_, err = ormer.Engine.Insert(ticket)
+	return err
+}
+
+func (store *databaseCasTicketStore) get(name string) (*CasTicket, error) {
+	ticket := CasTicket{Name: name}
+	existed, err := ormer.Engine.Get(&ticket)
+	if err != nil {
+		return nil, err
+	}
+
+	if !existed {
+		return nil, nil
+	}
+	return &ticket, nil
+}
+
+func (store *databaseCasTicketStore) use(name string) (*CasTicket, error) {
+	// the update is atomic, so that only one of the concurrent validations can succeed
+	affected, err := ormer.Engine.Where("name = ? and is_used = ?", name, false).Cols("is_used").Update(&CasTicket{IsUsed: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if affected == 0 {
+		return nil, nil
+	}
+	return store.get(name)
+}
+
+func (store *databaseCasTicketStore) takeBySid(sid string) ([]*CasTicket, error) {
+	tickets := []*CasTicket{}
+	err := ormer.Engine.Where("sid = ?", sid).Find(&tickets)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []*CasTicket{}
+	for _, ticket := range tickets {
+		affected, err := ormer.Engine.Where("name = ?", ticket.Name).Delete(&CasTicket{})
+		if err != nil {
+			return nil, err
+		}
+
+		// the ticket has been taken by another replica otherwise
+		if affected != 0 {
+			res = append(res, ticket)
+		}
+	}
+	return res, nil
+}
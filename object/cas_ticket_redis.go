@@ -0,0 +1,195 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	redisCasTicketPrefix = "cas:ticket:"
+	redisCasUsedPrefix   = "cas:used:"
+	redisCasSidPrefix    = "cas:sid:"
+)
+
+// redisCasTicketStore stores the tickets in a Redis-compatible server, the keys expire at the
+// retention time of the tickets
+type redisCasTicketStore struct {
+	pool *redis.Pool
+}
+
+// newRedisCasTicketStore connects to the server of the endpoint, which has the same format as
+// the one of the session provider: "<address>[,<pool size>[,<password>[,<db number>]]]"
+func newRedisCasTicketStore(endpoint string) *redisCasTicketStore {
+	configs := strings.Split(endpoint, ",")
+
+	maxIdle := 100
+	if len(configs) > 1 {
+		if poolSize, err := strconv.Atoi(configs[1]); err == nil && poolSize > 0 {
+			maxIdle = poolSize
+		}
+	}
+
+	options := []redis.DialOption{}
+	if len(configs) > 2 && configs[2] != "" {
+		options = append(options, redis.DialPassword(configs[2]))
+	}
+	if len(configs) > 3 {
+		if db, err := strconv.Atoi(configs[3]); err == nil && db > 0 {
+			options = append(options, redis.DialDatabase(db))
+		}
+	}
+
+	return &redisCasTicketStore{
+		pool: &redis.Pool{
+			Dial: func() (redis.Conn, error) {
+				return redis.Dial("tcp", configs[0], options...)
+			},
+			MaxIdle:     maxIdle,
+			IdleTimeout: 5 * time.Minute,
+		},
+	}
+}
+
+func getRedisTtl(retentionTime int64) int64 {
+	ttl := retentionTime - time.Now().Unix()
+	if ttl < 1 {
+		ttl = 1
+	}
+	return ttl
+}
+
+func (store *redisCasTicketStore) add(ticket *CasTicket) error {
+	value, err := json.Marshal(ticket)
+	if err != nil {
+		return err
+	}
+
+	conn := store.pool.Get()
+	defer conn.Close()
+
+	ttl := getRedisTtl(ticket.RetentionTime)
+	err = conn.Send("MULTI")
+	if err == nil {
+		err = conn.Send("SET", redisCasTicketPrefix+ticket.Name, value, "EX", ttl)
+	}
+	if err == nil && ticket.Sid != "" {
+		err = conn.Send("SADD", redisCasSidPrefix+ticket.Sid, ticket.Name)
+		if err == nil {
+			err = conn.Send("EXPIRE", redisCasSidPrefix+ticket.Sid, ttl)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Do("EXEC")
+	return err
+}
+
+func (store *redisCasTicketStore) getTicket(conn redis.Conn, name string) (*CasTicket, error) {
+	value, err := redis.Bytes(conn.Do("GET", redisCasTicketPrefix+name))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ticket CasTicket
+	err = json.Unmarshal(value, &ticket)
+	if err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+func (store *redisCasTicketStore) get(name string) (*CasTicket, error) {
+	conn := store.pool.Get()
+	defer conn.Close()
+
+	return store.getTicket(conn, name)
+}
+
+func (store *redisCasTicketStore) use(name string) (*CasTicket, error) {
+	conn := store.pool.Get()
+	defer conn.Close()
+
+	ticket, err := store.getTicket(conn, name)
+	if err != nil || ticket == nil {
+		return nil, err
+	}
+
+	// SET NX succeeds for only one of the concurrent validations
+	_, err = redis.String(conn.Do("SET", redisCasUsedPrefix+name, 1, "NX", "EX", getRedisTtl(ticket.RetentionTime)))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ticket.IsUsed = true
+	return ticket, nil
+}
+
+func (store *redisCasTicketStore) takeBySid(sid string) ([]*CasTicket, error) {
+	conn := store.pool.Get()
+	defer conn.Close()
+
+	err := conn.Send("MULTI")
+	if err == nil {
+		err = conn.Send("SMEMBERS", redisCasSidPrefix+sid)
+	}
+	if err == nil {
+		err = conn.Send("DEL", redisCasSidPrefix+sid)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := redis.Values(conn.Do("EXEC"))
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := redis.Strings(values[0], nil)
+	if err != nil {
+		return nil, err
+	}
+
+	tickets := []*CasTicket{}
+	for _, name := range names {
+		ticket, err := store.getTicket(conn, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if ticket != nil {
+			tickets = append(tickets, ticket)
+		}
+
+		_, err = conn.Do("DEL", redisCasTicketPrefix+name, redisCasUsedPrefix+name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tickets, nil
+}
@@ -226,6 +226,18 @@ func CheckOneTimePassword(user *User, dest, code, lang string) error {
 	return nil
 }
 
+// CheckPasswordResponse checks a response computed from the password of the user, like the NT
+// response of MS-CHAPv2, with the same failed signin accounting as CheckPassword
+func CheckPasswordResponse(user *User, isCorrect func() bool, lang string) error {
+	if err := checkSigninErrorTimes(user, lang); err != nil {
+		return err
+	}
+	if !isCorrect() {
+		return recordSigninErrorInfo(user, lang)
+	}
+	return resetUserSigninErrorTimes(user)
+}
+
 func CheckPasswordComplexityByOrg(organization *Organization, password string, lang string) string {
 	maxLen := organization.PasswordMaxLength
 	minLen := organization.PasswordMinLength
@@ -362,10 +374,12 @@ func CheckUserPassword(organization string, username string, password string, la
 	}
 
 	if user == nil || user.IsDeleted {
+		emitLoginFailedEvent(organization, util.GetId(organization, username), LoginFailureUserNotFound, 0)
 		return nil, fmt.Errorf(i18n.Translate(lang, "general:The user: %s doesn't exist"), util.GetId(organization, username))
 	}
 
 	if user.IsForbidden {
+		emitLoginFailedEvent(organization, user.GetId(), LoginFailureUserForbidden, 0)
 		return nil, fmt.Errorf(i18n.Translate(lang, "check:The user is forbidden to sign in, please contact the administrator"))
 	}
 
@@ -74,6 +74,8 @@ func recordSigninErrorInfo(user *User, lang string, options ...bool) error {
 		return err
 	}
 
+	emitLoginFailedEvent(user.Owner, user.GetId(), LoginFailureWrongCredentials, user.SigninWrongTimes)
+
 	leftChances := failedSigninLimit - user.SigninWrongTimes
 	if leftChances == 0 && enableCaptcha {
 		return fmt.Errorf(i18n.Translate(lang, "check:password or code is incorrect"))
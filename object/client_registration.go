@@ -0,0 +1,456 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/casdoor/casdoor/util"
+	"github.com/xorm-io/core"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	InvalidToken          = "invalid_token"
+	InvalidRedirectUri    = "invalid_redirect_uri"
+	InvalidClientMetadata = "invalid_client_metadata"
+
+	ImplicitGrantType = "implicit"
+)
+
+// ClientMetadata is the metadata of a client registered dynamically, see RFC 7591 section 2
+type ClientMetadata struct {
+	RedirectUris            []string        `json:"redirect_uris,omitempty"`
+	TokenEndpointAuthMethod string          `json:"token_endpoint_auth_method,omitempty"`
+	GrantTypes              []string        `json:"grant_types,omitempty"`
+	ResponseTypes           []string        `json:"response_types,omitempty"`
+	ClientName              string          `json:"client_name,omitempty"`
+	ClientUri               string          `json:"client_uri,omitempty"`
+	LogoUri                 string          `json:"logo_uri,omitempty"`
+	Scope                   string          `json:"scope,omitempty"`
+	TosUri                  string          `json:"tos_uri,omitempty"`
+	JwksUri                 string          `json:"jwks_uri,omitempty"`
+	Jwks                    json.RawMessage `json:"jwks,omitempty"`
+
+	FrontChannelLogoutUri              string `json:"frontchannel_logout_uri,omitempty"`
+	BackChannelLogoutUri               string `json:"backchannel_logout_uri,omitempty"`
+	RequirePushedAuthorizationRequests bool   `json:"require_pushed_authorization_requests,omitempty"`
+}
+
+// ClientInformation is the response of the client registration endpoint, see RFC 7591 section 3.2.1
+// and RFC 7592 section 3
+type ClientInformation struct {
+	ClientId                string `json:"client_id"`
+	ClientSecret            string `json:"client_secret,omitempty"`
+	ClientIdIssuedAt        int64  `json:"client_id_issued_at,omitempty"`
+	ClientSecretExpiresAt   int64  `json:"client_secret_expires_at"`
+	RegistrationAccessToken string `json:"registration_access_token,omitempty"`
+	RegistrationClientUri   string `json:"registration_client_uri,omitempty"`
+	ClientMetadata
+}
+
+// ClientRegistration records how an application was registered dynamically. The registration
+// access token is only stored hashed, it is returned to the client once at registration time.
+type ClientRegistration struct {
+	Owner       string `xorm:"varchar(100) notnull pk" json:"owner"`
+	Name        string `xorm:"varchar(100) notnull pk" json:"name"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+
+	Organization            string   `xorm:"varchar(100)" json:"organization"`
+	ClientId                string   `xorm:"varchar(100) index" json:"clientId"`
+	RegistrationAccessToken string   `xorm:"varchar(100)" json:"-"`
+	TokenEndpointAuthMethod string   `xorm:"varchar(100)" json:"tokenEndpointAuthMethod"`
+	ResponseTypes           []string `xorm:"varchar(200)" json:"responseTypes"`
+	Scope                   string   `xorm:"varchar(1000)" json:"scope"`
+}
+
+// registrableGrantTypes are the grant types a client can register for itself, the grants issuing
+// tokens without the consent of the user, like the password, the client credentials, the token
+// exchange and the JWT bearer grants, can only be enabled by an administrator
+var registrableGrantTypes = []string{"authorization_code", ImplicitGrantType, "refresh_token", DeviceCodeGrantType}
+
+func newClientMetadataError(error string, format string, a ...interface{}) *TokenError {
+	return &TokenError{
+		Error:            error,
+		ErrorDescription: fmt.Sprintf(format, a...),
+	}
+}
+
+func isAbsoluteUri(uri string) bool {
+	u, err := url.Parse(uri)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// getApplicationGrantTypes converts the registered grant types to the grant types of the application,
+// which allows the implicit grant by its response types "token" and "id_token"
+func getApplicationGrantTypes(grantTypes []string) []string {
+	res := []string{}
+	for _, grantType := range grantTypes {
+		if grantType == ImplicitGrantType {
+			res = append(res, "token", "id_token")
+		} else {
+			res = append(res, grantType)
+		}
+	}
+	return res
+}
+
+func getRegisteredGrantTypes(grantTypes []string) []string {
+	res := []string{}
+	for _, grantType := range grantTypes {
+		if grantType == "token" || grantType == "id_token" {
+			grantType = ImplicitGrantType
+		}
+		if !util.InSlice(res, grantType) {
+			res = append(res, grantType)
+		}
+	}
+	return res
+}
+
+// checkClientMetadata validates the metadata and fills in the defaults of RFC 7591 section 2
+func checkClientMetadata(metadata *ClientMetadata) *TokenError {
+	if len(metadata.GrantTypes) == 0 {
+		metadata.GrantTypes = []string{"authorization_code"}
+	}
+	for _, grantType := range metadata.GrantTypes {
+		if !util.InSlice(registrableGrantTypes, grantType) {
+			return newClientMetadataError(InvalidClientMetadata, "the grant type: %s is not supported", grantType)
+		}
+	}
+
+	if len(metadata.ResponseTypes) == 0 {
+		metadata.ResponseTypes = []string{}
+		if util.InSlice(metadata.GrantTypes, "authorization_code") {
+			metadata.ResponseTypes = append(metadata.ResponseTypes, "code")
+		}
+	}
+	for _, responseType := range metadata.ResponseTypes {
+		requiredGrantType := ImplicitGrantType
+		if responseType == "code" {
+			requiredGrantType = "authorization_code"
+		} else if responseType != "token" && responseType != "id_token" && responseType != "token id_token" && responseType != "id_token token" {
+			return newClientMetadataError(InvalidClientMetadata, "the response type: %s is not supported", responseType)
+		}
+
+		if !util.InSlice(metadata.GrantTypes, requiredGrantType) {
+			return newClientMetadataError(InvalidClientMetadata, "the response type: %s requires the grant type: %s", responseType, requiredGrantType)
+		}
+	}
+
+	if util.InSlice(metadata.GrantTypes, "authorization_code") || util.InSlice(metadata.GrantTypes, ImplicitGrantType) {
+		if len(metadata.RedirectUris) == 0 {
+			return newClientMetadataError(InvalidRedirectUri, "redirect_uris is required by the grant types: %v", metadata.GrantTypes)
+		}
+	}
+	for _, redirectUri := range metadata.RedirectUris {
+		u, err := url.Parse(redirectUri)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Fragment != "" {
+			return newClientMetadataError(InvalidRedirectUri, "the redirect URI: %s is not an absolute URI without fragment", redirectUri)
+		}
+	}
+
+	if metadata.TokenEndpointAuthMethod == "" {
+		metadata.TokenEndpointAuthMethod = "client_secret_basic"
+	}
+	if !util.InSlice(tokenEndpointAuthMethodsSupported, metadata.TokenEndpointAuthMethod) {
+		return newClientMetadataError(InvalidClientMetadata, "the token endpoint auth method: %s is not supported", metadata.TokenEndpointAuthMethod)
+	}
+
+	// the keys at jwks_uri would be fetched by the server on behalf of an unauthenticated client,
+	// only an administrator can set it
+	if metadata.JwksUri != "" {
+		return newClientMetadataError(InvalidClientMetadata, "jwks_uri is not supported, use jwks instead")
+	}
+	if len(metadata.Jwks) != 0 {
+		err := json.Unmarshal(metadata.Jwks, &jose.JSONWebKeySet{})
+		if err != nil {
+			return newClientMetadataError(InvalidClientMetadata, "jwks is invalid: %s", err.Error())
+		}
+	}
+	if metadata.TokenEndpointAuthMethod == "private_key_jwt" && len(metadata.Jwks) == 0 {
+		return newClientMetadataError(InvalidClientMetadata, "jwks is required by the token endpoint auth method: private_key_jwt")
+	}
+
+	// the logout tokens are posted by the server to backchannel_logout_uri, only an administrator can set it
+	if metadata.BackChannelLogoutUri != "" {
+		return newClientMetadataError(InvalidClientMetadata, "backchannel_logout_uri is not supported")
+	}
+
+	for _, uri := range []string{metadata.ClientUri, metadata.LogoUri, metadata.TosUri, metadata.FrontChannelLogoutUri} {
+		if uri != "" && !isAbsoluteUri(uri) {
+			return newClientMetadataError(InvalidClientMetadata, "the URI: %s is not an absolute URI", uri)
+		}
+	}
+
+	return nil
+}
+
+// applyClientMetadata sets the fields of the application described by the metadata, the
+// fields not covered by RFC 7591 keep their values
+func applyClientMetadata(application *Application, metadata *ClientMetadata) {
+	application.DisplayName = metadata.ClientName
+	if application.DisplayName == "" {
+		application.DisplayName = application.Name
+	}
+
+	application.HomepageUrl = metadata.ClientUri
+	application.Logo = metadata.LogoUri
+	application.TermsOfUse = metadata.TosUri
+	application.RedirectUris = metadata.RedirectUris
+	application.GrantTypes = getApplicationGrantTypes(metadata.GrantTypes)
+	application.Jwks = string(metadata.Jwks)
+	application.FrontChannelLogoutUri = metadata.FrontChannelLogoutUri
+	application.RequirePushedAuthorizationRequests = metadata.RequirePushedAuthorizationRequests
+}
+
+func getClientInformation(application *Application, clientRegistration *ClientRegistration) *ClientInformation {
+	clientInformation := &ClientInformation{
+		ClientId:     application.ClientId,
+		ClientSecret: application.ClientSecret,
+		ClientMetadata: ClientMetadata{
+			RedirectUris:            application.RedirectUris,
+			TokenEndpointAuthMethod: clientRegistration.TokenEndpointAuthMethod,
+			GrantTypes:              getRegisteredGrantTypes(application.GrantTypes),
+			ResponseTypes:           clientRegistration.ResponseTypes,
+			ClientName:              application.DisplayName,
+			ClientUri:               application.HomepageUrl,
+			LogoUri:                 application.Logo,
+			Scope:                   clientRegistration.Scope,
+			TosUri:                  application.TermsOfUse,
+			JwksUri:                 application.JwksUri,
+
+			FrontChannelLogoutUri:              application.FrontChannelLogoutUri,
+			BackChannelLogoutUri:               application.BackChannelLogoutUri,
+			RequirePushedAuthorizationRequests: application.RequirePushedAuthorizationRequests,
+		},
+	}
+
+	if application.Jwks != "" {
+		clientInformation.Jwks = json.RawMessage(application.Jwks)
+	}
+
+	createdTime, err := time.Parse(time.RFC3339, application.CreatedTime)
+	if err == nil {
+		clientInformation.ClientIdIssuedAt = createdTime.Unix()
+	}
+
+	return clientInformation
+}
+
+func getRegistrationClientUri(clientId string, host string) string {
+	_, originBackend := getOriginFromHost(host)
+	return fmt.Sprintf("%s/api/login/oauth/register?client_id=%s", originBackend, url.QueryEscape(clientId))
+}
+
+// RegisterClient creates an application in the organization of the initial access token from the
+// metadata of the client, see RFC 7591 section 3. The users consent to the application before it
+// signs them in. It returns a *TokenError if the request is rejected.
+func RegisterClient(initialAccessToken string, metadata *ClientMetadata, host string) (interface{}, error) {
+	token, err := getInitialAccessTokenByToken(initialAccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if token == nil {
+		return newClientMetadataError(InvalidToken, "the initial access token is invalid or has expired"), nil
+	}
+
+	if tokenError := checkClientMetadata(metadata); tokenError != nil {
+		return tokenError, nil
+	}
+
+	cert := "cert-built-in"
+	organization, err := getOrganization("admin", token.Owner)
+	if err != nil {
+		return nil, err
+	}
+	if organization == nil {
+		return newClientMetadataError(InvalidToken, "the organization: %s of the initial access token does not exist", token.Owner), nil
+	}
+	if organization.DefaultApplication != "" {
+		defaultApplication, err := getApplication("admin", organization.DefaultApplication)
+		if err != nil {
+			return nil, err
+		}
+		if defaultApplication != nil && defaultApplication.Cert != "" {
+			cert = defaultApplication.Cert
+		}
+	}
+
+	clientId := util.GenerateClientId()
+	application := &Application{
+		Owner:          "admin",
+		Name:           fmt.Sprintf("application_%s", clientId),
+		CreatedTime:    util.GetCurrentTime(),
+		Organization:   token.Owner,
+		Cert:           cert,
+		EnablePassword: true,
+		SigninMethods: []*SigninMethod{
+			{Name: "Password", DisplayName: "Password", Rule: "All"},
+		},
+		ClientId:             clientId,
+		ClientSecret:         util.GenerateClientSecret(),
+		TokenFormat:          "JWT",
+		ExpireInHours:        24 * 7,
+		RefreshExpireInHours: 24 * 7,
+		FormOffset:           2,
+		RequireConsent:       true,
+	}
+	applyClientMetadata(application, metadata)
+
+	registrationAccessToken := util.GenerateClientSecret()
+	clientRegistration := &ClientRegistration{
+		Owner:                   application.Owner,
+		Name:                    application.Name,
+		CreatedTime:             application.CreatedTime,
+		Organization:            application.Organization,
+		ClientId:                application.ClientId,
+		RegistrationAccessToken: util.GetSha256Hash(registrationAccessToken),
+		TokenEndpointAuthMethod: metadata.TokenEndpointAuthMethod,
+		ResponseTypes:           metadata.ResponseTypes,
+		Scope:                   metadata.Scope,
+	}
+
+	affected, err := AddApplication(application)
+	if err != nil {
+		return nil, err
+	}
+	if !affected {
+		return nil, fmt.Errorf("failed to add the application: %s", application.GetId())
+	}
+
+	_, err = ormer.Engine.Insert(clientRegistration)
+	if err != nil {
+		return nil, err
+	}
+
+	clientInformation := getClientInformation(application, clientRegistration)
+	clientInformation.RegistrationAccessToken = registrationAccessToken
+	clientInformation.RegistrationClientUri = getRegistrationClientUri(clientId, host)
+	return clientInformation, nil
+}
+
+// getRegisteredClient authenticates the registration access token of a client registered dynamically
+func getRegisteredClient(clientId string, registrationAccessToken string) (*Application, *ClientRegistration, error) {
+	if clientId == "" || registrationAccessToken == "" {
+		return nil, nil, nil
+	}
+
+	clientRegistration := ClientRegistration{ClientId: clientId}
+	existed, err := ormer.Engine.Get(&clientRegistration)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !existed || subtle.ConstantTimeCompare([]byte(clientRegistration.RegistrationAccessToken), []byte(util.GetSha256Hash(registrationAccessToken))) != 1 {
+		return nil, nil, nil
+	}
+
+	application, err := GetApplicationByClientId(clientId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if application == nil {
+		return nil, nil, nil
+	}
+
+	return application, &clientRegistration, nil
+}
+
+// GetRegisteredClient returns the current configuration of a client registered dynamically, see RFC 7592 section 2.1
+func GetRegisteredClient(clientId string, registrationAccessToken string, host string) (interface{}, error) {
+	application, clientRegistration, err := getRegisteredClient(clientId, registrationAccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if application == nil {
+		return newClientMetadataError(InvalidToken, "the registration access token is invalid"), nil
+	}
+
+	clientInformation := getClientInformation(application, clientRegistration)
+	clientInformation.RegistrationClientUri = getRegistrationClientUri(clientId, host)
+	return clientInformation, nil
+}
+
+// UpdateRegisteredClient replaces the metadata of a client registered dynamically, the omitted
+// fields are reset to their defaults, see RFC 7592 section 2.2
+func UpdateRegisteredClient(clientId string, registrationAccessToken string, clientSecret string, metadata *ClientMetadata, host string) (interface{}, error) {
+	application, clientRegistration, err := getRegisteredClient(clientId, registrationAccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if application == nil {
+		return newClientMetadataError(InvalidToken, "the registration access token is invalid"), nil
+	}
+
+	if clientSecret != "" && clientSecret != application.ClientSecret {
+		return newClientMetadataError(InvalidClientMetadata, "the client_secret does not match the registered one"), nil
+	}
+
+	if tokenError := checkClientMetadata(metadata); tokenError != nil {
+		return tokenError, nil
+	}
+
+	applyClientMetadata(application, metadata)
+	_, err = ormer.Engine.ID(core.PK{application.Owner, application.Name}).AllCols().Update(application)
+	if err != nil {
+		return nil, err
+	}
+
+	clientRegistration.TokenEndpointAuthMethod = metadata.TokenEndpointAuthMethod
+	clientRegistration.ResponseTypes = metadata.ResponseTypes
+	clientRegistration.Scope = metadata.Scope
+	_, err = ormer.Engine.ID(core.PK{clientRegistration.Owner, clientRegistration.Name}).AllCols().Update(clientRegistration)
+	if err != nil {
+		return nil, err
+	}
+
+	clientInformation := getClientInformation(application, clientRegistration)
+	clientInformation.RegistrationClientUri = getRegistrationClientUri(clientId, host)
+	return clientInformation, nil
+}
+
+// DeleteRegisteredClient deletes a client registered dynamically, see RFC 7592 section 2.3.
+// A nil *TokenError is returned on success.
+func DeleteRegisteredClient(clientId string, registrationAccessToken string) (*TokenError, error) {
+	application, clientRegistration, err := getRegisteredClient(clientId, registrationAccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if application == nil {
+		return newClientMetadataError(InvalidToken, "the registration access token is invalid"), nil
+	}
+
+	_, err = DeleteApplication(application)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = ormer.Engine.ID(core.PK{clientRegistration.Owner, clientRegistration.Name}).Delete(&ClientRegistration{})
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
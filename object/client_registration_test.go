@@ -0,0 +1,73 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckClientMetadata(t *testing.T) {
+	scenarios := []struct {
+		description string
+		metadata    ClientMetadata
+		error       string
+	}{
+		{"defaults", ClientMetadata{RedirectUris: []string{"https://app.example.com/callback"}}, ""},
+		{"missing redirect_uris", ClientMetadata{}, InvalidRedirectUri},
+		{"relative redirect URI", ClientMetadata{RedirectUris: []string{"/callback"}}, InvalidRedirectUri},
+		{"redirect URI with fragment", ClientMetadata{RedirectUris: []string{"https://app.example.com/callback#a"}}, InvalidRedirectUri},
+		{"device code without redirect_uris", ClientMetadata{GrantTypes: []string{DeviceCodeGrantType}}, ""},
+		{"unsupported grant type", ClientMetadata{GrantTypes: []string{"foo"}}, InvalidClientMetadata},
+		{"jwt bearer grant", ClientMetadata{GrantTypes: []string{JwtBearerGrantType}}, InvalidClientMetadata},
+		{"password grant", ClientMetadata{GrantTypes: []string{"password"}}, InvalidClientMetadata},
+		{"client credentials grant", ClientMetadata{GrantTypes: []string{"client_credentials"}}, InvalidClientMetadata},
+		{"token exchange grant", ClientMetadata{GrantTypes: []string{TokenExchangeGrantType}}, InvalidClientMetadata},
+		{"response type without its grant type", ClientMetadata{GrantTypes: []string{DeviceCodeGrantType}, ResponseTypes: []string{"code"}}, InvalidClientMetadata},
+		{"implicit", ClientMetadata{RedirectUris: []string{"https://app.example.com/callback"}, GrantTypes: []string{"implicit"}, ResponseTypes: []string{"id_token token"}}, ""},
+		{"unsupported auth method", ClientMetadata{GrantTypes: []string{DeviceCodeGrantType}, TokenEndpointAuthMethod: "tls_client_auth"}, InvalidClientMetadata},
+		{"private_key_jwt without keys", ClientMetadata{GrantTypes: []string{DeviceCodeGrantType}, TokenEndpointAuthMethod: "private_key_jwt"}, InvalidClientMetadata},
+		{"private_key_jwt with jwks", ClientMetadata{GrantTypes: []string{DeviceCodeGrantType}, TokenEndpointAuthMethod: "private_key_jwt", Jwks: json.RawMessage(`{"keys":[]}`)}, ""},
+		{"jwks_uri", ClientMetadata{GrantTypes: []string{DeviceCodeGrantType}, TokenEndpointAuthMethod: "private_key_jwt", JwksUri: "https://app.example.com/jwks"}, InvalidClientMetadata},
+		{"backchannel_logout_uri", ClientMetadata{GrantTypes: []string{DeviceCodeGrantType}, BackChannelLogoutUri: "https://app.example.com/logout"}, InvalidClientMetadata},
+		{"frontchannel_logout_uri", ClientMetadata{GrantTypes: []string{DeviceCodeGrantType}, FrontChannelLogoutUri: "https://app.example.com/logout"}, ""},
+		{"invalid jwks", ClientMetadata{GrantTypes: []string{DeviceCodeGrantType}, Jwks: json.RawMessage(`[]`)}, InvalidClientMetadata},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			tokenError := checkClientMetadata(&scenario.metadata)
+			if scenario.error == "" {
+				assert.Nil(t, tokenError)
+			} else if assert.NotNil(t, tokenError) {
+				assert.Equal(t, scenario.error, tokenError.Error)
+			}
+		})
+	}
+
+	metadata := &ClientMetadata{RedirectUris: []string{"https://app.example.com/callback"}}
+	assert.Nil(t, checkClientMetadata(metadata))
+	assert.Equal(t, []string{"authorization_code"}, metadata.GrantTypes)
+	assert.Equal(t, []string{"code"}, metadata.ResponseTypes)
+	assert.Equal(t, "client_secret_basic", metadata.TokenEndpointAuthMethod)
+}
+
+func TestRegisteredGrantTypes(t *testing.T) {
+	grantTypes := []string{"authorization_code", "implicit", "refresh_token"}
+	assert.Equal(t, []string{"authorization_code", "token", "id_token", "refresh_token"}, getApplicationGrantTypes(grantTypes))
+	assert.Equal(t, grantTypes, getRegisteredGrantTypes(getApplicationGrantTypes(grantTypes)))
+}
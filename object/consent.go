@@ -0,0 +1,193 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/casdoor/casdoor/util"
+	"github.com/xorm-io/core"
+)
+
+const NextConsent = "NextConsent"
+
+// Consent records the scopes a user has granted to an application, the user is not asked
+// again as long as the application requests no other scopes
+type Consent struct {
+	Owner       string `xorm:"varchar(100) notnull pk" json:"owner"`
+	Name        string `xorm:"varchar(100) notnull pk" json:"name"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+	UpdatedTime string `xorm:"varchar(100)" json:"updatedTime"`
+
+	User          string   `xorm:"varchar(100) index" json:"user"`
+	Application   string   `xorm:"varchar(100) index" json:"application"`
+	GrantedScopes []string `xorm:"varchar(1000)" json:"grantedScopes"`
+}
+
+// ConsentRequest is shown to the user on the consent page
+type ConsentRequest struct {
+	Application   *Application `json:"application"`
+	Scopes        []string     `json:"scopes"`
+	GrantedScopes []string     `json:"grantedScopes"`
+}
+
+func GetConsents(owner string, user string) ([]*Consent, error) {
+	consents := []*Consent{}
+	err := ormer.Engine.Desc("updated_time").Find(&consents, &Consent{Owner: owner, User: user})
+	if err != nil {
+		return consents, err
+	}
+
+	return consents, nil
+}
+
+func getConsent(owner string, name string) (*Consent, error) {
+	if owner == "" || name == "" {
+		return nil, nil
+	}
+
+	consent := Consent{Owner: owner, Name: name}
+	existed, err := ormer.Engine.Get(&consent)
+	if err != nil {
+		return &consent, err
+	}
+
+	if existed {
+		return &consent, nil
+	} else {
+		return nil, nil
+	}
+}
+
+func GetConsent(id string) (*Consent, error) {
+	owner, name := util.GetOwnerAndNameFromId(id)
+	return getConsent(owner, name)
+}
+
+func getConsentByUserAndApplication(user *User, application *Application) (*Consent, error) {
+	consent := Consent{Owner: user.Owner, User: user.Name, Application: application.Name}
+	existed, err := ormer.Engine.Get(&consent)
+	if err != nil {
+		return nil, err
+	}
+
+	if !existed {
+		return nil, nil
+	}
+
+	return &consent, nil
+}
+
+func getScopes(scope string) []string {
+	return strings.Fields(scope)
+}
+
+func isPromptConsent(prompt string) bool {
+	return util.InSlice(strings.Fields(prompt), "consent")
+}
+
+// GetConsentRequest returns the consent the user has to give before the application is authorized
+// with the scope, or nil if the user does not need to be asked. The user is always asked if the
+// client has passed prompt=consent, otherwise only if the application requires consent and the
+// scopes have not all been granted before.
+func GetConsentRequest(application *Application, user *User, scope string, prompt string) (*ConsentRequest, error) {
+	if !application.RequireConsent && !isPromptConsent(prompt) {
+		return nil, nil
+	}
+
+	consent, err := getConsentByUserAndApplication(user, application)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := getScopes(scope)
+	grantedScopes := []string{}
+	if consent != nil {
+		grantedScopes = consent.GrantedScopes
+	}
+
+	if consent != nil && !isPromptConsent(prompt) {
+		isGranted := true
+		for _, s := range scopes {
+			if !util.InSlice(grantedScopes, s) {
+				isGranted = false
+				break
+			}
+		}
+
+		if isGranted {
+			return nil, nil
+		}
+	}
+
+	return &ConsentRequest{
+		Application:   GetMaskedApplication(application, ""),
+		Scopes:        scopes,
+		GrantedScopes: grantedScopes,
+	}, nil
+}
+
+// GrantConsent adds the scope to the scopes the user has granted to the application
+func GrantConsent(application *Application, user *User, scope string) error {
+	consent, err := getConsentByUserAndApplication(user, application)
+	if err != nil {
+		return err
+	}
+
+	if consent == nil {
+		consent = &Consent{
+			Owner:         user.Owner,
+			Name:          util.GenerateId(),
+			CreatedTime:   util.GetCurrentTime(),
+			UpdatedTime:   util.GetCurrentTime(),
+			User:          user.Name,
+			Application:   application.Name,
+			GrantedScopes: getScopes(scope),
+		}
+		_, err = ormer.Engine.Insert(consent)
+		return err
+	}
+
+	for _, s := range getScopes(scope) {
+		if !util.InSlice(consent.GrantedScopes, s) {
+			consent.GrantedScopes = append(consent.GrantedScopes, s)
+		}
+	}
+	consent.UpdatedTime = util.GetCurrentTime()
+
+	_, err = ormer.Engine.ID(core.PK{consent.Owner, consent.Name}).Cols("updated_time", "granted_scopes").Update(consent)
+	return err
+}
+
+// RevokeConsent deletes the consent together with all the tokens the application has been issued
+// for the user, so that the application has to ask the user for consent again
+func RevokeConsent(consent *Consent) (bool, error) {
+	affected, err := ormer.Engine.ID(core.PK{consent.Owner, consent.Name}).Delete(&Consent{})
+	if err != nil {
+		return false, err
+	}
+
+	_, err = ormer.Engine.Delete(&Token{Organization: consent.Owner, User: consent.User, Application: consent.Application})
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+func (consent *Consent) GetId() string {
+	return fmt.Sprintf("%s/%s", consent.Owner, consent.Name)
+}
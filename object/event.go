@@ -0,0 +1,309 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/util"
+)
+
+const (
+	EventUserCreated           = "user.created"
+	EventUserUpdated           = "user.updated"
+	EventUserDeleted           = "user.deleted"
+	EventRoleMembershipChanged = "role.membership_changed"
+	EventLoginSucceeded        = "login.succeeded"
+	EventLoginFailed           = "login.failed"
+	EventMfaEnrolled           = "mfa.enrolled"
+	EventTokenIssued           = "token.issued"
+
+	LoginFailureUserNotFound     = "user_not_found"
+	LoginFailureUserForbidden    = "user_forbidden"
+	LoginFailureWrongCredentials = "wrong_credentials"
+
+	cloudEventsSpecVersion = "1.0"
+	cloudEventsContentType = "application/cloudevents+json"
+)
+
+var EventTypes = []string{
+	EventUserCreated, EventUserUpdated, EventUserDeleted, EventRoleMembershipChanged,
+	EventLoginSucceeded, EventLoginFailed, EventMfaEnrolled, EventTokenIssued,
+}
+
+// eventIgnoredUserFields are the user fields whose changes do not emit user.updated, the sign-in
+// bookkeeping is reported by the login events and the other fields are not stored with the user
+var eventIgnoredUserFields = []string{
+	"updatedTime", "signinWrongTimes", "lastSigninWrongTime", "lastSigninTime", "lastSigninIp", "isOnline",
+	"passwordChangeRequired", "multiFactorAuths", "roles", "permissions", "userIdProvider",
+}
+
+// Event is a domain event in the structured JSON format of CloudEvents 1.0, see
+// https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/spec.md
+type Event struct {
+	SpecVersion     string      `json:"specversion"`
+	Id              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Subject         string      `json:"subject,omitempty"`
+	Time            string      `json:"time"`
+	DataContentType string      `json:"datacontenttype"`
+	Data            interface{} `json:"data"`
+}
+
+type UserChange struct {
+	Field    string      `json:"field"`
+	Old      interface{} `json:"old"`
+	New      interface{} `json:"new"`
+	IsMasked bool        `json:"isMasked,omitempty"`
+}
+
+type UserEventData struct {
+	User    map[string]interface{} `json:"user"`
+	Changes []*UserChange          `json:"changes,omitempty"`
+}
+
+type RoleMembershipEventData struct {
+	Role          string   `json:"role"`
+	AddedUsers    []string `json:"addedUsers"`
+	RemovedUsers  []string `json:"removedUsers"`
+	AddedGroups   []string `json:"addedGroups"`
+	RemovedGroups []string `json:"removedGroups"`
+	AddedRoles    []string `json:"addedRoles"`
+	RemovedRoles  []string `json:"removedRoles"`
+}
+
+type LoginEventData struct {
+	User        string `json:"user"`
+	Application string `json:"application,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	WrongTimes  int    `json:"wrongTimes,omitempty"`
+}
+
+type MfaEventData struct {
+	User    string `json:"user"`
+	MfaType string `json:"mfaType"`
+}
+
+type TokenEventData struct {
+	Token       string `json:"token"`
+	Application string `json:"application"`
+	User        string `json:"user"`
+	GrantType   string `json:"grantType"`
+	Scope       string `json:"scope"`
+	ExpiresIn   int    `json:"expiresIn"`
+}
+
+func newEvent(organization string, eventType string, subject string, data interface{}) *Event {
+	return &Event{
+		SpecVersion:     cloudEventsSpecVersion,
+		Id:              util.GenerateId(),
+		Source:          "/organizations/" + organization,
+		Type:            eventType,
+		Subject:         subject,
+		Time:            util.GetCurrentTime(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+}
+
+// emitEvent adds the event to the outbox of the enabled webhooks of the organization which
+// subscribe to its type. The operation which emitted the event has already succeeded, so a
+// failure is only logged.
+func emitEvent(organization string, eventType string, subject string, data interface{}) {
+	webhooks, err := getWebhooksByOrganization(organization)
+	if err == nil {
+		var payload string
+		for _, webhook := range webhooks {
+			if !webhook.IsEnabled || !util.InSlice(webhook.Events, eventType) {
+				continue
+			}
+
+			if payload == "" {
+				payload = util.StructToJson(newEvent(organization, eventType, subject, data))
+			}
+
+			err = addWebhookDelivery(webhook, eventType, cloudEventsContentType, payload)
+			if err != nil {
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		logs.Warning("failed to emit the event: %s of %s: %s", eventType, subject, err.Error())
+	}
+}
+
+func getEventValues(v interface{}) map[string]interface{} {
+	values := map[string]interface{}{}
+	data, err := json.Marshal(v)
+	if err == nil {
+		err = json.Unmarshal(data, &values)
+	}
+	if err != nil {
+		logs.Warning("failed to get the values of the event: %s", err.Error())
+	}
+	return values
+}
+
+func isEmptyEventValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
+// getEventUser returns the values of the user without its credentials
+func getEventUser(user *User) map[string]interface{} {
+	return getEventValues(getUserWithoutSecrets(user))
+}
+
+// getUserChanges returns the user after the update and the changed fields, only the given columns
+// are compared if any as the user may only have the values of the updated columns. The values of
+// the credentials are never sent, only whether they changed.
+func getUserChanges(oldUser *User, user *User, columns []string) (map[string]interface{}, []*UserChange) {
+	oldValues := getEventValues(oldUser)
+	values := getEventValues(user)
+	maskedValues := getEventUser(user)
+
+	// the columns are in snake case and the fields in camel case
+	updatedFields := map[string]bool{}
+	for _, column := range columns {
+		updatedFields[strings.ToLower(strings.ReplaceAll(column, "_", ""))] = true
+	}
+
+	fields := []string{}
+	for field := range values {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	newValues := getEventUser(oldUser)
+
+	changes := []*UserChange{}
+	for _, field := range fields {
+		if len(columns) != 0 && !updatedFields[strings.ToLower(field)] {
+			continue
+		}
+
+		oldValue, value := oldValues[field], values[field]
+		newValues[field] = maskedValues[field]
+		if util.InSlice(eventIgnoredUserFields, field) || reflect.DeepEqual(oldValue, value) || (isEmptyEventValue(oldValue) && isEmptyEventValue(value)) {
+			continue
+		}
+
+		if isUserSecretField(field) {
+			changes = append(changes, &UserChange{Field: field, IsMasked: true})
+		} else {
+			changes = append(changes, &UserChange{Field: field, Old: oldValue, New: value})
+		}
+	}
+
+	return newValues, changes
+}
+
+func emitUserEvent(user *User, eventType string) {
+	emitEvent(user.Owner, eventType, user.GetId(), &UserEventData{User: getEventUser(user)})
+}
+
+// emitUserUpdatedEvent emits user.updated with the changed fields, nothing if no field changed
+func emitUserUpdatedEvent(oldUser *User, user *User, columns []string) {
+	newUser, changes := getUserChanges(oldUser, user, columns)
+	if len(changes) == 0 {
+		return
+	}
+
+	emitEvent(oldUser.Owner, EventUserUpdated, oldUser.GetId(), &UserEventData{User: newUser, Changes: changes})
+}
+
+func getAddedValues(oldValues []string, values []string) []string {
+	res := []string{}
+	for _, value := range values {
+		if !util.InSlice(oldValues, value) && !util.InSlice(res, value) {
+			res = append(res, value)
+		}
+	}
+	return res
+}
+
+// emitRoleMembershipChangedEvent emits role.membership_changed if users, groups or sub roles were
+// added to or removed from the role, the old role is nil for a new role and the role for a deleted one
+func emitRoleMembershipChangedEvent(oldRole *Role, role *Role) {
+	var data *RoleMembershipEventData
+	if role == nil {
+		data = &RoleMembershipEventData{Role: oldRole.GetId()}
+		role = &Role{}
+	} else {
+		data = &RoleMembershipEventData{Role: role.GetId()}
+	}
+	if oldRole == nil {
+		oldRole = &Role{}
+	}
+
+	data.AddedUsers, data.RemovedUsers = getAddedValues(oldRole.Users, role.Users), getAddedValues(role.Users, oldRole.Users)
+	data.AddedGroups, data.RemovedGroups = getAddedValues(oldRole.Groups, role.Groups), getAddedValues(role.Groups, oldRole.Groups)
+	data.AddedRoles, data.RemovedRoles = getAddedValues(oldRole.Roles, role.Roles), getAddedValues(role.Roles, oldRole.Roles)
+	if len(data.AddedUsers)+len(data.RemovedUsers)+len(data.AddedGroups)+len(data.RemovedGroups)+len(data.AddedRoles)+len(data.RemovedRoles) == 0 {
+		return
+	}
+
+	owner, _ := util.GetOwnerAndNameFromIdNoCheck(data.Role)
+	emitEvent(owner, EventRoleMembershipChanged, data.Role, data)
+}
+
+// EmitLoginSucceededEvent emits login.succeeded once the user has signed in to the application,
+// the type is the response type of the login, like login, code or token
+func EmitLoginSucceededEvent(user *User, application *Application, responseType string) {
+	emitEvent(user.Owner, EventLoginSucceeded, user.GetId(), &LoginEventData{
+		User:        user.GetId(),
+		Application: application.GetId(),
+		Type:        responseType,
+	})
+}
+
+func emitLoginFailedEvent(organization string, userId string, reason string, wrongTimes int) {
+	emitEvent(organization, EventLoginFailed, userId, &LoginEventData{
+		User:       userId,
+		Reason:     reason,
+		WrongTimes: wrongTimes,
+	})
+}
+
+func emitMfaEnrolledEvent(user *User, mfaType string) {
+	emitEvent(user.Owner, EventMfaEnrolled, user.GetId(), &MfaEventData{User: user.GetId(), MfaType: mfaType})
+}
+
+func emitTokenIssuedEvent(token *Token, grantType string) {
+	emitEvent(token.Organization, EventTokenIssued, token.GetId(), &TokenEventData{
+		Token:       token.GetId(),
+		Application: token.Application,
+		User:        token.User,
+		GrantType:   grantType,
+		Scope:       token.Scope,
+		ExpiresIn:   token.ExpiresIn,
+	})
+}
@@ -0,0 +1,71 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/casdoor/casdoor/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetUserChanges(t *testing.T) {
+	oldUser := &User{Owner: "built-in", Name: "alice", DisplayName: "Alice", CountryCode: "US", Password: "old", Groups: nil}
+	user := &User{
+		Owner: "built-in", Name: "alice", DisplayName: "Alice Liddell", CountryCode: "US", Password: "new", Groups: []string{}, SigninWrongTimes: 2,
+		ManagedAccounts: []ManagedAccount{{Application: "app", Username: "alice", Password: "secret"}},
+	}
+
+	newUser, changes := getUserChanges(oldUser, user, nil)
+	assert.Equal(t, []*UserChange{
+		{Field: "displayName", Old: "Alice", New: "Alice Liddell"},
+		{Field: "managedAccounts", IsMasked: true},
+		{Field: "password", IsMasked: true},
+	}, changes)
+	assert.Equal(t, "Alice Liddell", newUser["displayName"])
+	assert.Equal(t, "", newUser["password"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"application": "app", "username": "alice", "password": "", "signinUrl": ""}}, newUser["managedAccounts"])
+	assert.Equal(t, "secret", user.ManagedAccounts[0].Password)
+
+	// only the updated columns are compared, the user may only have their values
+	user = &User{CountryCode: "GB", DisplayName: "ignored"}
+	newUser, changes = getUserChanges(oldUser, user, []string{"country_code"})
+	assert.Equal(t, []*UserChange{{Field: "countryCode", Old: "US", New: "GB"}}, changes)
+	assert.Equal(t, "Alice", newUser["displayName"])
+	assert.Equal(t, "GB", newUser["countryCode"])
+
+	_, changes = getUserChanges(oldUser, &User{SigninWrongTimes: 3}, []string{"signin_wrong_times", "last_signin_wrong_time"})
+	assert.Empty(t, changes)
+}
+
+func TestGetAddedValues(t *testing.T) {
+	assert.Equal(t, []string{"built-in/bob"}, getAddedValues([]string{"built-in/alice"}, []string{"built-in/alice", "built-in/bob", "built-in/bob"}))
+	assert.Equal(t, []string{}, getAddedValues([]string{"built-in/alice"}, nil))
+}
+
+func TestNewEvent(t *testing.T) {
+	event := newEvent("built-in", EventMfaEnrolled, "built-in/alice", &MfaEventData{User: "built-in/alice", MfaType: "app"})
+
+	var values map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(util.StructToJson(event)), &values))
+	assert.Equal(t, "1.0", values["specversion"])
+	assert.Equal(t, "mfa.enrolled", values["type"])
+	assert.Equal(t, "/organizations/built-in", values["source"])
+	assert.Equal(t, "built-in/alice", values["subject"])
+	assert.NotEmpty(t, values["id"])
+	assert.NotEmpty(t, values["time"])
+	assert.Equal(t, map[string]interface{}{"user": "built-in/alice", "mfaType": "app"}, values["data"])
+}
@@ -117,6 +117,11 @@ func UpdateGroup(id string, group *Group) (bool, error) {
 		if err != nil {
 			return false, err
 		}
+
+		err = renameProvisionedGroup(util.GetId(owner, name), util.GetId(group.Owner, group.Name))
+		if err != nil {
+			return false, err
+		}
 	}
 
 	oldGroupReachablePermissions, err := subGroupPermissions(oldGroup)
@@ -139,6 +144,8 @@ func UpdateGroup(id string, group *Group) (bool, error) {
 		if err != nil {
 			return false, fmt.Errorf("ProcessPolicyDifference: %w", err)
 		}
+
+		enqueueGroupProvisioning(group, ProvisioningActionUpsert)
 	}
 
 	return affected != 0, nil
@@ -165,6 +172,8 @@ func AddGroup(group *Group) (bool, error) {
 		if err != nil {
 			return false, fmt.Errorf("ProcessPolicyDifference: %w", err)
 		}
+
+		enqueueGroupProvisioning(group, ProvisioningActionUpsert)
 	}
 
 	return affected != 0, nil
@@ -178,10 +187,22 @@ func AddGroups(groups []*Group) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+
+	if affected != 0 {
+		for _, group := range groups {
+			enqueueGroupProvisioning(group, ProvisioningActionUpsert)
+		}
+	}
 	return affected != 0, nil
 }
 
 func DeleteGroup(group *Group) (bool, error) {
+	return deleteGroup(group, false)
+}
+
+// deleteGroup deletes the group, even if it has users when allowUsers is true, the caller then
+// removes the users from the deleted group
+func deleteGroup(group *Group, allowUsers bool) (bool, error) {
 	_, err := ormer.Engine.Get(group)
 	if err != nil {
 		return false, err
@@ -193,10 +214,12 @@ func DeleteGroup(group *Group) (bool, error) {
 		return false, errors.New("group has children group")
 	}
 
-	if count, err := GetGroupUserCount(group.GetId(), "", ""); err != nil {
-		return false, err
-	} else if count > 0 {
-		return false, errors.New("group has users")
+	if !allowUsers {
+		if count, err := GetGroupUserCount(group.GetId(), "", ""); err != nil {
+			return false, err
+		} else if count > 0 {
+			return false, errors.New("group has users")
+		}
 	}
 
 	if count, err := GetRoleCount(group.Owner, "`groups`", group.GetId()); err != nil {
@@ -226,6 +249,8 @@ func DeleteGroup(group *Group) (bool, error) {
 		if err != nil {
 			return false, fmt.Errorf("ProcessPolicyDifference: %w", err)
 		}
+
+		enqueueGroupProvisioning(group, ProvisioningActionDelete)
 	}
 
 	return affected != 0, nil
@@ -267,6 +292,22 @@ func ConvertToTreeData(groups []*Group, parentId string) []*Group {
 	return treeData
 }
 
+// GetGroupIdsByUser returns the ids of the groups of the user, including their ancestor groups
+func GetGroupIdsByUser(user *User) ([]string, error) {
+	groups, err := GetAncestorGroups(user.Groups...)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []string{}
+	for _, group := range groups {
+		if !util.InSlice(res, group.GetId()) {
+			res = append(res, group.GetId())
+		}
+	}
+	return res, nil
+}
+
 // GetAncestorGroups returns a list of groups that contain the given groupIds
 func GetAncestorGroups(groupIds ...string) ([]*Group, error) {
 	if len(groupIds) == 0 {
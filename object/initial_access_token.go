@@ -0,0 +1,158 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/casdoor/casdoor/util"
+	"github.com/xorm-io/core"
+)
+
+// InitialAccessToken authorizes the dynamic registration of clients in the organization
+// that owns it, see RFC 7591 section 3
+type InitialAccessToken struct {
+	Owner       string `xorm:"varchar(100) notnull pk" json:"owner"`
+	Name        string `xorm:"varchar(100) notnull pk" json:"name"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+
+	Token      string `xorm:"varchar(100) index" json:"token"`
+	ExpireTime string `xorm:"varchar(100)" json:"expireTime"`
+}
+
+func GetInitialAccessTokenCount(owner, field, value string) (int64, error) {
+	session := GetSession(owner, -1, -1, field, value, "", "")
+	return session.Count(&InitialAccessToken{})
+}
+
+func GetInitialAccessTokens(owner string) ([]*InitialAccessToken, error) {
+	initialAccessTokens := []*InitialAccessToken{}
+	err := ormer.Engine.Desc("created_time").Find(&initialAccessTokens, &InitialAccessToken{Owner: owner})
+	if err != nil {
+		return initialAccessTokens, err
+	}
+
+	return initialAccessTokens, nil
+}
+
+func GetPaginationInitialAccessTokens(owner string, offset, limit int, field, value, sortField, sortOrder string) ([]*InitialAccessToken, error) {
+	initialAccessTokens := []*InitialAccessToken{}
+	session := GetSession(owner, offset, limit, field, value, sortField, sortOrder)
+	err := session.Find(&initialAccessTokens)
+	if err != nil {
+		return initialAccessTokens, err
+	}
+
+	return initialAccessTokens, nil
+}
+
+func getInitialAccessToken(owner string, name string) (*InitialAccessToken, error) {
+	if owner == "" || name == "" {
+		return nil, nil
+	}
+
+	initialAccessToken := InitialAccessToken{Owner: owner, Name: name}
+	existed, err := ormer.Engine.Get(&initialAccessToken)
+	if err != nil {
+		return &initialAccessToken, err
+	}
+
+	if existed {
+		return &initialAccessToken, nil
+	} else {
+		return nil, nil
+	}
+}
+
+func GetInitialAccessToken(id string) (*InitialAccessToken, error) {
+	owner, name := util.GetOwnerAndNameFromId(id)
+	return getInitialAccessToken(owner, name)
+}
+
+// getInitialAccessTokenByToken returns the initial access token with the given value if it has not expired
+func getInitialAccessTokenByToken(token string) (*InitialAccessToken, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	initialAccessToken := InitialAccessToken{Token: token}
+	existed, err := ormer.Engine.Get(&initialAccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if !existed || initialAccessToken.IsExpired() {
+		return nil, nil
+	}
+
+	return &initialAccessToken, nil
+}
+
+func UpdateInitialAccessToken(id string, initialAccessToken *InitialAccessToken) (bool, error) {
+	owner, name := util.GetOwnerAndNameFromId(id)
+	if t, err := getInitialAccessToken(owner, name); err != nil {
+		return false, err
+	} else if t == nil {
+		return false, nil
+	}
+
+	affected, err := ormer.Engine.ID(core.PK{owner, name}).AllCols().Update(initialAccessToken)
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+func AddInitialAccessToken(initialAccessToken *InitialAccessToken) (bool, error) {
+	if initialAccessToken.Token == "" {
+		initialAccessToken.Token = util.GenerateClientSecret()
+	}
+
+	affected, err := ormer.Engine.Insert(initialAccessToken)
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+func DeleteInitialAccessToken(initialAccessToken *InitialAccessToken) (bool, error) {
+	affected, err := ormer.Engine.ID(core.PK{initialAccessToken.Owner, initialAccessToken.Name}).Delete(&InitialAccessToken{})
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+func (initialAccessToken *InitialAccessToken) GetId() string {
+	return fmt.Sprintf("%s/%s", initialAccessToken.Owner, initialAccessToken.Name)
+}
+
+// IsExpired reports whether the token has passed its expire time, an empty expire time never expires
+func (initialAccessToken *InitialAccessToken) IsExpired() bool {
+	if initialAccessToken.ExpireTime == "" {
+		return false
+	}
+
+	expireTime, err := time.Parse(time.RFC3339, initialAccessToken.ExpireTime)
+	if err != nil {
+		return true
+	}
+
+	return time.Now().After(expireTime)
+}
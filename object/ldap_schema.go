@@ -0,0 +1,194 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+
+	"github.com/casdoor/casdoor/util"
+)
+
+const (
+	LdapAttributeSourceUser     = "User"
+	LdapAttributeSourceProperty = "Property"
+	LdapAttributeSourceTemplate = "Template"
+	LdapAttributeSourceStatic   = "Static"
+)
+
+// LdapAttribute is an attribute of the user entries published by the LDAP server. The value is
+// the name of a user field, the key of a user property, a static value, or a template in which
+// ${<field>}, ${properties.<key>} and ${uidNumber} are replaced, e.g. "/home/${name}".
+// Several attributes with the same name make a multi-valued attribute.
+type LdapAttribute struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Value  string `json:"value"`
+}
+
+// reservedLdapAttributes are computed by the LDAP server and cannot be defined by the schema
+var reservedLdapAttributes = []string{"objectclass", "memberof", "userpassword"}
+
+var (
+	ldapAttributeNameRegex     = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9-]*$`)
+	ldapAttributeTemplateRegex = regexp.MustCompile(`\$\{([^}]*)\}`)
+)
+
+var DefaultLdapObjectClasses = []string{"top", "person", "organizationalPerson", "inetOrgPerson", "posixAccount"}
+
+// DefaultLdapAttributes is the schema of the organizations which do not define one
+var DefaultLdapAttributes = []*LdapAttribute{
+	{Name: "cn", Source: LdapAttributeSourceUser, Value: "name"},
+	{Name: "uid", Source: LdapAttributeSourceUser, Value: "id"},
+	{Name: "uid", Source: LdapAttributeSourceUser, Value: "name"},
+	{Name: "uidNumber", Source: LdapAttributeSourceTemplate, Value: "${uidNumber}"},
+	{Name: "gidNumber", Source: LdapAttributeSourceTemplate, Value: "${uidNumber}"},
+	{Name: "homeDirectory", Source: LdapAttributeSourceTemplate, Value: "/home/${name}"},
+	{Name: "loginShell", Source: LdapAttributeSourceStatic, Value: "/bin/bash"},
+	{Name: "displayName", Source: LdapAttributeSourceUser, Value: "displayName"},
+	{Name: "email", Source: LdapAttributeSourceUser, Value: "email"},
+	{Name: "mail", Source: LdapAttributeSourceUser, Value: "email"},
+	{Name: "mobile", Source: LdapAttributeSourceUser, Value: "phone"},
+	{Name: "title", Source: LdapAttributeSourceUser, Value: "tag"},
+}
+
+// GetLdapSchema returns the object classes and the attributes of the user entries of the
+// organization in the LDAP server
+func GetLdapSchema(organization *Organization) ([]string, []*LdapAttribute) {
+	objectClasses, attributes := DefaultLdapObjectClasses, DefaultLdapAttributes
+	if organization == nil {
+		return objectClasses, attributes
+	}
+
+	if len(organization.LdapObjectClasses) != 0 {
+		objectClasses = organization.LdapObjectClasses
+	}
+	if len(organization.LdapAttributes) != 0 {
+		attributes = organization.LdapAttributes
+	}
+	return objectClasses, attributes
+}
+
+// GetLdapUidNumber returns the default POSIX uid number of the user
+func GetLdapUidNumber(user *User) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(user.Name))
+	return h.Sum32()
+}
+
+func getLdapStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case nil:
+		return []string{}
+	case []string:
+		return v
+	case string:
+		if v == "" {
+			return []string{}
+		}
+		return []string{v}
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+func getLdapTemplateVariable(user *User, variable string) (string, bool) {
+	if variable == "uidNumber" {
+		return fmt.Sprintf("%v", GetLdapUidNumber(user)), true
+	}
+
+	if key, ok := strings.CutPrefix(variable, "properties."); ok {
+		return user.Properties[key], true
+	}
+
+	value, ok := getUserFieldValue(user, variable)
+	if !ok {
+		return "", false
+	}
+	return strings.Join(getLdapStrings(value), ","), true
+}
+
+// expandUserTemplate replaces the ${<field>}, ${properties.<key>} and ${uidNumber} variables of
+// the template with the values of the user
+func expandUserTemplate(user *User, template string) string {
+	return ldapAttributeTemplateRegex.ReplaceAllStringFunc(template, func(s string) string {
+		value, _ := getLdapTemplateVariable(user, s[2:len(s)-1])
+		return value
+	})
+}
+
+// checkUserTemplate checks that the variables of the template exist
+func checkUserTemplate(template string) error {
+	for _, match := range ldapAttributeTemplateRegex.FindAllStringSubmatch(template, -1) {
+		if isUserSecretField(match[1]) {
+			return fmt.Errorf("the template variable: \"%s\" holds credentials and cannot be published", match[1])
+		}
+		if _, ok := getLdapTemplateVariable(&User{}, match[1]); !ok {
+			return fmt.Errorf("the template variable: \"%s\" does not exist", match[1])
+		}
+	}
+	return nil
+}
+
+// GetValues returns the values of the attribute for the user
+func (attribute *LdapAttribute) GetValues(user *User) []string {
+	switch attribute.Source {
+	case LdapAttributeSourceUser:
+		value, _ := getUserFieldValue(user, attribute.Value)
+		return getLdapStrings(value)
+	case LdapAttributeSourceProperty:
+		return getLdapStrings(user.Properties[attribute.Value])
+	case LdapAttributeSourceTemplate:
+		return getLdapStrings(expandUserTemplate(user, attribute.Value))
+	default:
+		return getLdapStrings(attribute.Value)
+	}
+}
+
+// CheckLdapSchema checks the LDAP schema of an organization before it is saved
+func CheckLdapSchema(objectClasses []string, attributes []*LdapAttribute) error {
+	for _, objectClass := range objectClasses {
+		if !ldapAttributeNameRegex.MatchString(objectClass) {
+			return fmt.Errorf("the LDAP object class: \"%s\" is invalid", objectClass)
+		}
+	}
+
+	for _, attribute := range attributes {
+		if !ldapAttributeNameRegex.MatchString(attribute.Name) || util.InSlice(reservedLdapAttributes, strings.ToLower(attribute.Name)) {
+			return fmt.Errorf("the LDAP attribute name: \"%s\" is invalid", attribute.Name)
+		}
+
+		switch attribute.Source {
+		case LdapAttributeSourceUser:
+			if isUserSecretField(attribute.Value) {
+				return fmt.Errorf("the user field: \"%s\" of the LDAP attribute: %s holds credentials and cannot be published", attribute.Value, attribute.Name)
+			}
+			if _, ok := getUserFieldValue(&User{}, attribute.Value); !ok {
+				return fmt.Errorf("the user field: \"%s\" of the LDAP attribute: %s does not exist", attribute.Value, attribute.Name)
+			}
+		case LdapAttributeSourceTemplate:
+			if err := checkUserTemplate(attribute.Value); err != nil {
+				return fmt.Errorf("the LDAP attribute: %s is invalid: %s", attribute.Name, err.Error())
+			}
+		case LdapAttributeSourceProperty, LdapAttributeSourceStatic:
+		default:
+			return fmt.Errorf("the source: \"%s\" of the LDAP attribute: %s is invalid", attribute.Source, attribute.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLdapAttributeValues(t *testing.T) {
+	user := &User{Name: "alice", DisplayName: "Alice", Properties: map[string]string{"shell": "/bin/zsh"}}
+
+	assert.Equal(t, []string{"Alice"}, (&LdapAttribute{Source: LdapAttributeSourceUser, Value: "displayName"}).GetValues(user))
+	assert.Equal(t, []string{}, (&LdapAttribute{Source: LdapAttributeSourceUser, Value: "email"}).GetValues(user))
+	assert.Equal(t, []string{"/bin/zsh"}, (&LdapAttribute{Source: LdapAttributeSourceProperty, Value: "shell"}).GetValues(user))
+	assert.Equal(t, []string{"/home/alice"}, (&LdapAttribute{Source: LdapAttributeSourceTemplate, Value: "/home/${name}"}).GetValues(user))
+	assert.Equal(t, []string{fmt.Sprintf("%v", GetLdapUidNumber(user))}, (&LdapAttribute{Source: LdapAttributeSourceTemplate, Value: "${uidNumber}"}).GetValues(user))
+	assert.Equal(t, []string{"/bin/zsh"}, (&LdapAttribute{Source: LdapAttributeSourceTemplate, Value: "${properties.shell}"}).GetValues(user))
+
+	// the schemas saved before the secret fields were rejected publish nothing
+	user.TotpSecret = "secret"
+	assert.Equal(t, []string{}, (&LdapAttribute{Source: LdapAttributeSourceUser, Value: "totpSecret"}).GetValues(user))
+	assert.Equal(t, []string{}, (&LdapAttribute{Source: LdapAttributeSourceTemplate, Value: "${totpSecret}"}).GetValues(user))
+}
+
+func TestCheckLdapSchema(t *testing.T) {
+	scenarios := []struct {
+		description   string
+		objectClasses []string
+		attributes    []*LdapAttribute
+		isValid       bool
+	}{
+		{"default schema", DefaultLdapObjectClasses, DefaultLdapAttributes, true},
+		{"invalid object class", []string{"posix account"}, nil, false},
+		{"reserved attribute", nil, []*LdapAttribute{{Name: "memberOf", Source: LdapAttributeSourceStatic}}, false},
+		{"unknown user field", nil, []*LdapAttribute{{Name: "cn", Source: LdapAttributeSourceUser, Value: "foo"}}, false},
+		{"unknown template variable", nil, []*LdapAttribute{{Name: "homeDirectory", Source: LdapAttributeSourceTemplate, Value: "/home/${foo}"}}, false},
+		{"secret user field", nil, []*LdapAttribute{{Name: "userSecret", Source: LdapAttributeSourceUser, Value: "totpSecret"}}, false},
+		{"secret template variable", nil, []*LdapAttribute{{Name: "description", Source: LdapAttributeSourceTemplate, Value: "${passwordSalt}:${password}"}}, false},
+		{"unknown source", nil, []*LdapAttribute{{Name: "cn", Source: "foo"}}, false},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			err := CheckLdapSchema(scenario.objectClasses, scenario.attributes)
+			assert.Equal(t, scenario.isValid, err == nil)
+		})
+	}
+}
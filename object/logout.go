@@ -0,0 +1,288 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/proxy"
+	"github.com/casdoor/casdoor/util"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	BackChannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+	logoutTokenExpireIn      = 2 * time.Minute
+	backChannelLogoutTimeout = 10 * time.Second
+)
+
+// LogoutTokenClaims are the claims of the logout token of OpenID Connect Back-Channel Logout 1.0
+type LogoutTokenClaims struct {
+	Events map[string]interface{} `json:"events"`
+	Sid    string                 `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func signJwtByApplication(application *Application, claims jwt.Claims, tokenType string) (string, error) {
+	cert, err := getCertByApplication(application)
+	if err != nil {
+		return "", err
+	}
+
+	if cert == nil {
+		return "", fmt.Errorf("The cert of the application \"%s\" does not exist", application.GetId())
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(cert.PrivateKey))
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = cert.Name
+	if tokenType != "" {
+		token.Header["typ"] = tokenType
+	}
+
+	return token.SignedString(key)
+}
+
+func generateLogoutToken(application *Application, user *User, sid string, host string) (string, error) {
+	_, originBackend := getOriginFromHost(host)
+	nowTime := time.Now()
+
+	claims := LogoutTokenClaims{
+		Events: map[string]interface{}{BackChannelLogoutEvent: map[string]interface{}{}},
+		Sid:    sid,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    originBackend,
+			Subject:   user.Id,
+			Audience:  []string{application.ClientId},
+			IssuedAt:  jwt.NewNumericDate(nowTime),
+			ExpiresAt: jwt.NewNumericDate(nowTime.Add(logoutTokenExpireIn)),
+			ID:        util.GenerateId(),
+		},
+	}
+
+	return signJwtByApplication(application, claims, "logout+jwt")
+}
+
+func sendBackChannelLogout(application *Application, logoutToken string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), backChannelLogoutTimeout)
+	defer cancel()
+
+	body := url.Values{"logout_token": {logoutToken}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, application.BackChannelLogoutUri, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := proxy.DefaultHttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("the back-channel logout URI responded with: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// sendCasSingleLogout sends a logout request to the service which the service ticket was issued
+// to, the ticket is the index of the session to end in the service
+func sendCasSingleLogout(ticket *CasTicket) error {
+	_, name := util.GetOwnerAndNameFromIdNoCheck(ticket.UserId)
+	logoutRequest := CasLogoutRequest{
+		XmlnsSamlp:   "urn:oasis:names:tc:SAML:2.0:protocol",
+		XmlnsSaml:    "urn:oasis:names:tc:SAML:2.0:assertion",
+		ID:           fmt.Sprintf("LR-%s", util.GenerateId()),
+		Version:      "2.0",
+		IssueInstant: time.Now().UTC().Format(time.RFC3339),
+		NameID:       name,
+		SessionIndex: ticket.Name,
+	}
+
+	data, err := xml.Marshal(logoutRequest)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backChannelLogoutTimeout)
+	defer cancel()
+
+	body := url.Values{"logoutRequest": {string(data)}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ticket.Service, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := proxy.DefaultHttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("the service responded with: %s", resp.Status)
+	}
+
+	return nil
+}
+
+func getFrontChannelLogoutUrl(application *Application, sid string, host string) string {
+	_, originBackend := getOriginFromHost(host)
+
+	sep := "?"
+	if strings.Contains(application.FrontChannelLogoutUri, "?") {
+		sep = "&"
+	}
+
+	return fmt.Sprintf("%s%siss=%s&sid=%s", application.FrontChannelLogoutUri, sep, url.QueryEscape(originBackend), url.QueryEscape(sid))
+}
+
+// getSessionsBySid returns the sessions of the user per application that were signed in within
+// the browser session identified by sid, together with the ID of the browser session.
+func getSessionsBySid(userId string, sid string) ([]*Session, string, error) {
+	owner, name := util.GetOwnerAndNameFromId(userId)
+
+	sessions := []*Session{}
+	err := ormer.Engine.Where("owner = ? and name = ?", owner, name).Find(&sessions)
+	if err != nil {
+		return nil, "", err
+	}
+
+	res := []*Session{}
+	browserSessionId := ""
+	for _, session := range sessions {
+		for _, sessionId := range session.SessionId {
+			if util.GetSid(userId, sessionId) == sid {
+				res = append(res, session)
+				browserSessionId = sessionId
+				break
+			}
+		}
+	}
+
+	return res, browserSessionId, nil
+}
+
+// LogoutSession ends the browser session identified by sid in all the applications the user has
+// signed in to with it. Applications with a back-channel logout URI are sent a logout token in the
+// background, the front-channel logout URLs of the others are returned to be loaded by the browser,
+// together with the LogoutRequest URLs of the SAML SPs. The CAS services which were issued service
+// tickets in the session are sent a logout request.
+func LogoutSession(userId string, sid string, host string) ([]string, error) {
+	if userId == "" || sid == "" {
+		return []string{}, nil
+	}
+
+	user, err := GetUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return []string{}, nil
+	}
+
+	sessions, browserSessionId, err := getSessionsBySid(userId, sid)
+	if err != nil {
+		return nil, err
+	}
+
+	frontChannelLogoutUrls := []string{}
+	for _, session := range sessions {
+		_, err = DeleteSessionId(session.GetId(), browserSessionId)
+		if err != nil {
+			return nil, err
+		}
+
+		application, err := getApplication("admin", session.Application)
+		if err != nil {
+			return nil, err
+		}
+
+		if application == nil {
+			continue
+		}
+
+		if application.FrontChannelLogoutUri != "" {
+			frontChannelLogoutUrls = append(frontChannelLogoutUrls, getFrontChannelLogoutUrl(application, sid, host))
+		}
+
+		// the SAML SPs are sent a LogoutRequest by the browser like a front-channel logout
+		samlLogoutUrl, err := getSamlLogoutRequestUrl(application, user, sid, host)
+		if err != nil {
+			logs.Warning("SAML single logout of the application %s failed: %s", application.GetId(), err.Error())
+		} else if samlLogoutUrl != "" {
+			frontChannelLogoutUrls = append(frontChannelLogoutUrls, samlLogoutUrl)
+		}
+
+		if application.BackChannelLogoutUri != "" {
+			logoutToken, err := generateLogoutToken(application, user, sid, host)
+			if err != nil {
+				return nil, err
+			}
+
+			go func(application *Application) {
+				err := sendBackChannelLogout(application, logoutToken)
+				if err != nil {
+					logs.Warning("back-channel logout of the application %s failed: %s", application.GetId(), err.Error())
+				}
+			}(application)
+		}
+	}
+
+	// the CAS services are sent a logout request for the service tickets issued in the session
+	casTickets, err := getCasTicketStore().takeBySid(sid)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ticket := range casTickets {
+		go func(ticket *CasTicket) {
+			err := sendCasSingleLogout(ticket)
+			if err != nil {
+				logs.Warning("CAS single logout of the service %s failed: %s", ticket.Service, err.Error())
+			}
+		}(ticket)
+	}
+
+	return frontChannelLogoutUrls, nil
+}
+
+// GetTokenSid returns the sid claim of a token issued by this server, the signature is not verified
+// again as the token has already been found in the database. The token may have expired.
+func GetTokenSid(token string) string {
+	claims := &Claims{}
+	_, _, err := jwt.NewParser().ParseUnverified(token, claims)
+	if err != nil {
+		return ""
+	}
+
+	return claims.Sid
+}
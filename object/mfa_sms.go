@@ -107,6 +107,8 @@ func (mfa *SmsMfa) Enable(ctx *context.Context, user *User) error {
 		return err
 	}
 
+	emitMfaEnrolledEvent(user, mfa.Config.MfaType)
+
 	ctx.Input.CruSession.Delete(MfaRecoveryCodesSession)
 	ctx.Input.CruSession.Delete(MfaDestSession)
 	ctx.Input.CruSession.Delete(MfaCountryCodeSession)
@@ -121,6 +121,8 @@ func (mfa *TotpMfa) Enable(ctx *context.Context, user *User) error {
 		return err
 	}
 
+	emitMfaEnrolledEvent(user, mfa.Config.MfaType)
+
 	ctx.Input.CruSession.Delete(MfaRecoveryCodesSession)
 	ctx.Input.CruSession.Delete(MfaTotpSecretSession)
 
@@ -35,6 +35,7 @@ func DoMigration() {
 		&Migrator_1_342_0_PR_94{},
 		&Migrator_1_19504_0_PR_105{},
 		&Migrator_1_19503_0_PR_106{},
+		&Migrator_1_19505_0_PR_107{},
 		// more migrators add here in chronological order...
 	}
 
@@ -46,14 +47,20 @@ func DoMigration() {
 		}
 	}
 
+	err := DoMigrations(migrations)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// DoMigrations runs the migrations which have not run yet, it is also used for the migrations which
+// need more than the tables, like the default policies of the enforcers once they are initialized
+func DoMigrations(migrations []*migrate.Migration) error {
 	options := &migrate.Options{
 		TableName:    "migration",
 		IDColumnName: "id",
 	}
 
 	m := migrate.New(ormer.Engine, options, migrations)
-	err := m.Migrate()
-	if err != nil {
-		panic(err)
-	}
+	return m.Migrate()
 }
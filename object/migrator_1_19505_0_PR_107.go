@@ -0,0 +1,58 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"github.com/xorm-io/xorm"
+	"github.com/xorm-io/xorm/migrate"
+)
+
+// legacyApiTokenUserTag is the prefix of the tag of the users which were created for the legacy
+// API tokens, the token was the plaintext access key and access secret of such a user
+const legacyApiTokenUserTag = "<access-token><access-token-user-id:"
+
+type Migrator_1_19505_0_PR_107 struct{}
+
+func (*Migrator_1_19505_0_PR_107) IsMigrationNeeded() bool {
+	exist, _ := ormer.Engine.IsTableExist("user")
+	return exist
+}
+
+// DoMigration deletes the users of the legacy API tokens, they would still authenticate with their
+// access key and access secret. Their tokens cannot become personal access tokens, which are only
+// stored as hashes, so their owners create new ones.
+func (*Migrator_1_19505_0_PR_107) DoMigration() *migrate.Migration {
+	migration := migrate.Migration{
+		ID: "20240318DeleteApiTokenUsers -- Delete the users of the legacy API tokens",
+		Migrate: func(engine *xorm.Engine) error {
+			tx := engine.NewSession()
+			defer tx.Close()
+
+			err := tx.Begin()
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.Where("tag LIKE ?", legacyApiTokenUserTag+"%").Delete(&User{})
+			if err != nil {
+				return err
+			}
+
+			return tx.Commit()
+		},
+	}
+
+	return &migration
+}
@@ -26,23 +26,36 @@ import (
 	"gopkg.in/square/go-jose.v2"
 )
 
+var tokenEndpointAuthMethodsSupported = []string{"client_secret_basic", "client_secret_post", "client_secret_jwt", "private_key_jwt"}
+
 type OidcDiscovery struct {
-	Issuer                                 string   `json:"issuer"`
-	AuthorizationEndpoint                  string   `json:"authorization_endpoint"`
-	TokenEndpoint                          string   `json:"token_endpoint"`
-	UserinfoEndpoint                       string   `json:"userinfo_endpoint"`
-	JwksUri                                string   `json:"jwks_uri"`
-	IntrospectionEndpoint                  string   `json:"introspection_endpoint"`
-	ResponseTypesSupported                 []string `json:"response_types_supported"`
-	ResponseModesSupported                 []string `json:"response_modes_supported"`
-	GrantTypesSupported                    []string `json:"grant_types_supported"`
-	SubjectTypesSupported                  []string `json:"subject_types_supported"`
-	IdTokenSigningAlgValuesSupported       []string `json:"id_token_signing_alg_values_supported"`
-	ScopesSupported                        []string `json:"scopes_supported"`
-	ClaimsSupported                        []string `json:"claims_supported"`
-	RequestParameterSupported              bool     `json:"request_parameter_supported"`
-	RequestObjectSigningAlgValuesSupported []string `json:"request_object_signing_alg_values_supported"`
-	EndSessionEndpoint                     string   `json:"end_session_endpoint"`
+	Issuer                                     string   `json:"issuer"`
+	AuthorizationEndpoint                      string   `json:"authorization_endpoint"`
+	TokenEndpoint                              string   `json:"token_endpoint"`
+	UserinfoEndpoint                           string   `json:"userinfo_endpoint"`
+	JwksUri                                    string   `json:"jwks_uri"`
+	IntrospectionEndpoint                      string   `json:"introspection_endpoint"`
+	RevocationEndpoint                         string   `json:"revocation_endpoint"`
+	DeviceAuthorizationEndpoint                string   `json:"device_authorization_endpoint"`
+	RegistrationEndpoint                       string   `json:"registration_endpoint"`
+	ResponseTypesSupported                     []string `json:"response_types_supported"`
+	ResponseModesSupported                     []string `json:"response_modes_supported"`
+	GrantTypesSupported                        []string `json:"grant_types_supported"`
+	TokenEndpointAuthMethodsSupported          []string `json:"token_endpoint_auth_methods_supported"`
+	TokenEndpointAuthSigningAlgValuesSupported []string `json:"token_endpoint_auth_signing_alg_values_supported"`
+	SubjectTypesSupported                      []string `json:"subject_types_supported"`
+	IdTokenSigningAlgValuesSupported           []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                            []string `json:"scopes_supported"`
+	ClaimsSupported                            []string `json:"claims_supported"`
+	RequestParameterSupported                  bool     `json:"request_parameter_supported"`
+	RequestUriParameterSupported               bool     `json:"request_uri_parameter_supported"`
+	PushedAuthorizationRequestEndpoint         string   `json:"pushed_authorization_request_endpoint"`
+	RequestObjectSigningAlgValuesSupported     []string `json:"request_object_signing_alg_values_supported"`
+	EndSessionEndpoint                         string   `json:"end_session_endpoint"`
+	FrontChannelLogoutSupported                bool     `json:"frontchannel_logout_supported"`
+	FrontChannelLogoutSessionSupported         bool     `json:"frontchannel_logout_session_supported"`
+	BackChannelLogoutSupported                 bool     `json:"backchannel_logout_supported"`
+	BackChannelLogoutSessionSupported          bool     `json:"backchannel_logout_session_supported"`
 }
 
 func isIpAddress(host string) bool {
@@ -95,7 +108,9 @@ func getOriginFromHost(host string) (string, string) {
 	}
 }
 
-func GetOidcDiscovery(host string) OidcDiscovery {
+// GetOidcDiscovery returns the discovery document of the server, or of the application if its name
+// is not empty, which also lists the scopes and claims defined by the application
+func GetOidcDiscovery(host string, applicationName string) (OidcDiscovery, error) {
 	originFrontend, originBackend := getOriginFromHost(host)
 
 	// Examples:
@@ -104,25 +119,62 @@ func GetOidcDiscovery(host string) OidcDiscovery {
 	// https://accounts.google.com/.well-known/openid-configuration
 	// https://access.line.me/.well-known/openid-configuration
 	oidcDiscovery := OidcDiscovery{
-		Issuer:                                 originBackend,
-		AuthorizationEndpoint:                  fmt.Sprintf("%s/login/oauth/authorize", originFrontend),
-		TokenEndpoint:                          fmt.Sprintf("%s/api/login/oauth/access_token", originBackend),
-		UserinfoEndpoint:                       fmt.Sprintf("%s/api/userinfo", originBackend),
-		JwksUri:                                fmt.Sprintf("%s/.well-known/jwks", originBackend),
-		IntrospectionEndpoint:                  fmt.Sprintf("%s/api/login/oauth/introspect", originBackend),
-		ResponseTypesSupported:                 []string{"code", "token", "id_token", "code token", "code id_token", "token id_token", "code token id_token", "none"},
-		ResponseModesSupported:                 []string{"query", "fragment", "login", "code", "link"},
-		GrantTypesSupported:                    []string{"password", "authorization_code"},
-		SubjectTypesSupported:                  []string{"public"},
-		IdTokenSigningAlgValuesSupported:       []string{"RS256"},
-		ScopesSupported:                        []string{"openid", "email", "profile", "address", "phone", "offline_access"},
-		ClaimsSupported:                        []string{"iss", "ver", "sub", "aud", "iat", "exp", "id", "type", "displayName", "avatar", "permanentAvatar", "email", "phone", "location", "affiliation", "title", "homepage", "bio", "tag", "region", "language", "score", "ranking", "isOnline", "isAdmin", "isForbidden", "signupApplication", "ldap"},
-		RequestParameterSupported:              true,
-		RequestObjectSigningAlgValuesSupported: []string{"HS256", "HS384", "HS512"},
-		EndSessionEndpoint:                     fmt.Sprintf("%s/api/logout", originBackend),
+		Issuer:                            originBackend,
+		AuthorizationEndpoint:             fmt.Sprintf("%s/login/oauth/authorize", originFrontend),
+		TokenEndpoint:                     fmt.Sprintf("%s/api/login/oauth/access_token", originBackend),
+		UserinfoEndpoint:                  fmt.Sprintf("%s/api/userinfo", originBackend),
+		JwksUri:                           fmt.Sprintf("%s/.well-known/jwks", originBackend),
+		IntrospectionEndpoint:             fmt.Sprintf("%s/api/login/oauth/introspect", originBackend),
+		RevocationEndpoint:                fmt.Sprintf("%s/api/login/oauth/revoke", originBackend),
+		DeviceAuthorizationEndpoint:       fmt.Sprintf("%s/api/login/oauth/device_authorization", originBackend),
+		RegistrationEndpoint:              fmt.Sprintf("%s/api/login/oauth/register", originBackend),
+		ResponseTypesSupported:            []string{"code", "token", "id_token", "code token", "code id_token", "token id_token", "code token id_token", "none"},
+		ResponseModesSupported:            []string{"query", "fragment", "login", "code", "link"},
+		GrantTypesSupported:               []string{"password", "authorization_code", DeviceCodeGrantType, TokenExchangeGrantType, JwtBearerGrantType},
+		TokenEndpointAuthMethodsSupported: tokenEndpointAuthMethodsSupported,
+		TokenEndpointAuthSigningAlgValuesSupported: []string{"HS256", "HS384", "HS512", "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"},
+		SubjectTypesSupported:                      []string{"public"},
+		IdTokenSigningAlgValuesSupported:           []string{"RS256"},
+		ScopesSupported:                            []string{"openid", "email", "profile", "address", "phone", "offline_access"},
+		ClaimsSupported:                            []string{"iss", "ver", "sub", "aud", "iat", "exp", "id", "type", "displayName", "avatar", "permanentAvatar", "email", "phone", "location", "affiliation", "title", "homepage", "bio", "tag", "region", "language", "score", "ranking", "isOnline", "isAdmin", "isForbidden", "signupApplication", "ldap"},
+		RequestParameterSupported:                  true,
+		RequestUriParameterSupported:               true,
+		PushedAuthorizationRequestEndpoint:         fmt.Sprintf("%s/api/login/oauth/par", originBackend),
+		RequestObjectSigningAlgValuesSupported:     []string{"HS256", "HS384", "HS512", "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"},
+		EndSessionEndpoint:                         fmt.Sprintf("%s/api/logout", originBackend),
+		FrontChannelLogoutSupported:                true,
+		FrontChannelLogoutSessionSupported:         true,
+		BackChannelLogoutSupported:                 true,
+		BackChannelLogoutSessionSupported:          true,
+	}
+
+	if applicationName == "" {
+		return oidcDiscovery, nil
+	}
+
+	application, err := getApplication("admin", applicationName)
+	if err != nil {
+		return oidcDiscovery, err
+	}
+
+	if application == nil {
+		return oidcDiscovery, fmt.Errorf("the application: %s does not exist", util.GetId("admin", applicationName))
+	}
+
+	// the scopes defined by the application, and the claims they add to the tokens
+	scopes, claims := getApplicationScopesAndClaims(application)
+	for _, scope := range scopes {
+		if !util.InSlice(oidcDiscovery.ScopesSupported, scope) {
+			oidcDiscovery.ScopesSupported = append(oidcDiscovery.ScopesSupported, scope)
+		}
+	}
+	for _, claim := range claims {
+		if !util.InSlice(oidcDiscovery.ClaimsSupported, claim) {
+			oidcDiscovery.ClaimsSupported = append(oidcDiscovery.ClaimsSupported, claim)
+		}
 	}
 
-	return oidcDiscovery
+	return oidcDiscovery, nil
 }
 
 func GetJsonWebKeySet() (jose.JSONWebKeySet, error) {
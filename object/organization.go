@@ -74,9 +74,15 @@ type Organization struct {
 	EnableSoftDeletion     bool       `json:"enableSoftDeletion"`
 	IsProfilePublic        bool       `json:"isProfilePublic"`
 	PasswordSpecialChars   string     `xorm:"mediumtext" json:"passwordSpecialChars"`
+	AuditRetentionDays     int        `json:"auditRetentionDays"`
 
 	MfaItems     []*MfaItem     `xorm:"varchar(300)" json:"mfaItems"`
 	AccountItems []*AccountItem `xorm:"varchar(5000)" json:"accountItems"`
+
+	LdapObjectClasses []string         `xorm:"varchar(500)" json:"ldapObjectClasses"`
+	LdapAttributes    []*LdapAttribute `xorm:"mediumtext" json:"ldapAttributes"`
+
+	RadiusReplyRules []*RadiusReplyRule `xorm:"mediumtext" json:"radiusReplyRules"`
 }
 
 func GetOrganizationCount(owner, field, value string) (int64, error) {
@@ -515,6 +521,11 @@ func organizationChangeTrigger(ctx context.Context, oldName string, newName stri
 		return err
 	}
 
+	err = repo.UpdateEntitiesFieldValue(ctx, "provisioner", "organization", newName, map[string]interface{}{"organization": oldName})
+	if err != nil {
+		return err
+	}
+
 	err = repo.UpdateEntitiesFieldValue(ctx, "syncer", "organization", newName, map[string]interface{}{"organization": oldName})
 	if err != nil {
 		return err
@@ -388,4 +388,94 @@ func (a *Ormer) createTable() {
 	if err != nil {
 		panic(err)
 	}
+
+	err = a.Engine.Sync2(new(DeviceAuth))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(AssertionJti))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(PushedAuthRequest))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(InitialAccessToken))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(ClientRegistration))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(Consent))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(RadiusClient))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(CasTicket))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(Provisioner))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(ProvisioningTask))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(ProvisionedObject))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(SyncerRecord))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(WebhookDelivery))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(WebhookAttempt))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(AuditEntry))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(AuditCheckpoint))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(AuditExportCursor))
+	if err != nil {
+		panic(err)
+	}
+
+	err = a.Engine.Sync2(new(ApiToken))
+	if err != nil {
+		panic(err)
+	}
 }
@@ -0,0 +1,452 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	goldap "github.com/go-ldap/ldap/v3"
+
+	"github.com/casdoor/casdoor/util"
+	"github.com/xorm-io/core"
+)
+
+const (
+	ProvisionerTypeScim = "SCIM"
+	ProvisionerTypeLdap = "LDAP"
+	ProvisionerTypeHttp = "HTTP"
+)
+
+const (
+	ProvisioningEventUserCreate  = "user.create"
+	ProvisioningEventUserUpdate  = "user.update"
+	ProvisioningEventUserDelete  = "user.delete"
+	ProvisioningEventGroupCreate = "group.create"
+	ProvisioningEventGroupUpdate = "group.update"
+	ProvisioningEventGroupDelete = "group.delete"
+)
+
+const defaultProvisioningMaxAttempts = 10
+
+var provisioningEvents = []string{
+	ProvisioningEventUserCreate, ProvisioningEventUserUpdate, ProvisioningEventUserDelete,
+	ProvisioningEventGroupCreate, ProvisioningEventGroupUpdate, ProvisioningEventGroupDelete,
+}
+
+// scimAttributeNameRegex matches the attribute paths like "name.givenName", optionally prefixed by
+// the URN of a schema extension like "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:"
+var scimAttributeNameRegex = regexp.MustCompile(`^(urn:[^\s]+:)?[a-zA-Z][a-zA-Z0-9_$-]*(\.[a-zA-Z][a-zA-Z0-9_$-]*)*$`)
+
+// ProvisionerAttribute is an attribute of the users pushed to the target of a provisioner, it
+// has the sources of LdapAttribute. For a SCIM target, the name is the path of the SCIM attribute
+// like "name.givenName" or "emails".
+type ProvisionerAttribute LdapAttribute
+
+// ProvisionerHttpRequest is the request sent by an HTTP provisioner for an event. The path and the
+// body are templates: the user events have the variables of the LDAP attribute templates, the
+// group events have ${id}, ${name}, ${displayName} and ${members}, the JSON array of the external
+// IDs of the members, and all events have ${externalId}. The values are escaped for the URL path
+// and for the JSON strings of the body. The body is the JSON of the attributes if it is empty.
+type ProvisionerHttpRequest struct {
+	Event  string `json:"event"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Body   string `json:"body"`
+}
+
+// Provisioner pushes the users and the groups of an organization to a downstream system when they
+// change: a SCIM 2.0 service provider, an LDAP server or an HTTP API
+type Provisioner struct {
+	Owner       string `xorm:"varchar(100) notnull pk" json:"owner"`
+	Name        string `xorm:"varchar(100) notnull pk" json:"name"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+
+	DisplayName  string `xorm:"varchar(100)" json:"displayName"`
+	Organization string `xorm:"varchar(100) index" json:"organization"`
+	Type         string `xorm:"varchar(100)" json:"type"`
+
+	// Url is the base URL of the SCIM or HTTP API, or the ldap:// or ldaps:// URL of the LDAP server
+	Url     string    `xorm:"varchar(200)" json:"url"`
+	Headers []*Header `xorm:"mediumtext" json:"headers"`
+	// Username and Password are the basic authentication of the SCIM or HTTP API, or the bind DN
+	// and the password of the LDAP server
+	Username   string                  `xorm:"varchar(200)" json:"username"`
+	Password   string                  `xorm:"varchar(200)" json:"password"`
+	Attributes []*ProvisionerAttribute `xorm:"mediumtext" json:"attributes"`
+
+	UserBaseDn         string   `xorm:"varchar(200)" json:"userBaseDn"`
+	GroupBaseDn        string   `xorm:"varchar(200)" json:"groupBaseDn"`
+	UserObjectClasses  []string `xorm:"varchar(1000)" json:"userObjectClasses"`
+	GroupObjectClasses []string `xorm:"varchar(1000)" json:"groupObjectClasses"`
+	// RdnAttribute is the attribute of the user entries used in their DN
+	RdnAttribute string `xorm:"varchar(100)" json:"rdnAttribute"`
+	// DisabledAttribute is set to DisabledValue on the entries of the forbidden users, and removed
+	// from the other ones, e.g. "nsAccountLock" and "TRUE"
+	DisabledAttribute string `xorm:"varchar(100)" json:"disabledAttribute"`
+	DisabledValue     string `xorm:"varchar(100)" json:"disabledValue"`
+
+	HttpRequests []*ProvisionerHttpRequest `xorm:"mediumtext" json:"httpRequests"`
+
+	ProvisionGroups bool `json:"provisionGroups"`
+	// DisableDeletedUsers disables the users deleted from the organization instead of deleting them
+	// from a SCIM or LDAP target
+	DisableDeletedUsers bool `json:"disableDeletedUsers"`
+	// MaxAttempts is the number of attempts of a change before it fails, 10 by default
+	MaxAttempts int  `json:"maxAttempts"`
+	IsEnabled   bool `json:"isEnabled"`
+
+	LastSyncTime  string `xorm:"varchar(100)" json:"lastSyncTime"`
+	LastErrorTime string `xorm:"varchar(100)" json:"lastErrorTime"`
+	LastError     string `xorm:"mediumtext" json:"lastError"`
+}
+
+// DefaultScimProvisionerAttributes are the attributes of the SCIM provisioners which do not define any
+var DefaultScimProvisionerAttributes = []*ProvisionerAttribute{
+	{Name: "userName", Source: LdapAttributeSourceUser, Value: "name"},
+	{Name: "displayName", Source: LdapAttributeSourceUser, Value: "displayName"},
+	{Name: "name.givenName", Source: LdapAttributeSourceUser, Value: "firstName"},
+	{Name: "name.familyName", Source: LdapAttributeSourceUser, Value: "lastName"},
+	{Name: "emails", Source: LdapAttributeSourceUser, Value: "email"},
+	{Name: "phoneNumbers", Source: LdapAttributeSourceUser, Value: "phone"},
+	{Name: "title", Source: LdapAttributeSourceUser, Value: "title"},
+}
+
+// DefaultLdapProvisionerAttributes are the attributes of the LDAP provisioners which do not define any
+var DefaultLdapProvisionerAttributes = []*ProvisionerAttribute{
+	{Name: "uid", Source: LdapAttributeSourceUser, Value: "name"},
+	{Name: "cn", Source: LdapAttributeSourceUser, Value: "name"},
+	{Name: "sn", Source: LdapAttributeSourceUser, Value: "name"},
+	{Name: "displayName", Source: LdapAttributeSourceUser, Value: "displayName"},
+	{Name: "mail", Source: LdapAttributeSourceUser, Value: "email"},
+	{Name: "mobile", Source: LdapAttributeSourceUser, Value: "phone"},
+}
+
+// DefaultHttpProvisionerAttributes are the attributes of the HTTP provisioners which do not define any
+var DefaultHttpProvisionerAttributes = []*ProvisionerAttribute{
+	{Name: "id", Source: LdapAttributeSourceUser, Value: "id"},
+	{Name: "name", Source: LdapAttributeSourceUser, Value: "name"},
+	{Name: "displayName", Source: LdapAttributeSourceUser, Value: "displayName"},
+	{Name: "email", Source: LdapAttributeSourceUser, Value: "email"},
+	{Name: "phone", Source: LdapAttributeSourceUser, Value: "phone"},
+}
+
+var (
+	DefaultProvisionerUserObjectClasses  = []string{"top", "person", "organizationalPerson", "inetOrgPerson"}
+	DefaultProvisionerGroupObjectClasses = []string{"top", "groupOfNames"}
+)
+
+func GetProvisionerCount(owner, organization, field, value string) (int64, error) {
+	session := GetSession(owner, -1, -1, field, value, "", "")
+	return session.Count(&Provisioner{Organization: organization})
+}
+
+func GetProvisioners(owner string, organization string) ([]*Provisioner, error) {
+	provisioners := []*Provisioner{}
+	err := ormer.Engine.Desc("created_time").Find(&provisioners, &Provisioner{Owner: owner, Organization: organization})
+	if err != nil {
+		return provisioners, err
+	}
+
+	return provisioners, nil
+}
+
+func GetPaginationProvisioners(owner, organization string, offset, limit int, field, value, sortField, sortOrder string) ([]*Provisioner, error) {
+	provisioners := []*Provisioner{}
+	session := GetSession(owner, offset, limit, field, value, sortField, sortOrder)
+	err := session.Find(&provisioners, &Provisioner{Organization: organization})
+	if err != nil {
+		return provisioners, err
+	}
+
+	return provisioners, nil
+}
+
+func getEnabledProvisioners(organization string) ([]*Provisioner, error) {
+	provisioners := []*Provisioner{}
+	err := ormer.Engine.Find(&provisioners, &Provisioner{Organization: organization, IsEnabled: true})
+	if err != nil {
+		return provisioners, err
+	}
+
+	return provisioners, nil
+}
+
+func getProvisioner(owner string, name string) (*Provisioner, error) {
+	if owner == "" || name == "" {
+		return nil, nil
+	}
+
+	provisioner := Provisioner{Owner: owner, Name: name}
+	existed, err := ormer.Engine.Get(&provisioner)
+	if err != nil {
+		return &provisioner, err
+	}
+
+	if existed {
+		return &provisioner, nil
+	} else {
+		return nil, nil
+	}
+}
+
+func GetProvisioner(id string) (*Provisioner, error) {
+	owner, name := util.GetOwnerAndNameFromId(id)
+	return getProvisioner(owner, name)
+}
+
+func GetMaskedProvisioner(provisioner *Provisioner) *Provisioner {
+	if provisioner == nil {
+		return nil
+	}
+
+	if provisioner.Password != "" {
+		provisioner.Password = "***"
+	}
+	return provisioner
+}
+
+func GetMaskedProvisioners(provisioners []*Provisioner) []*Provisioner {
+	for _, provisioner := range provisioners {
+		provisioner = GetMaskedProvisioner(provisioner)
+	}
+	return provisioners
+}
+
+// getAttributes returns the attributes of the provisioner, or the default ones of its type
+func (provisioner *Provisioner) getAttributes() []*ProvisionerAttribute {
+	if len(provisioner.Attributes) != 0 {
+		return provisioner.Attributes
+	}
+
+	switch provisioner.Type {
+	case ProvisionerTypeScim:
+		return DefaultScimProvisionerAttributes
+	case ProvisionerTypeLdap:
+		return DefaultLdapProvisionerAttributes
+	default:
+		return DefaultHttpProvisionerAttributes
+	}
+}
+
+func (provisioner *Provisioner) getMaxAttempts() int {
+	if provisioner.MaxAttempts <= 0 {
+		return defaultProvisioningMaxAttempts
+	}
+	return provisioner.MaxAttempts
+}
+
+// GetValues returns the values of the attribute for the user
+func (attribute *ProvisionerAttribute) GetValues(user *User) []string {
+	return (*LdapAttribute)(attribute).GetValues(user)
+}
+
+func checkProvisionerAttributes(provisioner *Provisioner) error {
+	names := map[string]bool{}
+	for _, attribute := range provisioner.getAttributes() {
+		names[attribute.Name] = true
+
+		isValid := attribute.Name != ""
+		switch provisioner.Type {
+		case ProvisionerTypeScim:
+			isValid = scimAttributeNameRegex.MatchString(attribute.Name)
+		case ProvisionerTypeLdap:
+			isValid = ldapAttributeNameRegex.MatchString(attribute.Name) && !strings.EqualFold(attribute.Name, "objectClass")
+		}
+		if !isValid {
+			return fmt.Errorf("the attribute name: \"%s\" is invalid", attribute.Name)
+		}
+
+		switch attribute.Source {
+		case LdapAttributeSourceUser:
+			if isUserSecretField(attribute.Value) {
+				return fmt.Errorf("the user field: \"%s\" of the attribute: %s holds credentials and cannot be provisioned", attribute.Value, attribute.Name)
+			}
+			if _, ok := getUserFieldValue(&User{}, attribute.Value); !ok {
+				return fmt.Errorf("the user field: \"%s\" of the attribute: %s does not exist", attribute.Value, attribute.Name)
+			}
+		case LdapAttributeSourceTemplate:
+			if err := checkUserTemplate(attribute.Value); err != nil {
+				return fmt.Errorf("the attribute: %s is invalid: %s", attribute.Name, err.Error())
+			}
+		case LdapAttributeSourceProperty, LdapAttributeSourceStatic:
+		default:
+			return fmt.Errorf("the source: \"%s\" of the attribute: %s is invalid", attribute.Source, attribute.Name)
+		}
+	}
+
+	if provisioner.Type == ProvisionerTypeScim && !names["userName"] {
+		return fmt.Errorf("the attributes of a SCIM provisioner must define userName")
+	}
+	if provisioner.Type == ProvisionerTypeLdap && !names[provisioner.getRdnAttribute()] {
+		return fmt.Errorf("the attributes of an LDAP provisioner must define its RDN attribute: %s", provisioner.getRdnAttribute())
+	}
+
+	return nil
+}
+
+func checkProvisioner(provisioner *Provisioner) error {
+	u, err := url.Parse(provisioner.Url)
+	if err != nil {
+		return fmt.Errorf("the URL: \"%s\" of the provisioner is invalid: %s", provisioner.Url, err.Error())
+	}
+
+	schemes := []string{"http", "https"}
+	switch provisioner.Type {
+	case ProvisionerTypeScim, ProvisionerTypeHttp:
+	case ProvisionerTypeLdap:
+		schemes = []string{"ldap", "ldaps"}
+	default:
+		return fmt.Errorf("the provisioner type: \"%s\" is not supported", provisioner.Type)
+	}
+
+	if !util.InSlice(schemes, u.Scheme) || u.Host == "" {
+		return fmt.Errorf("the URL: \"%s\" of the provisioner must be an absolute %s URL", provisioner.Url, strings.Join(schemes, " or "))
+	}
+
+	if provisioner.MaxAttempts < 0 {
+		return fmt.Errorf("the max attempts of the provisioner cannot be negative")
+	}
+
+	err = checkProvisionerAttributes(provisioner)
+	if err != nil {
+		return err
+	}
+
+	switch provisioner.Type {
+	case ProvisionerTypeLdap:
+		if _, err = goldap.ParseDN(provisioner.UserBaseDn); err != nil || provisioner.UserBaseDn == "" {
+			return fmt.Errorf("the user base DN: \"%s\" of the provisioner is invalid", provisioner.UserBaseDn)
+		}
+
+		if _, err = goldap.ParseDN(provisioner.GroupBaseDn); provisioner.ProvisionGroups && (err != nil || provisioner.GroupBaseDn == "") {
+			return fmt.Errorf("the group base DN: \"%s\" of the provisioner is invalid", provisioner.GroupBaseDn)
+		}
+
+		for _, objectClass := range append(provisioner.UserObjectClasses, provisioner.GroupObjectClasses...) {
+			if !ldapAttributeNameRegex.MatchString(objectClass) {
+				return fmt.Errorf("the LDAP object class: \"%s\" is invalid", objectClass)
+			}
+		}
+
+		if provisioner.DisabledAttribute != "" && !ldapAttributeNameRegex.MatchString(provisioner.DisabledAttribute) {
+			return fmt.Errorf("the disabled attribute: \"%s\" of the provisioner is invalid", provisioner.DisabledAttribute)
+		}
+
+		if provisioner.DisableDeletedUsers && provisioner.DisabledAttribute == "" {
+			return fmt.Errorf("an LDAP provisioner needs a disabled attribute to disable the deleted users")
+		}
+	case ProvisionerTypeHttp:
+		for _, request := range provisioner.HttpRequests {
+			if !util.InSlice(provisioningEvents, request.Event) {
+				return fmt.Errorf("the event: \"%s\" of the HTTP request is not supported", request.Event)
+			}
+
+			if !util.InSlice([]string{"GET", "POST", "PUT", "PATCH", "DELETE"}, request.Method) {
+				return fmt.Errorf("the method: \"%s\" of the HTTP request for %s is not supported", request.Method, request.Event)
+			}
+
+			if strings.HasPrefix(request.Event, "user.") {
+				for _, template := range []string{request.Path, request.Body} {
+					err = checkUserTemplate(strings.ReplaceAll(template, "${externalId}", ""))
+					if err != nil {
+						return fmt.Errorf("the HTTP request for %s is invalid: %s", request.Event, err.Error())
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func UpdateProvisioner(id string, provisioner *Provisioner) (bool, error) {
+	owner, name := util.GetOwnerAndNameFromId(id)
+	if p, err := getProvisioner(owner, name); err != nil {
+		return false, err
+	} else if p == nil {
+		return false, nil
+	}
+
+	err := checkProvisioner(provisioner)
+	if err != nil {
+		return false, err
+	}
+
+	// the status is only written by the provisioning job
+	session := ormer.Engine.ID(core.PK{owner, name}).AllCols().Omit("last_sync_time", "last_error_time", "last_error")
+	if provisioner.Password == "***" {
+		session.Omit("password")
+	}
+	affected, err := session.Update(provisioner)
+	if err != nil {
+		return false, err
+	}
+
+	if affected != 0 && provisioner.GetId() != id {
+		_, err = ormer.Engine.Where("provisioner = ?", id).Update(&ProvisioningTask{Provisioner: provisioner.GetId()})
+		if err != nil {
+			return false, err
+		}
+
+		_, err = ormer.Engine.Where("provisioner = ?", id).Update(&ProvisionedObject{Provisioner: provisioner.GetId()})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return affected != 0, nil
+}
+
+func AddProvisioner(provisioner *Provisioner) (bool, error) {
+	err := checkProvisioner(provisioner)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := ormer.Engine.Insert(provisioner)
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+func DeleteProvisioner(provisioner *Provisioner) (bool, error) {
+	affected, err := ormer.Engine.ID(core.PK{provisioner.Owner, provisioner.Name}).Delete(&Provisioner{})
+	if err != nil {
+		return false, err
+	}
+
+	if affected != 0 {
+		_, err = ormer.Engine.Where("provisioner = ?", provisioner.GetId()).Delete(&ProvisioningTask{})
+		if err != nil {
+			return false, err
+		}
+
+		_, err = ormer.Engine.Where("provisioner = ?", provisioner.GetId()).Delete(&ProvisionedObject{})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return affected != 0, nil
+}
+
+func (provisioner *Provisioner) GetId() string {
+	return fmt.Sprintf("%s/%s", provisioner.Owner, provisioner.Name)
+}
@@ -0,0 +1,633 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/util"
+	"github.com/xorm-io/core"
+)
+
+const (
+	ProvisioningObjectUser  = "User"
+	ProvisioningObjectGroup = "Group"
+
+	ProvisioningActionUpsert = "Upsert"
+	ProvisioningActionDelete = "Delete"
+
+	ProvisioningTaskStatePending = "Pending"
+	ProvisioningTaskStateFailed  = "Failed"
+)
+
+const (
+	provisioningInterval      = 5 * time.Second
+	provisioningBatchSize     = 100
+	provisioningLease         = 5 * time.Minute
+	provisioningRetryDelay    = 30 * time.Second
+	provisioningMaxRetryDelay = time.Hour
+)
+
+// provisioningIgnoredUserColumns are the user columns whose updates are not pushed to the targets
+var provisioningIgnoredUserColumns = []string{
+	"signin_wrong_times", "last_signin_wrong_time", "last_signin_time", "last_signin_ip",
+	"webauthnCredentials", "recovery_codes", "preferred_mfa_type", "mfa_phone_enabled", "mfa_email_enabled", "totp_secret",
+}
+
+// ProvisioningTask is a change of a user or a group waiting to be pushed to the target of a
+// provisioner. The tasks of an object are run in order, and a failed task is retried with an
+// exponential backoff until the max attempts of the provisioner.
+type ProvisioningTask struct {
+	Id          int64  `xorm:"pk autoincr" json:"id"`
+	Provisioner string `xorm:"varchar(200) index" json:"provisioner"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+
+	ObjectType string `xorm:"varchar(100)" json:"objectType"`
+	// ObjectId is the ID of the user, or the owner/name of the group
+	ObjectId   string `xorm:"varchar(200)" json:"objectId"`
+	ObjectName string `xorm:"varchar(200)" json:"objectName"`
+	Action     string `xorm:"varchar(100)" json:"action"`
+	// Object is the JSON of the deleted user or group
+	Object string `xorm:"mediumtext" json:"object"`
+
+	State       string `xorm:"varchar(100) index" json:"state"`
+	Attempts    int    `json:"attempts"`
+	NextRunTime int64  `xorm:"index" json:"nextRunTime"`
+	LastError   string `xorm:"mediumtext" json:"lastError"`
+}
+
+// ProvisionedObject links a user or a group to its resource in the target of a provisioner
+type ProvisionedObject struct {
+	Provisioner string `xorm:"varchar(200) notnull pk" json:"provisioner"`
+	ObjectType  string `xorm:"varchar(100) notnull pk" json:"objectType"`
+	ObjectId    string `xorm:"varchar(200) notnull pk" json:"objectId"`
+
+	// ExternalId is the ID of the SCIM resource, the DN of the LDAP entry, or the ID returned by the HTTP API
+	ExternalId  string `xorm:"varchar(500)" json:"externalId"`
+	UpdatedTime string `xorm:"varchar(100)" json:"updatedTime"`
+}
+
+// ProvisionerStatus is the state of the queue and of the last runs of a provisioner
+type ProvisionerStatus struct {
+	Provisioner       string `json:"provisioner"`
+	IsEnabled         bool   `json:"isEnabled"`
+	PendingTaskCount  int64  `json:"pendingTaskCount"`
+	FailedTaskCount   int64  `json:"failedTaskCount"`
+	ProvisionedUsers  int64  `json:"provisionedUsers"`
+	ProvisionedGroups int64  `json:"provisionedGroups"`
+	LastSyncTime      string `json:"lastSyncTime"`
+	LastErrorTime     string `json:"lastErrorTime"`
+	LastError         string `json:"lastError"`
+}
+
+// provisioningConnector pushes the users and the groups to the target of a provisioner. The
+// external ID is empty if the object has not been provisioned yet, and members are the external
+// IDs of the provisioned users of a group.
+type provisioningConnector interface {
+	upsertUser(user *User, externalId string) (string, error)
+	deleteUser(user *User, externalId string) error
+	upsertGroup(group *Group, members []string, externalId string) (string, error)
+	deleteGroup(group *Group, externalId string) error
+	close()
+}
+
+func newProvisioningConnector(provisioner *Provisioner) (provisioningConnector, error) {
+	switch provisioner.Type {
+	case ProvisionerTypeScim:
+		return newScimProvisioningConnector(provisioner), nil
+	case ProvisionerTypeLdap:
+		return newLdapProvisioningConnector(provisioner)
+	case ProvisionerTypeHttp:
+		return newHttpProvisioningConnector(provisioner), nil
+	default:
+		return nil, fmt.Errorf("the provisioner type: \"%s\" is not supported", provisioner.Type)
+	}
+}
+
+func addProvisioningTask(provisioner *Provisioner, objectType string, objectId string, objectName string, action string, object interface{}) error {
+	task := &ProvisioningTask{
+		Provisioner: provisioner.GetId(),
+		CreatedTime: util.GetCurrentTime(),
+		ObjectType:  objectType,
+		ObjectId:    objectId,
+		ObjectName:  objectName,
+		Action:      action,
+		State:       ProvisioningTaskStatePending,
+		NextRunTime: time.Now().Unix(),
+	}
+
+	if action == ProvisioningActionUpsert {
+		// an upsert reads the object when it runs, so a pending one which has not been run yet is enough
+		count, err := ormer.Engine.Where("provisioner = ? and object_type = ? and object_id = ? and action = ? and state = ? and attempts = 0",
+			task.Provisioner, objectType, objectId, action, ProvisioningTaskStatePending).Count(&ProvisioningTask{})
+		if err != nil {
+			return err
+		}
+		if count != 0 {
+			return nil
+		}
+	} else {
+		task.Object = util.StructToJson(object)
+	}
+
+	_, err := ormer.Engine.Insert(task)
+	return err
+}
+
+// enqueueProvisioning adds the change of an object of the organization to the queues of its
+// enabled provisioners, the changes of groups are only added for the provisioners of groups
+func enqueueProvisioning(organization string, objectType string, objectId string, objectName string, action string, object interface{}) {
+	provisioners, err := getEnabledProvisioners(organization)
+	if err == nil {
+		for _, provisioner := range provisioners {
+			if objectType == ProvisioningObjectGroup && !provisioner.ProvisionGroups {
+				continue
+			}
+
+			err = addProvisioningTask(provisioner, objectType, objectId, objectName, action, object)
+			if err != nil {
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		logs.Warning("failed to add the provisioning task of %s: %s to the queue: %s", objectType, objectName, err.Error())
+	}
+}
+
+// enqueueUserProvisioning adds the change of the user to the provisioning queues, with the changes
+// of the groups the user joined or left
+func enqueueUserProvisioning(user *User, action string, oldGroups []string, groups []string) {
+	var object interface{}
+	if action == ProvisioningActionDelete {
+		// the secrets of the deleted user are not kept in the queue
+		object = getUserWithoutSecrets(user)
+	}
+	enqueueProvisioning(user.Owner, ProvisioningObjectUser, user.Id, user.GetId(), action, object)
+
+	changedGroups := []string{}
+	for _, group := range append(append([]string{}, oldGroups...), groups...) {
+		if util.InSlice(oldGroups, group) != util.InSlice(groups, group) && !util.InSlice(changedGroups, group) {
+			changedGroups = append(changedGroups, group)
+		}
+	}
+
+	for _, group := range changedGroups {
+		enqueueProvisioning(user.Owner, ProvisioningObjectGroup, group, group, ProvisioningActionUpsert, nil)
+	}
+}
+
+// enqueueUserColumnsProvisioning adds the update of the columns of the user to the provisioning
+// queues, unless only columns which are not provisioned are updated
+func enqueueUserColumnsProvisioning(oldUser *User, user *User, columns []string) {
+	for _, column := range columns {
+		if util.InSlice(provisioningIgnoredUserColumns, column) {
+			continue
+		}
+
+		// the user may only have the values of the updated columns
+		groups := oldUser.Groups
+		if util.InSlice(columns, "groups") {
+			groups = user.Groups
+		}
+		enqueueUserProvisioning(oldUser, ProvisioningActionUpsert, oldUser.Groups, groups)
+		return
+	}
+}
+
+func enqueueGroupProvisioning(group *Group, action string) {
+	var object interface{}
+	if action == ProvisioningActionDelete {
+		object = group
+	}
+	enqueueProvisioning(group.Owner, ProvisioningObjectGroup, group.GetId(), group.GetId(), action, object)
+}
+
+func getProvisionedObject(provisioner string, objectType string, objectId string) (*ProvisionedObject, error) {
+	provisionedObject := ProvisionedObject{Provisioner: provisioner, ObjectType: objectType, ObjectId: objectId}
+	existed, err := ormer.Engine.Get(&provisionedObject)
+	if err != nil {
+		return nil, err
+	}
+
+	if existed {
+		return &provisionedObject, nil
+	} else {
+		return nil, nil
+	}
+}
+
+func setProvisionedObject(provisioner string, objectType string, objectId string, externalId string) error {
+	provisionedObject := &ProvisionedObject{
+		Provisioner: provisioner,
+		ObjectType:  objectType,
+		ObjectId:    objectId,
+		ExternalId:  externalId,
+		UpdatedTime: util.GetCurrentTime(),
+	}
+
+	affected, err := ormer.Engine.ID(core.PK{provisioner, objectType, objectId}).AllCols().Update(provisionedObject)
+	if err != nil || affected != 0 {
+		return err
+	}
+
+	_, err = ormer.Engine.Insert(provisionedObject)
+	return err
+}
+
+func deleteProvisionedObject(provisioner string, objectType string, objectId string) error {
+	_, err := ormer.Engine.Delete(&ProvisionedObject{Provisioner: provisioner, ObjectType: objectType, ObjectId: objectId})
+	return err
+}
+
+// renameProvisionedGroup keeps the links of a renamed group to its resources in the targets
+func renameProvisionedGroup(oldId string, newId string) error {
+	_, err := ormer.Engine.Where("object_type = ? and object_id = ?", ProvisioningObjectGroup, oldId).
+		Update(&ProvisionedObject{ObjectId: newId})
+	return err
+}
+
+func getGroupMemberExternalIds(provisioner string, group *Group) ([]string, error) {
+	users, err := GetGroupUsers(group.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	userIds := []string{}
+	for _, user := range users {
+		userIds = append(userIds, user.Id)
+	}
+
+	provisionedObjects := []*ProvisionedObject{}
+	err = ormer.Engine.Where("provisioner = ? and object_type = ?", provisioner, ProvisioningObjectUser).
+		In("object_id", userIds).Asc("object_id").Find(&provisionedObjects)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []string{}
+	for _, provisionedObject := range provisionedObjects {
+		res = append(res, provisionedObject.ExternalId)
+	}
+	return res, nil
+}
+
+func runUserProvisioningTask(provisioner *Provisioner, connector provisioningConnector, task *ProvisioningTask, provisionedObject *ProvisionedObject) error {
+	if task.Action == ProvisioningActionDelete {
+		if provisionedObject == nil {
+			return nil
+		}
+
+		var user User
+		err := json.Unmarshal([]byte(task.Object), &user)
+		if err != nil {
+			return err
+		}
+
+		err = connector.deleteUser(&user, provisionedObject.ExternalId)
+		if err != nil {
+			return err
+		}
+		return deleteProvisionedObject(task.Provisioner, task.ObjectType, task.ObjectId)
+	}
+
+	user := User{Id: task.ObjectId}
+	existed, err := ormer.Engine.Get(&user)
+	if err != nil || !existed {
+		return err
+	}
+
+	externalId := ""
+	if provisionedObject != nil {
+		externalId = provisionedObject.ExternalId
+	}
+
+	newExternalId, err := connector.upsertUser(&user, externalId)
+	if err != nil {
+		return err
+	}
+
+	err = setProvisionedObject(task.Provisioner, task.ObjectType, task.ObjectId, newExternalId)
+	if err != nil {
+		return err
+	}
+
+	// the groups of the user may have been pushed before the user was created in the target, or
+	// have the old external ID of the user as member
+	if (externalId == "" || externalId != newExternalId) && provisioner.ProvisionGroups {
+		for _, group := range user.Groups {
+			err = addProvisioningTask(provisioner, ProvisioningObjectGroup, group, group, ProvisioningActionUpsert, nil)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func runGroupProvisioningTask(connector provisioningConnector, task *ProvisioningTask, provisionedObject *ProvisionedObject) error {
+	if task.Action == ProvisioningActionDelete {
+		if provisionedObject == nil {
+			return nil
+		}
+
+		var group Group
+		err := json.Unmarshal([]byte(task.Object), &group)
+		if err != nil {
+			return err
+		}
+
+		err = connector.deleteGroup(&group, provisionedObject.ExternalId)
+		if err != nil {
+			return err
+		}
+		return deleteProvisionedObject(task.Provisioner, task.ObjectType, task.ObjectId)
+	}
+
+	group, err := GetGroup(task.ObjectId)
+	if err != nil || group == nil {
+		return err
+	}
+
+	members, err := getGroupMemberExternalIds(task.Provisioner, group)
+	if err != nil {
+		return err
+	}
+
+	externalId := ""
+	if provisionedObject != nil {
+		externalId = provisionedObject.ExternalId
+	}
+
+	newExternalId, err := connector.upsertGroup(group, members, externalId)
+	if err != nil {
+		return err
+	}
+	return setProvisionedObject(task.Provisioner, task.ObjectType, task.ObjectId, newExternalId)
+}
+
+func runProvisioningTask(provisioner *Provisioner, connector provisioningConnector, task *ProvisioningTask) error {
+	provisionedObject, err := getProvisionedObject(task.Provisioner, task.ObjectType, task.ObjectId)
+	if err != nil {
+		return err
+	}
+
+	if task.ObjectType == ProvisioningObjectGroup {
+		return runGroupProvisioningTask(connector, task, provisionedObject)
+	}
+	return runUserProvisioningTask(provisioner, connector, task, provisionedObject)
+}
+
+// claim leases the task to this instance, it returns false if another instance leased it first
+func (task *ProvisioningTask) claim() (bool, error) {
+	nextRunTime := time.Now().Add(provisioningLease).Unix()
+	affected, err := ormer.Engine.Where("id = ? and state = ? and next_run_time = ?", task.Id, ProvisioningTaskStatePending, task.NextRunTime).
+		Cols("next_run_time").Update(&ProvisioningTask{NextRunTime: nextRunTime})
+	if err != nil {
+		return false, err
+	}
+
+	task.NextRunTime = nextRunTime
+	return affected != 0, nil
+}
+
+func (task *ProvisioningTask) fail(provisioner *Provisioner, taskErr error) error {
+	task.Attempts += 1
+	task.LastError = taskErr.Error()
+	if task.Attempts >= provisioner.getMaxAttempts() {
+		task.State = ProvisioningTaskStateFailed
+	} else {
+		delay := provisioningRetryDelay << (task.Attempts - 1)
+		if delay > provisioningMaxRetryDelay || delay <= 0 {
+			delay = provisioningMaxRetryDelay
+		}
+		task.NextRunTime = time.Now().Add(delay).Unix()
+	}
+
+	_, err := ormer.Engine.ID(task.Id).Cols("state", "attempts", "next_run_time", "last_error").Update(task)
+	if err != nil {
+		return err
+	}
+
+	provisioner.LastErrorTime = util.GetCurrentTime()
+	provisioner.LastError = fmt.Sprintf("%s %s: %s", task.ObjectType, task.ObjectName, task.LastError)
+	_, err = ormer.Engine.ID(core.PK{provisioner.Owner, provisioner.Name}).Cols("last_error_time", "last_error").Update(provisioner)
+	return err
+}
+
+func (task *ProvisioningTask) succeed(provisioner *Provisioner) error {
+	_, err := ormer.Engine.ID(task.Id).Delete(&ProvisioningTask{})
+	if err != nil {
+		return err
+	}
+
+	provisioner.LastSyncTime = util.GetCurrentTime()
+	_, err = ormer.Engine.ID(core.PK{provisioner.Owner, provisioner.Name}).Cols("last_sync_time").Update(provisioner)
+	return err
+}
+
+// runProvisioningTasks runs the due tasks in order. A task waits while an earlier task of the same
+// object is retried, and the other tasks of a provisioner wait for the next run when one fails.
+func runProvisioningTasks() error {
+	now := time.Now().Unix()
+
+	waitingTasks := []*ProvisioningTask{}
+	err := ormer.Engine.Cols("provisioner", "object_type", "object_id").
+		Where("state = ? and next_run_time > ?", ProvisioningTaskStatePending, now).Find(&waitingTasks)
+	if err != nil {
+		return err
+	}
+
+	isWaiting := map[string]bool{}
+	for _, task := range waitingTasks {
+		isWaiting[fmt.Sprintf("%s|%s|%s", task.Provisioner, task.ObjectType, task.ObjectId)] = true
+	}
+
+	tasks := []*ProvisioningTask{}
+	err = ormer.Engine.Where("state = ? and next_run_time <= ?", ProvisioningTaskStatePending, now).
+		Asc("id").Limit(provisioningBatchSize).Find(&tasks)
+	if err != nil {
+		return err
+	}
+
+	provisioners := map[string]*Provisioner{}
+	connectors := map[string]provisioningConnector{}
+	defer func() {
+		for _, connector := range connectors {
+			connector.close()
+		}
+	}()
+
+	for _, task := range tasks {
+		key := fmt.Sprintf("%s|%s|%s", task.Provisioner, task.ObjectType, task.ObjectId)
+		if isWaiting[key] {
+			continue
+		}
+
+		provisioner, ok := provisioners[task.Provisioner]
+		if !ok {
+			provisioner, err = GetProvisioner(task.Provisioner)
+			if err != nil {
+				return err
+			}
+			provisioners[task.Provisioner] = provisioner
+		}
+
+		// the tasks of a disabled provisioner are kept until it is enabled again
+		if provisioner == nil || !provisioner.IsEnabled {
+			continue
+		}
+
+		isWaiting[key] = true
+		var claimed bool
+		claimed, err = task.claim()
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		connector, ok := connectors[task.Provisioner]
+		if !ok {
+			connector, err = newProvisioningConnector(provisioner)
+			if err == nil {
+				connectors[task.Provisioner] = connector
+			}
+		}
+
+		if err == nil {
+			err = runProvisioningTask(provisioner, connector, task)
+		}
+
+		if err != nil {
+			provisioners[task.Provisioner] = nil
+			err = task.fail(provisioner, err)
+		} else {
+			delete(isWaiting, key)
+			err = task.succeed(provisioner)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RunProvisioningJob runs the provisioning tasks in the background
+func RunProvisioningJob() {
+	for {
+		err := runProvisioningTasks()
+		if err != nil {
+			logs.Warning("failed to run the provisioning tasks: %s", err.Error())
+		}
+
+		time.Sleep(provisioningInterval)
+	}
+}
+
+func GetProvisionerStatus(provisioner *Provisioner) (*ProvisionerStatus, error) {
+	id := provisioner.GetId()
+	status := &ProvisionerStatus{
+		Provisioner:   id,
+		IsEnabled:     provisioner.IsEnabled,
+		LastSyncTime:  provisioner.LastSyncTime,
+		LastErrorTime: provisioner.LastErrorTime,
+		LastError:     provisioner.LastError,
+	}
+
+	var err error
+	status.PendingTaskCount, err = ormer.Engine.Count(&ProvisioningTask{Provisioner: id, State: ProvisioningTaskStatePending})
+	if err != nil {
+		return nil, err
+	}
+
+	status.FailedTaskCount, err = ormer.Engine.Count(&ProvisioningTask{Provisioner: id, State: ProvisioningTaskStateFailed})
+	if err != nil {
+		return nil, err
+	}
+
+	status.ProvisionedUsers, err = ormer.Engine.Count(&ProvisionedObject{Provisioner: id, ObjectType: ProvisioningObjectUser})
+	if err != nil {
+		return nil, err
+	}
+
+	status.ProvisionedGroups, err = ormer.Engine.Count(&ProvisionedObject{Provisioner: id, ObjectType: ProvisioningObjectGroup})
+	if err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+func GetProvisioningTasks(provisioner string, state string) ([]*ProvisioningTask, error) {
+	tasks := []*ProvisioningTask{}
+	err := ormer.Engine.Asc("id").Find(&tasks, &ProvisioningTask{Provisioner: provisioner, State: state})
+	if err != nil {
+		return tasks, err
+	}
+
+	return tasks, nil
+}
+
+// RetryProvisioningTasks puts the failed tasks of the provisioner back in the queue
+func RetryProvisioningTasks(provisioner string) (bool, error) {
+	affected, err := ormer.Engine.Where("provisioner = ? and state = ?", provisioner, ProvisioningTaskStateFailed).
+		Cols("state", "attempts", "next_run_time").
+		Update(&ProvisioningTask{State: ProvisioningTaskStatePending, NextRunTime: time.Now().Unix()})
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+// SyncProvisioner adds all the users, and the groups if provisioned, of the organization of the
+// provisioner to its queue
+func SyncProvisioner(provisioner *Provisioner) (bool, error) {
+	users, err := GetUsers(provisioner.Organization)
+	if err != nil {
+		return false, err
+	}
+
+	for _, user := range users {
+		err = addProvisioningTask(provisioner, ProvisioningObjectUser, user.Id, user.GetId(), ProvisioningActionUpsert, nil)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if provisioner.ProvisionGroups {
+		groups, err := GetGroups(provisioner.Organization)
+		if err != nil {
+			return false, err
+		}
+
+		for _, group := range groups {
+			err = addProvisioningTask(provisioner, ProvisioningObjectGroup, group.GetId(), group.GetId(), ProvisioningActionUpsert, nil)
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,255 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	provisioningRequestTimeout = 30 * time.Second
+	provisioningMaxResponse    = 1 << 20
+)
+
+type httpProvisioningConnector struct {
+	provisioner *Provisioner
+	client      *http.Client
+}
+
+func newHttpProvisioningConnector(provisioner *Provisioner) *httpProvisioningConnector {
+	return &httpProvisioningConnector{
+		provisioner: provisioner,
+		client:      &http.Client{Timeout: provisioningRequestTimeout},
+	}
+}
+
+// sendProvisioningRequest sends a request to the API of the provisioner, path is relative to its URL
+func sendProvisioningRequest(client *http.Client, provisioner *Provisioner, method string, path string, contentType string, body []byte) (int, []byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(provisioner.Url, "/")+path, reader)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", contentType)
+	if provisioner.Username != "" {
+		req.SetBasicAuth(provisioner.Username, provisioner.Password)
+	}
+	for _, header := range provisioner.Headers {
+		req.Header.Set(header.Name, header.Value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, provisioningMaxResponse))
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, data, nil
+}
+
+// getProvisioningResponseError returns the error of a response which is not successful, with the
+// detail of a SCIM error or else the beginning of the body
+func getProvisioningResponseError(method string, path string, status int, body []byte) error {
+	if status >= 200 && status < 300 {
+		return nil
+	}
+
+	detail := strings.TrimSpace(string(body))
+	var scimError ScimError
+	if json.Unmarshal(body, &scimError) == nil && scimError.Detail != "" {
+		detail = scimError.Detail
+	}
+	if len(detail) > 500 {
+		detail = detail[:500]
+	}
+	return fmt.Errorf("%s %s returned the status %d: %s", method, path, status, detail)
+}
+
+// getProvisioningUserAttributes returns the values of the attributes of the provisioner for the
+// user, an attribute with several values is an array
+func getProvisioningUserAttributes(provisioner *Provisioner, user *User) map[string]interface{} {
+	res := map[string]interface{}{}
+	for _, attribute := range provisioner.getAttributes() {
+		values := attribute.GetValues(user)
+		if existing, ok := res[attribute.Name]; ok {
+			values = append(getLdapStrings(existing), values...)
+		}
+
+		if len(values) == 1 {
+			res[attribute.Name] = values[0]
+		} else if len(values) > 1 {
+			res[attribute.Name] = values
+		}
+	}
+	return res
+}
+
+func escapeProvisioningJsonString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data[1 : len(data)-1])
+}
+
+// expandProvisioningTemplate replaces the variables of the template with their values, or with the
+// values of the user template variables, escaped by escape
+func expandProvisioningTemplate(template string, user *User, variables map[string]string, escape func(variable string, value string) string) string {
+	return ldapAttributeTemplateRegex.ReplaceAllStringFunc(template, func(s string) string {
+		variable := s[2 : len(s)-1]
+		value, ok := variables[variable]
+		if !ok && user != nil {
+			value, _ = getLdapTemplateVariable(user, variable)
+		}
+		return escape(variable, value)
+	})
+}
+
+func (c *httpProvisioningConnector) getRequest(event string) *ProvisionerHttpRequest {
+	for _, request := range c.provisioner.HttpRequests {
+		if request.Event == event {
+			return request
+		}
+	}
+	return nil
+}
+
+// send sends the request of the event if there is one, and returns the "id" of the JSON response,
+// or else the external ID
+func (c *httpProvisioningConnector) send(event string, user *User, variables map[string]string, body interface{}) (string, error) {
+	request := c.getRequest(event)
+	if request == nil {
+		return variables["externalId"], nil
+	}
+
+	path := expandProvisioningTemplate(request.Path, user, variables, func(variable string, value string) string {
+		return url.PathEscape(value)
+	})
+
+	var data []byte
+	if request.Body != "" {
+		data = []byte(expandProvisioningTemplate(request.Body, user, variables, func(variable string, value string) string {
+			if variable == "members" {
+				return value
+			}
+			return escapeProvisioningJsonString(value)
+		}))
+	} else if request.Method != http.MethodGet && request.Method != http.MethodDelete {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	status, resp, err := sendProvisioningRequest(c.client, c.provisioner, request.Method, path, "application/json", data)
+	if err != nil {
+		return "", err
+	}
+
+	err = getProvisioningResponseError(request.Method, path, status, resp)
+	if err != nil {
+		return "", err
+	}
+
+	var res struct {
+		Id json.RawMessage `json:"id"`
+	}
+	if json.Unmarshal(resp, &res) == nil && len(res.Id) != 0 {
+		if id := strings.Trim(string(res.Id), `"`); id != "" && id != "null" {
+			return id, nil
+		}
+	}
+	return variables["externalId"], nil
+}
+
+func (c *httpProvisioningConnector) upsertUser(user *User, externalId string) (string, error) {
+	event := ProvisioningEventUserUpdate
+	if externalId == "" {
+		event = ProvisioningEventUserCreate
+	}
+
+	id, err := c.send(event, user, map[string]string{"externalId": externalId}, getProvisioningUserAttributes(c.provisioner, user))
+	if err != nil || id != "" {
+		return id, err
+	}
+	return user.Id, nil
+}
+
+func (c *httpProvisioningConnector) deleteUser(user *User, externalId string) error {
+	_, err := c.send(ProvisioningEventUserDelete, user, map[string]string{"externalId": externalId}, getProvisioningUserAttributes(c.provisioner, user))
+	return err
+}
+
+func (c *httpProvisioningConnector) getGroupVariables(group *Group, members []string, externalId string) (map[string]string, map[string]interface{}) {
+	if members == nil {
+		members = []string{}
+	}
+
+	data, _ := json.Marshal(members)
+	variables := map[string]string{
+		"externalId":  externalId,
+		"id":          group.GetId(),
+		"name":        group.Name,
+		"displayName": group.DisplayName,
+		"members":     string(data),
+	}
+
+	body := map[string]interface{}{
+		"id":          group.GetId(),
+		"name":        group.Name,
+		"displayName": group.DisplayName,
+		"members":     members,
+	}
+	return variables, body
+}
+
+func (c *httpProvisioningConnector) upsertGroup(group *Group, members []string, externalId string) (string, error) {
+	event := ProvisioningEventGroupUpdate
+	if externalId == "" {
+		event = ProvisioningEventGroupCreate
+	}
+
+	variables, body := c.getGroupVariables(group, members, externalId)
+	id, err := c.send(event, nil, variables, body)
+	if err != nil || id != "" {
+		return id, err
+	}
+	return group.GetId(), nil
+}
+
+func (c *httpProvisioningConnector) deleteGroup(group *Group, externalId string) error {
+	variables, body := c.getGroupVariables(group, nil, externalId)
+	_, err := c.send(ProvisioningEventGroupDelete, nil, variables, body)
+	return err
+}
+
+func (c *httpProvisioningConnector) close() {}
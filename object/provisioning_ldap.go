@@ -0,0 +1,248 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"strings"
+
+	goldap "github.com/go-ldap/ldap/v3"
+
+	"github.com/casdoor/casdoor/util"
+)
+
+type ldapProvisioningConnector struct {
+	provisioner *Provisioner
+	conn        *goldap.Conn
+}
+
+// ldapEntry is an entry written by the LDAP provisioner, the names keep the order of the attributes
+type ldapEntry struct {
+	dn            string
+	rdn           string
+	baseDn        string
+	objectClasses []string
+	names         []string
+	values        map[string][]string
+}
+
+func newLdapProvisioningConnector(provisioner *Provisioner) (*ldapProvisioningConnector, error) {
+	conn, err := goldap.DialURL(provisioner.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetTimeout(provisioningRequestTimeout)
+	if provisioner.Username != "" {
+		err = conn.Bind(provisioner.Username, provisioner.Password)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return &ldapProvisioningConnector{provisioner: provisioner, conn: conn}, nil
+}
+
+func (provisioner *Provisioner) getRdnAttribute() string {
+	if provisioner.RdnAttribute == "" {
+		return "uid"
+	}
+	return provisioner.RdnAttribute
+}
+
+// escapeLdapDnValue escapes an attribute value of a DN, see RFC 4514 section 2.4
+func escapeLdapDnValue(value string) string {
+	var b strings.Builder
+	for i, r := range value {
+		switch {
+		case strings.ContainsRune(`"+,;<>\`, r), r == '#' && i == 0, r == ' ' && (i == 0 || i == len(value)-1):
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r == 0:
+			b.WriteString(`\00`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func newLdapEntry(rdnAttribute string, rdnValue string, baseDn string, objectClasses []string) *ldapEntry {
+	rdn := fmt.Sprintf("%s=%s", rdnAttribute, escapeLdapDnValue(rdnValue))
+	return &ldapEntry{
+		dn:            fmt.Sprintf("%s,%s", rdn, baseDn),
+		rdn:           rdn,
+		baseDn:        baseDn,
+		objectClasses: objectClasses,
+		values:        map[string][]string{},
+	}
+}
+
+func (entry *ldapEntry) addValues(name string, values ...string) {
+	if _, ok := entry.values[name]; !ok {
+		entry.names = append(entry.names, name)
+		entry.values[name] = []string{}
+	}
+
+	for _, value := range values {
+		if !util.InSlice(entry.values[name], value) {
+			entry.values[name] = append(entry.values[name], value)
+		}
+	}
+}
+
+func (c *ldapProvisioningConnector) newUserEntry(user *User) (*ldapEntry, error) {
+	rdnAttribute := c.provisioner.getRdnAttribute()
+	values := map[string][]string{}
+	for _, attribute := range c.provisioner.getAttributes() {
+		values[attribute.Name] = append(values[attribute.Name], attribute.GetValues(user)...)
+	}
+
+	if len(values[rdnAttribute]) == 0 {
+		return nil, fmt.Errorf("the RDN attribute: %s of the user: %s has no value", rdnAttribute, user.GetId())
+	}
+
+	objectClasses := c.provisioner.UserObjectClasses
+	if len(objectClasses) == 0 {
+		objectClasses = DefaultProvisionerUserObjectClasses
+	}
+
+	entry := newLdapEntry(rdnAttribute, values[rdnAttribute][0], c.provisioner.UserBaseDn, objectClasses)
+	for _, attribute := range c.provisioner.getAttributes() {
+		entry.addValues(attribute.Name, values[attribute.Name]...)
+	}
+
+	if c.provisioner.DisabledAttribute != "" {
+		if user.IsForbidden || user.IsDeleted {
+			entry.addValues(c.provisioner.DisabledAttribute, c.provisioner.DisabledValue)
+		} else {
+			entry.addValues(c.provisioner.DisabledAttribute)
+		}
+	}
+	return entry, nil
+}
+
+func (c *ldapProvisioningConnector) modify(entry *ldapEntry) error {
+	req := goldap.NewModifyRequest(entry.dn, nil)
+	for _, name := range entry.names {
+		// replacing with no value removes the attribute
+		req.Replace(name, entry.values[name])
+	}
+	return c.conn.Modify(req)
+}
+
+func (c *ldapProvisioningConnector) add(entry *ldapEntry) error {
+	req := goldap.NewAddRequest(entry.dn, nil)
+	req.Attribute("objectClass", entry.objectClasses)
+	for _, name := range entry.names {
+		if len(entry.values[name]) != 0 {
+			req.Attribute(name, entry.values[name])
+		}
+	}
+	return c.conn.Add(req)
+}
+
+// upsertEntry modifies the entry with the external DN, renaming or moving it if its DN changed, or
+// adds it if there is none. It returns the DN of the entry.
+func (c *ldapProvisioningConnector) upsertEntry(entry *ldapEntry, externalId string) (string, error) {
+	if externalId != "" && !strings.EqualFold(externalId, entry.dn) {
+		newSuperior := ""
+		if !strings.HasSuffix(strings.ToLower(externalId), ","+strings.ToLower(entry.baseDn)) {
+			newSuperior = entry.baseDn
+		}
+
+		err := c.conn.ModifyDN(goldap.NewModifyDNRequest(externalId, entry.rdn, true, newSuperior))
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultNoSuchObject) {
+			externalId = ""
+		} else if err != nil {
+			return "", err
+		}
+	}
+
+	if externalId != "" {
+		err := c.modify(entry)
+		if !goldap.IsErrorWithCode(err, goldap.LDAPResultNoSuchObject) {
+			return entry.dn, err
+		}
+	}
+
+	err := c.add(entry)
+	if goldap.IsErrorWithCode(err, goldap.LDAPResultEntryAlreadyExists) {
+		err = c.modify(entry)
+	}
+	return entry.dn, err
+}
+
+func (c *ldapProvisioningConnector) delete(dn string) error {
+	err := c.conn.Del(goldap.NewDelRequest(dn, nil))
+	if goldap.IsErrorWithCode(err, goldap.LDAPResultNoSuchObject) {
+		return nil
+	}
+	return err
+}
+
+func (c *ldapProvisioningConnector) upsertUser(user *User, externalId string) (string, error) {
+	entry, err := c.newUserEntry(user)
+	if err != nil {
+		return "", err
+	}
+
+	return c.upsertEntry(entry, externalId)
+}
+
+func (c *ldapProvisioningConnector) deleteUser(user *User, externalId string) error {
+	if !c.provisioner.DisableDeletedUsers {
+		return c.delete(externalId)
+	}
+
+	req := goldap.NewModifyRequest(externalId, nil)
+	req.Replace(c.provisioner.DisabledAttribute, []string{c.provisioner.DisabledValue})
+	err := c.conn.Modify(req)
+	if goldap.IsErrorWithCode(err, goldap.LDAPResultNoSuchObject) {
+		return nil
+	}
+	return err
+}
+
+func (c *ldapProvisioningConnector) upsertGroup(group *Group, members []string, externalId string) (string, error) {
+	objectClasses := c.provisioner.GroupObjectClasses
+	if len(objectClasses) == 0 {
+		objectClasses = DefaultProvisionerGroupObjectClasses
+	}
+
+	entry := newLdapEntry("cn", group.Name, c.provisioner.GroupBaseDn, objectClasses)
+	entry.addValues("cn", group.Name)
+	entry.addValues("description")
+	if group.DisplayName != "" {
+		entry.addValues("description", group.DisplayName)
+	}
+
+	// groupOfNames requires a member, so an empty group is its own member
+	if len(members) == 0 {
+		members = []string{entry.dn}
+	}
+	entry.addValues("member", members...)
+
+	return c.upsertEntry(entry, externalId)
+}
+
+func (c *ldapProvisioningConnector) deleteGroup(group *Group, externalId string) error {
+	return c.delete(externalId)
+}
+
+func (c *ldapProvisioningConnector) close() {
+	c.conn.Close()
+}
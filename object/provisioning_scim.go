@@ -0,0 +1,267 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/casdoor/casdoor/util"
+)
+
+const scimContentType = "application/scim+json"
+
+// scimMultiValuedAttributes are the multi-valued attributes of the SCIM users whose values are
+// objects with a "value", see RFC 7643 section 2.4
+var scimMultiValuedAttributes = []string{"emails", "phoneNumbers", "ims", "photos", "entitlements", "roles", "x509Certificates"}
+
+type scimProvisioningConnector struct {
+	provisioner *Provisioner
+	client      *http.Client
+}
+
+func newScimProvisioningConnector(provisioner *Provisioner) *scimProvisioningConnector {
+	return &scimProvisioningConnector{
+		provisioner: provisioner,
+		client:      &http.Client{Timeout: provisioningRequestTimeout},
+	}
+}
+
+// setScimAttribute sets the values of the attribute at the path in the SCIM resource. The values
+// of a multi-valued attribute like "emails" are added to it, the first one being the primary one.
+func setScimAttribute(resource map[string]interface{}, path string, values []string) {
+	if strings.HasPrefix(path, "urn:") {
+		i := strings.LastIndex(path, ":")
+		schema := path[:i]
+		schemas := resource["schemas"].([]string)
+		if !util.InSlice(schemas, schema) {
+			resource["schemas"] = append(schemas, schema)
+		}
+
+		extension, ok := resource[schema].(map[string]interface{})
+		if !ok {
+			extension = map[string]interface{}{}
+			resource[schema] = extension
+		}
+		resource, path = extension, path[i+1:]
+	}
+
+	names := strings.Split(path, ".")
+	for _, name := range names[:len(names)-1] {
+		child, ok := resource[name].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			resource[name] = child
+		}
+		resource = child
+	}
+
+	name := names[len(names)-1]
+	if len(names) == 1 && util.InSlice(scimMultiValuedAttributes, name) {
+		items, _ := resource[name].([]*ScimMultiValue)
+		for _, value := range values {
+			items = append(items, &ScimMultiValue{Value: value, Primary: len(items) == 0})
+		}
+		resource[name] = items
+	} else if len(values) == 1 {
+		resource[name] = values[0]
+	} else {
+		resource[name] = values
+	}
+}
+
+// newScimUserResource returns the SCIM user of the user with the attributes of the provisioner
+func newScimUserResource(provisioner *Provisioner, user *User) map[string]interface{} {
+	resource := map[string]interface{}{"schemas": []string{ScimSchemaUser}}
+	for _, attribute := range provisioner.getAttributes() {
+		values := attribute.GetValues(user)
+		if len(values) != 0 {
+			setScimAttribute(resource, attribute.Name, values)
+		}
+	}
+
+	resource["externalId"] = user.Id
+	resource["active"] = !user.IsForbidden && !user.IsDeleted
+	return resource
+}
+
+func newScimGroupResource(group *Group, members []string) *ScimGroup {
+	resource := &ScimGroup{
+		Schemas:     []string{ScimSchemaGroup},
+		ExternalId:  group.GetId(),
+		DisplayName: group.DisplayName,
+		Members:     []*ScimMultiValue{},
+	}
+	if resource.DisplayName == "" {
+		resource.DisplayName = group.Name
+	}
+
+	for _, member := range members {
+		resource.Members = append(resource.Members, &ScimMultiValue{Value: member})
+	}
+	return resource
+}
+
+func getScimFilterString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+func (c *scimProvisioningConnector) send(method string, path string, resource interface{}) (int, []byte, error) {
+	var data []byte
+	if resource != nil {
+		var err error
+		data, err = json.Marshal(resource)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return sendProvisioningRequest(c.client, c.provisioner, method, path, scimContentType, data)
+}
+
+// findResource returns the ID of the resource of the endpoint matching the filter, or an empty
+// string if there is none
+func (c *scimProvisioningConnector) findResource(endpoint string, filter string) (string, error) {
+	path := fmt.Sprintf("%s?filter=%s", endpoint, url.QueryEscape(filter))
+	status, body, err := c.send(http.MethodGet, path, nil)
+	if err != nil {
+		return "", err
+	}
+
+	err = getProvisioningResponseError(http.MethodGet, path, status, body)
+	if err != nil {
+		return "", err
+	}
+
+	var res struct {
+		Resources []struct {
+			Id string `json:"id"`
+		} `json:"Resources"`
+	}
+	err = json.Unmarshal(body, &res)
+	if err != nil {
+		return "", err
+	}
+
+	if len(res.Resources) == 0 {
+		return "", nil
+	}
+	return res.Resources[0].Id, nil
+}
+
+// upsertResource replaces the resource with the external ID, or creates it if there is none. A
+// resource which already exists in the target is found with the filter and replaced.
+func (c *scimProvisioningConnector) upsertResource(endpoint string, resource interface{}, externalId string, filter string) (string, error) {
+	if externalId != "" {
+		path := endpoint + "/" + url.PathEscape(externalId)
+		status, body, err := c.send(http.MethodPut, path, resource)
+		if err != nil {
+			return "", err
+		}
+
+		// the resource has been deleted from the target
+		if status != http.StatusNotFound {
+			return externalId, getProvisioningResponseError(http.MethodPut, path, status, body)
+		}
+	}
+
+	status, body, err := c.send(http.MethodPost, endpoint, resource)
+	if err != nil {
+		return "", err
+	}
+
+	if status == http.StatusConflict {
+		id, err := c.findResource(endpoint, filter)
+		if err != nil {
+			return "", err
+		}
+		if id == "" {
+			return "", getProvisioningResponseError(http.MethodPost, endpoint, status, body)
+		}
+
+		path := endpoint + "/" + url.PathEscape(id)
+		status, body, err = c.send(http.MethodPut, path, resource)
+		if err != nil {
+			return "", err
+		}
+		return id, getProvisioningResponseError(http.MethodPut, path, status, body)
+	}
+
+	err = getProvisioningResponseError(http.MethodPost, endpoint, status, body)
+	if err != nil {
+		return "", err
+	}
+
+	var res struct {
+		Id string `json:"id"`
+	}
+	err = json.Unmarshal(body, &res)
+	if err != nil {
+		return "", err
+	}
+
+	if res.Id == "" {
+		return "", fmt.Errorf("POST %s returned a resource without id", endpoint)
+	}
+	return res.Id, nil
+}
+
+func (c *scimProvisioningConnector) deleteResource(endpoint string, externalId string) error {
+	path := endpoint + "/" + url.PathEscape(externalId)
+	status, body, err := c.send(http.MethodDelete, path, nil)
+	if err != nil || status == http.StatusNotFound {
+		return err
+	}
+
+	return getProvisioningResponseError(http.MethodDelete, path, status, body)
+}
+
+func (c *scimProvisioningConnector) upsertUser(user *User, externalId string) (string, error) {
+	resource := newScimUserResource(c.provisioner, user)
+	userName, _ := resource["userName"].(string)
+	return c.upsertResource("/Users", resource, externalId, "userName eq "+getScimFilterString(userName))
+}
+
+func (c *scimProvisioningConnector) deleteUser(user *User, externalId string) error {
+	if !c.provisioner.DisableDeletedUsers {
+		return c.deleteResource("/Users", externalId)
+	}
+
+	resource := newScimUserResource(c.provisioner, user)
+	resource["active"] = false
+
+	path := "/Users/" + url.PathEscape(externalId)
+	status, body, err := c.send(http.MethodPut, path, resource)
+	if err != nil || status == http.StatusNotFound {
+		return err
+	}
+
+	return getProvisioningResponseError(http.MethodPut, path, status, body)
+}
+
+func (c *scimProvisioningConnector) upsertGroup(group *Group, members []string, externalId string) (string, error) {
+	resource := newScimGroupResource(group, members)
+	return c.upsertResource("/Groups", resource, externalId, "displayName eq "+getScimFilterString(resource.DisplayName))
+}
+
+func (c *scimProvisioningConnector) deleteGroup(group *Group, externalId string) error {
+	return c.deleteResource("/Groups", externalId)
+}
+
+func (c *scimProvisioningConnector) close() {}
@@ -0,0 +1,205 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewScimUserResource(t *testing.T) {
+	provisioner := &Provisioner{
+		Type: ProvisionerTypeScim,
+		Attributes: []*ProvisionerAttribute{
+			{Name: "userName", Source: LdapAttributeSourceUser, Value: "name"},
+			{Name: "name.givenName", Source: LdapAttributeSourceUser, Value: "firstName"},
+			{Name: "emails", Source: LdapAttributeSourceUser, Value: "email"},
+			{Name: "emails", Source: LdapAttributeSourceProperty, Value: "workEmail"},
+			{Name: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department", Source: LdapAttributeSourceStatic, Value: "R&D"},
+			{Name: "title", Source: LdapAttributeSourceUser, Value: "title"},
+		},
+	}
+	user := &User{
+		Id:         "0b7bb2ec-7f43-4c55-a5d6-5ad6e0f4a0a1",
+		Name:       "alice",
+		FirstName:  "Alice",
+		Email:      "alice@example.com",
+		Properties: map[string]string{"workEmail": "alice@work.example.com"},
+	}
+
+	resource := newScimUserResource(provisioner, user)
+	data, err := json.Marshal(resource)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],
+		"userName": "alice",
+		"name": {"givenName": "Alice"},
+		"emails": [{"value": "alice@example.com", "primary": true}, {"value": "alice@work.example.com"}],
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"department": "R&D"},
+		"externalId": "0b7bb2ec-7f43-4c55-a5d6-5ad6e0f4a0a1",
+		"active": true
+	}`, string(data))
+
+	user.IsForbidden = true
+	assert.Equal(t, false, newScimUserResource(provisioner, user)["active"])
+}
+
+func TestScimProvisioningConnector(t *testing.T) {
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		username, password, _ := r.BasicAuth()
+		assert.Equal(t, "admin:secret", username+":"+password)
+
+		switch r.Method + " " + r.URL.Path {
+		case "PUT /scim/Users/deleted":
+			w.WriteHeader(http.StatusNotFound)
+		case "POST /scim/Users":
+			if len(requests) == 3 {
+				w.WriteHeader(http.StatusConflict)
+				w.Write([]byte(`{"schemas": ["urn:ietf:params:scim:api:messages:2.0:Error"], "status": "409", "detail": "userName exists"}`))
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": "scim-1"}`))
+		case "GET /scim/Users":
+			assert.Equal(t, `userName eq "alice"`, r.URL.Query().Get("filter"))
+			w.Write([]byte(`{"totalResults": 1, "Resources": [{"id": "scim-2"}]}`))
+		case "PUT /scim/Users/scim-2", "DELETE /scim/Users/scim-2":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"detail": "unexpected request"}`))
+		}
+	}))
+	defer server.Close()
+
+	provisioner := &Provisioner{Type: ProvisionerTypeScim, Url: server.URL + "/scim/", Username: "admin", Password: "secret"}
+	connector := newScimProvisioningConnector(provisioner)
+	user := &User{Id: "0b7bb2ec", Name: "alice"}
+
+	// the resource deleted from the target is created again
+	externalId, err := connector.upsertUser(user, "deleted")
+	assert.Nil(t, err)
+	assert.Equal(t, "scim-1", externalId)
+
+	// the existing resource is found and replaced
+	externalId, err = connector.upsertUser(user, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "scim-2", externalId)
+
+	err = connector.deleteUser(user, "scim-2")
+	assert.Nil(t, err)
+
+	_, err = connector.upsertGroup(&Group{Owner: "org", Name: "group"}, []string{"scim-2"}, "")
+	assert.EqualError(t, err, "POST /Groups returned the status 400: unexpected request")
+
+	assert.Equal(t, []string{
+		"PUT /scim/Users/deleted",
+		"POST /scim/Users",
+		"POST /scim/Users",
+		"GET /scim/Users?filter=userName+eq+%22alice%22",
+		"PUT /scim/Users/scim-2",
+		"DELETE /scim/Users/scim-2",
+		"POST /scim/Groups",
+	}, requests)
+}
+
+func TestHttpProvisioningConnector(t *testing.T) {
+	var request string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request = r.Method + " " + r.URL.EscapedPath() + " " + string(body)
+		w.Write([]byte(`{"id": 42}`))
+	}))
+	defer server.Close()
+
+	provisioner := &Provisioner{
+		Type: ProvisionerTypeHttp,
+		Url:  server.URL,
+		HttpRequests: []*ProvisionerHttpRequest{
+			{Event: ProvisioningEventUserCreate, Method: "POST", Path: "/users"},
+			{Event: ProvisioningEventUserUpdate, Method: "PUT", Path: "/users/${externalId}", Body: `{"name": "${name}"}`},
+			{Event: ProvisioningEventGroupUpdate, Method: "PUT", Path: "/groups/${name}", Body: `{"members": ${members}}`},
+		},
+	}
+	connector := newHttpProvisioningConnector(provisioner)
+	user := &User{Id: "0b7bb2ec", Name: `al"ice`, Email: "alice@example.com"}
+
+	externalId, err := connector.upsertUser(user, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "42", externalId)
+	assert.Equal(t, `POST /users {"email":"alice@example.com","id":"0b7bb2ec","name":"al\"ice"}`, request)
+
+	_, err = connector.upsertUser(user, "a/b")
+	assert.Nil(t, err)
+	assert.Equal(t, `PUT /users/a%2Fb {"name": "al\"ice"}`, request)
+
+	_, err = connector.upsertGroup(&Group{Owner: "org", Name: "group"}, []string{"1", "2"}, "7")
+	assert.Nil(t, err)
+	assert.Equal(t, `PUT /groups/group {"members": ["1","2"]}`, request)
+
+	// the events without request are skipped
+	request = ""
+	err = connector.deleteUser(user, "42")
+	assert.Nil(t, err)
+	assert.Equal(t, "", request)
+}
+
+func TestEscapeLdapDnValue(t *testing.T) {
+	assert.Equal(t, `alice`, escapeLdapDnValue("alice"))
+	assert.Equal(t, `Smith\, John`, escapeLdapDnValue("Smith, John"))
+	assert.Equal(t, `\#1 a\+b\\c`, escapeLdapDnValue(`#1 a+b\c`))
+	assert.Equal(t, `\ x\ `, escapeLdapDnValue(" x "))
+}
+
+func TestCheckProvisioner(t *testing.T) {
+	provisioner := &Provisioner{Type: ProvisionerTypeScim, Url: "https://scim.example.com/v2"}
+	assert.Nil(t, checkProvisioner(provisioner))
+
+	provisioner.Attributes = []*ProvisionerAttribute{{Name: "displayName", Source: LdapAttributeSourceUser, Value: "displayName"}}
+	assert.EqualError(t, checkProvisioner(provisioner), "the attributes of a SCIM provisioner must define userName")
+
+	provisioner.Attributes = []*ProvisionerAttribute{{Name: "userName", Source: LdapAttributeSourceUser, Value: "unknown"}}
+	assert.EqualError(t, checkProvisioner(provisioner), `the user field: "unknown" of the attribute: userName does not exist`)
+
+	provisioner.Attributes = []*ProvisionerAttribute{{Name: "userName", Source: LdapAttributeSourceUser, Value: "name"}, {Name: "password", Source: LdapAttributeSourceUser, Value: "password"}}
+	assert.EqualError(t, checkProvisioner(provisioner), `the user field: "password" of the attribute: password holds credentials and cannot be provisioned`)
+
+	provisioner = &Provisioner{Type: ProvisionerTypeLdap, Url: "https://ldap.example.com"}
+	assert.NotNil(t, checkProvisioner(provisioner))
+
+	provisioner.Url = "ldaps://ldap.example.com"
+	assert.EqualError(t, checkProvisioner(provisioner), `the user base DN: "" of the provisioner is invalid`)
+
+	provisioner.UserBaseDn = "ou=people,dc=example,dc=com"
+	provisioner.RdnAttribute = "cn"
+	assert.Nil(t, checkProvisioner(provisioner))
+
+	provisioner.ProvisionGroups = true
+	assert.NotNil(t, checkProvisioner(provisioner))
+
+	provisioner = &Provisioner{
+		Type:         ProvisionerTypeHttp,
+		Url:          "https://api.example.com",
+		HttpRequests: []*ProvisionerHttpRequest{{Event: ProvisioningEventUserDelete, Method: "DELETE", Path: "/users/${externalId}?name=${unknown}"}},
+	}
+	assert.EqualError(t, checkProvisioner(provisioner), `the HTTP request for user.delete is invalid: the template variable: "unknown" does not exist`)
+}
@@ -0,0 +1,218 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/casdoor/casdoor/util"
+	"github.com/xorm-io/core"
+)
+
+// RadiusClient is a network access server allowed to send requests to the RADIUS server. The
+// users of its requests are authenticated in the organization that owns it.
+type RadiusClient struct {
+	Owner       string `xorm:"varchar(100) notnull pk" json:"owner"`
+	Name        string `xorm:"varchar(100) notnull pk" json:"name"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+
+	DisplayName string `xorm:"varchar(100)" json:"displayName"`
+	// Address is the IP address or the CIDR range of the network access server
+	Address string `xorm:"varchar(100)" json:"address"`
+	Secret  string `xorm:"varchar(100)" json:"secret"`
+}
+
+func GetRadiusClientCount(owner, field, value string) (int64, error) {
+	session := GetSession(owner, -1, -1, field, value, "", "")
+	return session.Count(&RadiusClient{})
+}
+
+func GetRadiusClients(owner string) ([]*RadiusClient, error) {
+	radiusClients := []*RadiusClient{}
+	err := ormer.Engine.Desc("created_time").Find(&radiusClients, &RadiusClient{Owner: owner})
+	if err != nil {
+		return radiusClients, err
+	}
+
+	return radiusClients, nil
+}
+
+func GetPaginationRadiusClients(owner string, offset, limit int, field, value, sortField, sortOrder string) ([]*RadiusClient, error) {
+	radiusClients := []*RadiusClient{}
+	session := GetSession(owner, offset, limit, field, value, sortField, sortOrder)
+	err := session.Find(&radiusClients)
+	if err != nil {
+		return radiusClients, err
+	}
+
+	return radiusClients, nil
+}
+
+func getRadiusClient(owner string, name string) (*RadiusClient, error) {
+	if owner == "" || name == "" {
+		return nil, nil
+	}
+
+	radiusClient := RadiusClient{Owner: owner, Name: name}
+	existed, err := ormer.Engine.Get(&radiusClient)
+	if err != nil {
+		return &radiusClient, err
+	}
+
+	if existed {
+		return &radiusClient, nil
+	} else {
+		return nil, nil
+	}
+}
+
+func GetRadiusClient(id string) (*RadiusClient, error) {
+	owner, name := util.GetOwnerAndNameFromId(id)
+	return getRadiusClient(owner, name)
+}
+
+// getRadiusClientNet returns the network of the address of the RADIUS client, a single IP address
+// is a network of its own
+func getRadiusClientNet(address string) (*net.IPNet, error) {
+	if strings.Contains(address, "/") {
+		_, ipNet, err := net.ParseCIDR(address)
+		return ipNet, err
+	}
+
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address: %s", address)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// GetRadiusClientByIp returns the RADIUS client whose address matches the IP, the most specific
+// address wins and the ties are broken by the owner and the name of the clients
+func GetRadiusClientByIp(ip net.IP) (*RadiusClient, error) {
+	radiusClients := []*RadiusClient{}
+	err := ormer.Engine.Asc("owner", "name").Find(&radiusClients)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *RadiusClient
+	resOnes := -1
+	for _, radiusClient := range radiusClients {
+		ipNet, err := getRadiusClientNet(radiusClient.Address)
+		if err != nil || !ipNet.Contains(ip) {
+			continue
+		}
+
+		if ones, _ := ipNet.Mask.Size(); ones > resOnes {
+			res = radiusClient
+			resOnes = ones
+		}
+	}
+
+	return res, nil
+}
+
+// checkRadiusClient checks the RADIUS client, its address must not overlap the address of another
+// client as the requests of the address would be authenticated in the organization of either one.
+// The owner and the name of the client being updated are given to skip it.
+func checkRadiusClient(radiusClient *RadiusClient, owner string, name string) error {
+	ipNet, err := getRadiusClientNet(radiusClient.Address)
+	if err != nil {
+		return fmt.Errorf("the address: \"%s\" of the RADIUS client is neither an IP address nor a CIDR range", radiusClient.Address)
+	}
+
+	if radiusClient.Secret == "" {
+		return fmt.Errorf("the secret of the RADIUS client cannot be empty")
+	}
+
+	radiusClients := []*RadiusClient{}
+	err = ormer.Engine.Find(&radiusClients)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range radiusClients {
+		if other.Owner == owner && other.Name == name {
+			continue
+		}
+
+		otherNet, err := getRadiusClientNet(other.Address)
+		if err != nil {
+			continue
+		}
+		if ipNet.Contains(otherNet.IP) || otherNet.Contains(ipNet.IP) {
+			return fmt.Errorf("the address: \"%s\" of the RADIUS client overlaps the address: \"%s\" of the RADIUS client: %s", radiusClient.Address, other.Address, other.GetId())
+		}
+	}
+
+	return nil
+}
+
+func UpdateRadiusClient(id string, radiusClient *RadiusClient) (bool, error) {
+	owner, name := util.GetOwnerAndNameFromId(id)
+	if c, err := getRadiusClient(owner, name); err != nil {
+		return false, err
+	} else if c == nil {
+		return false, nil
+	}
+
+	err := checkRadiusClient(radiusClient, owner, name)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := ormer.Engine.ID(core.PK{owner, name}).AllCols().Update(radiusClient)
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+func AddRadiusClient(radiusClient *RadiusClient) (bool, error) {
+	if radiusClient.Secret == "" {
+		radiusClient.Secret = util.GenerateClientSecret()
+	}
+
+	err := checkRadiusClient(radiusClient, "", "")
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := ormer.Engine.Insert(radiusClient)
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+func DeleteRadiusClient(radiusClient *RadiusClient) (bool, error) {
+	affected, err := ormer.Engine.ID(core.PK{radiusClient.Owner, radiusClient.Name}).Delete(&RadiusClient{})
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+func (radiusClient *RadiusClient) GetId() string {
+	return fmt.Sprintf("%s/%s", radiusClient.Owner, radiusClient.Name)
+}
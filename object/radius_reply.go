@@ -0,0 +1,181 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/casdoor/casdoor/util"
+)
+
+const (
+	RadiusReplyMatchAny      = "Any"
+	RadiusReplyMatchRole     = "Role"
+	RadiusReplyMatchGroup    = "Group"
+	RadiusReplyMatchProperty = "Property"
+)
+
+// RadiusReplyRule adds an attribute to the Access-Accept of the users matching the rule. The
+// match value is the name of a role or a group of the organization, or "<key>=<value>" or
+// "<key>" for a user property. The value of the attribute is a template in which
+// ${<field>} and ${properties.<key>} are replaced, e.g. "${properties.vlan}".
+type RadiusReplyRule struct {
+	Match      string `json:"match"`
+	MatchValue string `json:"matchValue"`
+	Attribute  string `json:"attribute"`
+	Value      string `json:"value"`
+}
+
+type RadiusReplyAttribute struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// RadiusAttributeDefinition is the type of a RADIUS attribute, VendorId is 0 for the standard
+// attributes, and the attributes which are not integers are strings
+type RadiusAttributeDefinition struct {
+	VendorId  uint32
+	Type      byte
+	IsInteger bool
+}
+
+const (
+	radiusVendorCisco    = 9
+	radiusVendorJuniper  = 2636
+	radiusVendorFortinet = 12356
+)
+
+// RadiusAttributeDefinitions are the attributes which can be returned by the reply rules
+var RadiusAttributeDefinitions = map[string]*RadiusAttributeDefinition{
+	"Service-Type":            {Type: 6, IsInteger: true},
+	"Filter-Id":               {Type: 11},
+	"Reply-Message":           {Type: 18},
+	"Class":                   {Type: 25},
+	"Session-Timeout":         {Type: 27, IsInteger: true},
+	"Idle-Timeout":            {Type: 28, IsInteger: true},
+	"Tunnel-Type":             {Type: 64, IsInteger: true},
+	"Tunnel-Medium-Type":      {Type: 65, IsInteger: true},
+	"Tunnel-Private-Group-Id": {Type: 81},
+
+	"Cisco-AVPair": {VendorId: radiusVendorCisco, Type: 1},
+
+	"Juniper-Local-User-Name": {VendorId: radiusVendorJuniper, Type: 1},
+	"Juniper-Allow-Commands":  {VendorId: radiusVendorJuniper, Type: 2},
+	"Juniper-Deny-Commands":   {VendorId: radiusVendorJuniper, Type: 3},
+
+	"Fortinet-Group-Name":     {VendorId: radiusVendorFortinet, Type: 1},
+	"Fortinet-Vdom-Name":      {VendorId: radiusVendorFortinet, Type: 3},
+	"Fortinet-Access-Profile": {VendorId: radiusVendorFortinet, Type: 6},
+}
+
+// isRadiusReplyRuleMatched checks whether the rule applies to the user, roles are the names of
+// the roles of the user
+func isRadiusReplyRuleMatched(rule *RadiusReplyRule, user *User, roles []string) bool {
+	switch rule.Match {
+	case RadiusReplyMatchRole:
+		return util.InSlice(roles, rule.MatchValue)
+	case RadiusReplyMatchGroup:
+		return util.InSlice(user.Groups, util.GetId(user.Owner, rule.MatchValue))
+	case RadiusReplyMatchProperty:
+		key, value, hasValue := strings.Cut(rule.MatchValue, "=")
+		if !hasValue {
+			return user.Properties[key] != ""
+		}
+		return user.Properties[key] == value
+	default:
+		return true
+	}
+}
+
+func getRadiusReplyRoles(user *User, rules []*RadiusReplyRule) ([]string, error) {
+	for _, rule := range rules {
+		if rule.Match == RadiusReplyMatchRole {
+			roles, err := getRolesByUser(user.GetId())
+			if err != nil {
+				return nil, err
+			}
+
+			res := []string{}
+			for _, role := range roles {
+				if role.Owner == user.Owner {
+					res = append(res, role.Name)
+				}
+			}
+			return res, nil
+		}
+	}
+
+	// the roles are only queried when a rule needs them
+	return nil, nil
+}
+
+// GetRadiusReplyAttributes returns the attributes added by the rules to the Access-Accept of the
+// user, the attributes whose values are empty are skipped
+func GetRadiusReplyAttributes(user *User, rules []*RadiusReplyRule) ([]*RadiusReplyAttribute, error) {
+	roles, err := getRadiusReplyRoles(user, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := []*RadiusReplyAttribute{}
+	for _, rule := range rules {
+		if !isRadiusReplyRuleMatched(rule, user, roles) {
+			continue
+		}
+
+		value := expandUserTemplate(user, rule.Value)
+		if value == "" {
+			continue
+		}
+
+		attributes = append(attributes, &RadiusReplyAttribute{Name: rule.Attribute, Value: value})
+	}
+
+	return attributes, nil
+}
+
+// CheckRadiusReplyRules checks the RADIUS reply rules of an organization before they are saved
+func CheckRadiusReplyRules(rules []*RadiusReplyRule) error {
+	for _, rule := range rules {
+		definition, ok := RadiusAttributeDefinitions[rule.Attribute]
+		if !ok {
+			return fmt.Errorf("the RADIUS attribute: \"%s\" is not supported", rule.Attribute)
+		}
+
+		switch rule.Match {
+		case RadiusReplyMatchAny:
+		case RadiusReplyMatchRole, RadiusReplyMatchGroup, RadiusReplyMatchProperty:
+			if rule.MatchValue == "" {
+				return fmt.Errorf("the match value of the RADIUS attribute: %s is empty", rule.Attribute)
+			}
+		default:
+			return fmt.Errorf("the match: \"%s\" of the RADIUS attribute: %s is invalid", rule.Match, rule.Attribute)
+		}
+
+		if err := checkUserTemplate(rule.Value); err != nil {
+			return fmt.Errorf("the RADIUS attribute: %s is invalid: %s", rule.Attribute, err.Error())
+		}
+
+		if definition.IsInteger && !ldapAttributeTemplateRegex.MatchString(rule.Value) {
+			if _, err := strconv.ParseUint(rule.Value, 10, 32); err != nil {
+				return fmt.Errorf("the value of the RADIUS attribute: %s must be an integer", rule.Attribute)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRadiusReplyAttributes(t *testing.T) {
+	user := &User{
+		Owner:      "built-in",
+		Name:       "alice",
+		Groups:     []string{"built-in/network-admins"},
+		Properties: map[string]string{"vlan": "42", "department": "sales"},
+	}
+
+	rules := []*RadiusReplyRule{
+		{Match: RadiusReplyMatchAny, Attribute: "Tunnel-Type", Value: "13"},
+		{Match: RadiusReplyMatchProperty, MatchValue: "vlan", Attribute: "Tunnel-Private-Group-Id", Value: "${properties.vlan}"},
+		{Match: RadiusReplyMatchProperty, MatchValue: "department=sales", Attribute: "Filter-Id", Value: "sales"},
+		{Match: RadiusReplyMatchProperty, MatchValue: "department=it", Attribute: "Filter-Id", Value: "it"},
+		{Match: RadiusReplyMatchGroup, MatchValue: "network-admins", Attribute: "Cisco-AVPair", Value: "shell:priv-lvl=15"},
+		{Match: RadiusReplyMatchGroup, MatchValue: "guests", Attribute: "Cisco-AVPair", Value: "shell:priv-lvl=1"},
+		{Match: RadiusReplyMatchAny, Attribute: "Class", Value: "${properties.unknown}"},
+	}
+
+	attributes, err := GetRadiusReplyAttributes(user, rules)
+	assert.Nil(t, err)
+	assert.Equal(t, []*RadiusReplyAttribute{
+		{Name: "Tunnel-Type", Value: "13"},
+		{Name: "Tunnel-Private-Group-Id", Value: "42"},
+		{Name: "Filter-Id", Value: "sales"},
+		{Name: "Cisco-AVPair", Value: "shell:priv-lvl=15"},
+	}, attributes)
+}
+
+func TestCheckRadiusReplyRules(t *testing.T) {
+	scenarios := []struct {
+		description string
+		rule        *RadiusReplyRule
+		isValid     bool
+	}{
+		{"vendor specific attribute", &RadiusReplyRule{Match: RadiusReplyMatchRole, MatchValue: "admin", Attribute: "Fortinet-Group-Name", Value: "admins"}, true},
+		{"integer template", &RadiusReplyRule{Match: RadiusReplyMatchAny, Attribute: "Session-Timeout", Value: "${properties.timeout}"}, true},
+		{"unknown attribute", &RadiusReplyRule{Match: RadiusReplyMatchAny, Attribute: "Foo", Value: "bar"}, false},
+		{"unknown match", &RadiusReplyRule{Match: "Foo", Attribute: "Filter-Id", Value: "bar"}, false},
+		{"empty match value", &RadiusReplyRule{Match: RadiusReplyMatchGroup, Attribute: "Filter-Id", Value: "bar"}, false},
+		{"invalid integer", &RadiusReplyRule{Match: RadiusReplyMatchAny, Attribute: "Session-Timeout", Value: "one hour"}, false},
+		{"unknown template variable", &RadiusReplyRule{Match: RadiusReplyMatchAny, Attribute: "Filter-Id", Value: "${foo}"}, false},
+		{"secret template variable", &RadiusReplyRule{Match: RadiusReplyMatchAny, Attribute: "Filter-Id", Value: "${accessSecret}"}, false},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			err := CheckRadiusReplyRules([]*RadiusReplyRule{scenario.rule})
+			assert.Equal(t, scenario.isValid, err == nil)
+		})
+	}
+}
@@ -28,7 +28,7 @@ import (
 
 var logPostOnly bool
 
-var sensitiveDataKeys = []string{"password", "passwordSalt", "clientSecret", "accessSecret", "totpSecret"}
+var sensitiveDataKeys = []string{"password", "passwordSalt", "clientSecret", "accessSecret", "totpSecret", "apiToken"}
 
 func init() {
 	logPostOnly = conf.GetConfigBool("logPostOnly")
@@ -48,6 +48,7 @@ type Record struct {
 	RequestUri   string `xorm:"varchar(1000)" json:"requestUri"`
 	Action       string `xorm:"varchar(1000)" json:"action"`
 	StatusCode   string `xorm:"varchar(5)" json:"statusCode"`
+	ApiToken     string `xorm:"varchar(200)" json:"apiToken"`
 
 	Object       string        `xorm:"text" json:"object"`
 	Response     string        `xorm:"text" json:"response"`
@@ -104,7 +105,8 @@ func NewRecord(ctx *context.Context) *Record {
 }
 
 func AddRecord(record *Record) bool {
-	if logPostOnly {
+	// the requests authorized by an API token are always audited
+	if logPostOnly && record.ApiToken == "" {
 		if record.Method == "GET" {
 			logs.Info("record not saved, reason: GET request")
 
@@ -134,6 +136,11 @@ func AddRecord(record *Record) bool {
 		panic(err)
 	}
 
+	err = addAuditEntry(record)
+	if err != nil {
+		logs.Error("audit entry not saved: %s", err.Error())
+	}
+
 	return affected != 0
 }
 
@@ -185,6 +192,8 @@ func GetRecordsByField(record *Record) ([]*Record, error) {
 	return records, nil
 }
 
+// SendWebhooks adds the record to the outbox of the enabled webhooks of its organization which
+// subscribe to its action, the deliveries are sent by RunWebhookJob
 func SendWebhooks(record *Record) error {
 	webhooks, err := getWebhooksByOrganization(record.Organization)
 	if err != nil {
@@ -215,7 +224,7 @@ func SendWebhooks(record *Record) error {
 				record.ExtendedUser = user
 			}
 
-			err := sendWebhook(webhook, record)
+			err := addWebhookDelivery(webhook, record.Action, "", util.StructToJson(record))
 			if err != nil {
 				return err
 			}
@@ -152,6 +152,8 @@ func UpdateRole(id string, role *Role) (bool, error) {
 		if err != nil {
 			return false, fmt.Errorf("ProcessPolicyDifference: %w", err)
 		}
+
+		emitRoleMembershipChangedEvent(oldRole, role)
 	}
 
 	return affected != 0, nil
@@ -173,6 +175,8 @@ func AddRole(role *Role) (bool, error) {
 		if err != nil {
 			return false, err
 		}
+
+		emitRoleMembershipChangedEvent(nil, role)
 	}
 
 	return affected != 0, nil
@@ -278,6 +282,8 @@ func DeleteRole(role *Role) (bool, error) {
 		if err != nil {
 			return false, fmt.Errorf("ProcessPolicyDifference: %w", err)
 		}
+
+		emitRoleMembershipChangedEvent(role, nil)
 	}
 
 	return affected != 0, nil
@@ -396,6 +402,22 @@ func GetMaskedRoles(roles []*Role) []*Role {
 	return roles
 }
 
+// GetRoleIdsByUser returns the ids of the roles of the user, including the roles which contain them
+func GetRoleIdsByUser(userId string) ([]string, error) {
+	roles, err := getRolesByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []string{}
+	for _, role := range roles {
+		if !util.InSlice(res, role.GetId()) {
+			res = append(res, role.GetId())
+		}
+	}
+	return res, nil
+}
+
 // GetAncestorRoles returns a list of roles that contain the given roleIds
 func GetAncestorRoles(roleIds ...string) ([]*Role, error) {
 	if len(roleIds) == 0 {
@@ -25,19 +25,96 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
-	"io"
 	"time"
 
 	"github.com/RobotsAndPencils/go-saml"
 	"github.com/beevik/etree"
+	"github.com/casdoor/casdoor/util"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
-	dsig "github.com/russellhaering/goxmldsig"
 )
 
+const (
+	SamlNameIdFormatUnspecified = "urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified"
+	SamlNameIdFormatEmail       = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+	SamlNameIdFormatPersistent  = "urn:oasis:names:tc:SAML:2.0:nameid-format:persistent"
+	SamlNameIdFormatTransient   = "urn:oasis:names:tc:SAML:2.0:nameid-format:transient"
+
+	SamlSigningModeResponse  = "Response"
+	SamlSigningModeAssertion = "Assertion"
+	SamlSigningModeBoth      = "Both"
+)
+
+// samlNameIdFormats are the NameID formats which can be set in an application, the empty one
+// lets the SP choose it in the NameIDPolicy of the AuthnRequest
+var samlNameIdFormats = map[string]bool{
+	"":                          true,
+	SamlNameIdFormatUnspecified: true,
+	SamlNameIdFormatEmail:       true,
+	SamlNameIdFormatPersistent:  true,
+	SamlNameIdFormatTransient:   true,
+}
+
+type samlSignatureAlgorithm struct {
+	// Uri identifies the algorithm both in XML signatures and in the SigAlg of the Redirect binding
+	Uri  string
+	Hash crypto.Hash
+}
+
+// samlSignatureAlgorithms are the signature algorithms which can be set in an application, the
+// empty one is RSA-SHA1 for compatibility
+var samlSignatureAlgorithms = map[string]*samlSignatureAlgorithm{
+	"":           {Uri: "http://www.w3.org/2000/09/xmldsig#rsa-sha1", Hash: crypto.SHA1},
+	"RSA-SHA1":   {Uri: "http://www.w3.org/2000/09/xmldsig#rsa-sha1", Hash: crypto.SHA1},
+	"RSA-SHA256": {Uri: "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256", Hash: crypto.SHA256},
+	"RSA-SHA512": {Uri: "http://www.w3.org/2001/04/xmldsig-more#rsa-sha512", Hash: crypto.SHA512},
+}
+
+func getSamlSignatureAlgorithmByUri(uri string) *samlSignatureAlgorithm {
+	for _, algorithm := range samlSignatureAlgorithms {
+		if algorithm.Uri == uri {
+			return algorithm
+		}
+	}
+	return nil
+}
+
+// getSamlNameIdFormat returns the NameID format set in the application, or else the one requested
+// by the SP if it is supported
+func getSamlNameIdFormat(application *Application, requestedFormat string) string {
+	if application.SamlNameIdFormat != "" {
+		return application.SamlNameIdFormat
+	}
+
+	if samlNameIdFormats[requestedFormat] {
+		return requestedFormat
+	}
+	return ""
+}
+
+// getSamlNameId returns the NameID of the user in the format, the transient one is derived from
+// the browser session so that it is different in each session but can be found again for the logout
+func getSamlNameId(user *User, application *Application, format string, sid string) string {
+	switch format {
+	case SamlNameIdFormatEmail:
+		return user.Email
+	case SamlNameIdFormatPersistent:
+		return user.Id
+	case SamlNameIdFormatTransient:
+		return "_" + util.GetHmacSha256(sid, application.GetId()+"/transient")
+	default:
+		return user.Name
+	}
+}
+
+// getSamlSessionIndex returns the SessionIndex of the browser session in the application
+func getSamlSessionIndex(application *Application, sid string) string {
+	return "_" + util.GetHmacSha256(sid, application.GetId())
+}
+
 // NewSamlResponse
 // returns a saml2 response
-func NewSamlResponse(user *User, host string, certificate string, destination string, iss string, requestId string, redirectUri []string) (*etree.Element, error) {
+func NewSamlResponse(user *User, host string, certificate string, destination string, iss string, requestId string, redirectUri []string, nameId string, nameIdFormat string, sessionIndex string) (*etree.Element, error) {
 	samlResponse := &etree.Element{
 		Space: "samlp",
 		Tag:   "Response",
@@ -58,6 +135,8 @@ func NewSamlResponse(user *User, host string, certificate string, destination st
 	samlResponse.CreateElement("samlp:Status").CreateElement("samlp:StatusCode").CreateAttr("Value", "urn:oasis:names:tc:SAML:2.0:status:Success")
 
 	assertion := samlResponse.CreateElement("saml:Assertion")
+	// the namespace is declared again so that the assertion can be signed or encrypted on its own
+	assertion.CreateAttr("xmlns:saml", "urn:oasis:names:tc:SAML:2.0:assertion")
 	assertion.CreateAttr("xmlns:xsi", "http://www.w3.org/2001/XMLSchema-instance")
 	assertion.CreateAttr("xmlns:xs", "http://www.w3.org/2001/XMLSchema")
 	assertion.CreateAttr("ID", fmt.Sprintf("_%s", uuid.New()))
@@ -65,7 +144,11 @@ func NewSamlResponse(user *User, host string, certificate string, destination st
 	assertion.CreateAttr("IssueInstant", now)
 	assertion.CreateElement("saml:Issuer").SetText(host)
 	subject := assertion.CreateElement("saml:Subject")
-	subject.CreateElement("saml:NameID").SetText(user.Name)
+	nameIdElement := subject.CreateElement("saml:NameID")
+	if nameIdFormat != "" {
+		nameIdElement.CreateAttr("Format", nameIdFormat)
+	}
+	nameIdElement.SetText(nameId)
 	subjectConfirmation := subject.CreateElement("saml:SubjectConfirmation")
 	subjectConfirmation.CreateAttr("Method", "urn:oasis:names:tc:SAML:2.0:cm:bearer")
 	subjectConfirmationData := subjectConfirmation.CreateElement("saml:SubjectConfirmationData")
@@ -82,7 +165,7 @@ func NewSamlResponse(user *User, host string, certificate string, destination st
 	}
 	authnStatement := assertion.CreateElement("saml:AuthnStatement")
 	authnStatement.CreateAttr("AuthnInstant", now)
-	authnStatement.CreateAttr("SessionIndex", fmt.Sprintf("_%s", uuid.New()))
+	authnStatement.CreateAttr("SessionIndex", sessionIndex)
 	authnStatement.CreateAttr("SessionNotOnOrAfter", expireTime)
 	authnStatement.CreateElement("saml:AuthnContext").CreateElement("saml:AuthnContextClassRef").SetText("urn:oasis:names:tc:SAML:2.0:ac:classes:PasswordProtectedTransport")
 
@@ -166,9 +249,10 @@ type IdpSSODescriptor struct {
 	XMLName                    xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:metadata IDPSSODescriptor"`
 	ProtocolSupportEnumeration string   `xml:"protocolSupportEnumeration,attr"`
 	SigningKeyDescriptor       KeyDescriptor
-	NameIDFormats              []NameIDFormat      `xml:"NameIDFormat"`
-	SingleSignOnService        SingleSignOnService `xml:"SingleSignOnService"`
-	Attribute                  []Attribute         `xml:"Attribute"`
+	SingleLogoutServices       []SingleLogoutService `xml:"SingleLogoutService"`
+	NameIDFormats              []NameIDFormat        `xml:"NameIDFormat"`
+	SingleSignOnService        SingleSignOnService   `xml:"SingleSignOnService"`
+	Attribute                  []Attribute           `xml:"Attribute"`
 }
 
 type NameIDFormat struct {
@@ -182,6 +266,12 @@ type SingleSignOnService struct {
 	Location string `xml:"Location,attr"`
 }
 
+type SingleLogoutService struct {
+	XMLName  xml.Name
+	Binding  string `xml:"Binding,attr"`
+	Location string `xml:"Location,attr"`
+}
+
 type Attribute struct {
 	XMLName      xml.Name
 	Name         string `xml:"Name,attr"`
@@ -204,6 +294,7 @@ func GetSamlMeta(application *Application, host string) (*IdpEntityDescriptor, e
 	certificate := base64.StdEncoding.EncodeToString(block.Bytes)
 
 	originFrontend, originBackend := getOriginFromHost(host)
+	sloLocation := getSamlSingleLogoutUrl(application, originBackend)
 
 	d := IdpEntityDescriptor{
 		XMLName: xml.Name{
@@ -224,6 +315,10 @@ func GetSamlMeta(application *Application, host string) (*IdpEntityDescriptor, e
 					},
 				},
 			},
+			SingleLogoutServices: []SingleLogoutService{
+				{Binding: SamlBindingRedirect, Location: sloLocation},
+				{Binding: SamlBindingPost, Location: sloLocation},
+			},
 			NameIDFormats: []NameIDFormat{
 				{Value: "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"},
 				{Value: "urn:oasis:names:tc:SAML:2.0:nameid-format:persistent"},
@@ -246,39 +341,50 @@ func GetSamlMeta(application *Application, host string) (*IdpEntityDescriptor, e
 }
 
 // GetSamlResponse generates a SAML2.0 response
-// parameter samlRequest is saml request in base64 format
-func GetSamlResponse(application *Application, user *User, samlRequest string, host string) (string, string, string, error) {
+// parameter samlRequest is saml request in base64 format, relayState is the one of the request,
+// samlQuery is the query string of the HTTP-Redirect binding as received, sid identifies the
+// browser session of the user
+func GetSamlResponse(application *Application, user *User, samlRequest string, relayState string, samlQuery string, host string, sid string) (string, string, string, error) {
 	// request type
 	method := "GET"
 
-	// base64 decode
-	defated, err := base64.StdEncoding.DecodeString(samlRequest)
+	// base64 decode and decompress
+	requestXml, err := decodeSamlMessage(samlRequest)
 	if err != nil {
 		return "", "", method, fmt.Errorf("err: Failed to decode SAML request , %s", err.Error())
 	}
 
-	// decompress
-	var buffer bytes.Buffer
-	rdr := flate.NewReader(bytes.NewReader(defated))
+	requestDoc := etree.NewDocument()
+	err = requestDoc.ReadFromBytes(requestXml)
+	if err != nil {
+		return "", "", method, fmt.Errorf("err: Failed to unmarshal AuthnRequest, please check the SAML request. %s", err.Error())
+	}
 
-	for {
-		_, err := io.CopyN(&buffer, rdr, 1024)
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return "", "", "", err
-		}
+	sp, err := application.GetSamlSpMetadata()
+	if err != nil {
+		return "", "", method, err
+	}
+
+	requestElement, isSigned, err := verifySamlMessageSignature(sp, requestDoc, "SAMLRequest", samlRequest, samlQuery)
+	if err != nil {
+		return "", "", method, fmt.Errorf("err: Failed to verify the signature of the SAML request, %s", err.Error())
+	}
+	if !isSigned && sp != nil && sp.AuthnRequestsSigned {
+		return "", "", method, fmt.Errorf("err: The SAML request must be signed according to the SP metadata")
 	}
 
 	var authnRequest saml.AuthnRequest
-	err = xml.Unmarshal(buffer.Bytes(), &authnRequest)
+	err = unmarshalSamlElement(requestElement, &authnRequest)
 	if err != nil {
 		return "", "", method, fmt.Errorf("err: Failed to unmarshal AuthnRequest, please check the SAML request. %s", err.Error())
 	}
 
 	// verify samlRequest
-	if isValid := application.IsRedirectUriValid(authnRequest.Issuer.Url); !isValid {
+	if sp != nil {
+		if authnRequest.Issuer.Url != sp.EntityId {
+			return "", "", method, fmt.Errorf("err: Issuer URI: %s doesn't match the entity ID of the SP metadata", authnRequest.Issuer.Url)
+		}
+	} else if isValid := application.IsRedirectUriValid(authnRequest.Issuer.Url); !isValid {
 		return "", "", method, fmt.Errorf("err: Issuer URI: %s doesn't exist in the allowed Redirect URI list", authnRequest.Issuer.Url)
 	}
 
@@ -288,6 +394,10 @@ func GetSamlResponse(application *Application, user *User, samlRequest string, h
 		return "", "", "", err
 	}
 
+	if cert == nil {
+		return "", "", "", errors.New("please set a cert for the application first")
+	}
+
 	block, _ := pem.Decode([]byte(cert.Certificate))
 	certificate := base64.StdEncoding.EncodeToString(block.Bytes)
 
@@ -295,25 +405,63 @@ func GetSamlResponse(application *Application, user *User, samlRequest string, h
 	if application.SamlReplyUrl != "" {
 		method = "POST"
 		authnRequest.AssertionConsumerServiceURL = application.SamlReplyUrl
+	} else if sp != nil && len(sp.AssertionConsumerServiceUrls) != 0 {
+		method = "POST"
+		if authnRequest.AssertionConsumerServiceURL == "" {
+			authnRequest.AssertionConsumerServiceURL = sp.AssertionConsumerServiceUrls[0]
+		} else if !util.InSlice(sp.AssertionConsumerServiceUrls, authnRequest.AssertionConsumerServiceURL) {
+			return "", "", "", fmt.Errorf("err: AssertionConsumerServiceURL: %s doesn't exist in the SP metadata", authnRequest.AssertionConsumerServiceURL)
+		}
 	} else if authnRequest.AssertionConsumerServiceURL == "" {
 		return "", "", "", fmt.Errorf("err: SAML request don't has attribute 'AssertionConsumerServiceURL' in <samlp:AuthnRequest>")
 	}
 
+	requestedNameIdFormat := ""
+	if nameIdPolicy := requestElement.FindElement("./NameIDPolicy"); nameIdPolicy != nil {
+		requestedNameIdFormat = nameIdPolicy.SelectAttrValue("Format", "")
+	}
+	nameIdFormat := getSamlNameIdFormat(application, requestedNameIdFormat)
+	nameId := getSamlNameId(user, application, nameIdFormat, sid)
+
 	_, originBackend := getOriginFromHost(host)
 	// build signedResponse
-	samlResponse, _ := NewSamlResponse(user, originBackend, certificate, authnRequest.AssertionConsumerServiceURL, authnRequest.Issuer.Url, authnRequest.ID, application.RedirectUris)
-	randomKeyStore := &X509Key{
-		PrivateKey:      cert.PrivateKey,
-		X509Certificate: certificate,
-	}
-	ctx := dsig.NewDefaultSigningContext(randomKeyStore)
-	ctx.Hash = crypto.SHA1
-	//signedXML, err := ctx.SignEnvelopedLimix(samlResponse)
-	//if err != nil {
-	//	return "", "", fmt.Errorf("err: %s", err.Error())
-	//}
-	sig, err := ctx.ConstructSignature(samlResponse, true)
-	samlResponse.InsertChildAt(1, sig)
+	samlResponse, err := NewSamlResponse(user, originBackend, certificate, authnRequest.AssertionConsumerServiceURL, authnRequest.Issuer.Url, authnRequest.ID, application.RedirectUris, nameId, nameIdFormat, getSamlSessionIndex(application, sid))
+	if err != nil {
+		return "", "", method, err
+	}
+
+	ctx, err := newSamlSigningContext(application, cert, certificate)
+	if err != nil {
+		return "", "", method, err
+	}
+
+	// the assertion is signed before it is encrypted, and the response after
+	assertion := samlResponse.FindElement("./Assertion")
+	signingMode := application.SamlSigningMode
+	if signingMode == SamlSigningModeAssertion || signingMode == SamlSigningModeBoth || (sp != nil && sp.WantAssertionsSigned) {
+		err = signSamlElement(ctx, assertion)
+		if err != nil {
+			return "", "", method, fmt.Errorf("err: Failed to sign the SAML assertion, %s", err.Error())
+		}
+	}
+
+	if application.EnableSamlAssertionEncryption {
+		if sp == nil || sp.EncryptionCertificate == nil {
+			return "", "", method, fmt.Errorf("err: The SP metadata of the application has no encryption certificate")
+		}
+
+		err = encryptSamlAssertion(samlResponse, assertion, sp.EncryptionCertificate)
+		if err != nil {
+			return "", "", method, fmt.Errorf("err: Failed to encrypt the SAML assertion, %s", err.Error())
+		}
+	}
+
+	if signingMode == "" || signingMode == SamlSigningModeResponse || signingMode == SamlSigningModeBoth {
+		err = signSamlElement(ctx, samlResponse)
+		if err != nil {
+			return "", "", method, fmt.Errorf("err: Failed to sign the SAML response, %s", err.Error())
+		}
+	}
 
 	doc := etree.NewDocument()
 	doc.SetRoot(samlResponse)
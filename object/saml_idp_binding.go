@@ -0,0 +1,237 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+// decodeSamlMessage decodes a SAML message of the HTTP-Redirect binding, which is deflated, or of
+// the HTTP-POST binding, which is not
+func decodeSamlMessage(message string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(message)
+	if err != nil {
+		return nil, err
+	}
+
+	inflated, err := io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+	if err == nil && bytes.HasPrefix(bytes.TrimSpace(inflated), []byte("<")) {
+		return inflated, nil
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("the SAML message is not XML")
+}
+
+// encodeSamlRedirectMessage deflates and encodes a SAML message for the HTTP-Redirect binding
+func encodeSamlRedirectMessage(data []byte) (string, error) {
+	var buffer bytes.Buffer
+	writer, err := flate.NewWriter(&buffer, flate.DefaultCompression)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = writer.Write(data)
+	if err != nil {
+		return "", err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buffer.Bytes()), nil
+}
+
+// getSamlSignedQuery returns the part of the query string which is signed in the HTTP-Redirect
+// binding, parameter is SAMLRequest or SAMLResponse
+func getSamlSignedQuery(parameter string, message string, relayState string, sigAlg string) string {
+	query := parameter + "=" + url.QueryEscape(message)
+	if relayState != "" {
+		query += "&RelayState=" + url.QueryEscape(relayState)
+	}
+	return query + "&SigAlg=" + url.QueryEscape(sigAlg)
+}
+
+func verifySamlQuerySignature(certificates []*x509.Certificate, query string, sigAlg string, signature string) error {
+	algorithm := getSamlSignatureAlgorithmByUri(sigAlg)
+	if algorithm == nil {
+		return fmt.Errorf("the signature algorithm: \"%s\" is not supported", sigAlg)
+	}
+
+	signatureBytes, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return err
+	}
+
+	hash := algorithm.Hash.New()
+	hash.Write([]byte(query))
+	digest := hash.Sum(nil)
+
+	for _, certificate := range certificates {
+		publicKey, ok := certificate.PublicKey.(*rsa.PublicKey)
+		if ok && rsa.VerifyPKCS1v15(publicKey, algorithm.Hash, digest, signatureBytes) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("the signature does not match any signing certificate of the SP")
+}
+
+func signSamlQuery(privateKey *rsa.PrivateKey, algorithm *samlSignatureAlgorithm, query string) (string, error) {
+	hash := algorithm.Hash.New()
+	hash.Write([]byte(query))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, algorithm.Hash, hash.Sum(nil))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// getSamlReceivedSignedQuery returns the part of the received query string which is signed in the
+// HTTP-Redirect binding. The parameters are kept as they were encoded by the SP, as the encoding
+// of a reconstructed query may differ from it, see SAML bindings 3.4.4.1.
+func getSamlReceivedSignedQuery(rawQuery string, parameter string) (string, error) {
+	values := map[string]string{}
+	for _, part := range strings.Split(strings.TrimPrefix(rawQuery, "?"), "&") {
+		key, value, _ := strings.Cut(part, "=")
+		if key == parameter || key == "RelayState" || key == "SigAlg" {
+			if _, ok := values[key]; ok {
+				return "", fmt.Errorf("the query string has more than one parameter: %s", key)
+			}
+			values[key] = value
+		}
+	}
+
+	if values[parameter] == "" || values["SigAlg"] == "" {
+		return "", fmt.Errorf("the signed query string has no %s or SigAlg", parameter)
+	}
+
+	query := parameter + "=" + values[parameter]
+	if relayState, ok := values["RelayState"]; ok {
+		query += "&RelayState=" + relayState
+	}
+	return query + "&SigAlg=" + values["SigAlg"], nil
+}
+
+// verifySamlMessageSignature verifies the signature of a message sent by the SP, either in the
+// query string of the HTTP-Redirect binding, given as received in rawQuery, or enveloped in the
+// message. A signature is verified only when the SP metadata has signing certificates, otherwise
+// the message is handled as an unsigned one. It returns the element of the message which is
+// verified, the message must be read from it rather than from doc, and whether it is signed.
+func verifySamlMessageSignature(sp *SamlSpMetadata, doc *etree.Document, parameter string, message string, rawQuery string) (*etree.Element, bool, error) {
+	query, err := url.ParseQuery(strings.TrimPrefix(rawQuery, "?"))
+	if err != nil {
+		return nil, false, err
+	}
+
+	signature := query.Get("Signature")
+	isQuerySigned := signature != ""
+	if !isQuerySigned && doc.Root().FindElement("./Signature") == nil {
+		return doc.Root(), false, nil
+	}
+
+	if sp == nil || len(sp.SigningCertificates) == 0 {
+		return doc.Root(), false, nil
+	}
+
+	if isQuerySigned {
+		if query.Get(parameter) != message {
+			return nil, true, fmt.Errorf("the signed query string doesn't carry the message")
+		}
+
+		signedQuery, err := getSamlReceivedSignedQuery(rawQuery, parameter)
+		if err != nil {
+			return nil, true, err
+		}
+
+		sigAlg := query.Get("SigAlg")
+		err = verifySamlQuerySignature(sp.SigningCertificates, signedQuery, sigAlg, signature)
+		if err != nil {
+			return nil, true, err
+		}
+		return doc.Root(), true, nil
+	}
+
+	certificateStore := &dsig.MemoryX509CertificateStore{Roots: sp.SigningCertificates}
+	el, err := dsig.NewDefaultValidationContext(certificateStore).Validate(doc.Root())
+	if err != nil {
+		return nil, true, err
+	}
+	return el, true, nil
+}
+
+// unmarshalSamlElement unmarshals the element of a SAML message into v
+func unmarshalSamlElement(el *etree.Element, v interface{}) error {
+	doc := etree.NewDocument()
+	doc.SetRoot(el.Copy())
+	data, err := doc.WriteToBytes()
+	if err != nil {
+		return err
+	}
+
+	return xml.Unmarshal(data, v)
+}
+
+// newSamlSigningContext returns the context to sign the messages of the application with its
+// cert and signature algorithm, certificate is the DER certificate in base64
+func newSamlSigningContext(application *Application, cert *Cert, certificate string) (*dsig.SigningContext, error) {
+	algorithm, ok := samlSignatureAlgorithms[application.SamlSignatureAlgorithm]
+	if !ok {
+		return nil, fmt.Errorf("the SAML signature algorithm: \"%s\" is not supported", application.SamlSignatureAlgorithm)
+	}
+
+	ctx := dsig.NewDefaultSigningContext(&X509Key{
+		PrivateKey:      cert.PrivateKey,
+		X509Certificate: certificate,
+	})
+	// the exclusive canonicalization lets the assertion be verified on its own, see SAML core 5.4.3
+	ctx.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+	err := ctx.SetSignatureMethod(algorithm.Uri)
+	if err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
+// signSamlElement inserts the enveloped signature of the element after its Issuer
+func signSamlElement(ctx *dsig.SigningContext, el *etree.Element) error {
+	signature, err := ctx.ConstructSignature(el, true)
+	if err != nil {
+		return err
+	}
+
+	el.InsertChildAt(1, signature)
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/beevik/etree"
+)
+
+// XML Encryption, see https://www.w3.org/TR/xmlenc-core1/
+const (
+	xmlEncNamespace     = "http://www.w3.org/2001/04/xmlenc#"
+	xmlEncElement       = "http://www.w3.org/2001/04/xmlenc#Element"
+	xmlEncAes256Cbc     = "http://www.w3.org/2001/04/xmlenc#aes256-cbc"
+	xmlEncRsaOaepMgf1p  = "http://www.w3.org/2001/04/xmlenc#rsa-oaep-mgf1p"
+	xmlDsigNamespace    = "http://www.w3.org/2000/09/xmldsig#"
+	xmlDsigSha1Digest   = "http://www.w3.org/2000/09/xmldsig#sha1"
+	samlAssertionPrefix = "saml"
+)
+
+func createCipherData(parent *etree.Element, value []byte) {
+	parent.CreateElement("xenc:CipherData").CreateElement("xenc:CipherValue").SetText(base64.StdEncoding.EncodeToString(value))
+}
+
+// encryptSamlAssertion replaces the assertion of the response with an EncryptedAssertion. The
+// assertion is encrypted with AES-256-CBC, and the key with RSA-OAEP for the certificate of the SP.
+func encryptSamlAssertion(response *etree.Element, assertion *etree.Element, certificate *x509.Certificate) error {
+	publicKey, ok := certificate.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("the encryption certificate of the SP is not an RSA certificate")
+	}
+
+	doc := etree.NewDocument()
+	doc.SetRoot(assertion.Copy())
+	plaintext, err := doc.WriteToBytes()
+	if err != nil {
+		return err
+	}
+
+	key := make([]byte, 32)
+	_, err = rand.Read(key)
+	if err != nil {
+		return err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+
+	// the padding of XML Encryption only requires the last byte to be the length of the padding
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	for i := 0; i < padding; i++ {
+		plaintext = append(plaintext, byte(padding))
+	}
+
+	ciphertext := make([]byte, aes.BlockSize+len(plaintext))
+	_, err = rand.Read(ciphertext[:aes.BlockSize])
+	if err != nil {
+		return err
+	}
+	cipher.NewCBCEncrypter(block, ciphertext[:aes.BlockSize]).CryptBlocks(ciphertext[aes.BlockSize:], plaintext)
+
+	encryptedKey, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, publicKey, key, nil)
+	if err != nil {
+		return err
+	}
+
+	encryptedAssertion := etree.NewElement(samlAssertionPrefix + ":EncryptedAssertion")
+	encryptedData := encryptedAssertion.CreateElement("xenc:EncryptedData")
+	encryptedData.CreateAttr("xmlns:xenc", xmlEncNamespace)
+	encryptedData.CreateAttr("Type", xmlEncElement)
+	encryptedData.CreateElement("xenc:EncryptionMethod").CreateAttr("Algorithm", xmlEncAes256Cbc)
+
+	keyInfo := encryptedData.CreateElement("ds:KeyInfo")
+	keyInfo.CreateAttr("xmlns:ds", xmlDsigNamespace)
+	encryptedKeyElement := keyInfo.CreateElement("xenc:EncryptedKey")
+	keyEncryptionMethod := encryptedKeyElement.CreateElement("xenc:EncryptionMethod")
+	keyEncryptionMethod.CreateAttr("Algorithm", xmlEncRsaOaepMgf1p)
+	keyEncryptionMethod.CreateElement("ds:DigestMethod").CreateAttr("Algorithm", xmlDsigSha1Digest)
+	createCipherData(encryptedKeyElement, encryptedKey)
+
+	createCipherData(encryptedData, ciphertext)
+
+	index := assertion.Index()
+	response.RemoveChildAt(index)
+	response.InsertChildAt(index, encryptedAssertion)
+	return nil
+}
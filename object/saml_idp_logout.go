@@ -0,0 +1,280 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	"github.com/casdoor/casdoor/util"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+// SamlLogoutRequest is a LogoutRequest received from a SP
+type SamlLogoutRequest struct {
+	XMLName        xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol LogoutRequest"`
+	ID             string   `xml:"ID,attr"`
+	Issuer         string   `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	NameId         string   `xml:"urn:oasis:names:tc:SAML:2.0:assertion NameID"`
+	SessionIndexes []string `xml:"urn:oasis:names:tc:SAML:2.0:protocol SessionIndex"`
+}
+
+// SamlLogoutResponse is the LogoutResponse to send back to a SP. With the HTTP-Redirect binding,
+// Url has the message in its query string. With the HTTP-POST binding, the message and the relay
+// state are posted to Url in a form.
+type SamlLogoutResponse struct {
+	Url          string
+	Method       string
+	SamlResponse string
+	RelayState   string
+}
+
+// getSamlSingleLogoutUrl returns the location of the single logout service of the application
+func getSamlSingleLogoutUrl(application *Application, originBackend string) string {
+	return fmt.Sprintf("%s/api/saml/slo?application=%s", originBackend, url.QueryEscape(application.GetId()))
+}
+
+func getSamlCert(application *Application) (*Cert, string, error) {
+	cert, err := getCertByApplication(application)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if cert == nil {
+		return nil, "", fmt.Errorf("The cert of the application \"%s\" does not exist", application.GetId())
+	}
+
+	block, _ := pem.Decode([]byte(cert.Certificate))
+	if block == nil {
+		return nil, "", fmt.Errorf("The cert of the application \"%s\" is invalid", application.GetId())
+	}
+	return cert, base64.StdEncoding.EncodeToString(block.Bytes), nil
+}
+
+func newSamlLogoutMessage(tag string, destination string, host string) *etree.Element {
+	_, originBackend := getOriginFromHost(host)
+
+	message := etree.NewElement("samlp:" + tag)
+	message.CreateAttr("xmlns:samlp", "urn:oasis:names:tc:SAML:2.0:protocol")
+	message.CreateAttr("xmlns:saml", "urn:oasis:names:tc:SAML:2.0:assertion")
+	message.CreateAttr("ID", fmt.Sprintf("_%s", uuid.New()))
+	message.CreateAttr("Version", "2.0")
+	message.CreateAttr("IssueInstant", time.Now().UTC().Format(time.RFC3339))
+	message.CreateAttr("Destination", destination)
+	message.CreateElement("saml:Issuer").SetText(originBackend)
+	return message
+}
+
+// getSamlRedirectUrl returns the URL which sends the message to the destination with the
+// HTTP-Redirect binding, parameter is SAMLRequest or SAMLResponse
+func getSamlRedirectUrl(application *Application, cert *Cert, destination string, parameter string, message *etree.Element, relayState string) (string, error) {
+	doc := etree.NewDocument()
+	doc.SetRoot(message)
+	data, err := doc.WriteToBytes()
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := encodeSamlRedirectMessage(data)
+	if err != nil {
+		return "", err
+	}
+
+	algorithm, ok := samlSignatureAlgorithms[application.SamlSignatureAlgorithm]
+	if !ok {
+		return "", fmt.Errorf("the SAML signature algorithm: \"%s\" is not supported", application.SamlSignatureAlgorithm)
+	}
+
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(cert.PrivateKey))
+	if err != nil {
+		return "", err
+	}
+
+	query := getSamlSignedQuery(parameter, encoded, relayState, algorithm.Uri)
+	signature, err := signSamlQuery(privateKey, algorithm, query)
+	if err != nil {
+		return "", err
+	}
+
+	sep := "?"
+	if strings.Contains(destination, "?") {
+		sep = "&"
+	}
+	return fmt.Sprintf("%s%s%s&Signature=%s", destination, sep, query, url.QueryEscape(signature)), nil
+}
+
+// getSamlLogoutRequestUrl returns the URL which sends a LogoutRequest for the browser session
+// identified by sid to the SP of the application, or an empty string if the SP metadata has no
+// single logout service of the HTTP-Redirect binding
+func getSamlLogoutRequestUrl(application *Application, user *User, sid string, host string) (string, error) {
+	sp, err := application.GetSamlSpMetadata()
+	if err != nil {
+		return "", err
+	}
+
+	if sp == nil || sp.SingleLogoutServiceBinding != SamlBindingRedirect {
+		return "", nil
+	}
+
+	cert, _, err := getSamlCert(application)
+	if err != nil {
+		return "", err
+	}
+
+	// the NameID format requested by the SP at the sign-in is not known anymore
+	nameIdFormat := getSamlNameIdFormat(application, "")
+	logoutRequest := newSamlLogoutMessage("LogoutRequest", sp.SingleLogoutServiceUrl, host)
+	nameId := logoutRequest.CreateElement("saml:NameID")
+	if nameIdFormat != "" {
+		nameId.CreateAttr("Format", nameIdFormat)
+	}
+	nameId.SetText(getSamlNameId(user, application, nameIdFormat, sid))
+	logoutRequest.CreateElement("samlp:SessionIndex").SetText(getSamlSessionIndex(application, sid))
+
+	return getSamlRedirectUrl(application, cert, sp.SingleLogoutServiceUrl, "SAMLRequest", logoutRequest, "")
+}
+
+// ParseSamlLogoutRequest parses and verifies a LogoutRequest sent by the SP of the application,
+// samlRequest is the message of the HTTP-Redirect or HTTP-POST binding and samlQuery is the query
+// string of the HTTP-Redirect binding as received
+func ParseSamlLogoutRequest(application *Application, samlRequest string, samlQuery string) (*SamlLogoutRequest, error) {
+	sp, err := application.GetSamlSpMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	if sp == nil || sp.SingleLogoutServiceUrl == "" {
+		return nil, fmt.Errorf("the application: %s has no SP metadata with a single logout service", application.GetId())
+	}
+
+	data, err := decodeSamlMessage(samlRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the SAML logout request: %s", err.Error())
+	}
+
+	doc := etree.NewDocument()
+	err = doc.ReadFromBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the SAML logout request: %s", err.Error())
+	}
+
+	el, isSigned, err := verifySamlMessageSignature(sp, doc, "SAMLRequest", samlRequest, samlQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify the signature of the SAML logout request: %s", err.Error())
+	}
+	if !isSigned && len(sp.SigningCertificates) != 0 {
+		return nil, fmt.Errorf("the SAML logout request must be signed")
+	}
+
+	var logoutRequest SamlLogoutRequest
+	err = unmarshalSamlElement(el, &logoutRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the SAML logout request: %s", err.Error())
+	}
+
+	if logoutRequest.Issuer != sp.EntityId {
+		return nil, fmt.Errorf("the issuer: %s of the SAML logout request doesn't match the entity ID of the SP metadata", logoutRequest.Issuer)
+	}
+
+	return &logoutRequest, nil
+}
+
+// IsSamlLogoutRequestOfSession returns whether the LogoutRequest is for the browser session of the
+// user identified by sid, by its SessionIndex or else by its NameID
+func IsSamlLogoutRequestOfSession(application *Application, logoutRequest *SamlLogoutRequest, userId string, sid string) (bool, error) {
+	if len(logoutRequest.SessionIndexes) != 0 {
+		return util.InSlice(logoutRequest.SessionIndexes, getSamlSessionIndex(application, sid)), nil
+	}
+
+	user, err := GetUser(userId)
+	if err != nil {
+		return false, err
+	}
+
+	if user == nil {
+		return false, nil
+	}
+
+	for format := range samlNameIdFormats {
+		if logoutRequest.NameId == getSamlNameId(user, application, format, sid) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetSamlLogoutResponse returns the successful LogoutResponse to the LogoutRequest of the SP of
+// the application, with the binding of the single logout service of the SP metadata
+func GetSamlLogoutResponse(application *Application, logoutRequest *SamlLogoutRequest, relayState string, host string) (*SamlLogoutResponse, error) {
+	sp, err := application.GetSamlSpMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	if sp == nil || sp.SingleLogoutServiceResponseUrl == "" {
+		return nil, fmt.Errorf("the application: %s has no SP metadata with a single logout service", application.GetId())
+	}
+
+	cert, certificate, err := getSamlCert(application)
+	if err != nil {
+		return nil, err
+	}
+
+	destination := sp.SingleLogoutServiceResponseUrl
+	logoutResponse := newSamlLogoutMessage("LogoutResponse", destination, host)
+	logoutResponse.CreateAttr("InResponseTo", logoutRequest.ID)
+	logoutResponse.CreateElement("samlp:Status").CreateElement("samlp:StatusCode").CreateAttr("Value", "urn:oasis:names:tc:SAML:2.0:status:Success")
+
+	if sp.SingleLogoutServiceBinding == SamlBindingRedirect {
+		redirectUrl, err := getSamlRedirectUrl(application, cert, destination, "SAMLResponse", logoutResponse, relayState)
+		if err != nil {
+			return nil, err
+		}
+
+		return &SamlLogoutResponse{Url: redirectUrl, Method: "GET"}, nil
+	}
+
+	ctx, err := newSamlSigningContext(application, cert, certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	err = signSamlElement(ctx, logoutResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := etree.NewDocument()
+	doc.SetRoot(logoutResponse)
+	data, err := doc.WriteToBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	res := &SamlLogoutResponse{
+		Url:          destination,
+		Method:       "POST",
+		SamlResponse: base64.StdEncoding.EncodeToString(data),
+		RelayState:   relayState,
+	}
+	return res, nil
+}
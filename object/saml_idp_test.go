@@ -0,0 +1,238 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSamlCertificate(t *testing.T) (*rsa.PrivateKey, *x509.Certificate) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sp.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	data, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	assert.Nil(t, err)
+
+	certificate, err := x509.ParseCertificate(data)
+	assert.Nil(t, err)
+	return privateKey, certificate
+}
+
+func TestParseSamlSpMetadata(t *testing.T) {
+	_, signingCertificate := newTestSamlCertificate(t)
+	_, encryptionCertificate := newTestSamlCertificate(t)
+
+	metadata := fmt.Sprintf(`<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" xmlns:ds="http://www.w3.org/2000/09/xmldsig#" entityID="https://sp.example.com/saml">
+  <md:SPSSODescriptor AuthnRequestsSigned="true" WantAssertionsSigned="true" protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <md:KeyDescriptor use="signing"><ds:KeyInfo><ds:X509Data><ds:X509Certificate>%s</ds:X509Certificate></ds:X509Data></ds:KeyInfo></md:KeyDescriptor>
+    <md:KeyDescriptor use="encryption"><ds:KeyInfo><ds:X509Data><ds:X509Certificate>%s</ds:X509Certificate></ds:X509Data></ds:KeyInfo></md:KeyDescriptor>
+    <md:SingleLogoutService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="https://sp.example.com/slo/post"/>
+    <md:SingleLogoutService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect" Location="https://sp.example.com/slo"/>
+    <md:NameIDFormat>urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress</md:NameIDFormat>
+    <md:AssertionConsumerService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="https://sp.example.com/acs/1" index="1"/>
+    <md:AssertionConsumerService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Artifact" Location="https://sp.example.com/acs/artifact" index="2"/>
+    <md:AssertionConsumerService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="https://sp.example.com/acs/0" index="0" isDefault="true"/>
+  </md:SPSSODescriptor>
+</md:EntityDescriptor>`,
+		base64.StdEncoding.EncodeToString(signingCertificate.Raw),
+		base64.StdEncoding.EncodeToString(encryptionCertificate.Raw))
+
+	sp, err := ParseSamlSpMetadata(metadata)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://sp.example.com/saml", sp.EntityId)
+	assert.True(t, sp.AuthnRequestsSigned)
+	assert.True(t, sp.WantAssertionsSigned)
+	assert.Equal(t, []string{SamlNameIdFormatEmail}, sp.NameIdFormats)
+	assert.Equal(t, []string{"https://sp.example.com/acs/0", "https://sp.example.com/acs/1"}, sp.AssertionConsumerServiceUrls)
+	assert.Equal(t, "https://sp.example.com/slo", sp.SingleLogoutServiceUrl)
+	assert.Equal(t, "https://sp.example.com/slo", sp.SingleLogoutServiceResponseUrl)
+	assert.Equal(t, SamlBindingRedirect, sp.SingleLogoutServiceBinding)
+	assert.Equal(t, 1, len(sp.SigningCertificates))
+	assert.True(t, sp.SigningCertificates[0].Equal(signingCertificate))
+	assert.True(t, sp.EncryptionCertificate.Equal(encryptionCertificate))
+
+	_, err = ParseSamlSpMetadata(`<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="https://sp.example.com/saml"/>`)
+	assert.NotNil(t, err)
+
+	_, err = ParseSamlSpMetadata("not metadata")
+	assert.NotNil(t, err)
+}
+
+func TestEncryptSamlAssertion(t *testing.T) {
+	privateKey, certificate := newTestSamlCertificate(t)
+
+	response := etree.NewElement("samlp:Response")
+	response.CreateAttr("xmlns:samlp", "urn:oasis:names:tc:SAML:2.0:protocol")
+	response.CreateElement("saml:Issuer").SetText("https://idp.example.com")
+	assertion := response.CreateElement("saml:Assertion")
+	assertion.CreateAttr("xmlns:saml", "urn:oasis:names:tc:SAML:2.0:assertion")
+	assertion.CreateAttr("ID", "_assertion")
+	assertion.CreateElement("saml:Subject").CreateElement("saml:NameID").SetText("alice")
+
+	err := encryptSamlAssertion(response, assertion, certificate)
+	assert.Nil(t, err)
+	assert.Nil(t, response.FindElement("./Assertion"))
+
+	encryptedData := response.FindElement("./EncryptedAssertion/EncryptedData")
+	assert.NotNil(t, encryptedData)
+
+	decode := func(path string) []byte {
+		data, err := base64.StdEncoding.DecodeString(encryptedData.FindElement(path).Text())
+		assert.Nil(t, err)
+		return data
+	}
+
+	key, err := rsa.DecryptOAEP(sha1.New(), rand.Reader, privateKey, decode("./KeyInfo/EncryptedKey/CipherData/CipherValue"), nil)
+	assert.Nil(t, err)
+
+	ciphertext := decode("./CipherData/CipherValue")
+	block, err := aes.NewCipher(key)
+	assert.Nil(t, err)
+	plaintext := make([]byte, len(ciphertext)-aes.BlockSize)
+	cipher.NewCBCDecrypter(block, ciphertext[:aes.BlockSize]).CryptBlocks(plaintext, ciphertext[aes.BlockSize:])
+	plaintext = plaintext[:len(plaintext)-int(plaintext[len(plaintext)-1])]
+
+	doc := etree.NewDocument()
+	err = doc.ReadFromBytes(plaintext)
+	assert.Nil(t, err)
+	assert.Equal(t, "_assertion", doc.Root().SelectAttrValue("ID", ""))
+	assert.Equal(t, "alice", doc.Root().FindElement("./Subject/NameID").Text())
+}
+
+func TestDecodeSamlMessage(t *testing.T) {
+	message := []byte(`<samlp:LogoutRequest xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" ID="_request"/>`)
+
+	encoded, err := encodeSamlRedirectMessage(message)
+	assert.Nil(t, err)
+
+	data, err := decodeSamlMessage(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, message, data)
+
+	data, err = decodeSamlMessage(base64.StdEncoding.EncodeToString(message))
+	assert.Nil(t, err)
+	assert.Equal(t, message, data)
+
+	_, err = decodeSamlMessage(base64.StdEncoding.EncodeToString([]byte("not a message")))
+	assert.NotNil(t, err)
+}
+
+func TestVerifySamlRedirectSignature(t *testing.T) {
+	privateKey, certificate := newTestSamlCertificate(t)
+	sp := &SamlSpMetadata{SigningCertificates: []*x509.Certificate{certificate}}
+	algorithm := samlSignatureAlgorithms["RSA-SHA256"]
+
+	message := []byte(`<samlp:AuthnRequest xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" ID="_request"/>`)
+	encoded, err := encodeSamlRedirectMessage(message)
+	assert.Nil(t, err)
+
+	doc := etree.NewDocument()
+	err = doc.ReadFromBytes(message)
+	assert.Nil(t, err)
+
+	// the SP encodes the parameters with lower case escapes, which differ from url.QueryEscape
+	escape := func(value string) string {
+		return strings.NewReplacer("+", "%2b", "/", "%2f", "=", "%3d", ":", "%3a", "#", "%23").Replace(value)
+	}
+	signedQuery := "SAMLRequest=" + escape(encoded) + "&RelayState=state&SigAlg=" + escape(algorithm.Uri)
+	signature, err := signSamlQuery(privateKey, algorithm, signedQuery)
+	assert.Nil(t, err)
+	rawQuery := "application=admin%2fapp&" + signedQuery + "&Signature=" + url.QueryEscape(signature)
+
+	el, isSigned, err := verifySamlMessageSignature(sp, doc, "SAMLRequest", encoded, rawQuery)
+	assert.Nil(t, err)
+	assert.True(t, isSigned)
+	assert.Equal(t, "_request", el.SelectAttrValue("ID", ""))
+
+	_, _, err = verifySamlMessageSignature(sp, doc, "SAMLRequest", encoded, strings.Replace(rawQuery, "RelayState=state", "RelayState=other", 1))
+	assert.NotNil(t, err)
+
+	other, err := encodeSamlRedirectMessage([]byte(`<samlp:AuthnRequest xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" ID="_other"/>`))
+	assert.Nil(t, err)
+	_, _, err = verifySamlMessageSignature(sp, doc, "SAMLRequest", other, rawQuery)
+	assert.NotNil(t, err)
+
+	// without signing certificates, the signature cannot be verified and the message is unsigned
+	_, isSigned, err = verifySamlMessageSignature(&SamlSpMetadata{}, doc, "SAMLRequest", encoded, rawQuery)
+	assert.Nil(t, err)
+	assert.False(t, isSigned)
+}
+
+func TestVerifySamlPostSignature(t *testing.T) {
+	privateKey, certificate := newTestSamlCertificate(t)
+	sp := &SamlSpMetadata{SigningCertificates: []*x509.Certificate{certificate}}
+
+	request := etree.NewElement("samlp:LogoutRequest")
+	request.CreateAttr("xmlns:samlp", "urn:oasis:names:tc:SAML:2.0:protocol")
+	request.CreateAttr("xmlns:saml", "urn:oasis:names:tc:SAML:2.0:assertion")
+	request.CreateAttr("ID", "_request")
+	request.CreateElement("saml:Issuer").SetText("https://sp.example.com")
+	request.CreateElement("saml:NameID").SetText("alice")
+
+	ctx := dsig.NewDefaultSigningContext(dsig.TLSCertKeyStore(tls.Certificate{Certificate: [][]byte{certificate.Raw}, PrivateKey: privateKey}))
+	ctx.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+	err := signSamlElement(ctx, request)
+	assert.Nil(t, err)
+
+	doc := etree.NewDocument()
+	doc.SetRoot(request)
+	data, err := doc.WriteToBytes()
+	assert.Nil(t, err)
+
+	read := func(data []byte) *etree.Document {
+		doc := etree.NewDocument()
+		err := doc.ReadFromBytes(data)
+		assert.Nil(t, err)
+		return doc
+	}
+
+	el, isSigned, err := verifySamlMessageSignature(sp, read(data), "SAMLRequest", base64.StdEncoding.EncodeToString(data), "")
+	assert.Nil(t, err)
+	assert.True(t, isSigned)
+
+	var logoutRequest SamlLogoutRequest
+	err = unmarshalSamlElement(el, &logoutRequest)
+	assert.Nil(t, err)
+	assert.Equal(t, "_request", logoutRequest.ID)
+	assert.Equal(t, "alice", logoutRequest.NameId)
+
+	tampered := []byte(strings.Replace(string(data), ">alice<", ">bob<", 1))
+	_, _, err = verifySamlMessageSignature(sp, read(tampered), "SAMLRequest", base64.StdEncoding.EncodeToString(tampered), "")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,184 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+const (
+	SamlBindingRedirect = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	SamlBindingPost     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+)
+
+type samlSpEndpoint struct {
+	Binding          string `xml:"Binding,attr"`
+	Location         string `xml:"Location,attr"`
+	ResponseLocation string `xml:"ResponseLocation,attr"`
+	IsDefault        bool   `xml:"isDefault,attr"`
+}
+
+type samlSpKeyDescriptor struct {
+	Use          string   `xml:"use,attr"`
+	Certificates []string `xml:"KeyInfo>X509Data>X509Certificate"`
+}
+
+type samlSpEntityDescriptor struct {
+	XMLName         xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityId        string   `xml:"entityID,attr"`
+	SpSsoDescriptor *struct {
+		AuthnRequestsSigned       bool                   `xml:"AuthnRequestsSigned,attr"`
+		WantAssertionsSigned      bool                   `xml:"WantAssertionsSigned,attr"`
+		KeyDescriptors            []*samlSpKeyDescriptor `xml:"KeyDescriptor"`
+		SingleLogoutServices      []*samlSpEndpoint      `xml:"SingleLogoutService"`
+		NameIdFormats             []string               `xml:"NameIDFormat"`
+		AssertionConsumerServices []*samlSpEndpoint      `xml:"AssertionConsumerService"`
+	} `xml:"SPSSODescriptor"`
+}
+
+// SamlSpMetadata is the metadata of a SAML service provider imported in an application
+type SamlSpMetadata struct {
+	EntityId             string
+	AuthnRequestsSigned  bool
+	WantAssertionsSigned bool
+	NameIdFormats        []string
+
+	// AssertionConsumerServiceUrls are the URLs of the HTTP-POST binding, the default one first
+	AssertionConsumerServiceUrls []string
+
+	SingleLogoutServiceUrl         string
+	SingleLogoutServiceResponseUrl string
+	SingleLogoutServiceBinding     string
+
+	SigningCertificates   []*x509.Certificate
+	EncryptionCertificate *x509.Certificate
+}
+
+func parseSamlCertificate(s string) (*x509.Certificate, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(data)
+}
+
+// ParseSamlSpMetadata parses the SP metadata imported in an application
+func ParseSamlSpMetadata(metadata string) (*SamlSpMetadata, error) {
+	var descriptor samlSpEntityDescriptor
+	err := xml.Unmarshal([]byte(metadata), &descriptor)
+	if err != nil {
+		return nil, fmt.Errorf("the SAML SP metadata is invalid: %s", err.Error())
+	}
+
+	sp := descriptor.SpSsoDescriptor
+	if descriptor.EntityId == "" || sp == nil {
+		return nil, fmt.Errorf("the SAML SP metadata has no entity ID or SPSSODescriptor")
+	}
+
+	res := &SamlSpMetadata{
+		EntityId:             descriptor.EntityId,
+		AuthnRequestsSigned:  sp.AuthnRequestsSigned,
+		WantAssertionsSigned: sp.WantAssertionsSigned,
+		NameIdFormats:        sp.NameIdFormats,
+	}
+
+	for _, keyDescriptor := range sp.KeyDescriptors {
+		for _, s := range keyDescriptor.Certificates {
+			certificate, err := parseSamlCertificate(s)
+			if err != nil {
+				return nil, fmt.Errorf("the certificate of the SAML SP metadata is invalid: %s", err.Error())
+			}
+
+			// a key descriptor without use is used for both signing and encryption
+			if keyDescriptor.Use != "encryption" {
+				res.SigningCertificates = append(res.SigningCertificates, certificate)
+			}
+			if keyDescriptor.Use != "signing" && res.EncryptionCertificate == nil {
+				res.EncryptionCertificate = certificate
+			}
+		}
+	}
+
+	for _, acs := range sp.AssertionConsumerServices {
+		if acs.Binding != SamlBindingPost {
+			continue
+		}
+
+		if acs.IsDefault {
+			res.AssertionConsumerServiceUrls = append([]string{acs.Location}, res.AssertionConsumerServiceUrls...)
+		} else {
+			res.AssertionConsumerServiceUrls = append(res.AssertionConsumerServiceUrls, acs.Location)
+		}
+	}
+
+	// the Redirect binding is preferred as it can also be used by the IdP-initiated logout
+	for _, slo := range sp.SingleLogoutServices {
+		if slo.Binding != SamlBindingRedirect && slo.Binding != SamlBindingPost {
+			continue
+		}
+
+		if res.SingleLogoutServiceUrl == "" || slo.Binding == SamlBindingRedirect {
+			res.SingleLogoutServiceUrl = slo.Location
+			res.SingleLogoutServiceResponseUrl = slo.ResponseLocation
+			res.SingleLogoutServiceBinding = slo.Binding
+		}
+	}
+	if res.SingleLogoutServiceResponseUrl == "" {
+		res.SingleLogoutServiceResponseUrl = res.SingleLogoutServiceUrl
+	}
+
+	return res, nil
+}
+
+// GetSamlSpMetadata returns the imported SP metadata of the application, or nil if there is none
+func (application *Application) GetSamlSpMetadata() (*SamlSpMetadata, error) {
+	if strings.TrimSpace(application.SamlSpMetadata) == "" {
+		return nil, nil
+	}
+
+	return ParseSamlSpMetadata(application.SamlSpMetadata)
+}
+
+// CheckSamlSettings checks the SAML settings of an application before it is saved
+func CheckSamlSettings(application *Application) error {
+	sp, err := application.GetSamlSpMetadata()
+	if err != nil {
+		return err
+	}
+
+	if application.EnableSamlAssertionEncryption && (sp == nil || sp.EncryptionCertificate == nil) {
+		return fmt.Errorf("the encryption of the SAML assertions requires SP metadata with an encryption certificate")
+	}
+
+	if _, ok := samlNameIdFormats[application.SamlNameIdFormat]; !ok {
+		return fmt.Errorf("the SAML NameID format: \"%s\" is not supported", application.SamlNameIdFormat)
+	}
+
+	if _, ok := samlSignatureAlgorithms[application.SamlSignatureAlgorithm]; !ok {
+		return fmt.Errorf("the SAML signature algorithm: \"%s\" is not supported", application.SamlSignatureAlgorithm)
+	}
+
+	switch application.SamlSigningMode {
+	case "", SamlSigningModeResponse, SamlSigningModeAssertion, SamlSigningModeBoth:
+	default:
+		return fmt.Errorf("the SAML signing mode: \"%s\" is not supported", application.SamlSigningMode)
+	}
+
+	return nil
+}
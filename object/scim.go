@@ -0,0 +1,921 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/casdoor/casdoor/util"
+)
+
+const (
+	ScimSchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	ScimSchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	ScimSchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	ScimSchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	ScimSchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	ScimSchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	ScimSchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	ScimSchemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+
+	// UserPropertiesScimExternalId keeps the identifier of the user in the provisioning client
+	UserPropertiesScimExternalId = "scimExternalId"
+
+	ScimMaxResults = 1000
+)
+
+var scimUserColumns = []string{
+	"name", "display_name", "first_name", "last_name", "email", "phone", "avatar", "address",
+	"location", "region", "title", "language", "is_forbidden", "properties", "updated_time",
+}
+
+type ScimError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+func NewScimError(status int, scimType string, detail string) *ScimError {
+	return &ScimError{
+		Schemas:  []string{ScimSchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	}
+}
+
+func (e *ScimError) Error() string {
+	return e.Detail
+}
+
+type ScimMeta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Location     string `json:"location,omitempty"`
+}
+
+type ScimName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+}
+
+type ScimMultiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+type ScimAddress struct {
+	Formatted string `json:"formatted,omitempty"`
+	Locality  string `json:"locality,omitempty"`
+	Region    string `json:"region,omitempty"`
+	Country   string `json:"country,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Primary   bool   `json:"primary,omitempty"`
+}
+
+type ScimUser struct {
+	Schemas           []string          `json:"schemas"`
+	Id                string            `json:"id,omitempty"`
+	ExternalId        string            `json:"externalId,omitempty"`
+	UserName          string            `json:"userName"`
+	Name              *ScimName         `json:"name,omitempty"`
+	DisplayName       string            `json:"displayName,omitempty"`
+	Title             string            `json:"title,omitempty"`
+	PreferredLanguage string            `json:"preferredLanguage,omitempty"`
+	Active            *bool             `json:"active,omitempty"`
+	Password          string            `json:"password,omitempty"`
+	Emails            []*ScimMultiValue `json:"emails,omitempty"`
+	PhoneNumbers      []*ScimMultiValue `json:"phoneNumbers,omitempty"`
+	Photos            []*ScimMultiValue `json:"photos,omitempty"`
+	Addresses         []*ScimAddress    `json:"addresses,omitempty"`
+	Groups            []*ScimMultiValue `json:"groups,omitempty"`
+	Meta              *ScimMeta         `json:"meta,omitempty"`
+}
+
+type ScimGroup struct {
+	Schemas     []string          `json:"schemas"`
+	Id          string            `json:"id,omitempty"`
+	ExternalId  string            `json:"externalId,omitempty"`
+	DisplayName string            `json:"displayName"`
+	Members     []*ScimMultiValue `json:"members,omitempty"`
+	Meta        *ScimMeta         `json:"meta,omitempty"`
+}
+
+type ScimListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+func getScimBaseUrl(host string, organization string) string {
+	_, originBackend := getOriginFromHost(host)
+	return fmt.Sprintf("%s/scim/v2/%s", originBackend, organization)
+}
+
+func checkScimOrganization(organization string) error {
+	org, err := getOrganization("admin", organization)
+	if err != nil {
+		return err
+	}
+	if org == nil {
+		return NewScimError(404, "", fmt.Sprintf("the organization: %s is not found", organization))
+	}
+	return nil
+}
+
+func getScimPrimaryValue(values []*ScimMultiValue) string {
+	for _, value := range values {
+		if value != nil && value.Primary {
+			return value.Value
+		}
+	}
+	for _, value := range values {
+		if value != nil {
+			return value.Value
+		}
+	}
+	return ""
+}
+
+// toScimMap converts a SCIM resource into its JSON object form, which is what filters and PATCH operate on
+func toScimMap(resource interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	m := map[string]interface{}{}
+	err = json.Unmarshal(data, &m)
+	return m, err
+}
+
+// fromScimMap is the reverse of toScimMap, tolerating the string booleans some clients send, e.g. "active": "False"
+func fromScimMap(m map[string]interface{}, resource interface{}) error {
+	normalizeScimBooleans(m)
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(data, resource)
+	if err != nil {
+		return NewScimError(400, "invalidValue", err.Error())
+	}
+	return nil
+}
+
+func normalizeScimBooleans(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			if s, ok := item.(string); ok && (strings.EqualFold(k, "active") || strings.EqualFold(k, "primary")) {
+				if b, err := strconv.ParseBool(s); err == nil {
+					v[k] = b
+				}
+				continue
+			}
+			normalizeScimBooleans(item)
+		}
+	case []interface{}:
+		for _, item := range v {
+			normalizeScimBooleans(item)
+		}
+	}
+}
+
+func filterScimResources[T any](resources []T, filter string) ([]T, error) {
+	if filter == "" {
+		return resources, nil
+	}
+
+	f, err := parseScimFilter(filter)
+	if err != nil {
+		return nil, NewScimError(400, "invalidFilter", err.Error())
+	}
+
+	return matchScimResources(resources, f)
+}
+
+func matchScimResources[T any](resources []T, f scimFilter) ([]T, error) {
+	res := []T{}
+	for _, resource := range resources {
+		m, err := toScimMap(resource)
+		if err != nil {
+			return nil, err
+		}
+		if f.match(m) {
+			res = append(res, resource)
+		}
+	}
+	return res, nil
+}
+
+func getScimPage(startIndex int, count int) (int, int) {
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count < 0 || count > ScimMaxResults {
+		count = ScimMaxResults
+	}
+	return startIndex, count
+}
+
+func newScimListResponse[T any](resources []T, startIndex int, count int) *ScimListResponse {
+	startIndex, count = getScimPage(startIndex, count)
+
+	page := []T{}
+	for i := startIndex - 1; i < len(resources) && len(page) < count; i++ {
+		page = append(page, resources[i])
+	}
+
+	return newScimListPageResponse(page, len(resources), startIndex)
+}
+
+// newScimListPageResponse returns the page of the resources starting at startIndex, out of totalResults
+func newScimListPageResponse[T any](page []T, totalResults int, startIndex int) *ScimListResponse {
+	resources := []interface{}{}
+	for _, resource := range page {
+		resources = append(resources, resource)
+	}
+
+	return &ScimListResponse{
+		Schemas:      []string{ScimSchemaListResponse},
+		TotalResults: totalResults,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+func getScimGroupMap(organization string) (map[string]*Group, error) {
+	groups, err := GetGroups(organization)
+	if err != nil {
+		return nil, err
+	}
+
+	m := map[string]*Group{}
+	for _, group := range groups {
+		m[group.GetId()] = group
+	}
+	return m, nil
+}
+
+func userToScimUser(user *User, groupMap map[string]*Group, baseUrl string) *ScimUser {
+	active := !user.IsForbidden
+	scimUser := &ScimUser{
+		Schemas:           []string{ScimSchemaUser},
+		Id:                user.Id,
+		ExternalId:        user.Properties[UserPropertiesScimExternalId],
+		UserName:          user.Name,
+		DisplayName:       user.DisplayName,
+		Title:             user.Title,
+		PreferredLanguage: user.Language,
+		Active:            &active,
+		Meta: &ScimMeta{
+			ResourceType: "User",
+			Created:      user.CreatedTime,
+			LastModified: user.UpdatedTime,
+			Location:     fmt.Sprintf("%s/Users/%s", baseUrl, user.Id),
+		},
+	}
+
+	if user.FirstName != "" || user.LastName != "" || user.DisplayName != "" {
+		scimUser.Name = &ScimName{
+			Formatted:  user.DisplayName,
+			FamilyName: user.LastName,
+			GivenName:  user.FirstName,
+		}
+	}
+	if user.Email != "" {
+		scimUser.Emails = []*ScimMultiValue{{Value: user.Email, Type: "work", Primary: true}}
+	}
+	if user.Phone != "" {
+		scimUser.PhoneNumbers = []*ScimMultiValue{{Value: user.Phone, Type: "work", Primary: true}}
+	}
+	if user.Avatar != "" {
+		scimUser.Photos = []*ScimMultiValue{{Value: user.Avatar, Type: "photo", Primary: true}}
+	}
+	if address := strings.Join(user.Address, " "); address != "" || user.Location != "" || user.Region != "" {
+		scimUser.Addresses = []*ScimAddress{{Formatted: address, Locality: user.Location, Region: user.Region, Type: "work", Primary: true}}
+	}
+
+	for _, groupId := range user.Groups {
+		_, groupName := util.GetOwnerAndNameFromIdNoCheck(groupId)
+		member := &ScimMultiValue{
+			Value: groupName,
+			Ref:   fmt.Sprintf("%s/Groups/%s", baseUrl, groupName),
+		}
+		if group, ok := groupMap[groupId]; ok {
+			member.Display = group.DisplayName
+		}
+		scimUser.Groups = append(scimUser.Groups, member)
+	}
+
+	return scimUser
+}
+
+// applyScimUser copies the writable SCIM attributes into the user, as a full replacement of these attributes
+func applyScimUser(user *User, scimUser *ScimUser) {
+	user.Name = scimUser.UserName
+	user.DisplayName = scimUser.DisplayName
+	user.FirstName = ""
+	user.LastName = ""
+	if scimUser.Name != nil {
+		user.FirstName = scimUser.Name.GivenName
+		user.LastName = scimUser.Name.FamilyName
+		if user.DisplayName == "" {
+			user.DisplayName = scimUser.Name.Formatted
+		}
+		if user.DisplayName == "" {
+			user.DisplayName = strings.TrimSpace(fmt.Sprintf("%s %s", scimUser.Name.GivenName, scimUser.Name.FamilyName))
+		}
+	}
+
+	user.Title = scimUser.Title
+	user.Language = scimUser.PreferredLanguage
+	user.Email = getScimPrimaryValue(scimUser.Emails)
+	user.Phone = getScimPrimaryValue(scimUser.PhoneNumbers)
+	if avatar := getScimPrimaryValue(scimUser.Photos); avatar != "" {
+		user.Avatar = avatar
+	}
+
+	user.Address = []string{}
+	user.Location = ""
+	user.Region = ""
+	for _, address := range scimUser.Addresses {
+		if address == nil {
+			continue
+		}
+		if address.Formatted != "" {
+			user.Address = []string{address.Formatted}
+		}
+		user.Location = address.Locality
+		user.Region = address.Region
+		if address.Primary {
+			break
+		}
+	}
+
+	if scimUser.Active != nil {
+		user.IsForbidden = !*scimUser.Active
+	}
+
+	if user.Properties == nil {
+		user.Properties = map[string]string{}
+	}
+	if scimUser.ExternalId != "" {
+		user.Properties[UserPropertiesScimExternalId] = scimUser.ExternalId
+	} else {
+		delete(user.Properties, UserPropertiesScimExternalId)
+	}
+}
+
+func getScimUserById(organization string, id string) (*User, error) {
+	err := checkScimOrganization(organization)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := getUserById(organization, id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, NewScimError(404, "", fmt.Sprintf("the user: %s is not found", id))
+	}
+	return user, nil
+}
+
+// getScimUserNameFilter returns the user name of the filter `userName eq "<name>"`, the only filter
+// looked up in the database, the user names are compared case-insensitively like in the filters
+func getScimUserNameFilter(f scimFilter) (string, bool) {
+	attrFilter, ok := f.(*scimAttrFilter)
+	if !ok || attrFilter.op != "eq" || !strings.EqualFold(attrFilter.path.attr, "userName") || attrFilter.path.subAttr != "" {
+		return "", false
+	}
+
+	userName, ok := attrFilter.value.(string)
+	return userName, ok
+}
+
+// getScimUsersPage returns the page of the users of the organization, and the count of the users
+func getScimUsersPage(organization string, startIndex int, count int) ([]*User, int64, error) {
+	total, err := ormer.Engine.Count(&User{Owner: organization})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	users := []*User{}
+	if count == 0 {
+		return users, total, nil
+	}
+
+	err = ormer.Engine.Desc("created_time").Asc("name").Limit(count, startIndex-1).Find(&users, &User{Owner: organization})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// getScimFilteredUsers returns the users of the organization matching the filter
+func getScimFilteredUsers(organization string, filter string, groupMap map[string]*Group, baseUrl string) ([]*ScimUser, error) {
+	f, err := parseScimFilter(filter)
+	if err != nil {
+		return nil, NewScimError(400, "invalidFilter", err.Error())
+	}
+
+	scimUsers := []*ScimUser{}
+	if userName, ok := getScimUserNameFilter(f); ok {
+		users := []*User{}
+		err = ormer.Engine.Where("owner = ? and lower(name) = ?", organization, strings.ToLower(userName)).Find(&users)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, user := range users {
+			scimUsers = append(scimUsers, userToScimUser(user, groupMap, baseUrl))
+		}
+		return scimUsers, nil
+	}
+
+	users, err := GetUsers(organization)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		scimUsers = append(scimUsers, userToScimUser(user, groupMap, baseUrl))
+	}
+	return matchScimResources(scimUsers, f)
+}
+
+// GetScimUsers returns the users of the organization matching the filter. Without filter, the users are
+// paginated by the database, and the filter `userName eq "<name>"` is looked up in the database too.
+func GetScimUsers(organization string, filter string, startIndex int, count int, host string) (*ScimListResponse, error) {
+	err := checkScimOrganization(organization)
+	if err != nil {
+		return nil, err
+	}
+
+	groupMap, err := getScimGroupMap(organization)
+	if err != nil {
+		return nil, err
+	}
+
+	baseUrl := getScimBaseUrl(host, organization)
+	if filter != "" {
+		scimUsers, err := getScimFilteredUsers(organization, filter, groupMap, baseUrl)
+		if err != nil {
+			return nil, err
+		}
+
+		return newScimListResponse(scimUsers, startIndex, count), nil
+	}
+
+	startIndex, count = getScimPage(startIndex, count)
+	users, total, err := getScimUsersPage(organization, startIndex, count)
+	if err != nil {
+		return nil, err
+	}
+
+	scimUsers := []*ScimUser{}
+	for _, user := range users {
+		scimUsers = append(scimUsers, userToScimUser(user, groupMap, baseUrl))
+	}
+
+	return newScimListPageResponse(scimUsers, int(total), startIndex), nil
+}
+
+func GetScimUser(organization string, id string, host string) (*ScimUser, error) {
+	user, err := getScimUserById(organization, id)
+	if err != nil {
+		return nil, err
+	}
+
+	groupMap, err := getScimGroupMap(organization)
+	if err != nil {
+		return nil, err
+	}
+
+	return userToScimUser(user, groupMap, getScimBaseUrl(host, organization)), nil
+}
+
+func AddScimUser(organization string, scimUser *ScimUser, host string) (*ScimUser, error) {
+	err := checkScimOrganization(organization)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg := CheckUsername(scimUser.UserName, "en"); msg != "" {
+		return nil, NewScimError(400, "invalidValue", msg)
+	}
+
+	existingUser, err := getUser(organization, scimUser.UserName)
+	if err != nil {
+		return nil, err
+	}
+	if existingUser != nil {
+		return nil, NewScimError(409, "uniqueness", fmt.Sprintf("the user: %s already exists", scimUser.UserName))
+	}
+
+	org, err := getOrganization("admin", organization)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &User{
+		Owner:             organization,
+		CreatedTime:       util.GetCurrentTime(),
+		Type:              "normal-user",
+		Avatar:            org.DefaultAvatar,
+		SignupApplication: org.DefaultApplication,
+		Password:          scimUser.Password,
+		Properties:        map[string]string{},
+	}
+	applyScimUser(user, scimUser)
+
+	affected, err := AddUser(user)
+	if err != nil {
+		return nil, err
+	}
+	if !affected {
+		return nil, NewScimError(400, "invalidValue", fmt.Sprintf("failed to add the user: %s", scimUser.UserName))
+	}
+
+	return GetScimUser(organization, user.Id, host)
+}
+
+func updateScimUser(user *User, scimUser *ScimUser) error {
+	oldId := user.GetId()
+	oldName := user.Name
+	applyScimUser(user, scimUser)
+
+	if user.Name != oldName {
+		if msg := CheckUsername(user.Name, "en"); msg != "" {
+			return NewScimError(400, "invalidValue", msg)
+		}
+		existingUser, err := getUser(user.Owner, user.Name)
+		if err != nil {
+			return err
+		}
+		if existingUser != nil {
+			return NewScimError(409, "uniqueness", fmt.Sprintf("the user: %s already exists", user.Name))
+		}
+	}
+
+	columns := append([]string{}, scimUserColumns...)
+	if scimUser.Password != "" {
+		org, err := getOrganization("admin", user.Owner)
+		if err != nil {
+			return err
+		}
+
+		user.Password = scimUser.Password
+		user.UpdateUserPassword(org)
+		columns = append(columns, "password", "password_salt", "password_type")
+	}
+
+	user.UpdatedTime = util.GetCurrentTime()
+	_, err := UpdateUser(oldId, user, columns, false)
+	return err
+}
+
+func UpdateScimUser(organization string, id string, scimUser *ScimUser, host string) (*ScimUser, error) {
+	user, err := getScimUserById(organization, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = updateScimUser(user, scimUser)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetScimUser(organization, id, host)
+}
+
+func PatchScimUser(organization string, id string, patch *ScimPatchRequest, host string) (*ScimUser, error) {
+	user, err := getScimUserById(organization, id)
+	if err != nil {
+		return nil, err
+	}
+
+	groupMap, err := getScimGroupMap(organization)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := toScimMap(userToScimUser(user, groupMap, getScimBaseUrl(host, organization)))
+	if err != nil {
+		return nil, err
+	}
+
+	err = applyScimPatch(m, patch.Operations)
+	if err != nil {
+		return nil, err
+	}
+
+	scimUser := &ScimUser{}
+	err = fromScimMap(m, scimUser)
+	if err != nil {
+		return nil, err
+	}
+
+	err = updateScimUser(user, scimUser)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetScimUser(organization, id, host)
+}
+
+func DeleteScimUser(organization string, id string) error {
+	user, err := getScimUserById(organization, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = DeleteUser(user)
+	return err
+}
+
+func groupToScimGroup(group *Group, baseUrl string) (*ScimGroup, error) {
+	users, err := GetGroupUsers(group.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	scimGroup := &ScimGroup{
+		Schemas:     []string{ScimSchemaGroup},
+		Id:          group.Name,
+		DisplayName: group.DisplayName,
+		Members:     []*ScimMultiValue{},
+		Meta: &ScimMeta{
+			ResourceType: "Group",
+			Created:      group.CreatedTime,
+			LastModified: group.UpdatedTime,
+			Location:     fmt.Sprintf("%s/Groups/%s", baseUrl, group.Name),
+		},
+	}
+
+	for _, user := range users {
+		scimGroup.Members = append(scimGroup.Members, &ScimMultiValue{
+			Value:   user.Id,
+			Display: user.Name,
+			Type:    "User",
+			Ref:     fmt.Sprintf("%s/Users/%s", baseUrl, user.Id),
+		})
+	}
+
+	return scimGroup, nil
+}
+
+func getScimGroup(organization string, id string) (*Group, error) {
+	err := checkScimOrganization(organization)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := getGroup(organization, id)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, NewScimError(404, "", fmt.Sprintf("the group: %s is not found", id))
+	}
+	return group, nil
+}
+
+// setScimGroupMembers makes the given user ids the only members of the group
+func setScimGroupMembers(group *Group, members []*ScimMultiValue) error {
+	groupId := group.GetId()
+
+	memberIds := map[string]bool{}
+	for _, member := range members {
+		if member != nil {
+			memberIds[member.Value] = true
+		}
+	}
+
+	users, err := GetGroupUsers(groupId)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if memberIds[user.Id] {
+			delete(memberIds, user.Id)
+			continue
+		}
+
+		user.Groups = util.DeleteVal(user.Groups, groupId)
+		_, err = UpdateUser(user.GetId(), user, []string{"groups"}, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	for userId := range memberIds {
+		user, err := getUserById(group.Owner, userId)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return NewScimError(400, "invalidValue", fmt.Sprintf("the member: %s is not found", userId))
+		}
+
+		if !util.InSlice(user.Groups, groupId) {
+			user.Groups = append(user.Groups, groupId)
+			_, err = UpdateUser(user.GetId(), user, []string{"groups"}, false)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func GetScimGroups(organization string, filter string, startIndex int, count int, host string) (*ScimListResponse, error) {
+	err := checkScimOrganization(organization)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := GetGroups(organization)
+	if err != nil {
+		return nil, err
+	}
+
+	baseUrl := getScimBaseUrl(host, organization)
+	scimGroups := []*ScimGroup{}
+	for _, group := range groups {
+		scimGroup, err := groupToScimGroup(group, baseUrl)
+		if err != nil {
+			return nil, err
+		}
+		scimGroups = append(scimGroups, scimGroup)
+	}
+
+	scimGroups, err = filterScimResources(scimGroups, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return newScimListResponse(scimGroups, startIndex, count), nil
+}
+
+func GetScimGroup(organization string, id string, host string) (*ScimGroup, error) {
+	group, err := getScimGroup(organization, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupToScimGroup(group, getScimBaseUrl(host, organization))
+}
+
+func AddScimGroup(organization string, scimGroup *ScimGroup, host string) (*ScimGroup, error) {
+	err := checkScimOrganization(organization)
+	if err != nil {
+		return nil, err
+	}
+
+	name := scimGroup.DisplayName
+	if name == "" || strings.Contains(name, "/") {
+		return nil, NewScimError(400, "invalidValue", fmt.Sprintf("invalid displayName: %s", name))
+	}
+
+	existingGroup, err := getGroup(organization, name)
+	if err != nil {
+		return nil, err
+	}
+	if existingGroup != nil {
+		return nil, NewScimError(409, "uniqueness", fmt.Sprintf("the group: %s already exists", name))
+	}
+
+	group := &Group{
+		Owner:       organization,
+		Name:        name,
+		CreatedTime: util.GetCurrentTime(),
+		UpdatedTime: util.GetCurrentTime(),
+		DisplayName: scimGroup.DisplayName,
+		ParentId:    organization,
+		IsTopGroup:  true,
+		IsEnabled:   true,
+	}
+
+	_, err = AddGroup(group)
+	if err != nil {
+		return nil, NewScimError(400, "invalidValue", err.Error())
+	}
+
+	err = setScimGroupMembers(group, scimGroup.Members)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetScimGroup(organization, group.Name, host)
+}
+
+func updateScimGroup(group *Group, scimGroup *ScimGroup) error {
+	if scimGroup.DisplayName != "" && scimGroup.DisplayName != group.DisplayName {
+		group.DisplayName = scimGroup.DisplayName
+		group.UpdatedTime = util.GetCurrentTime()
+		_, err := UpdateGroup(group.GetId(), group)
+		if err != nil {
+			return err
+		}
+	}
+
+	return setScimGroupMembers(group, scimGroup.Members)
+}
+
+func UpdateScimGroup(organization string, id string, scimGroup *ScimGroup, host string) (*ScimGroup, error) {
+	group, err := getScimGroup(organization, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = updateScimGroup(group, scimGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetScimGroup(organization, id, host)
+}
+
+func PatchScimGroup(organization string, id string, patch *ScimPatchRequest, host string) (*ScimGroup, error) {
+	group, err := getScimGroup(organization, id)
+	if err != nil {
+		return nil, err
+	}
+
+	scimGroup, err := groupToScimGroup(group, getScimBaseUrl(host, organization))
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := toScimMap(scimGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	err = applyScimPatch(m, patch.Operations)
+	if err != nil {
+		return nil, err
+	}
+
+	scimGroup = &ScimGroup{}
+	err = fromScimMap(m, scimGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	err = updateScimGroup(group, scimGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetScimGroup(organization, id, host)
+}
+
+// DeleteScimGroup deletes the group before removing its members, so that the members are kept
+// if the group can't be deleted
+func DeleteScimGroup(organization string, id string) error {
+	group, err := getScimGroup(organization, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = deleteGroup(group, true)
+	if err != nil {
+		return NewScimError(400, "mutability", err.Error())
+	}
+
+	return setScimGroupMembers(group, nil)
+}
@@ -0,0 +1,474 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// scimFilter is a parsed SCIM filter expression, see RFC 7644 section 3.4.2.2
+type scimFilter interface {
+	match(resource map[string]interface{}) bool
+}
+
+type scimLogicalFilter struct {
+	op    string
+	left  scimFilter
+	right scimFilter
+}
+
+type scimNotFilter struct {
+	filter scimFilter
+}
+
+type scimAttrFilter struct {
+	path  scimAttrPath
+	op    string
+	value interface{}
+}
+
+type scimValuePathFilter struct {
+	attr   string
+	filter scimFilter
+}
+
+type scimAttrPath struct {
+	attr    string
+	subAttr string
+}
+
+var scimCompareOps = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true,
+	"gt": true, "ge": true, "lt": true, "le": true,
+}
+
+func (f *scimLogicalFilter) match(resource map[string]interface{}) bool {
+	if f.op == "and" {
+		return f.left.match(resource) && f.right.match(resource)
+	}
+	return f.left.match(resource) || f.right.match(resource)
+}
+
+func (f *scimNotFilter) match(resource map[string]interface{}) bool {
+	return !f.filter.match(resource)
+}
+
+func (f *scimValuePathFilter) match(resource map[string]interface{}) bool {
+	_, value := getScimMapValue(resource, f.attr)
+	for _, item := range toScimSlice(value) {
+		if m, ok := item.(map[string]interface{}); ok && f.filter.match(m) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *scimAttrFilter) match(resource map[string]interface{}) bool {
+	values := f.path.values(resource)
+
+	switch f.op {
+	case "pr":
+		for _, value := range values {
+			if !isScimValueEmpty(value) {
+				return true
+			}
+		}
+		return false
+	case "ne":
+		return !(&scimAttrFilter{path: f.path, op: "eq", value: f.value}).match(resource)
+	}
+
+	if f.value == nil {
+		// "attr eq null" is the same as "not (attr pr)"
+		return f.op == "eq" && !(&scimAttrFilter{path: f.path, op: "pr"}).match(resource)
+	}
+
+	for _, value := range values {
+		if compareScimValue(value, f.op, f.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// values returns every value addressed by the path. Multi-valued attributes
+// yield one value per element, and complex elements without an explicit
+// sub-attribute are compared by their "value" sub-attribute.
+func (p scimAttrPath) values(resource map[string]interface{}) []interface{} {
+	_, value := getScimMapValue(resource, p.attr)
+	if value == nil {
+		return nil
+	}
+
+	subAttr := p.subAttr
+	res := []interface{}{}
+	for _, item := range toScimSlice(value) {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			if subAttr == "" {
+				res = append(res, item)
+			}
+			continue
+		}
+
+		key := subAttr
+		if key == "" {
+			key = "value"
+		}
+		if _, v := getScimMapValue(m, key); v != nil {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func toScimSlice(value interface{}) []interface{} {
+	if value == nil {
+		return nil
+	}
+	if s, ok := value.([]interface{}); ok {
+		return s
+	}
+	return []interface{}{value}
+}
+
+func isScimValueEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// getScimMapValue looks up an attribute case-insensitively, as SCIM attribute names are case-insensitive
+func getScimMapValue(m map[string]interface{}, name string) (string, interface{}) {
+	if v, ok := m[name]; ok {
+		return name, v
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, name) {
+			return k, v
+		}
+	}
+	return "", nil
+}
+
+func compareScimValue(actual interface{}, op string, expected interface{}) bool {
+	switch e := expected.(type) {
+	case string:
+		a, ok := actual.(string)
+		if !ok {
+			a = fmt.Sprintf("%v", actual)
+		}
+		a = strings.ToLower(a)
+		e = strings.ToLower(e)
+		switch op {
+		case "eq":
+			return a == e
+		case "co":
+			return strings.Contains(a, e)
+		case "sw":
+			return strings.HasPrefix(a, e)
+		case "ew":
+			return strings.HasSuffix(a, e)
+		case "gt":
+			return a > e
+		case "ge":
+			return a >= e
+		case "lt":
+			return a < e
+		case "le":
+			return a <= e
+		}
+	case bool:
+		a, ok := actual.(bool)
+		if !ok {
+			s, isString := actual.(string)
+			if !isString {
+				return false
+			}
+			var err error
+			if a, err = strconv.ParseBool(s); err != nil {
+				return false
+			}
+		}
+		return op == "eq" && a == e
+	case float64:
+		var a float64
+		switch v := actual.(type) {
+		case float64:
+			a = v
+		case json.Number:
+			a, _ = v.Float64()
+		case string:
+			var err error
+			if a, err = strconv.ParseFloat(v, 64); err != nil {
+				return false
+			}
+		default:
+			return false
+		}
+		switch op {
+		case "eq":
+			return a == e
+		case "gt":
+			return a > e
+		case "ge":
+			return a >= e
+		case "lt":
+			return a < e
+		case "le":
+			return a <= e
+		}
+	}
+	return false
+}
+
+// parseScimAttrPath parses "name.givenName" or
+// "urn:ietf:params:scim:schemas:core:2.0:User:name.givenName"
+func parseScimAttrPath(s string) scimAttrPath {
+	if strings.HasPrefix(strings.ToLower(s), "urn:") {
+		s = s[strings.LastIndex(s, ":")+1:]
+	}
+
+	tokens := strings.SplitN(s, ".", 2)
+	path := scimAttrPath{attr: tokens[0]}
+	if len(tokens) == 2 {
+		path.subAttr = tokens[1]
+	}
+	return path
+}
+
+type scimFilterParser struct {
+	tokens []string
+	pos    int
+}
+
+func parseScimFilter(s string) (scimFilter, error) {
+	tokens, err := tokenizeScimFilter(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("the filter is empty")
+	}
+
+	p := &scimFilterParser{tokens: tokens}
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("unexpected token \"%s\" in filter", p.tokens[p.pos])
+	}
+	return filter, nil
+}
+
+func tokenizeScimFilter(s string) ([]string, error) {
+	tokens := []string{}
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')' || c == '[' || c == ']':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string in filter")
+			}
+			tokens = append(tokens, s[i:j+1])
+			i = j + 1
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\n\r()[]\"", rune(s[j])) {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+func (p *scimFilterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *scimFilterParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *scimFilterParser) expect(token string) error {
+	if t := p.next(); t != token {
+		return fmt.Errorf("expected \"%s\" but got \"%s\" in filter", token, t)
+	}
+	return nil
+}
+
+func (p *scimFilterParser) parseOr() (scimFilter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for strings.EqualFold(p.peek(), "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &scimLogicalFilter{op: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *scimFilterParser) parseAnd() (scimFilter, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for strings.EqualFold(p.peek(), "and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &scimLogicalFilter{op: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *scimFilterParser) parseNot() (scimFilter, error) {
+	if strings.EqualFold(p.peek(), "not") {
+		p.next()
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(")"); err != nil {
+			return nil, err
+		}
+		return &scimNotFilter{filter: filter}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *scimFilterParser) parsePrimary() (scimFilter, error) {
+	token := p.next()
+	if token == "" {
+		return nil, fmt.Errorf("unexpected end of filter")
+	}
+
+	if token == "(" {
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(")"); err != nil {
+			return nil, err
+		}
+		return filter, nil
+	}
+
+	if strings.ContainsAny(token, "()[]\"") {
+		return nil, fmt.Errorf("invalid attribute path \"%s\" in filter", token)
+	}
+
+	if p.peek() == "[" {
+		p.next()
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect("]"); err != nil {
+			return nil, err
+		}
+		return &scimValuePathFilter{attr: parseScimAttrPath(token).attr, filter: filter}, nil
+	}
+
+	path := parseScimAttrPath(token)
+	op := strings.ToLower(p.next())
+	if op == "pr" {
+		return &scimAttrFilter{path: path, op: op}, nil
+	}
+	if !scimCompareOps[op] {
+		return nil, fmt.Errorf("unsupported operator \"%s\" in filter", op)
+	}
+
+	value, err := parseScimFilterValue(p.next())
+	if err != nil {
+		return nil, err
+	}
+	return &scimAttrFilter{path: path, op: op, value: value}, nil
+}
+
+func parseScimFilterValue(token string) (interface{}, error) {
+	if token == "" {
+		return nil, fmt.Errorf("missing comparison value in filter")
+	}
+
+	if strings.HasPrefix(token, "\"") {
+		var s string
+		err := json.Unmarshal([]byte(token), &s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s in filter", token)
+		}
+		return s, nil
+	}
+
+	switch strings.ToLower(token) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+
+	f, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid comparison value \"%s\" in filter", token)
+	}
+	return f, nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestScimUserMap(t *testing.T) map[string]interface{} {
+	active := true
+	m, err := toScimMap(&ScimUser{
+		Schemas:     []string{ScimSchemaUser},
+		Id:          "b1a1e2f0",
+		UserName:    "alice",
+		DisplayName: "Alice Smith",
+		Name:        &ScimName{GivenName: "Alice", FamilyName: "Smith"},
+		Active:      &active,
+		Emails: []*ScimMultiValue{
+			{Value: "alice@example.com", Type: "work", Primary: true},
+			{Value: "alice@home.example.com", Type: "home"},
+		},
+		Meta: &ScimMeta{ResourceType: "User", Created: "2024-01-02T03:04:05Z"},
+	})
+	assert.Nil(t, err)
+	return m
+}
+
+func TestScimFilter(t *testing.T) {
+	m := getTestScimUserMap(t)
+
+	for filter, expected := range map[string]bool{
+		`userName eq "alice"`:                                true,
+		`UserName eq "ALICE"`:                                true,
+		`userName ne "alice"`:                                false,
+		`userName sw "al" and displayName ew "smith"`:        true,
+		`userName eq "bob" or name.givenName co "lic"`:       true,
+		`not (userName eq "alice")`:                          false,
+		`title pr`:                                           false,
+		`title eq null`:                                      true,
+		`active eq true`:                                     true,
+		`emails co "home.example"`:                           true,
+		`emails.type eq "home"`:                              true,
+		`emails[type eq "work" and value ew "@example.com"]`: true,
+		`emails[type eq "other"]`:                            false,
+		`meta.created gt "2023-12-31T00:00:00Z"`:             true,
+		`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "alice"`: true,
+		`(userName eq "bob" or userName eq "alice") and active eq false`: false,
+	} {
+		f, err := parseScimFilter(filter)
+		assert.Nil(t, err, filter)
+		assert.Equal(t, expected, f.match(m), filter)
+	}
+
+	for _, filter := range []string{
+		``,
+		`userName`,
+		`userName eq`,
+		`userName xx "alice"`,
+		`(userName eq "alice"`,
+		`userName eq "alice`,
+		`userName eq "alice" and`,
+	} {
+		_, err := parseScimFilter(filter)
+		assert.NotNil(t, err, filter)
+	}
+}
+
+func TestScimUserNameFilter(t *testing.T) {
+	scenarios := []struct {
+		filter   string
+		userName string
+		ok       bool
+	}{
+		{`userName eq "Alice"`, "Alice", true},
+		{`USERNAME eq "alice"`, "alice", true},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "alice"`, "alice", true},
+		{`userName sw "a"`, "", false},
+		{`userName eq "alice" and active eq true`, "", false},
+		{`emails.value eq "alice@example.com"`, "", false},
+		{`userName eq null`, "", false},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.filter, func(t *testing.T) {
+			f, err := parseScimFilter(scenario.filter)
+			assert.Nil(t, err)
+
+			userName, ok := getScimUserNameFilter(f)
+			assert.Equal(t, scenario.ok, ok)
+			assert.Equal(t, scenario.userName, userName)
+		})
+	}
+}
+
+func TestScimListResponse(t *testing.T) {
+	resources := []string{"a", "b", "c"}
+
+	res := newScimListResponse(resources, 2, 1)
+	assert.Equal(t, 3, res.TotalResults)
+	assert.Equal(t, 2, res.StartIndex)
+	assert.Equal(t, []interface{}{"b"}, res.Resources)
+
+	res = newScimListResponse(resources, 0, -1)
+	assert.Equal(t, 1, res.StartIndex)
+	assert.Equal(t, 3, res.ItemsPerPage)
+
+	// the page of the resources paginated by the database
+	res = newScimListPageResponse([]string{"c"}, 3, 3)
+	assert.Equal(t, 3, res.TotalResults)
+	assert.Equal(t, 3, res.StartIndex)
+	assert.Equal(t, 1, res.ItemsPerPage)
+	assert.Equal(t, []interface{}{"c"}, res.Resources)
+}
+
+func TestScimPatch(t *testing.T) {
+	m := getTestScimUserMap(t)
+
+	err := applyScimPatch(m, []*ScimPatchOperation{
+		{Op: "Replace", Path: "name.givenName", Value: "Alicia"},
+		{Op: "replace", Path: `emails[type eq "work"].value`, Value: "alicia@example.com"},
+		{Op: "add", Path: `phoneNumbers[type eq "mobile"].value`, Value: "+10000000000"},
+		{Op: "remove", Path: `emails[type eq "home"]`},
+		{Op: "replace", Value: map[string]interface{}{"active": "False", "title": "Engineer"}},
+	})
+	assert.Nil(t, err)
+
+	scimUser := &ScimUser{}
+	err = fromScimMap(m, scimUser)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "Alicia", scimUser.Name.GivenName)
+	assert.Equal(t, "Engineer", scimUser.Title)
+	assert.False(t, *scimUser.Active)
+	assert.Equal(t, 1, len(scimUser.Emails))
+	assert.Equal(t, "alicia@example.com", scimUser.Emails[0].Value)
+	assert.Equal(t, 1, len(scimUser.PhoneNumbers))
+	assert.Equal(t, "mobile", scimUser.PhoneNumbers[0].Type)
+	assert.Equal(t, "+10000000000", scimUser.PhoneNumbers[0].Value)
+
+	err = applyScimPatch(m, []*ScimPatchOperation{{Op: "remove"}})
+	assert.NotNil(t, err)
+}
+
+func TestScimPatchGroupMembers(t *testing.T) {
+	m, err := toScimMap(&ScimGroup{
+		Schemas:     []string{ScimSchemaGroup},
+		Id:          "admins",
+		DisplayName: "Admins",
+		Members:     []*ScimMultiValue{{Value: "1"}, {Value: "2"}},
+	})
+	assert.Nil(t, err)
+
+	err = applyScimPatch(m, []*ScimPatchOperation{
+		{Op: "add", Path: "members", Value: []interface{}{map[string]interface{}{"value": "3"}, map[string]interface{}{"value": "1"}}},
+		{Op: "remove", Path: `members[value eq "2"]`},
+		{Op: "remove", Path: "members", Value: []interface{}{map[string]interface{}{"value": "1"}}},
+	})
+	assert.Nil(t, err)
+
+	scimGroup := &ScimGroup{}
+	err = fromScimMap(m, scimGroup)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 1, len(scimGroup.Members))
+	assert.Equal(t, "3", scimGroup.Members[0].Value)
+}
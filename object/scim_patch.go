@@ -0,0 +1,286 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ScimPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+type ScimPatchRequest struct {
+	Schemas    []string              `json:"schemas"`
+	Operations []*ScimPatchOperation `json:"Operations"`
+}
+
+// scimPatchPath is the PATH of a PATCH operation, e.g. `emails[type eq "work"].value`
+type scimPatchPath struct {
+	attr    string
+	filter  scimFilter
+	subAttr string
+}
+
+func parseScimPatchPath(s string) (*scimPatchPath, error) {
+	base, rest := s, ""
+	if i := strings.Index(s, "["); i >= 0 {
+		j := strings.LastIndex(s, "]")
+		if j < i {
+			return nil, NewScimError(400, "invalidPath", fmt.Sprintf("invalid path: %s", s))
+		}
+		base, rest = s[:i], s[j+1:]
+
+		filter, err := parseScimFilter(s[i+1 : j])
+		if err != nil {
+			return nil, NewScimError(400, "invalidPath", err.Error())
+		}
+
+		path := &scimPatchPath{attr: parseScimAttrPath(base).attr, filter: filter}
+		if rest != "" {
+			if !strings.HasPrefix(rest, ".") {
+				return nil, NewScimError(400, "invalidPath", fmt.Sprintf("invalid path: %s", s))
+			}
+			path.subAttr = rest[1:]
+		}
+		return path, nil
+	}
+
+	attrPath := parseScimAttrPath(base)
+	if attrPath.attr == "" {
+		return nil, NewScimError(400, "invalidPath", fmt.Sprintf("invalid path: %s", s))
+	}
+	return &scimPatchPath{attr: attrPath.attr, subAttr: attrPath.subAttr}, nil
+}
+
+// applyScimPatch applies the PATCH operations to the JSON representation of a resource
+func applyScimPatch(resource map[string]interface{}, operations []*ScimPatchOperation) error {
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "replace" && op != "remove" {
+			return NewScimError(400, "invalidSyntax", fmt.Sprintf("unsupported patch operation: %s", operation.Op))
+		}
+
+		if operation.Path == "" {
+			if op == "remove" {
+				return NewScimError(400, "noTarget", "the path is required for remove operations")
+			}
+
+			values, ok := operation.Value.(map[string]interface{})
+			if !ok {
+				return NewScimError(400, "invalidValue", "the value should be an object when the path is omitted")
+			}
+
+			for k, v := range values {
+				if strings.HasPrefix(strings.ToLower(k), "urn:") {
+					// extension schema object, e.g. {"urn:...:enterprise:2.0:User": {...}}
+					if m, ok := v.(map[string]interface{}); ok {
+						for k2, v2 := range m {
+							if err := applyScimPatchOperation(resource, op, k2, v2); err != nil {
+								return err
+							}
+						}
+						continue
+					}
+				}
+
+				if err := applyScimPatchOperation(resource, op, k, v); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := applyScimPatchOperation(resource, op, operation.Path, operation.Value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func applyScimPatchOperation(resource map[string]interface{}, op string, pathStr string, value interface{}) error {
+	path, err := parseScimPatchPath(pathStr)
+	if err != nil {
+		return err
+	}
+
+	key, current := getScimMapValue(resource, path.attr)
+	if key == "" {
+		key = path.attr
+	}
+
+	if path.filter != nil {
+		return applyScimPatchFilteredOperation(resource, key, current, path, op, value)
+	}
+
+	if path.subAttr != "" {
+		switch c := current.(type) {
+		case []interface{}:
+			for _, item := range c {
+				if m, ok := item.(map[string]interface{}); ok {
+					setOrRemoveScimSubAttr(m, path.subAttr, op, value)
+				}
+			}
+		case map[string]interface{}:
+			setOrRemoveScimSubAttr(c, path.subAttr, op, value)
+		default:
+			if op != "remove" {
+				resource[key] = map[string]interface{}{path.subAttr: value}
+			}
+		}
+		return nil
+	}
+
+	switch op {
+	case "remove":
+		items, isArray := current.([]interface{})
+		removed := toScimSlice(value)
+		if !isArray || len(removed) == 0 {
+			delete(resource, key)
+			return nil
+		}
+
+		// e.g. {"op": "remove", "path": "members", "value": [{"value": "id"}]}
+		res := []interface{}{}
+		for _, item := range items {
+			if !containsScimValue(removed, item) {
+				res = append(res, item)
+			}
+		}
+		resource[key] = res
+	case "add":
+		items, isArray := current.([]interface{})
+		if _, isValueArray := value.([]interface{}); isArray || isValueArray {
+			for _, item := range toScimSlice(value) {
+				if !containsScimValue(items, item) {
+					items = append(items, item)
+				}
+			}
+			resource[key] = items
+			return nil
+		}
+
+		if m, ok := current.(map[string]interface{}); ok {
+			if v, ok := value.(map[string]interface{}); ok {
+				for k2, v2 := range v {
+					m[k2] = v2
+				}
+				return nil
+			}
+		}
+		resource[key] = value
+	case "replace":
+		resource[key] = value
+	}
+
+	return nil
+}
+
+func applyScimPatchFilteredOperation(resource map[string]interface{}, key string, current interface{}, path *scimPatchPath, op string, value interface{}) error {
+	items := toScimSlice(current)
+
+	matched := false
+	res := []interface{}{}
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok || !path.filter.match(m) {
+			res = append(res, item)
+			continue
+		}
+
+		matched = true
+		switch {
+		case op == "remove" && path.subAttr == "":
+			continue
+		case path.subAttr != "":
+			setOrRemoveScimSubAttr(m, path.subAttr, op, value)
+		case op == "replace":
+			if v, ok := value.(map[string]interface{}); ok {
+				m = v
+			}
+		default:
+			if v, ok := value.(map[string]interface{}); ok {
+				for k2, v2 := range v {
+					m[k2] = v2
+				}
+			}
+		}
+		res = append(res, m)
+	}
+
+	if !matched {
+		if op == "remove" {
+			return nil
+		}
+
+		// e.g. `emails[type eq "work"].value` when there is no work email yet
+		item := map[string]interface{}{}
+		if f, ok := path.filter.(*scimAttrFilter); ok && f.op == "eq" && f.path.subAttr == "" {
+			item[f.path.attr] = f.value
+		} else {
+			return NewScimError(400, "noTarget", fmt.Sprintf("no value matches the filter of path: %s", path.attr))
+		}
+
+		if path.subAttr != "" {
+			item[path.subAttr] = value
+		} else if v, ok := value.(map[string]interface{}); ok {
+			for k2, v2 := range v {
+				item[k2] = v2
+			}
+		}
+		res = append(res, item)
+	}
+
+	resource[key] = res
+	return nil
+}
+
+func setOrRemoveScimSubAttr(m map[string]interface{}, subAttr string, op string, value interface{}) {
+	key, _ := getScimMapValue(m, subAttr)
+	if key == "" {
+		key = subAttr
+	}
+
+	if op == "remove" {
+		delete(m, key)
+	} else {
+		m[key] = value
+	}
+}
+
+// containsScimValue reports whether item is in items, complex values are compared by their "value" sub-attribute
+func containsScimValue(items []interface{}, item interface{}) bool {
+	itemValue := getScimItemValue(item)
+	for _, i := range items {
+		if getScimItemValue(i) == itemValue {
+			return true
+		}
+	}
+	return false
+}
+
+func getScimItemValue(item interface{}) string {
+	if m, ok := item.(map[string]interface{}); ok {
+		if _, v := getScimMapValue(m, "value"); v != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return fmt.Sprintf("%v", m)
+	}
+	return fmt.Sprintf("%v", item)
+}
@@ -0,0 +1,282 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ScimSupported struct {
+	Supported bool `json:"supported"`
+}
+
+type ScimBulkSupported struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type ScimFilterSupported struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type ScimAuthenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary,omitempty"`
+}
+
+type ScimServiceProviderConfig struct {
+	Schemas               []string                    `json:"schemas"`
+	Patch                 ScimSupported               `json:"patch"`
+	Bulk                  ScimBulkSupported           `json:"bulk"`
+	Filter                ScimFilterSupported         `json:"filter"`
+	ChangePassword        ScimSupported               `json:"changePassword"`
+	Sort                  ScimSupported               `json:"sort"`
+	Etag                  ScimSupported               `json:"etag"`
+	AuthenticationSchemes []*ScimAuthenticationScheme `json:"authenticationSchemes"`
+	Meta                  *ScimMeta                   `json:"meta"`
+}
+
+type ScimResourceType struct {
+	Schemas     []string  `json:"schemas"`
+	Id          string    `json:"id"`
+	Name        string    `json:"name"`
+	Endpoint    string    `json:"endpoint"`
+	Description string    `json:"description"`
+	Schema      string    `json:"schema"`
+	Meta        *ScimMeta `json:"meta"`
+}
+
+type ScimSchemaAttribute struct {
+	Name          string                 `json:"name"`
+	Type          string                 `json:"type"`
+	MultiValued   bool                   `json:"multiValued"`
+	Required      bool                   `json:"required"`
+	CaseExact     bool                   `json:"caseExact"`
+	Mutability    string                 `json:"mutability"`
+	Returned      string                 `json:"returned"`
+	Uniqueness    string                 `json:"uniqueness"`
+	SubAttributes []*ScimSchemaAttribute `json:"subAttributes,omitempty"`
+}
+
+type ScimSchemaDefinition struct {
+	Schemas     []string               `json:"schemas"`
+	Id          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Attributes  []*ScimSchemaAttribute `json:"attributes"`
+	Meta        *ScimMeta              `json:"meta"`
+}
+
+func newScimAttribute(name string, attributeType string, mutability string, subAttributes ...*ScimSchemaAttribute) *ScimSchemaAttribute {
+	return &ScimSchemaAttribute{
+		Name:          name,
+		Type:          attributeType,
+		Mutability:    mutability,
+		Returned:      "default",
+		Uniqueness:    "none",
+		SubAttributes: subAttributes,
+	}
+}
+
+func newScimMultiValuedAttribute(name string, mutability string, subAttributes ...*ScimSchemaAttribute) *ScimSchemaAttribute {
+	attribute := newScimAttribute(name, "complex", mutability, subAttributes...)
+	attribute.MultiValued = true
+	return attribute
+}
+
+func getScimMultiValueSubAttributes(mutability string) []*ScimSchemaAttribute {
+	return []*ScimSchemaAttribute{
+		newScimAttribute("value", "string", mutability),
+		newScimAttribute("display", "string", mutability),
+		newScimAttribute("type", "string", mutability),
+		newScimAttribute("primary", "boolean", mutability),
+	}
+}
+
+func getScimUserSchema() *ScimSchemaDefinition {
+	userName := newScimAttribute("userName", "string", "readWrite")
+	userName.Required = true
+	userName.Uniqueness = "server"
+
+	password := newScimAttribute("password", "string", "writeOnly")
+	password.Returned = "never"
+
+	return &ScimSchemaDefinition{
+		Schemas:     []string{ScimSchemaSchema},
+		Id:          ScimSchemaUser,
+		Name:        "User",
+		Description: "User Account",
+		Attributes: []*ScimSchemaAttribute{
+			userName,
+			newScimAttribute("externalId", "string", "readWrite"),
+			newScimAttribute("name", "complex", "readWrite",
+				newScimAttribute("formatted", "string", "readWrite"),
+				newScimAttribute("familyName", "string", "readWrite"),
+				newScimAttribute("givenName", "string", "readWrite"),
+			),
+			newScimAttribute("displayName", "string", "readWrite"),
+			newScimAttribute("title", "string", "readWrite"),
+			newScimAttribute("preferredLanguage", "string", "readWrite"),
+			newScimAttribute("active", "boolean", "readWrite"),
+			password,
+			newScimMultiValuedAttribute("emails", "readWrite", getScimMultiValueSubAttributes("readWrite")...),
+			newScimMultiValuedAttribute("phoneNumbers", "readWrite", getScimMultiValueSubAttributes("readWrite")...),
+			newScimMultiValuedAttribute("photos", "readWrite", getScimMultiValueSubAttributes("readWrite")...),
+			newScimMultiValuedAttribute("addresses", "readWrite",
+				newScimAttribute("formatted", "string", "readWrite"),
+				newScimAttribute("locality", "string", "readWrite"),
+				newScimAttribute("region", "string", "readWrite"),
+				newScimAttribute("country", "string", "readWrite"),
+				newScimAttribute("type", "string", "readWrite"),
+				newScimAttribute("primary", "boolean", "readWrite"),
+			),
+			newScimMultiValuedAttribute("groups", "readOnly", getScimMultiValueSubAttributes("readOnly")...),
+		},
+	}
+}
+
+func getScimGroupSchema() *ScimSchemaDefinition {
+	displayName := newScimAttribute("displayName", "string", "readWrite")
+	displayName.Required = true
+
+	return &ScimSchemaDefinition{
+		Schemas:     []string{ScimSchemaSchema},
+		Id:          ScimSchemaGroup,
+		Name:        "Group",
+		Description: "Group",
+		Attributes: []*ScimSchemaAttribute{
+			displayName,
+			newScimMultiValuedAttribute("members", "readWrite", getScimMultiValueSubAttributes("immutable")...),
+		},
+	}
+}
+
+func GetScimServiceProviderConfig(organization string, host string) (*ScimServiceProviderConfig, error) {
+	err := checkScimOrganization(organization)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScimServiceProviderConfig{
+		Schemas:        []string{ScimSchemaServiceProviderConfig},
+		Patch:          ScimSupported{Supported: true},
+		Bulk:           ScimBulkSupported{Supported: false},
+		Filter:         ScimFilterSupported{Supported: true, MaxResults: ScimMaxResults},
+		ChangePassword: ScimSupported{Supported: true},
+		Sort:           ScimSupported{Supported: false},
+		Etag:           ScimSupported{Supported: false},
+		AuthenticationSchemes: []*ScimAuthenticationScheme{
+			{
+				Type:        "oauthbearertoken",
+				Name:        "OAuth Bearer Token",
+				Description: "Access token issued to the application by the client credentials grant",
+				Primary:     true,
+			},
+			{
+				Type:        "httpbasic",
+				Name:        "HTTP Basic",
+				Description: "Client ID and client secret of the application",
+			},
+		},
+		Meta: &ScimMeta{
+			ResourceType: "ServiceProviderConfig",
+			Location:     fmt.Sprintf("%s/ServiceProviderConfig", getScimBaseUrl(host, organization)),
+		},
+	}, nil
+}
+
+func getScimResourceTypes(organization string, host string) []*ScimResourceType {
+	baseUrl := getScimBaseUrl(host, organization)
+	return []*ScimResourceType{
+		{
+			Schemas:     []string{ScimSchemaResourceType},
+			Id:          "User",
+			Name:        "User",
+			Endpoint:    "/Users",
+			Description: "User Account",
+			Schema:      ScimSchemaUser,
+			Meta:        &ScimMeta{ResourceType: "ResourceType", Location: fmt.Sprintf("%s/ResourceTypes/User", baseUrl)},
+		},
+		{
+			Schemas:     []string{ScimSchemaResourceType},
+			Id:          "Group",
+			Name:        "Group",
+			Endpoint:    "/Groups",
+			Description: "Group",
+			Schema:      ScimSchemaGroup,
+			Meta:        &ScimMeta{ResourceType: "ResourceType", Location: fmt.Sprintf("%s/ResourceTypes/Group", baseUrl)},
+		},
+	}
+}
+
+func GetScimResourceTypes(organization string, host string) (*ScimListResponse, error) {
+	err := checkScimOrganization(organization)
+	if err != nil {
+		return nil, err
+	}
+
+	return newScimListResponse(getScimResourceTypes(organization, host), 1, ScimMaxResults), nil
+}
+
+func GetScimResourceType(organization string, id string, host string) (*ScimResourceType, error) {
+	err := checkScimOrganization(organization)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, resourceType := range getScimResourceTypes(organization, host) {
+		if strings.EqualFold(resourceType.Id, id) {
+			return resourceType, nil
+		}
+	}
+	return nil, NewScimError(404, "", fmt.Sprintf("the resource type: %s is not found", id))
+}
+
+func getScimSchemas(organization string, host string) []*ScimSchemaDefinition {
+	baseUrl := getScimBaseUrl(host, organization)
+	schemas := []*ScimSchemaDefinition{getScimUserSchema(), getScimGroupSchema()}
+	for _, schema := range schemas {
+		schema.Meta = &ScimMeta{ResourceType: "Schema", Location: fmt.Sprintf("%s/Schemas/%s", baseUrl, schema.Id)}
+	}
+	return schemas
+}
+
+func GetScimSchemas(organization string, host string) (*ScimListResponse, error) {
+	err := checkScimOrganization(organization)
+	if err != nil {
+		return nil, err
+	}
+
+	return newScimListResponse(getScimSchemas(organization, host), 1, ScimMaxResults), nil
+}
+
+func GetScimSchema(organization string, id string, host string) (*ScimSchemaDefinition, error) {
+	err := checkScimOrganization(organization)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, schema := range getScimSchemas(organization, host) {
+		if schema.Id == id {
+			return schema, nil
+		}
+	}
+	return nil, NewScimError(404, "", fmt.Sprintf("the schema: %s is not found", id))
+}
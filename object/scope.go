@@ -0,0 +1,311 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Knetic/govaluate"
+	"github.com/casdoor/casdoor/util"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	ScopeClaimSourceUser     = "User"
+	ScopeClaimSourceProperty = "Property"
+	ScopeClaimSourceRoles    = "Roles"
+	ScopeClaimSourceGroups   = "Groups"
+	ScopeClaimSourceStatic   = "Static"
+)
+
+// reservedClaims cannot be overridden by the claims of a scope, they are the registered claims
+// and the claims identifying the user
+var reservedClaims = []string{
+	"iss", "sub", "aud", "exp", "nbf", "iat", "jti", "azp", "nonce", "scope", "sid", "act", "tokenType",
+	"owner", "name", "id", "type", "isAdmin", "isForbidden", "isDeleted", "signupApplication",
+}
+
+// ScopeClaim is a claim added to the tokens when its scope is requested. The value is
+// taken from the source, and can be transformed by an expression in which the value
+// is available as `value`, e.g. `lower(value)` or `value + "@example.com"`.
+type ScopeClaim struct {
+	Name      string `json:"name"`
+	Source    string `json:"source"`
+	Value     string `json:"value"`
+	Transform string `json:"transform"`
+}
+
+// ScopeItem is a scope defined by the application, in addition to the standard scopes
+type ScopeItem struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Claims      []*ScopeClaim `json:"claims"`
+}
+
+var scopeClaimFunctions = map[string]govaluate.ExpressionFunction{
+	"lower": func(args ...interface{}) (interface{}, error) {
+		return mapScopeClaimStrings(args, strings.ToLower)
+	},
+	"upper": func(args ...interface{}) (interface{}, error) {
+		return mapScopeClaimStrings(args, strings.ToUpper)
+	},
+	"trim": func(args ...interface{}) (interface{}, error) {
+		return mapScopeClaimStrings(args, strings.TrimSpace)
+	},
+	"join": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("join() expects 2 arguments, got %d", len(args))
+		}
+		return strings.Join(getScopeClaimStrings(args[0]), fmt.Sprint(args[1])), nil
+	},
+	"split": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("split() expects 2 arguments, got %d", len(args))
+		}
+		return strings.Split(fmt.Sprint(args[0]), fmt.Sprint(args[1])), nil
+	},
+	"replace": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 3 {
+			return nil, fmt.Errorf("replace() expects 3 arguments, got %d", len(args))
+		}
+		return strings.ReplaceAll(fmt.Sprint(args[0]), fmt.Sprint(args[1]), fmt.Sprint(args[2])), nil
+	},
+	"contains": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("contains() expects 2 arguments, got %d", len(args))
+		}
+		if s, ok := args[0].(string); ok {
+			return strings.Contains(s, fmt.Sprint(args[1])), nil
+		}
+		return util.InSlice(getScopeClaimStrings(args[0]), fmt.Sprint(args[1])), nil
+	},
+}
+
+func getScopeClaimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		res := []string{}
+		for _, item := range v {
+			res = append(res, fmt.Sprint(item))
+		}
+		return res
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+// mapScopeClaimStrings applies f to a string or to every string of a list
+func mapScopeClaimStrings(args []interface{}, f func(string) string) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expects 1 argument, got %d", len(args))
+	}
+
+	if s, ok := args[0].(string); ok {
+		return f(s), nil
+	}
+
+	res := []string{}
+	for _, s := range getScopeClaimStrings(args[0]) {
+		res = append(res, f(s))
+	}
+	return res, nil
+}
+
+// getUserFieldValue returns the value of the user field, which is named either by its json
+// name like "displayName", or by its Go name like "DisplayName". The fields holding credentials
+// are never returned.
+func getUserFieldValue(user *User, field string) (interface{}, bool) {
+	if isUserSecretField(field) {
+		return nil, false
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(user))
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		jsonName := strings.Split(f.Tag.Get("json"), ",")[0]
+		if jsonName == "-" || (f.Name != field && jsonName != field) {
+			continue
+		}
+
+		return v.Field(i).Interface(), true
+	}
+
+	return nil, false
+}
+
+func (claim *ScopeClaim) getSourceValue(user *User) interface{} {
+	switch claim.Source {
+	case ScopeClaimSourceUser:
+		value, _ := getUserFieldValue(user, claim.Value)
+		return value
+	case ScopeClaimSourceProperty:
+		return user.Properties[claim.Value]
+	case ScopeClaimSourceRoles:
+		roles := []string{}
+		for _, role := range user.Roles {
+			roles = append(roles, role.Name)
+		}
+		return roles
+	case ScopeClaimSourceGroups:
+		if user.Groups == nil {
+			return []string{}
+		}
+		return user.Groups
+	default:
+		return claim.Value
+	}
+}
+
+func (claim *ScopeClaim) getValue(user *User) (interface{}, error) {
+	value := claim.getSourceValue(user)
+	if claim.Transform == "" {
+		return value, nil
+	}
+
+	expression, err := govaluate.NewEvaluableExpressionWithFunctions(claim.Transform, scopeClaimFunctions)
+	if err != nil {
+		return nil, err
+	}
+
+	// govaluate only knows about lists of interface{}
+	if values, ok := value.([]string); ok {
+		list := []interface{}{}
+		for _, v := range values {
+			list = append(list, v)
+		}
+		value = list
+	}
+
+	res, err := expression.Evaluate(map[string]interface{}{"value": value})
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform the claim: %s: %s", claim.Name, err.Error())
+	}
+
+	return res, nil
+}
+
+// CheckScopes checks the scopes defined by an application before they are saved
+func CheckScopes(scopes []*ScopeItem) error {
+	scopeNames := []string{}
+	for _, scope := range scopes {
+		if scope.Name == "" || strings.ContainsAny(scope.Name, " \"\\") {
+			return fmt.Errorf("the scope name: \"%s\" is invalid", scope.Name)
+		}
+		if util.InSlice(scopeNames, scope.Name) {
+			return fmt.Errorf("the scope: %s is defined more than once", scope.Name)
+		}
+		scopeNames = append(scopeNames, scope.Name)
+
+		for _, claim := range scope.Claims {
+			if claim.Name == "" || util.InSlice(reservedClaims, claim.Name) {
+				return fmt.Errorf("the claim name: \"%s\" of the scope: %s is invalid", claim.Name, scope.Name)
+			}
+
+			switch claim.Source {
+			case ScopeClaimSourceUser:
+				if isUserSecretField(claim.Value) {
+					return fmt.Errorf("the user field: \"%s\" of the claim: %s holds credentials and cannot be published", claim.Value, claim.Name)
+				}
+				if _, ok := getUserFieldValue(&User{}, claim.Value); !ok {
+					return fmt.Errorf("the user field: \"%s\" of the claim: %s does not exist", claim.Value, claim.Name)
+				}
+			case ScopeClaimSourceProperty, ScopeClaimSourceRoles, ScopeClaimSourceGroups, ScopeClaimSourceStatic:
+			default:
+				return fmt.Errorf("the source: \"%s\" of the claim: %s is invalid", claim.Source, claim.Name)
+			}
+
+			if claim.Transform != "" {
+				_, err := govaluate.NewEvaluableExpressionWithFunctions(claim.Transform, scopeClaimFunctions)
+				if err != nil {
+					return fmt.Errorf("the transform of the claim: %s is invalid: %s", claim.Name, err.Error())
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// getScopeClaims returns the claims of the scopes of the application requested by the scope
+func getScopeClaims(application *Application, user *User, scope string) (map[string]interface{}, error) {
+	res := map[string]interface{}{}
+	requestedScopes := getScopes(scope)
+	for _, scopeItem := range application.Scopes {
+		if !util.InSlice(requestedScopes, scopeItem.Name) {
+			continue
+		}
+
+		for _, claim := range scopeItem.Claims {
+			value, err := claim.getValue(user)
+			if err != nil {
+				return nil, err
+			}
+
+			res[claim.Name] = value
+		}
+	}
+
+	return res, nil
+}
+
+// addScopeClaims merges the claims of the requested scopes into the claims of a token
+func addScopeClaims(claims jwt.Claims, scopeClaims map[string]interface{}) (jwt.Claims, error) {
+	if len(scopeClaims) == 0 {
+		return claims, nil
+	}
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	res := jwt.MapClaims{}
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range scopeClaims {
+		res[name] = value
+	}
+
+	return res, nil
+}
+
+// getApplicationScopesAndClaims returns the names of the scopes and claims defined by the
+// application, for its discovery document
+func getApplicationScopesAndClaims(application *Application) ([]string, []string) {
+	scopes := []string{}
+	claims := []string{}
+	for _, scopeItem := range application.Scopes {
+		if !util.InSlice(scopes, scopeItem.Name) {
+			scopes = append(scopes, scopeItem.Name)
+		}
+
+		for _, claim := range scopeItem.Claims {
+			if !util.InSlice(claims, claim.Name) {
+				claims = append(claims, claim.Name)
+			}
+		}
+	}
+
+	return scopes, claims
+}
@@ -0,0 +1,116 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckScopes(t *testing.T) {
+	scenarios := []struct {
+		description string
+		scopes      []*ScopeItem
+		isValid     bool
+	}{
+		{"user field", []*ScopeItem{{Name: "hr", Claims: []*ScopeClaim{{Name: "department", Source: ScopeClaimSourceUser, Value: "affiliation"}}}}, true},
+		{"go field name", []*ScopeItem{{Name: "hr", Claims: []*ScopeClaim{{Name: "department", Source: ScopeClaimSourceUser, Value: "Affiliation"}}}}, true},
+		{"unknown user field", []*ScopeItem{{Name: "hr", Claims: []*ScopeClaim{{Name: "department", Source: ScopeClaimSourceUser, Value: "foo"}}}}, false},
+		{"unknown source", []*ScopeItem{{Name: "hr", Claims: []*ScopeClaim{{Name: "department", Source: "foo"}}}}, false},
+		{"reserved claim", []*ScopeItem{{Name: "hr", Claims: []*ScopeClaim{{Name: "sub", Source: ScopeClaimSourceStatic, Value: "foo"}}}}, false},
+		{"identity claim", []*ScopeItem{{Name: "hr", Claims: []*ScopeClaim{{Name: "isAdmin", Source: ScopeClaimSourceStatic, Value: "true"}}}}, false},
+		{"password field", []*ScopeItem{{Name: "hr", Claims: []*ScopeClaim{{Name: "secret", Source: ScopeClaimSourceUser, Value: "password"}}}}, false},
+		{"totp secret go field name", []*ScopeItem{{Name: "hr", Claims: []*ScopeClaim{{Name: "secret", Source: ScopeClaimSourceUser, Value: "TotpSecret"}}}}, false},
+		{"managed accounts field", []*ScopeItem{{Name: "hr", Claims: []*ScopeClaim{{Name: "accounts", Source: ScopeClaimSourceUser, Value: "managedAccounts"}}}}, false},
+		{"scope name with space", []*ScopeItem{{Name: "h r"}}, false},
+		{"duplicated scope", []*ScopeItem{{Name: "hr"}, {Name: "hr"}}, false},
+		{"invalid transform", []*ScopeItem{{Name: "hr", Claims: []*ScopeClaim{{Name: "department", Source: ScopeClaimSourceStatic, Transform: "lower("}}}}, false},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			err := CheckScopes(scenario.scopes)
+			assert.Equal(t, scenario.isValid, err == nil, err)
+		})
+	}
+}
+
+func TestGetScopeClaims(t *testing.T) {
+	application := &Application{
+		Scopes: []*ScopeItem{
+			{
+				Name: "hr",
+				Claims: []*ScopeClaim{
+					{Name: "department", Source: ScopeClaimSourceUser, Value: "affiliation", Transform: "lower(value)"},
+					{Name: "employee_id", Source: ScopeClaimSourceProperty, Value: "employeeId", Transform: "\"E\" + value"},
+					{Name: "roles", Source: ScopeClaimSourceRoles},
+				},
+			},
+			{
+				Name:   "teams",
+				Claims: []*ScopeClaim{{Name: "teams", Source: ScopeClaimSourceGroups}},
+			},
+		},
+	}
+	user := &User{
+		Affiliation: "Sales",
+		Properties:  map[string]string{"employeeId": "42"},
+		Roles:       []*Role{{Name: "manager"}},
+		Groups:      []string{"org/team-a"},
+	}
+
+	claims, err := getScopeClaims(application, user, "openid hr")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"department":  "sales",
+		"employee_id": "E42",
+		"roles":       []string{"manager"},
+	}, claims)
+
+	claims, err = getScopeClaims(application, user, "openid")
+	assert.Nil(t, err)
+	assert.Empty(t, claims)
+}
+
+func TestGetUserFieldValue(t *testing.T) {
+	user := &User{Affiliation: "Sales", Password: "123", TotpSecret: "secret"}
+
+	value, ok := getUserFieldValue(user, "affiliation")
+	assert.True(t, ok)
+	assert.Equal(t, "Sales", value)
+
+	for _, field := range []string{"password", "Password", "totpSecret", "accessSecret", "recoveryCodes", "managedAccounts"} {
+		_, ok = getUserFieldValue(user, field)
+		assert.False(t, ok, field)
+	}
+}
+
+func TestGetApplicationScopesAndClaims(t *testing.T) {
+	application := &Application{
+		Scopes: []*ScopeItem{
+			{Name: "hr", Claims: []*ScopeClaim{{Name: "department"}, {Name: "employee_id"}}},
+			{Name: "teams", Claims: []*ScopeClaim{{Name: "teams"}, {Name: "department"}}},
+		},
+	}
+
+	scopes, claims := getApplicationScopesAndClaims(application)
+	assert.Equal(t, []string{"hr", "teams"}, scopes)
+	assert.Equal(t, []string{"department", "employee_id", "teams"}, claims)
+
+	scopes, claims = getApplicationScopesAndClaims(&Application{})
+	assert.Empty(t, scopes)
+	assert.Empty(t, claims)
+}
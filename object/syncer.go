@@ -21,6 +21,16 @@ import (
 	"github.com/xorm-io/core"
 )
 
+const (
+	SyncerModeFull        = "Full"
+	SyncerModeIncremental = "Incremental"
+	SyncerModeChangeLog   = "Change log"
+
+	SyncerConflictPolicySource = "Source"
+	SyncerConflictPolicyLocal  = "Local"
+	SyncerConflictPolicyField  = "Field"
+)
+
 type TableColumn struct {
 	Name        string   `json:"name"`
 	Type        string   `json:"type"`
@@ -54,6 +64,14 @@ type Syncer struct {
 	IsReadOnly       bool           `json:"isReadOnly"`
 	IsEnabled        bool           `json:"isEnabled"`
 
+	SyncMode           string `xorm:"varchar(100)" json:"syncMode"`
+	ChangeColumn       string `xorm:"varchar(100)" json:"changeColumn"`
+	ChangeLogTable     string `xorm:"varchar(100)" json:"changeLogTable"`
+	ChangeLogKeyColumn string `xorm:"varchar(100)" json:"changeLogKeyColumn"`
+	ConflictPolicy     string `xorm:"varchar(100)" json:"conflictPolicy"`
+	SyncDeletions      bool   `json:"syncDeletions"`
+	SyncCursor         string `xorm:"varchar(100)" json:"syncCursor"`
+
 	Ormer *Ormer `xorm:"-" json:"-"`
 }
 
@@ -136,21 +154,41 @@ func GetMaskedSyncers(syncers []*Syncer) []*Syncer {
 
 func UpdateSyncer(id string, syncer *Syncer) (bool, error) {
 	owner, name := util.GetOwnerAndNameFromId(id)
-	if s, err := getSyncer(owner, name); err != nil {
+	s, err := getSyncer(owner, name)
+	if err != nil {
 		return false, err
 	} else if s == nil {
 		return false, nil
 	}
 
+	err = checkSyncer(syncer)
+	if err != nil {
+		return false, err
+	}
+
 	session := ormer.Engine.ID(core.PK{owner, name}).AllCols()
 	if syncer.Password == "***" {
 		session.Omit("password")
 	}
+	// the cursor is only kept while it refers to the same changes
+	if syncer.getSyncMode() == s.getSyncMode() && syncer.ChangeColumn == s.ChangeColumn && syncer.ChangeLogTable == s.ChangeLogTable && syncer.Table == s.Table {
+		session.Omit("sync_cursor")
+		syncer.SyncCursor = s.SyncCursor
+	} else {
+		syncer.SyncCursor = ""
+	}
 	affected, err := session.Update(syncer)
 	if err != nil {
 		return false, err
 	}
 
+	if syncer.GetId() != s.GetId() {
+		_, err = ormer.Engine.Where("syncer = ?", s.GetId()).Update(&SyncerRecord{Syncer: syncer.GetId()})
+		if err != nil {
+			return false, err
+		}
+	}
+
 	if affected == 1 {
 		err = addSyncerJob(syncer)
 		if err != nil {
@@ -182,6 +220,11 @@ func updateSyncerErrorText(syncer *Syncer, line string) (bool, error) {
 }
 
 func AddSyncer(syncer *Syncer) (bool, error) {
+	err := checkSyncer(syncer)
+	if err != nil {
+		return false, err
+	}
+
 	affected, err := ormer.Engine.Insert(syncer)
 	if err != nil {
 		return false, err
@@ -205,6 +248,11 @@ func DeleteSyncer(syncer *Syncer) (bool, error) {
 
 	if affected == 1 {
 		deleteSyncerJob(syncer)
+
+		err = deleteSyncerRecords(syncer.GetId())
+		if err != nil {
+			return false, err
+		}
 	}
 
 	return affected != 0, nil
@@ -214,6 +262,42 @@ func (syncer *Syncer) GetId() string {
 	return fmt.Sprintf("%s/%s", syncer.Owner, syncer.Name)
 }
 
+func (syncer *Syncer) getSyncMode() string {
+	if syncer.SyncMode == "" {
+		return SyncerModeFull
+	}
+	return syncer.SyncMode
+}
+
+func (syncer *Syncer) getConflictPolicy() string {
+	if syncer.ConflictPolicy == "" {
+		return SyncerConflictPolicySource
+	}
+	return syncer.ConflictPolicy
+}
+
+func checkSyncer(syncer *Syncer) error {
+	switch syncer.getSyncMode() {
+	case SyncerModeFull:
+	case SyncerModeIncremental:
+		if syncer.ChangeColumn == "" {
+			return fmt.Errorf("the change column of an incremental syncer should not be empty")
+		}
+	case SyncerModeChangeLog:
+		if syncer.ChangeLogTable == "" || syncer.ChangeLogKeyColumn == "" || syncer.ChangeColumn == "" {
+			return fmt.Errorf("the change log table, its key column and its change column should not be empty")
+		}
+	default:
+		return fmt.Errorf("the sync mode: \"%s\" is not supported", syncer.SyncMode)
+	}
+
+	if !util.InSlice([]string{SyncerConflictPolicySource, SyncerConflictPolicyLocal, SyncerConflictPolicyField}, syncer.getConflictPolicy()) {
+		return fmt.Errorf("the conflict policy: \"%s\" is not supported", syncer.ConflictPolicy)
+	}
+
+	return nil
+}
+
 func (syncer *Syncer) getTableColumnsTypeMap() map[string]string {
 	m := map[string]string{}
 	for _, tableColumn := range syncer.TableColumns {
@@ -230,6 +314,14 @@ func (syncer *Syncer) getTable() string {
 	}
 }
 
+func (syncer *Syncer) getChangeLogTable() string {
+	if syncer.DatabaseType == "mssql" {
+		return fmt.Sprintf("[%s]", syncer.ChangeLogTable)
+	} else {
+		return syncer.ChangeLogTable
+	}
+}
+
 func (syncer *Syncer) getKey() string {
 	key := "id"
 	hasKey := false
@@ -259,3 +351,18 @@ func RunSyncer(syncer *Syncer) error {
 
 	return syncer.syncUsers()
 }
+
+// DryRunSyncer returns the report of the changes the syncer would make, without making them
+func DryRunSyncer(syncer *Syncer) (*SyncerReport, error) {
+	err := syncer.initAdapter()
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := syncer.getSyncPlan()
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.report, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"github.com/casdoor/casdoor/util"
+	"github.com/xorm-io/core"
+)
+
+const syncerRecordBatchSize = 500
+
+// SyncerRecord is the row of the original table as it was after the last sync of a user, it is
+// the common base used to tell which side changed a field
+type SyncerRecord struct {
+	Syncer      string            `xorm:"varchar(200) notnull pk" json:"syncer"`
+	SourceKey   string            `xorm:"varchar(100) notnull pk" json:"sourceKey"`
+	Values      map[string]string `xorm:"mediumtext" json:"values"`
+	UpdatedTime string            `xorm:"varchar(100)" json:"updatedTime"`
+}
+
+func (syncer *Syncer) getRecordMap(keys []string) (map[string]*SyncerRecord, error) {
+	m := map[string]*SyncerRecord{}
+	for i := 0; i < len(keys); i += syncerRecordBatchSize {
+		records := []*SyncerRecord{}
+		err := ormer.Engine.Where("syncer = ?", syncer.GetId()).In("source_key", keys[i:min(i+syncerRecordBatchSize, len(keys))]).Find(&records)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, record := range records {
+			m[record.SourceKey] = record
+		}
+	}
+	return m, nil
+}
+
+func (syncer *Syncer) getAllRecordMap() (map[string]*SyncerRecord, error) {
+	records := []*SyncerRecord{}
+	err := ormer.Engine.Find(&records, &SyncerRecord{Syncer: syncer.GetId()})
+	if err != nil {
+		return nil, err
+	}
+
+	m := map[string]*SyncerRecord{}
+	for _, record := range records {
+		m[record.SourceKey] = record
+	}
+	return m, nil
+}
+
+func (syncer *Syncer) setRecord(key string, values map[string]string, existed bool) error {
+	record := &SyncerRecord{
+		Syncer:      syncer.GetId(),
+		SourceKey:   key,
+		Values:      values,
+		UpdatedTime: util.GetCurrentTime(),
+	}
+
+	if existed {
+		_, err := ormer.Engine.ID(core.PK{record.Syncer, record.SourceKey}).AllCols().Update(record)
+		return err
+	}
+
+	_, err := ormer.Engine.Insert(record)
+	return err
+}
+
+func (syncer *Syncer) addRecordsInBatch(records []*SyncerRecord) error {
+	for i := 0; i < len(records); i += syncerRecordBatchSize {
+		_, err := ormer.Engine.Insert(records[i:min(i+syncerRecordBatchSize, len(records))])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (syncer *Syncer) deleteRecord(key string) error {
+	_, err := ormer.Engine.ID(core.PK{syncer.GetId(), key}).Delete(&SyncerRecord{})
+	return err
+}
+
+func deleteSyncerRecords(syncerId string) error {
+	_, err := ormer.Engine.Delete(&SyncerRecord{Syncer: syncerId})
+	return err
+}
@@ -16,27 +16,320 @@ package object
 
 import (
 	"fmt"
+	"sort"
 	"time"
+
+	"github.com/casdoor/casdoor/util"
+	"github.com/xorm-io/core"
 )
 
-func (syncer *Syncer) syncUsers() error {
+const syncerReportMaxChanges = 1000
+
+const (
+	SyncerActionCreate = "Create"
+	SyncerActionUpdate = "Update"
+	SyncerActionDelete = "Delete"
+
+	SyncerTargetLocal  = "Local"
+	SyncerTargetSource = "Source"
+)
+
+// SyncerChange is a change of a sync, made to the local users or to the original table
+type SyncerChange struct {
+	Action     string   `json:"action"`
+	Target     string   `json:"target"`
+	Key        string   `json:"key"`
+	User       string   `json:"user"`
+	Fields     []string `json:"fields"`
+	IsConflict bool     `json:"isConflict"`
+}
+
+// SyncerReport is the report of the changes of a sync, only the first changes are listed
+type SyncerReport struct {
+	SyncMode      string          `json:"syncMode"`
+	Cursor        string          `json:"cursor"`
+	SourceCount   int             `json:"sourceCount"`
+	CreateCount   int             `json:"createCount"`
+	UpdateCount   int             `json:"updateCount"`
+	DeleteCount   int             `json:"deleteCount"`
+	ConflictCount int             `json:"conflictCount"`
+	Changes       []*SyncerChange `json:"changes"`
+}
+
+type syncerPlan struct {
+	report     *SyncerReport
+	newUsers   []*User
+	newRecords []*SyncerRecord
+	operations []func() error
+}
+
+func (plan *syncerPlan) addChange(change *SyncerChange) {
+	switch change.Action {
+	case SyncerActionCreate:
+		plan.report.CreateCount++
+	case SyncerActionUpdate:
+		plan.report.UpdateCount++
+	case SyncerActionDelete:
+		plan.report.DeleteCount++
+	}
+
+	if len(plan.report.Changes) < syncerReportMaxChanges {
+		plan.report.Changes = append(plan.report.Changes, change)
+	}
+}
+
+func (plan *syncerPlan) addOperation(operation func() error) {
+	plan.operations = append(plan.operations, operation)
+}
+
+// getHashedValues returns the values of the hashed columns, which are the ones compared to find
+// the changes
+func (syncer *Syncer) getHashedValues(user *OriginalUser) map[string]string {
+	m := syncer.getMapFromOriginalUser(user)
+	res := map[string]string{}
+	for _, tableColumn := range syncer.TableColumns {
+		if tableColumn.IsHashed {
+			res[tableColumn.Name] = m[tableColumn.Name]
+		}
+	}
+	return res
+}
+
+// mergeUserValues merges the values of the local user and of the original user according to the
+// conflict policy. The base values are the ones of the last sync, or nil if they are unknown. It
+// returns the merged values and the columns changed on both sides.
+func (syncer *Syncer) mergeUserValues(local map[string]string, source map[string]string, base map[string]string) (map[string]string, []string) {
+	merged := map[string]string{}
+	conflicts := []string{}
+	isLocalChanged := false
+	isSourceChanged := false
+	for name, value := range source {
+		// without base, a difference is a change on both sides
+		isLocalFieldChanged := local[name] != value
+		isSourceFieldChanged := local[name] != value
+		if b, ok := base[name]; ok {
+			isLocalFieldChanged = local[name] != b
+			isSourceFieldChanged = value != b
+		}
+
+		isLocalChanged = isLocalChanged || isLocalFieldChanged
+		isSourceChanged = isSourceChanged || isSourceFieldChanged
+		if isLocalFieldChanged && isSourceFieldChanged && local[name] != value {
+			conflicts = append(conflicts, name)
+		}
+
+		// at the field level, the change of the original user wins over the one of the local user
+		if isSourceFieldChanged {
+			merged[name] = value
+		} else {
+			merged[name] = local[name]
+		}
+	}
+	sort.Strings(conflicts)
+
+	switch syncer.getConflictPolicy() {
+	case SyncerConflictPolicySource:
+		if isSourceChanged {
+			merged = source
+		} else {
+			merged = local
+		}
+	case SyncerConflictPolicyLocal:
+		if isLocalChanged {
+			merged = local
+		} else {
+			merged = source
+		}
+	}
+
+	return merged, conflicts
+}
+
+func getChangedColumns(values map[string]string, merged map[string]string) []string {
+	res := []string{}
+	for name, value := range merged {
+		if values[name] != value {
+			res = append(res, name)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func isSameValues(a map[string]string, b map[string]string) bool {
+	return len(a) == len(b) && len(getChangedColumns(a, b)) == 0
+}
+
+func (syncer *Syncer) planUser(plan *syncerPlan, primary string, user *User, oUser *OriginalUser, record *SyncerRecord, affiliationMap map[int]string) {
+	source := syncer.getHashedValues(oUser)
+	if user == nil {
+		newUser := syncer.createUserFromOriginalUser(oUser, affiliationMap)
+		plan.newUsers = append(plan.newUsers, newUser)
+		plan.addChange(&SyncerChange{Action: SyncerActionCreate, Target: SyncerTargetLocal, Key: primary, User: newUser.Name})
+
+		if record == nil {
+			plan.newRecords = append(plan.newRecords, &SyncerRecord{Syncer: syncer.GetId(), SourceKey: primary, Values: source, UpdatedTime: util.GetCurrentTime()})
+		} else {
+			plan.addOperation(func() error {
+				return syncer.setRecord(primary, source, true)
+			})
+		}
+		return
+	}
+
+	local := syncer.getHashedValues(syncer.createOriginalUserFromUser(user))
+	var base map[string]string
+	if record != nil {
+		base = record.Values
+	} else if user.Hash == user.PreHash {
+		// the user was synced before the records and has not been changed since
+		base = local
+	}
+
+	merged, conflicts := syncer.mergeUserValues(local, source, base)
+	if len(conflicts) != 0 {
+		plan.report.ConflictCount++
+	}
+
+	localFields := getChangedColumns(local, merged)
+	if len(localFields) != 0 {
+		updatedOUser := *oUser
+		for _, tableColumn := range syncer.TableColumns {
+			if value, ok := merged[tableColumn.Name]; ok {
+				syncer.setUserByKeyValue(&updatedOUser, tableColumn.CasdoorName, value)
+			}
+		}
+
+		updatedUser := syncer.createUserFromOriginalUser(&updatedOUser, affiliationMap)
+		updatedUser.Hash = syncer.calculateHash(&updatedOUser)
+		updatedUser.PreHash = updatedUser.Hash
+
+		plan.addChange(&SyncerChange{Action: SyncerActionUpdate, Target: SyncerTargetLocal, Key: primary, User: user.Name, Fields: localFields, IsConflict: len(conflicts) != 0})
+		plan.addOperation(func() error {
+			_, err := syncer.updateUserForOriginalByFields(updatedUser, syncer.getKey())
+			return err
+		})
+	}
+
+	synced := source
+	sourceFields := getChangedColumns(source, merged)
+	if len(sourceFields) != 0 && !syncer.IsReadOnly {
+		values := syncer.getMapFromOriginalUser(syncer.createOriginalUserFromUser(user))
+		for name, value := range merged {
+			values[name] = value
+		}
+
+		synced = merged
+		plan.addChange(&SyncerChange{Action: SyncerActionUpdate, Target: SyncerTargetSource, Key: primary, User: user.Name, Fields: sourceFields, IsConflict: len(conflicts) != 0})
+		plan.addOperation(func() error {
+			_, err := syncer.updateUserValues(values)
+			if err != nil {
+				return err
+			}
+
+			if len(localFields) == 0 {
+				_, err = SetUserField(user, "pre_hash", user.Hash)
+			}
+			return err
+		})
+	}
+
+	if record == nil {
+		plan.newRecords = append(plan.newRecords, &SyncerRecord{Syncer: syncer.GetId(), SourceKey: primary, Values: synced, UpdatedTime: util.GetCurrentTime()})
+	} else if !isSameValues(record.Values, synced) {
+		plan.addOperation(func() error {
+			return syncer.setRecord(primary, synced, true)
+		})
+	}
+}
+
+func (syncer *Syncer) planDeletedUser(plan *syncerPlan, primary string, user *User, record *SyncerRecord) {
+	if user == nil {
+		if record != nil {
+			plan.addOperation(func() error {
+				return syncer.deleteRecord(primary)
+			})
+		}
+		return
+	}
+
+	plan.addChange(&SyncerChange{Action: SyncerActionDelete, Target: SyncerTargetLocal, Key: primary, User: user.Name})
+	plan.addOperation(func() error {
+		_, err := DeleteUser(user)
+		if err != nil {
+			return err
+		}
+
+		return syncer.deleteRecord(primary)
+	})
+}
+
+func (syncer *Syncer) planNewOriginalUser(plan *syncerPlan, primary string, user *User, record *SyncerRecord) {
+	newOUser := syncer.createOriginalUserFromUser(user)
+	values := syncer.getHashedValues(newOUser)
+
+	plan.addChange(&SyncerChange{Action: SyncerActionCreate, Target: SyncerTargetSource, Key: primary, User: user.Name})
+	plan.addOperation(func() error {
+		_, err := syncer.addUser(newOUser)
+		if err != nil {
+			return err
+		}
+
+		return syncer.setRecord(primary, values, record != nil)
+	})
+}
+
+// getSyncPlan reads the changes of the original table according to the sync mode and returns the
+// changes to make on both sides, without making them
+func (syncer *Syncer) getSyncPlan() (*syncerPlan, error) {
 	if len(syncer.TableColumns) == 0 {
-		return fmt.Errorf("The syncer table columns should not be empty")
+		return nil, fmt.Errorf("The syncer table columns should not be empty")
 	}
 
-	fmt.Printf("Running syncUsers()..\n")
+	plan := &syncerPlan{report: &SyncerReport{SyncMode: syncer.getSyncMode(), Cursor: syncer.SyncCursor, Changes: []*SyncerChange{}}}
+	isFull := syncer.getSyncMode() == SyncerModeFull
 
-	users, _, _ := syncer.getUserMap()
-	oUsers, _, err := syncer.getOriginalUserMap()
+	var oUsers []*OriginalUser
+	var deletedKeys []string
+	var err error
+	switch syncer.getSyncMode() {
+	case SyncerModeIncremental:
+		oUsers, plan.report.Cursor, err = syncer.getChangedOriginalUsers()
+	case SyncerModeChangeLog:
+		oUsers, deletedKeys, plan.report.Cursor, err = syncer.getChangeLogOriginalUsers()
+	default:
+		oUsers, err = syncer.getOriginalUsers()
+	}
 	if err != nil {
-		fmt.Printf(err.Error())
+		return nil, err
+	}
+	plan.report.SourceCount = len(oUsers)
 
-		timestamp := time.Now().Format("2006-01-02 15:04:05")
-		line := fmt.Sprintf("[%s] %s\n", timestamp, err.Error())
-		_, err = updateSyncerErrorText(syncer, line)
+	key := syncer.getKey()
+	var users []*User
+	var records map[string]*SyncerRecord
+	if isFull {
+		users, err = GetUsers(syncer.Organization)
 		if err != nil {
-			return err
+			return nil, err
 		}
+
+		records, err = syncer.getAllRecordMap()
+	} else {
+		keys := append([]string{}, deletedKeys...)
+		for _, oUser := range oUsers {
+			keys = append(keys, syncer.getUserValue(oUser, key))
+		}
+
+		users, err = syncer.getLocalUsers(keys)
+		if err != nil {
+			return nil, err
+		}
+
+		records, err = syncer.getRecordMap(keys)
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	fmt.Printf("Users: %d, oUsers: %d\n", len(users), len(oUsers))
@@ -44,105 +337,101 @@ func (syncer *Syncer) syncUsers() error {
 	var affiliationMap map[int]string
 	if syncer.AffiliationTable != "" {
 		_, affiliationMap, err = syncer.getAffiliationMap()
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	key := syncer.getKey()
-
 	myUsers := map[string]*User{}
-	for _, m := range users {
-		myUsers[syncer.getUserValue(m, key)] = m
+	for _, user := range users {
+		myUsers[syncer.getUserValue(user, key)] = user
 	}
 
-	myOUsers := map[string]*User{}
-	for _, m := range oUsers {
-		myOUsers[syncer.getUserValue(m, key)] = m
-	}
-
-	newUsers := []*User{}
+	myOUsers := map[string]bool{}
 	for _, oUser := range oUsers {
 		primary := syncer.getUserValue(oUser, key)
+		myOUsers[primary] = true
+		syncer.planUser(plan, primary, myUsers[primary], oUser, records[primary], affiliationMap)
+	}
 
-		if _, ok := myUsers[primary]; !ok {
-			newUser := syncer.createUserFromOriginalUser(oUser, affiliationMap)
-			fmt.Printf("New user: %v\n", newUser)
-			newUsers = append(newUsers, newUser)
-		} else {
-			user := myUsers[primary]
-			oHash := syncer.calculateHash(oUser)
-			if user.Hash == user.PreHash {
-				if user.Hash != oHash {
-					updatedUser := syncer.createUserFromOriginalUser(oUser, affiliationMap)
-					updatedUser.Hash = oHash
-					updatedUser.PreHash = oHash
-
-					fmt.Printf("Update from oUser to user: %v\n", updatedUser)
-					_, err = syncer.updateUserForOriginalByFields(updatedUser, key)
-					if err != nil {
-						return err
-					}
-				}
-			} else {
-				if user.PreHash == oHash {
-					if !syncer.IsReadOnly {
-						updatedOUser := syncer.createOriginalUserFromUser(user)
-
-						fmt.Printf("Update from user to oUser: %v\n", updatedOUser)
-						_, err = syncer.updateUser(updatedOUser)
-						if err != nil {
-							return err
-						}
-					}
-
-					// update preHash
-					user.PreHash = user.Hash
-					_, err = SetUserField(user, "pre_hash", user.PreHash)
-					if err != nil {
-						return err
-					}
-				} else {
-					if user.Hash == oHash {
-						// update preHash
-						user.PreHash = user.Hash
-						_, err = SetUserField(user, "pre_hash", user.PreHash)
-						if err != nil {
-							return err
-						}
-					} else {
-						updatedUser := syncer.createUserFromOriginalUser(oUser, affiliationMap)
-						updatedUser.Hash = oHash
-						updatedUser.PreHash = oHash
-
-						fmt.Printf("Update from oUser to user (2nd condition): %v\n", updatedUser)
-						_, err = syncer.updateUserForOriginalByFields(updatedUser, key)
-						if err != nil {
-							return err
-						}
-					}
+	if syncer.SyncDeletions {
+		if isFull {
+			// the synced users which are not in the table anymore have been deleted from it
+			for primary := range records {
+				if !myOUsers[primary] {
+					deletedKeys = append(deletedKeys, primary)
 				}
 			}
+			sort.Strings(deletedKeys)
+		}
+
+		for _, primary := range deletedKeys {
+			syncer.planDeletedUser(plan, primary, myUsers[primary], records[primary])
 		}
 	}
 
-	_, err = AddUsersInBatch(newUsers)
+	// only a full sync knows the users missing from the table
+	if isFull && !syncer.IsReadOnly {
+		for _, user := range users {
+			primary := syncer.getUserValue(user, key)
+			if !myOUsers[primary] && (!syncer.SyncDeletions || records[primary] == nil) {
+				syncer.planNewOriginalUser(plan, primary, user, records[primary])
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+func (syncer *Syncer) applySyncPlan(plan *syncerPlan) error {
+	_, err := AddUsersInBatch(plan.newUsers)
 	if err != nil {
 		return err
 	}
 
-	if !syncer.IsReadOnly {
-		for _, user := range users {
-			primary := syncer.getUserValue(user, key)
-			if _, ok := myOUsers[primary]; !ok {
-				newOUser := syncer.createOriginalUserFromUser(user)
+	for _, operation := range plan.operations {
+		err = operation()
+		if err != nil {
+			return err
+		}
+	}
 
-				fmt.Printf("New oUser: %v\n", newOUser)
-				_, err = syncer.addUser(newOUser)
-				if err != nil {
-					return err
-				}
-			}
+	err = syncer.addRecordsInBatch(plan.newRecords)
+	if err != nil {
+		return err
+	}
+
+	if plan.report.Cursor != syncer.SyncCursor {
+		syncer.SyncCursor = plan.report.Cursor
+		_, err = ormer.Engine.ID(core.PK{syncer.Owner, syncer.Name}).Cols("sync_cursor").Update(syncer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (syncer *Syncer) syncUsers() error {
+	fmt.Printf("Running syncUsers()..\n")
+
+	plan, err := syncer.getSyncPlan()
+	if err == nil {
+		err = syncer.applySyncPlan(plan)
+	}
+
+	if err != nil {
+		timestamp := time.Now().Format("2006-01-02 15:04:05")
+		line := fmt.Sprintf("[%s] %s\n", timestamp, err.Error())
+		_, updateErr := updateSyncerErrorText(syncer, line)
+		if updateErr != nil {
+			return updateErr
 		}
+		return err
 	}
 
+	report := plan.report
+	fmt.Printf("Synced users: %d created, %d updated, %d deleted, %d conflicts\n", report.CreateCount, report.UpdateCount, report.DeleteCount, report.ConflictCount)
 	return nil
 }
 
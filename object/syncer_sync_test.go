@@ -0,0 +1,51 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeUserValues(t *testing.T) {
+	base := map[string]string{"name": "alice", "email": "alice@example.com", "phone": "1"}
+	local := map[string]string{"name": "alice", "email": "alice@local.example.com", "phone": "2"}
+	source := map[string]string{"name": "alice", "email": "alice@source.example.com", "phone": "1"}
+
+	syncer := &Syncer{ConflictPolicy: SyncerConflictPolicySource}
+	merged, conflicts := syncer.mergeUserValues(local, source, base)
+	assert.Equal(t, source, merged)
+	assert.Equal(t, []string{"email"}, conflicts)
+
+	syncer.ConflictPolicy = SyncerConflictPolicyLocal
+	merged, _ = syncer.mergeUserValues(local, source, base)
+	assert.Equal(t, local, merged)
+
+	syncer.ConflictPolicy = SyncerConflictPolicyField
+	merged, _ = syncer.mergeUserValues(local, source, base)
+	assert.Equal(t, map[string]string{"name": "alice", "email": "alice@source.example.com", "phone": "2"}, merged)
+
+	// the local changes are kept when the original user has not changed
+	merged, conflicts = syncer.mergeUserValues(local, base, base)
+	assert.Equal(t, local, merged)
+	assert.Empty(t, conflicts)
+
+	// without base, every difference is a conflict
+	syncer.ConflictPolicy = ""
+	merged, conflicts = syncer.mergeUserValues(local, source, nil)
+	assert.Equal(t, source, merged)
+	assert.Equal(t, []string{"email", "phone"}, conflicts)
+}
@@ -50,6 +50,84 @@ func (syncer *Syncer) getOriginalUsers() ([]*OriginalUser, error) {
 	return users, nil
 }
 
+// getChangedOriginalUsers returns the original users whose change column is not lower than the
+// cursor, and the greatest value of the column
+func (syncer *Syncer) getChangedOriginalUsers() ([]*OriginalUser, string, error) {
+	column := syncer.Ormer.Engine.Quote(syncer.ChangeColumn)
+	session := syncer.Ormer.Engine.Table(syncer.getTable()).OrderBy(column)
+	if syncer.SyncCursor != "" {
+		// the rows changed at the same time as the last one of the previous sync are read again
+		session = session.Where(fmt.Sprintf("%s >= ?", column), syncer.SyncCursor)
+	}
+
+	var results []map[string]sql.NullString
+	err := session.Find(&results)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cursor := syncer.SyncCursor
+	if len(results) != 0 {
+		cursor = results[len(results)-1][syncer.ChangeColumn].String
+	}
+
+	return syncer.getOriginalUsersFromMap(results), cursor, nil
+}
+
+// getChangeLogOriginalUsers returns the original users whose keys were added to the change log
+// after the cursor, the keys which are not in the table anymore, and the last value of the change
+// column of the log
+func (syncer *Syncer) getChangeLogOriginalUsers() ([]*OriginalUser, []string, string, error) {
+	column := syncer.Ormer.Engine.Quote(syncer.ChangeColumn)
+	session := syncer.Ormer.Engine.Table(syncer.getChangeLogTable()).Cols(syncer.ChangeLogKeyColumn, syncer.ChangeColumn).OrderBy(column)
+	if syncer.SyncCursor != "" {
+		session = session.Where(fmt.Sprintf("%s > ?", column), syncer.SyncCursor)
+	}
+
+	var changes []map[string]sql.NullString
+	err := session.Find(&changes)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	cursor := syncer.SyncCursor
+	keys := []string{}
+	isChanged := map[string]bool{}
+	for _, change := range changes {
+		key := change[syncer.ChangeLogKeyColumn].String
+		if !isChanged[key] {
+			isChanged[key] = true
+			keys = append(keys, key)
+		}
+		cursor = change[syncer.ChangeColumn].String
+	}
+
+	key := syncer.getKey()
+	users := []*OriginalUser{}
+	isFound := map[string]bool{}
+	for i := 0; i < len(keys); i += syncerRecordBatchSize {
+		var results []map[string]sql.NullString
+		err = syncer.Ormer.Engine.Table(syncer.getTable()).In(key, keys[i:min(i+syncerRecordBatchSize, len(keys))]).Find(&results)
+		if err != nil {
+			return nil, nil, "", err
+		}
+
+		for _, result := range results {
+			isFound[result[key].String] = true
+		}
+		users = append(users, syncer.getOriginalUsersFromMap(results)...)
+	}
+
+	deletedKeys := []string{}
+	for _, key := range keys {
+		if !isFound[key] {
+			deletedKeys = append(deletedKeys, key)
+		}
+	}
+
+	return users, deletedKeys, cursor, nil
+}
+
 func (syncer *Syncer) getOriginalUserMap() ([]*OriginalUser, map[string]*OriginalUser, error) {
 	users, err := syncer.getOriginalUsers()
 	if err != nil {
@@ -84,8 +162,16 @@ func (syncer *Syncer) getCasdoorColumns() []string {
 }
 
 func (syncer *Syncer) updateUser(user *OriginalUser) (bool, error) {
+	return syncer.updateUserValues(syncer.getMapFromOriginalUser(user))
+}
+
+// updateUserValues updates the row of the table with the values of its columns
+func (syncer *Syncer) updateUserValues(values map[string]string) (bool, error) {
 	key := syncer.getKey()
-	m := syncer.getMapFromOriginalUser(user)
+	m := map[string]string{}
+	for k, v := range values {
+		m[k] = v
+	}
 	pkValue := m[key]
 	delete(m, key)
 
@@ -96,6 +182,24 @@ func (syncer *Syncer) updateUser(user *OriginalUser) (bool, error) {
 	return affected != 0, nil
 }
 
+// getLocalUsers returns the users of the organization whose key is one of the keys, see getUserValue
+func (syncer *Syncer) getLocalUsers(keys []string) ([]*User, error) {
+	column := "id"
+	field := util.SnakeToCamel(syncer.getKey())
+	if _, ok := getUserFieldValue(&User{}, field); ok {
+		column = util.CamelToSnakeCase(field)
+	}
+
+	users := []*User{}
+	for i := 0; i < len(keys); i += syncerRecordBatchSize {
+		err := ormer.Engine.Where("owner = ?", syncer.Organization).In(column, keys[i:min(i+syncerRecordBatchSize, len(keys))]).Find(&users)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return users, nil
+}
+
 func (syncer *Syncer) updateUserForOriginalFields(user *User) (bool, error) {
 	var err error
 	owner, name := util.GetOwnerAndNameFromId(user.GetId())
@@ -35,6 +35,10 @@ const (
 	UnsupportedGrantType = "unsupported_grant_type"
 	InvalidScope         = "invalid_scope"
 	EndpointError        = "endpoint_error"
+
+	UnsupportedTokenType = "unsupported_token_type"
+
+	ClientCredentialsGrantType = "client_credentials"
 )
 
 type Code struct {
@@ -60,6 +64,9 @@ type Token struct {
 	CodeChallenge string `xorm:"varchar(100)" json:"codeChallenge"`
 	CodeIsUsed    bool   `json:"codeIsUsed"`
 	CodeExpireIn  int64  `json:"codeExpireIn"`
+	// GrantType is only set for the tokens of the client credentials grant, which represent the
+	// application itself
+	GrantType string `xorm:"varchar(100)" json:"grantType"`
 }
 
 type TokenWrapper struct {
@@ -69,6 +76,8 @@ type TokenWrapper struct {
 	TokenType    string `json:"token_type"`
 	ExpiresIn    int    `json:"expires_in"`
 	Scope        string `json:"scope"`
+
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
 }
 
 type TokenError struct {
@@ -218,6 +227,82 @@ func ExpireTokenByAccessToken(accessToken string) (bool, *Application, *Token, e
 	return affected != 0, application, &token, nil
 }
 
+func getTokenByRefreshToken(refreshToken string) (*Token, error) {
+	if refreshToken == "" {
+		return nil, nil
+	}
+
+	token := Token{RefreshToken: refreshToken}
+	existed, err := ormer.Engine.Get(&token)
+	if err != nil {
+		return nil, err
+	}
+
+	if !existed {
+		return nil, nil
+	}
+
+	return &token, nil
+}
+
+// RevokeToken revokes an access token or a refresh token issued to the application, see RFC 7009.
+// Revoking an access token only expires it, while revoking a refresh token also invalidates the
+// access token issued together with it, as that is the access token derived from the refresh token.
+func RevokeToken(application *Application, tokenValue string, tokenTypeHint string) (*TokenError, error) {
+	if tokenTypeHint != "" && tokenTypeHint != "access_token" && tokenTypeHint != "refresh_token" {
+		return &TokenError{
+			Error:            UnsupportedTokenType,
+			ErrorDescription: fmt.Sprintf("token_type_hint: %s is not supported", tokenTypeHint),
+		}, nil
+	}
+
+	if tokenValue == "" {
+		return &TokenError{
+			Error:            InvalidRequest,
+			ErrorDescription: "token should not be empty",
+		}, nil
+	}
+
+	// the hint only decides which kind of token is looked up first
+	lookups := []func(string) (*Token, error){GetTokenByAccessToken, getTokenByRefreshToken}
+	if tokenTypeHint == "refresh_token" {
+		lookups = []func(string) (*Token, error){getTokenByRefreshToken, GetTokenByAccessToken}
+	}
+
+	var token *Token
+	for _, lookup := range lookups {
+		var err error
+		token, err = lookup(tokenValue)
+		if err != nil {
+			return nil, err
+		}
+		if token != nil {
+			break
+		}
+	}
+
+	// invalid tokens do not cause an error response, the purpose of the request has been achieved already
+	if token == nil {
+		return nil, nil
+	}
+
+	if token.Owner != application.Owner || token.Application != application.Name {
+		return &TokenError{
+			Error:            UnauthorizedClient,
+			ErrorDescription: "the token was not issued to this application (client_id)",
+		}, nil
+	}
+
+	if token.RefreshToken == tokenValue {
+		_, err := DeleteToken(token)
+		return nil, err
+	}
+
+	token.ExpiresIn = 0
+	_, err := ormer.Engine.ID(core.PK{token.Owner, token.Name}).Cols("expires_in").Update(token)
+	return nil, err
+}
+
 func GetTokenByAccessToken(accessToken string) (*Token, error) {
 	// Check if the accessToken is in the database
 	token := Token{AccessToken: accessToken}
@@ -342,7 +427,7 @@ func GetOAuthCode(userId string, clientId string, responseType string, redirectU
 	}, nil
 }
 
-func GetOAuthToken(grantType string, clientId string, clientSecret string, code string, verifier string, scope string, username string, password string, host string, refreshToken string, tag string, avatar string, lang string) (interface{}, error) {
+func GetOAuthToken(grantType string, clientId string, clientSecret string, code string, verifier string, scope string, username string, password string, host string, refreshToken string, tag string, avatar string, exchangeParams *TokenExchangeParams, lang string) (interface{}, error) {
 	application, err := GetApplicationByClientId(clientId)
 	if err != nil {
 		return nil, err
@@ -373,6 +458,12 @@ func GetOAuthToken(grantType string, clientId string, clientSecret string, code
 		token, tokenError, err = GetPasswordToken(application, username, password, scope, host)
 	case "client_credentials": // Client Credentials Grant
 		token, tokenError, err = GetClientCredentialsToken(application, clientSecret, scope, host)
+	case DeviceCodeGrantType: // Device Authorization Grant, the device code is passed as the code
+		token, tokenError, err = GetDeviceCodeToken(application, clientSecret, code, host)
+	case JwtBearerGrantType: // JWT Bearer Authorization Grant, the assertion is passed as the code
+		token, tokenError, err = GetJwtBearerToken(application, clientSecret, code, scope, host)
+	case TokenExchangeGrantType: // Token Exchange
+		token, tokenError, err = GetTokenExchangeToken(application, clientSecret, exchangeParams, scope, host)
 	case "refresh_token":
 		refreshToken2, err := RefreshToken(grantType, refreshToken, scope, clientId, clientSecret, host)
 		if err != nil {
@@ -401,6 +492,8 @@ func GetOAuthToken(grantType string, clientId string, clientSecret string, code
 
 	go updateUsedByCode(token)
 
+	emitTokenIssuedEvent(token, grantType)
+
 	tokenWrapper := &TokenWrapper{
 		AccessToken:  token.AccessToken,
 		IdToken:      token.AccessToken,
@@ -410,6 +503,10 @@ func GetOAuthToken(grantType string, clientId string, clientSecret string, code
 		Scope:        token.Scope,
 	}
 
+	if grantType == TokenExchangeGrantType {
+		tokenWrapper.IssuedTokenType = AccessTokenType
+	}
+
 	return tokenWrapper, nil
 }
 
@@ -509,6 +606,8 @@ func RefreshToken(grantType string, refreshToken string, scope string, clientId
 		return nil, err
 	}
 
+	emitTokenIssuedEvent(newToken, grantType)
+
 	tokenWrapper := &TokenWrapper{
 		AccessToken:  newToken.AccessToken,
 		IdToken:      newToken.AccessToken,
@@ -724,6 +823,7 @@ func GetClientCredentialsToken(application *Application, clientSecret string, sc
 		Scope:        scope,
 		TokenType:    "Bearer",
 		CodeIsUsed:   true,
+		GrantType:    ClientCredentialsGrantType,
 	}
 	_, err = AddToken(token)
 	if err != nil {
@@ -766,6 +866,8 @@ func GetTokenByUser(application *Application, user *User, scope string, host str
 		return nil, err
 	}
 
+	emitTokenIssuedEvent(token, "implicit")
+
 	return token, nil
 }
 
@@ -21,8 +21,6 @@ import (
 	"encoding/pem"
 	"encoding/xml"
 	"fmt"
-	"math/rand"
-	"sync"
 	"time"
 
 	"github.com/beevik/etree"
@@ -87,12 +85,6 @@ type CasAnyAttribute struct {
 	Value   string `xml:",chardata"`
 }
 
-type CasAuthenticationSuccessWrapper struct {
-	AuthenticationSuccess *CasAuthenticationSuccess // the token we issued
-	Service               string                    // to which service this token is issued
-	UserId                string
-}
-
 type CasProxySuccess struct {
 	XMLName     xml.Name `xml:"cas:proxySuccess" json:"-"`
 	ProxyTicket string   `xml:"cas:proxyTicket"`
@@ -103,6 +95,19 @@ type CasProxyFailure struct {
 	Message string   `xml:",innerxml"`
 }
 
+// CasLogoutRequest is sent to the services when the session of the user ends, see
+// https://apereo.github.io/cas/6.6.x/installation/Logout-Single-Signout.html
+type CasLogoutRequest struct {
+	XMLName      xml.Name `xml:"samlp:LogoutRequest"`
+	XmlnsSamlp   string   `xml:"xmlns:samlp,attr"`
+	XmlnsSaml    string   `xml:"xmlns:saml,attr"`
+	ID           string   `xml:"ID,attr"`
+	Version      string   `xml:"Version,attr"`
+	IssueInstant string   `xml:"IssueInstant,attr"`
+	NameID       string   `xml:"saml:NameID"`
+	SessionIndex string   `xml:"samlp:SessionIndex"`
+}
+
 type Saml11Request struct {
 	XMLName           xml.Name `xml:"Request"`
 	SAMLP             string   `xml:"samlp,attr"`
@@ -117,12 +122,6 @@ type Saml11AssertionArtifact struct {
 	InnerXML string   `xml:",innerxml"`
 }
 
-// st is short for service ticket
-var stToServiceResponse sync.Map
-
-// pgt is short for proxy granting ticket
-var pgtToServiceResponse sync.Map
-
 func CheckCasLogin(application *Application, lang string, service string) error {
 	if len(application.RedirectUris) > 0 && !application.IsRedirectUriValid(service) {
 		return fmt.Errorf(i18n.Translate(lang, "token:Redirect URI: %s doesn't exist in the allowed Redirect URI list"), service)
@@ -130,18 +129,9 @@ func CheckCasLogin(application *Application, lang string, service string) error
 	return nil
 }
 
-func StoreCasTokenForPgt(token *CasAuthenticationSuccess, service, userId string) string {
-	pgt := fmt.Sprintf("PGT-%s", util.GenerateId())
-	pgtToServiceResponse.Store(pgt, &CasAuthenticationSuccessWrapper{
-		AuthenticationSuccess: token,
-		Service:               service,
-		UserId:                userId,
-	})
-	return pgt
-}
-
-func GenerateId() {
-	panic("unimplemented")
+// StoreCasTokenForPgt issues a proxy-granting ticket, which can be used until it expires
+func StoreCasTokenForPgt(token *CasAuthenticationSuccess, service, userId string) (string, error) {
+	return addCasTicket("PGT", token, service, userId, "", casProxyGrantingTicketExpireIn)
 }
 
 // GetCasTokenByPgt
@@ -150,13 +140,10 @@ func GenerateId() {
 @ret2: token, nil if not found
 @ret3: the service URL who requested to issue this token
 @ret4: userIf of user who requested to issue this token
+@ret5: error
 */
-func GetCasTokenByPgt(pgt string) (bool, *CasAuthenticationSuccess, string, string) {
-	if responseWrapperType, ok := pgtToServiceResponse.LoadAndDelete(pgt); ok {
-		responseWrapperTypeCast := responseWrapperType.(*CasAuthenticationSuccessWrapper)
-		return true, responseWrapperTypeCast.AuthenticationSuccess, responseWrapperTypeCast.Service, responseWrapperTypeCast.UserId
-	}
-	return false, nil, "", ""
+func GetCasTokenByPgt(pgt string) (bool, *CasAuthenticationSuccess, string, string, error) {
+	return getCasToken(pgt, false)
 }
 
 // GetCasTokenByTicket
@@ -165,26 +152,35 @@ func GetCasTokenByPgt(pgt string) (bool, *CasAuthenticationSuccess, string, stri
 @ret2: token, nil if not found
 @ret3: the service URL who requested to issue this token
 @ret4: userIf of user who requested to issue this token
+@ret5: error
 */
-func GetCasTokenByTicket(ticket string) (bool, *CasAuthenticationSuccess, string, string) {
-	if responseWrapperType, ok := stToServiceResponse.LoadAndDelete(ticket); ok {
-		responseWrapperTypeCast := responseWrapperType.(*CasAuthenticationSuccessWrapper)
-		return true, responseWrapperTypeCast.AuthenticationSuccess, responseWrapperTypeCast.Service, responseWrapperTypeCast.UserId
+func GetCasTokenByTicket(ticket string) (bool, *CasAuthenticationSuccess, string, string, error) {
+	return getCasToken(ticket, true)
+}
+
+func getCasToken(name string, isSingleUse bool) (bool, *CasAuthenticationSuccess, string, string, error) {
+	ticket, err := getCasTicket(name, isSingleUse)
+	if err != nil {
+		return false, nil, "", "", err
+	}
+	if ticket == nil {
+		return false, nil, "", "", nil
 	}
-	return false, nil, "", ""
+
+	response, err := ticket.getResponse()
+	if err != nil {
+		return false, nil, "", "", err
+	}
+	return true, response, ticket.Service, ticket.UserId, nil
 }
 
-func StoreCasTokenForProxyTicket(token *CasAuthenticationSuccess, targetService, userId string) string {
-	proxyTicket := fmt.Sprintf("PT-%s", util.GenerateId())
-	stToServiceResponse.Store(proxyTicket, &CasAuthenticationSuccessWrapper{
-		AuthenticationSuccess: token,
-		Service:               targetService,
-		UserId:                userId,
-	})
-	return proxyTicket
+func StoreCasTokenForProxyTicket(token *CasAuthenticationSuccess, targetService, userId string) (string, error) {
+	return addCasTicket("PT", token, targetService, userId, "", casServiceTicketExpireIn)
 }
 
-func GenerateCasToken(userId string, service string) (string, error) {
+// GenerateCasToken issues a service ticket, sid is the browser session which the service is sent
+// a single logout request for when it ends
+func GenerateCasToken(userId string, service string, sid string) (string, error) {
 	user, err := GetUser(userId)
 	if err != nil {
 		return "", err
@@ -229,13 +225,7 @@ func GenerateCasToken(userId string, service string) (string, error) {
 		}
 	}
 
-	st := fmt.Sprintf("ST-%d", rand.Int())
-	stToServiceResponse.Store(st, &CasAuthenticationSuccessWrapper{
-		AuthenticationSuccess: &authenticationSuccess,
-		Service:               service,
-		UserId:                userId,
-	})
-	return st, nil
+	return addCasTicket("ST", &authenticationSuccess, service, userId, sid, casServiceTicketExpireIn)
 }
 
 // GetValidationBySaml
@@ -256,7 +246,10 @@ func GetValidationBySaml(samlRequest string, host string) (string, string, error
 		return "", "", fmt.Errorf("samlp:AssertionArtifact field not found")
 	}
 
-	ok, _, service, userId := GetCasTokenByTicket(ticket)
+	ok, _, service, userId, err := GetCasTokenByTicket(ticket)
+	if err != nil {
+		return "", "", err
+	}
 	if !ok {
 		return "", "", fmt.Errorf("ticket %s found", ticket)
 	}
@@ -0,0 +1,402 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/casdoor/casdoor/i18n"
+	"github.com/casdoor/casdoor/util"
+	"github.com/xorm-io/core"
+)
+
+const (
+	DeviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+	AuthorizationPending = "authorization_pending"
+	SlowDown             = "slow_down"
+	AccessDenied         = "access_denied"
+	ExpiredToken         = "expired_token"
+
+	DeviceAuthStatePending  = "Pending"
+	DeviceAuthStateApproved = "Approved"
+	DeviceAuthStateDenied   = "Denied"
+	DeviceAuthStateUsed     = "Used"
+
+	deviceCodeExpireInSeconds = 600
+	deviceCodeInterval        = 5
+)
+
+// userCodeCharset has no vowels to avoid generating words, and no look-alike characters, see RFC 8628 section 6.1
+const userCodeCharset = "BCDFGHJKLMNPQRSTVWXZ"
+
+type DeviceAuth struct {
+	Owner       string `xorm:"varchar(100) notnull pk" json:"owner"`
+	Name        string `xorm:"varchar(100) notnull pk" json:"name"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+
+	Application  string `xorm:"varchar(100)" json:"application"`
+	Organization string `xorm:"varchar(100)" json:"organization"`
+	User         string `xorm:"varchar(100)" json:"user"`
+
+	DeviceCode   string `xorm:"varchar(100) index" json:"deviceCode"`
+	UserCode     string `xorm:"varchar(100) index" json:"userCode"`
+	Scope        string `xorm:"varchar(100)" json:"scope"`
+	State        string `xorm:"varchar(100)" json:"state"`
+	ExpireIn     int64  `json:"expireIn"`
+	Interval     int    `json:"interval"`
+	LastPollTime int64  `json:"lastPollTime"`
+}
+
+type DeviceAuthResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationUri         string `json:"verification_uri"`
+	VerificationUriComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+func generateUserCode() (string, error) {
+	res := make([]byte, 8)
+	for i := range res {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(userCodeCharset))))
+		if err != nil {
+			return "", err
+		}
+		res[i] = userCodeCharset[n.Int64()]
+	}
+
+	return fmt.Sprintf("%s-%s", res[:4], res[4:]), nil
+}
+
+// normalizeUserCode accepts the user code as typed by the user, e.g. "bcdf ghjk" or "BCDFGHJK"
+func normalizeUserCode(userCode string) string {
+	s := strings.ToUpper(userCode)
+	s = strings.NewReplacer("-", "", " ", "").Replace(s)
+	if len(s) != 8 {
+		return s
+	}
+	return fmt.Sprintf("%s-%s", s[:4], s[4:])
+}
+
+func (deviceAuth *DeviceAuth) GetId() string {
+	return fmt.Sprintf("%s/%s", deviceAuth.Owner, deviceAuth.Name)
+}
+
+func (deviceAuth *DeviceAuth) isExpired() bool {
+	return time.Now().Unix() > deviceAuth.ExpireIn
+}
+
+func getDeviceAuth(deviceAuth *DeviceAuth) (*DeviceAuth, error) {
+	existed, err := ormer.Engine.Get(deviceAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	if existed {
+		return deviceAuth, nil
+	}
+
+	return nil, nil
+}
+
+func getDeviceAuthByDeviceCode(deviceCode string) (*DeviceAuth, error) {
+	if deviceCode == "" {
+		return nil, nil
+	}
+
+	return getDeviceAuth(&DeviceAuth{DeviceCode: deviceCode})
+}
+
+func GetDeviceAuthByUserCode(userCode string) (*DeviceAuth, error) {
+	userCode = normalizeUserCode(userCode)
+	if userCode == "" {
+		return nil, nil
+	}
+
+	return getDeviceAuth(&DeviceAuth{UserCode: userCode, State: DeviceAuthStatePending})
+}
+
+func updateDeviceAuth(deviceAuth *DeviceAuth, columns ...string) (bool, error) {
+	affected, err := ormer.Engine.ID(core.PK{deviceAuth.Owner, deviceAuth.Name}).Cols(columns...).Update(deviceAuth)
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+// DeleteExpiredDeviceAuths removes the device authorizations that can no longer be used
+func DeleteExpiredDeviceAuths() (int64, error) {
+	return ormer.Engine.Where("expire_in < ?", time.Now().Unix()).Delete(&DeviceAuth{})
+}
+
+// GetDeviceAuthorization starts the device authorization flow, see RFC 8628 section 3.1
+func GetDeviceAuthorization(clientId string, clientSecret string, scope string, host string) (interface{}, error) {
+	application, err := GetApplicationByClientId(clientId)
+	if err != nil {
+		return nil, err
+	}
+
+	if application == nil {
+		return &TokenError{
+			Error:            InvalidClient,
+			ErrorDescription: "client_id is invalid",
+		}, nil
+	}
+
+	// device clients are usually public clients, but if the secret is provided, it must be accurate
+	if clientSecret != "" && application.ClientSecret != clientSecret {
+		return &TokenError{
+			Error:            InvalidClient,
+			ErrorDescription: "client_secret is invalid",
+		}, nil
+	}
+
+	if !IsGrantTypeValid(DeviceCodeGrantType, application.GrantTypes) {
+		return &TokenError{
+			Error:            UnauthorizedClient,
+			ErrorDescription: fmt.Sprintf("grant_type: %s is not supported in this application", DeviceCodeGrantType),
+		}, nil
+	}
+
+	_, err = DeleteExpiredDeviceAuths()
+	if err != nil {
+		return nil, err
+	}
+
+	userCode, err := generateUserCode()
+	if err != nil {
+		return nil, err
+	}
+
+	deviceAuth := &DeviceAuth{
+		Owner:        application.Owner,
+		Name:         util.GenerateId(),
+		CreatedTime:  util.GetCurrentTime(),
+		Application:  application.Name,
+		Organization: application.Organization,
+		DeviceCode:   util.GenerateClientSecret(),
+		UserCode:     userCode,
+		Scope:        scope,
+		State:        DeviceAuthStatePending,
+		ExpireIn:     time.Now().Add(deviceCodeExpireInSeconds * time.Second).Unix(),
+		Interval:     deviceCodeInterval,
+	}
+	_, err = ormer.Engine.Insert(deviceAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	originFrontend, _ := getOriginFromHost(host)
+	verificationUri := fmt.Sprintf("%s/login/oauth/device", originFrontend)
+
+	return &DeviceAuthResponse{
+		DeviceCode:              deviceAuth.DeviceCode,
+		UserCode:                deviceAuth.UserCode,
+		VerificationUri:         verificationUri,
+		VerificationUriComplete: fmt.Sprintf("%s/%s", verificationUri, deviceAuth.UserCode),
+		ExpiresIn:               deviceCodeExpireInSeconds,
+		Interval:                deviceAuth.Interval,
+	}, nil
+}
+
+// ApproveDeviceAuth records the decision of the signed-in user on the device identified by the user code
+func ApproveDeviceAuth(userCode string, user *User, approved bool, lang string) (string, error) {
+	deviceAuth, err := GetDeviceAuthByUserCode(userCode)
+	if err != nil {
+		return "", err
+	}
+
+	if deviceAuth == nil || deviceAuth.isExpired() {
+		return i18n.Translate(lang, "token:The user code is invalid or has expired"), nil
+	}
+
+	if user.Owner != deviceAuth.Organization {
+		return fmt.Sprintf(i18n.Translate(lang, "token:The user of organization: %s is not allowed to authorize the application: %s"), user.Owner, deviceAuth.Application), nil
+	}
+
+	if approved && user.IsForbidden {
+		return i18n.Translate(lang, "check:The user is forbidden to sign in, please contact the administrator"), nil
+	}
+
+	deviceAuth.User = user.Name
+	deviceAuth.State = DeviceAuthStateDenied
+	if approved {
+		deviceAuth.State = DeviceAuthStateApproved
+	}
+
+	affected, err := decideDeviceAuth(deviceAuth)
+	if err != nil {
+		return "", err
+	}
+	if !affected {
+		return i18n.Translate(lang, "token:The user code has already been used"), nil
+	}
+
+	return "", nil
+}
+
+// decideDeviceAuth saves the user and the decision only if the device authorization is still pending,
+// so that concurrent decisions on the same user code can't overwrite each other
+func decideDeviceAuth(deviceAuth *DeviceAuth) (bool, error) {
+	affected, err := ormer.Engine.ID(core.PK{deviceAuth.Owner, deviceAuth.Name}).Where("state = ?", DeviceAuthStatePending).Cols("user", "state").Update(deviceAuth)
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+// GetDeviceCodeToken
+// Device Authorization Grant flow, polled by the device until the user has made a decision
+func GetDeviceCodeToken(application *Application, clientSecret string, deviceCode string, host string) (*Token, *TokenError, error) {
+	if clientSecret != "" && application.ClientSecret != clientSecret {
+		return nil, &TokenError{
+			Error:            InvalidClient,
+			ErrorDescription: "client_secret is invalid",
+		}, nil
+	}
+
+	if deviceCode == "" {
+		return nil, &TokenError{
+			Error:            InvalidRequest,
+			ErrorDescription: "device_code should not be empty",
+		}, nil
+	}
+
+	deviceAuth, err := getDeviceAuthByDeviceCode(deviceCode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if deviceAuth == nil || deviceAuth.Owner != application.Owner || deviceAuth.Application != application.Name {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "device_code is invalid",
+		}, nil
+	}
+
+	if deviceAuth.isExpired() {
+		return nil, &TokenError{
+			Error:            ExpiredToken,
+			ErrorDescription: "device_code has expired",
+		}, nil
+	}
+
+	switch deviceAuth.State {
+	case DeviceAuthStateDenied:
+		return nil, &TokenError{
+			Error:            AccessDenied,
+			ErrorDescription: "the user has denied the authorization request",
+		}, nil
+	case DeviceAuthStateUsed:
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "device_code has been used",
+		}, nil
+	case DeviceAuthStatePending:
+		now := time.Now().Unix()
+		tooFast := now-deviceAuth.LastPollTime < int64(deviceAuth.Interval)
+		deviceAuth.LastPollTime = now
+		columns := []string{"last_poll_time"}
+		if tooFast {
+			// the client must add 5 seconds to its polling interval, see RFC 8628 section 3.5
+			deviceAuth.Interval += 5
+			columns = append(columns, "interval")
+		}
+
+		_, err = updateDeviceAuth(deviceAuth, columns...)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if tooFast {
+			return nil, &TokenError{
+				Error:            SlowDown,
+				ErrorDescription: fmt.Sprintf("the polling interval should be at least %d seconds", deviceAuth.Interval),
+			}, nil
+		}
+		return nil, &TokenError{
+			Error:            AuthorizationPending,
+			ErrorDescription: "the user has not completed the authorization request yet",
+		}, nil
+	}
+
+	// mark the device code as used before issuing the token, so a concurrent poll can't redeem it twice
+	deviceAuth.State = DeviceAuthStateUsed
+	affected, err := ormer.Engine.ID(core.PK{deviceAuth.Owner, deviceAuth.Name}).Where("state = ?", DeviceAuthStateApproved).Cols("state").Update(deviceAuth)
+	if err != nil {
+		return nil, nil, err
+	}
+	if affected == 0 {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "device_code has been used",
+		}, nil
+	}
+
+	user, err := getUser(deviceAuth.Organization, deviceAuth.User)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if user == nil || user.IsForbidden {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "the user is forbidden to sign in, please contact the administrator",
+		}, nil
+	}
+
+	err = ExtendUserWithRolesAndPermissions(user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accessToken, refreshToken, tokenName, err := generateJwtToken(application, user, "", deviceAuth.Scope, host, "")
+	if err != nil {
+		return nil, &TokenError{
+			Error:            EndpointError,
+			ErrorDescription: fmt.Sprintf("generate jwt token error: %s", err.Error()),
+		}, nil
+	}
+
+	token := &Token{
+		Owner:        application.Owner,
+		Name:         tokenName,
+		CreatedTime:  util.GetCurrentTime(),
+		Application:  application.Name,
+		Organization: user.Owner,
+		User:         user.Name,
+		Code:         util.GenerateClientId(),
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    application.ExpireInHours * hourSeconds,
+		Scope:        deviceAuth.Scope,
+		TokenType:    "Bearer",
+		CodeIsUsed:   true,
+	}
+	_, err = AddToken(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return token, nil, nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !skipCi
+// +build !skipCi
+
+package object
+
+import (
+	"testing"
+	"time"
+
+	"github.com/casdoor/casdoor/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceCodeGrant(t *testing.T) {
+	InitConfig()
+
+	application := &Application{Owner: "admin", Name: "test_device_app", Organization: "test_device_org"}
+	alice := &User{Owner: application.Organization, Name: "alice"}
+	bob := &User{Owner: application.Organization, Name: "bob"}
+
+	newDeviceAuth := func(state string) *DeviceAuth {
+		userCode, err := generateUserCode()
+		assert.Nil(t, err)
+
+		deviceAuth := &DeviceAuth{
+			Owner:        application.Owner,
+			Name:         util.GenerateId(),
+			CreatedTime:  util.GetCurrentTime(),
+			Application:  application.Name,
+			Organization: application.Organization,
+			DeviceCode:   util.GenerateClientSecret(),
+			UserCode:     userCode,
+			State:        state,
+			ExpireIn:     time.Now().Add(deviceCodeExpireInSeconds * time.Second).Unix(),
+			Interval:     deviceCodeInterval,
+		}
+		_, err = ormer.Engine.Insert(deviceAuth)
+		assert.Nil(t, err)
+		return deviceAuth
+	}
+	defer ormer.Engine.Where("application = ?", application.Name).Delete(&DeviceAuth{})
+
+	getTokenError := func(deviceCode string) string {
+		token, tokenError, err := GetDeviceCodeToken(application, "", deviceCode, "")
+		assert.Nil(t, err)
+		assert.Nil(t, token)
+		if tokenError == nil {
+			return ""
+		}
+		return tokenError.Error
+	}
+
+	t.Run("pending", func(t *testing.T) {
+		deviceAuth := newDeviceAuth(DeviceAuthStatePending)
+		assert.Equal(t, AuthorizationPending, getTokenError(deviceAuth.DeviceCode))
+		assert.Equal(t, SlowDown, getTokenError(deviceAuth.DeviceCode))
+		assert.Equal(t, InvalidGrant, getTokenError("unknown"))
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		deviceAuth := newDeviceAuth(DeviceAuthStatePending)
+		msg, err := ApproveDeviceAuth(deviceAuth.UserCode, alice, false, "en")
+		assert.Nil(t, err)
+		assert.Equal(t, "", msg)
+		assert.Equal(t, AccessDenied, getTokenError(deviceAuth.DeviceCode))
+	})
+
+	t.Run("used", func(t *testing.T) {
+		deviceAuth := newDeviceAuth(DeviceAuthStateUsed)
+		assert.Equal(t, InvalidGrant, getTokenError(deviceAuth.DeviceCode))
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		deviceAuth := newDeviceAuth(DeviceAuthStatePending)
+		deviceAuth.ExpireIn = time.Now().Add(-time.Second).Unix()
+		_, err := updateDeviceAuth(deviceAuth, "expire_in")
+		assert.Nil(t, err)
+		assert.Equal(t, ExpiredToken, getTokenError(deviceAuth.DeviceCode))
+
+		msg, err := ApproveDeviceAuth(deviceAuth.UserCode, alice, true, "en")
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", msg)
+	})
+
+	t.Run("other organization", func(t *testing.T) {
+		deviceAuth := newDeviceAuth(DeviceAuthStatePending)
+		msg, err := ApproveDeviceAuth(deviceAuth.UserCode, &User{Owner: "other_org", Name: "alice"}, true, "en")
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", msg)
+	})
+
+	t.Run("decided once", func(t *testing.T) {
+		deviceAuth := newDeviceAuth(DeviceAuthStatePending)
+		msg, err := ApproveDeviceAuth(deviceAuth.UserCode, alice, true, "en")
+		assert.Nil(t, err)
+		assert.Equal(t, "", msg)
+
+		// the user code is no longer pending
+		msg, err = ApproveDeviceAuth(deviceAuth.UserCode, bob, true, "en")
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", msg)
+
+		// a concurrent decision read the device authorization while it was pending
+		deviceAuth.User = bob.Name
+		deviceAuth.State = DeviceAuthStateApproved
+		affected, err := decideDeviceAuth(deviceAuth)
+		assert.Nil(t, err)
+		assert.False(t, affected)
+
+		approved, err := getDeviceAuthByDeviceCode(deviceAuth.DeviceCode)
+		assert.Nil(t, err)
+		assert.Equal(t, alice.Name, approved.User)
+		assert.Equal(t, DeviceAuthStateApproved, approved.State)
+	})
+}
@@ -0,0 +1,284 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/casdoor/casdoor/util"
+)
+
+const (
+	TokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+	AccessTokenType = "urn:ietf:params:oauth:token-type:access_token"
+	JwtTokenType    = "urn:ietf:params:oauth:token-type:jwt"
+
+	InvalidTarget = "invalid_target"
+)
+
+// TokenExchangeParams holds the RFC 8693 specific parameters of a token exchange request.
+type TokenExchangeParams struct {
+	SubjectToken     string
+	SubjectTokenType string
+	ActorToken       string
+	ActorTokenType   string
+	Audience         string
+}
+
+// exchangeableToken is an access token issued by this server that has been
+// checked to be present, unexpired and correctly signed.
+type exchangeableToken struct {
+	token       *Token
+	claims      *Claims
+	application *Application
+}
+
+func isExchangeableTokenType(tokenType string) bool {
+	return tokenType == AccessTokenType || tokenType == JwtTokenType
+}
+
+func getExchangeableToken(tokenValue string, tokenType string, name string) (*exchangeableToken, *TokenError, error) {
+	if tokenValue == "" {
+		return nil, &TokenError{
+			Error:            InvalidRequest,
+			ErrorDescription: fmt.Sprintf("%s is required", name),
+		}, nil
+	}
+
+	if !isExchangeableTokenType(tokenType) {
+		return nil, &TokenError{
+			Error:            InvalidRequest,
+			ErrorDescription: fmt.Sprintf("%s_type: %s is not supported", name, tokenType),
+		}, nil
+	}
+
+	token, err := GetTokenByAccessToken(tokenValue)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if token == nil || util.IsTokenExpired(token.CreatedTime, token.ExpiresIn) {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("%s is invalid, expired or revoked", name),
+		}, nil
+	}
+
+	application, err := getApplication(token.Owner, token.Application)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if application == nil {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("the application of %s does not exist", name),
+		}, nil
+	}
+
+	claims, err := ParseJwtTokenByApplication(tokenValue, application)
+	if err != nil {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("parse %s error: %s", name, err.Error()),
+		}, nil
+	}
+
+	return &exchangeableToken{token: token, claims: claims, application: application}, nil, nil
+}
+
+// getExchangeSubject returns the user that the subject token was issued to. Tokens
+// issued through the client credentials grant are represented by the same
+// application placeholder user that GetClientCredentialsToken signs.
+func getExchangeSubject(subject *exchangeableToken) (*User, error) {
+	user, err := getUser(subject.token.Organization, subject.token.User)
+	if err != nil {
+		return nil, err
+	}
+
+	if user != nil {
+		err = ExtendUserWithRolesAndPermissions(user)
+		if err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	if subject.token.User == subject.application.Name {
+		return &User{
+			Owner: subject.application.Owner,
+			Id:    subject.application.GetId(),
+			Name:  subject.application.Name,
+			Type:  "application",
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// getExchangeTarget resolves the audience of a token exchange. The audience is the
+// client ID or the name of the application the new token is issued for, and
+// defaults to the requesting application.
+func getExchangeTarget(application *Application, audience string) (*Application, error) {
+	if audience == "" || audience == application.ClientId || audience == application.Name {
+		return application, nil
+	}
+
+	target, err := GetApplicationByClientId(audience)
+	if err != nil {
+		return nil, err
+	}
+	if target != nil {
+		return target, nil
+	}
+
+	return getApplication(application.Owner, audience)
+}
+
+// IsTokenExchangeAllowed reports whether the application may exchange a token issued to the source
+// application into the target application. Both the source and the target must be the application
+// itself or list it in their token exchange allow-list, so a client cannot exchange the tokens of
+// other clients it got hold of.
+func IsTokenExchangeAllowed(application *Application, source *Application, target *Application) bool {
+	isTrusted := func(app *Application) bool {
+		return application.GetId() == app.GetId() || util.InSlice(app.TokenExchangeAllowList, application.Name)
+	}
+
+	return isTrusted(source) && isTrusted(target)
+}
+
+// getExchangeScope narrows the requested scope to the scope of the subject token.
+// An empty request inherits the scope of the subject token.
+func getExchangeScope(requestedScope string, subjectScope string) (string, bool) {
+	if requestedScope == "" {
+		return subjectScope, true
+	}
+
+	subjectScopes := strings.Fields(subjectScope)
+	for _, scope := range strings.Fields(requestedScope) {
+		if !util.InSlice(subjectScopes, scope) {
+			return "", false
+		}
+	}
+
+	return requestedScope, true
+}
+
+// GetTokenExchangeToken
+// Token Exchange (RFC 8693)
+func GetTokenExchangeToken(application *Application, clientSecret string, params *TokenExchangeParams, scope string, host string) (*Token, *TokenError, error) {
+	if application.ClientSecret != clientSecret {
+		return nil, &TokenError{
+			Error:            InvalidClient,
+			ErrorDescription: "client_secret is invalid",
+		}, nil
+	}
+
+	subject, tokenError, err := getExchangeableToken(params.SubjectToken, params.SubjectTokenType, "subject_token")
+	if tokenError != nil || err != nil {
+		return nil, tokenError, err
+	}
+
+	user, err := getExchangeSubject(subject)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if user == nil || user.IsForbidden || user.IsDeleted {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "the subject of subject_token is invalid",
+		}, nil
+	}
+
+	target, err := getExchangeTarget(application, params.Audience)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if target == nil || target.Organization != subject.token.Organization {
+		return nil, &TokenError{
+			Error:            InvalidTarget,
+			ErrorDescription: fmt.Sprintf("audience: %s is invalid", params.Audience),
+		}, nil
+	}
+
+	if !IsTokenExchangeAllowed(application, subject.application, target) {
+		return nil, &TokenError{
+			Error:            UnauthorizedClient,
+			ErrorDescription: fmt.Sprintf("the application: %s is not allowed to exchange the tokens of the application: %s for the application: %s", application.Name, subject.application.Name, target.Name),
+		}, nil
+	}
+
+	exchangeScope, ok := getExchangeScope(scope, subject.token.Scope)
+	if !ok {
+		return nil, &TokenError{
+			Error:            InvalidScope,
+			ErrorDescription: "the requested scope exceeds the scope of subject_token",
+		}, nil
+	}
+
+	// Without an actor token the exchange is an impersonation and no "act" claim is issued,
+	// with one it is a delegation and the actor is recorded on top of any previous actors
+	var act *ActorClaim
+	if params.ActorToken != "" {
+		actor, tokenError, err := getExchangeableToken(params.ActorToken, params.ActorTokenType, "actor_token")
+		if tokenError != nil || err != nil {
+			return nil, tokenError, err
+		}
+
+		act = &ActorClaim{
+			Sub:      actor.claims.Subject,
+			ClientId: actor.application.ClientId,
+			Act:      subject.claims.Act,
+		}
+	} else if params.ActorTokenType != "" {
+		return nil, &TokenError{
+			Error:            InvalidRequest,
+			ErrorDescription: "actor_token_type must not be set without actor_token",
+		}, nil
+	}
+
+	accessToken, _, tokenName, err := generateJwtTokenWithActor(target, user, "", exchangeScope, host, subject.claims.Sid, act)
+	if err != nil {
+		return nil, &TokenError{
+			Error:            EndpointError,
+			ErrorDescription: fmt.Sprintf("generate jwt token error: %s", err.Error()),
+		}, nil
+	}
+
+	token := &Token{
+		Owner:        target.Owner,
+		Name:         tokenName,
+		CreatedTime:  util.GetCurrentTime(),
+		Application:  target.Name,
+		Organization: target.Organization,
+		User:         user.Name,
+		Code:         util.GenerateClientId(),
+		AccessToken:  accessToken,
+		ExpiresIn:    target.ExpireInHours * hourSeconds,
+		Scope:        exchangeScope,
+		TokenType:    "Bearer",
+		CodeIsUsed:   true,
+	}
+	_, err = AddToken(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return token, nil, nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTokenExchangeAllowed(t *testing.T) {
+	app := &Application{Owner: "admin", Name: "app"}
+	other := &Application{Owner: "admin", Name: "other"}
+	trusting := &Application{Owner: "admin", Name: "trusting", TokenExchangeAllowList: []string{"app"}}
+
+	scenarios := []struct {
+		description string
+		source      *Application
+		target      *Application
+		expected    bool
+	}{
+		{"own token for itself", app, app, true},
+		{"token of another application for itself", other, app, false},
+		{"own token for another application", app, other, false},
+		{"token of a trusting application for itself", trusting, app, true},
+		{"own token for a trusting application", app, trusting, true},
+		{"token of another application for a trusting application", other, trusting, false},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			assert.Equal(t, scenario.expected, IsTokenExchangeAllowed(app, scenario.source, scenario.target))
+		})
+	}
+}
+
+func TestGetExchangeScope(t *testing.T) {
+	scope, ok := getExchangeScope("", "openid profile")
+	assert.True(t, ok)
+	assert.Equal(t, "openid profile", scope)
+
+	scope, ok = getExchangeScope("profile", "openid profile")
+	assert.True(t, ok)
+	assert.Equal(t, "profile", scope)
+
+	_, ok = getExchangeScope("openid email", "openid profile")
+	assert.False(t, ok)
+}
+
+func TestGetExchangeableToken(t *testing.T) {
+	_, tokenError, err := getExchangeableToken("", AccessTokenType, "subject_token")
+	assert.Nil(t, err)
+	assert.Equal(t, InvalidRequest, tokenError.Error)
+
+	_, tokenError, err = getExchangeableToken("token", "urn:ietf:params:oauth:token-type:saml2", "subject_token")
+	assert.Nil(t, err)
+	assert.Equal(t, InvalidRequest, tokenError.Error)
+}
@@ -24,14 +24,23 @@ import (
 
 type Claims struct {
 	*User
-	TokenType string `json:"tokenType,omitempty"`
-	Nonce     string `json:"nonce,omitempty"`
-	Tag       string `json:"tag"`
-	Scope     string `json:"scope,omitempty"`
-	Sid       string `json:"sid,omitempty"`
+	TokenType string      `json:"tokenType,omitempty"`
+	Nonce     string      `json:"nonce,omitempty"`
+	Tag       string      `json:"tag"`
+	Scope     string      `json:"scope,omitempty"`
+	Sid       string      `json:"sid,omitempty"`
+	Act       *ActorClaim `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// ActorClaim is the "act" claim of RFC 8693, identifying the party acting on
+// behalf of the subject. Prior actors in a delegation chain are nested in Act.
+type ActorClaim struct {
+	Sub      string      `json:"sub,omitempty"`
+	ClientId string      `json:"client_id,omitempty"`
+	Act      *ActorClaim `json:"act,omitempty"`
+}
+
 type UserShort struct {
 	Owner       string `xorm:"varchar(100) notnull pk" json:"owner"`
 	Name        string `xorm:"varchar(100) notnull pk" json:"name"`
@@ -130,20 +139,22 @@ type UserWithoutThirdIdp struct {
 
 type ClaimsShort struct {
 	*UserShort
-	TokenType string `json:"tokenType,omitempty"`
-	Nonce     string `json:"nonce,omitempty"`
-	Scope     string `json:"scope,omitempty"`
-	Sid       string `json:"sid,omitempty"`
+	TokenType string      `json:"tokenType,omitempty"`
+	Nonce     string      `json:"nonce,omitempty"`
+	Scope     string      `json:"scope,omitempty"`
+	Sid       string      `json:"sid,omitempty"`
+	Act       *ActorClaim `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
 type ClaimsWithoutThirdIdp struct {
 	*UserWithoutThirdIdp
-	TokenType string `json:"tokenType,omitempty"`
-	Nonce     string `json:"nonce,omitempty"`
-	Tag       string `json:"tag"`
-	Scope     string `json:"scope,omitempty"`
-	Sid       string `json:"sid,omitempty"`
+	TokenType string      `json:"tokenType,omitempty"`
+	Nonce     string      `json:"nonce,omitempty"`
+	Tag       string      `json:"tag"`
+	Scope     string      `json:"scope,omitempty"`
+	Sid       string      `json:"sid,omitempty"`
+	Act       *ActorClaim `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -251,6 +262,7 @@ func getShortClaims(claims Claims) ClaimsShort {
 		Nonce:            claims.Nonce,
 		Scope:            claims.Scope,
 		Sid:              claims.Sid,
+		Act:              claims.Act,
 		RegisteredClaims: claims.RegisteredClaims,
 	}
 	return res
@@ -263,6 +275,7 @@ func getShortClaimsWithProperties(claims Claims) ClaimsShort {
 		Nonce:            claims.Nonce,
 		Scope:            claims.Scope,
 		Sid:              claims.Sid,
+		Act:              claims.Act,
 		RegisteredClaims: claims.RegisteredClaims,
 	}
 
@@ -278,6 +291,7 @@ func getClaimsWithoutThirdIdp(claims Claims) ClaimsWithoutThirdIdp {
 		Tag:                 claims.Tag,
 		Scope:               claims.Scope,
 		Sid:                 claims.Sid,
+		Act:                 claims.Act,
 		RegisteredClaims:    claims.RegisteredClaims,
 	}
 	return res
@@ -306,6 +320,10 @@ func refineUser(user *User) *User {
 }
 
 func generateJwtToken(application *Application, user *User, nonce string, scope string, host string, sid string) (string, string, string, error) {
+	return generateJwtTokenWithActor(application, user, nonce, scope, host, sid, nil)
+}
+
+func generateJwtTokenWithActor(application *Application, user *User, nonce string, scope string, host string, sid string, act *ActorClaim) (string, string, string, error) {
 	nowTime := time.Now()
 	expireTime := nowTime.Add(time.Duration(application.ExpireInHours) * time.Hour)
 	refreshExpireTime := nowTime.Add(time.Duration(application.RefreshExpireInHours) * time.Hour)
@@ -328,6 +346,7 @@ func generateJwtToken(application *Application, user *User, nonce string, scope
 		Tag:   user.Tag,
 		Scope: scope,
 		Sid:   sid,
+		Act:   act,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    originBackend,
 			Subject:   user.Id,
@@ -368,6 +387,16 @@ func generateJwtToken(application *Application, user *User, nonce string, scope
 		refreshToken = jwt.NewWithClaims(jwt.SigningMethodRS256, claimsWithoutThirdIdp)
 	}
 
+	scopeClaims, err := getScopeClaims(application, user, scope)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	token.Claims, err = addScopeClaims(token.Claims, scopeClaims)
+	if err != nil {
+		return "", "", "", err
+	}
+
 	cert, err := getCertByApplication(application)
 	if err != nil {
 		return "", "", "", err
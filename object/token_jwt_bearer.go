@@ -0,0 +1,401 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/casdoor/casdoor/proxy"
+	"github.com/casdoor/casdoor/util"
+	"github.com/golang-jwt/jwt/v4"
+	"gopkg.in/square/go-jose.v2"
+)
+
+const (
+	JwtBearerGrantType           = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	JwtBearerClientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+	maxAssertionLifetime = time.Hour
+	jwksCacheDuration    = 5 * time.Minute
+	jwksFetchTimeout     = 10 * time.Second
+)
+
+// AssertionJti records the "jti" of every JWT assertion accepted from a client, so that
+// an assertion can only be used once until it expires, see RFC 7523 section 3.
+type AssertionJti struct {
+	Owner       string `xorm:"varchar(100) notnull pk" json:"owner"`
+	Name        string `xorm:"varchar(100) notnull pk" json:"name"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+
+	Jti      string `xorm:"varchar(500)" json:"jti"`
+	ExpireIn int64  `xorm:"index" json:"expireIn"`
+}
+
+type jwksCacheItem struct {
+	jwks      *jose.JSONWebKeySet
+	expiresAt time.Time
+}
+
+var jwksCache sync.Map
+
+func fetchJwks(jwksUri string) (*jose.JSONWebKeySet, error) {
+	if item, ok := jwksCache.Load(jwksUri); ok {
+		cacheItem := item.(*jwksCacheItem)
+		if time.Now().Before(cacheItem.expiresAt) {
+			return cacheItem.jwks, nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksUri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := proxy.DefaultHttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch JWKS from %s error: %s", jwksUri, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	jwks := &jose.JSONWebKeySet{}
+	err = json.Unmarshal(body, jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	jwksCache.Store(jwksUri, &jwksCacheItem{jwks: jwks, expiresAt: time.Now().Add(jwksCacheDuration)})
+	return jwks, nil
+}
+
+// getApplicationJwks returns the keys registered by the application, the inline JWKS
+// takes precedence over the JWKS URI.
+func getApplicationJwks(application *Application) (*jose.JSONWebKeySet, error) {
+	if application.Jwks != "" {
+		jwks := &jose.JSONWebKeySet{}
+		err := json.Unmarshal([]byte(application.Jwks), jwks)
+		if err != nil {
+			return nil, fmt.Errorf("the JWKS of the application \"%s\" is invalid: %s", application.GetId(), err.Error())
+		}
+		return jwks, nil
+	}
+
+	if application.JwksUri != "" {
+		return fetchJwks(application.JwksUri)
+	}
+
+	return nil, nil
+}
+
+func getAssertionKeyFunc(application *Application) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodHMAC:
+			// client_secret_jwt
+			if application.ClientSecret == "" {
+				return nil, fmt.Errorf("the application has no client secret")
+			}
+			return []byte(application.ClientSecret), nil
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA, *jwt.SigningMethodEd25519:
+			// private_key_jwt
+			jwks, err := getApplicationJwks(application)
+			if err != nil {
+				return nil, err
+			}
+			if jwks == nil {
+				return nil, fmt.Errorf("the application has no registered JWKS")
+			}
+
+			var keys []jose.JSONWebKey
+			if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+				keys = jwks.Key(kid)
+			} else {
+				keys = jwks.Keys
+			}
+
+			for _, key := range keys {
+				if key.Use != "" && key.Use != "sig" {
+					continue
+				}
+				if key.Algorithm != "" && key.Algorithm != token.Method.Alg() {
+					continue
+				}
+				return key.Public().Key, nil
+			}
+			return nil, fmt.Errorf("no matching key is found in the JWKS")
+		default:
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+	}
+}
+
+// getAssertionAudiences returns the audiences that an assertion sent to this server
+// may carry: the issuer identifier or the URL of the token endpoint.
+func getAssertionAudiences(host string) []string {
+	_, originBackend := getOriginFromHost(host)
+	_, originBackendWithConf := getOriginFromHostWithConfPriority(host)
+
+	res := []string{}
+	for _, origin := range []string{originBackend, originBackendWithConf} {
+		res = append(res, origin, fmt.Sprintf("%s/api/login/oauth/access_token", origin))
+	}
+	return res
+}
+
+// checkAssertionJti rejects an assertion whose "jti" has already been used by the client
+func checkAssertionJti(clientId string, jti string, expireTime time.Time) (bool, error) {
+	_, err := ormer.Engine.Where("expire_in < ?", time.Now().Unix()).Delete(&AssertionJti{})
+	if err != nil {
+		return false, err
+	}
+
+	// the jti may be arbitrary long, so its hash is used as the primary key
+	name := util.GetSha256Hash(fmt.Sprintf("%s/%s", clientId, jti))
+	existed, err := ormer.Engine.Exist(&AssertionJti{Owner: clientId, Name: name})
+	if err != nil {
+		return false, err
+	}
+	if existed {
+		return false, nil
+	}
+
+	assertionJti := &AssertionJti{
+		Owner:       clientId,
+		Name:        name,
+		CreatedTime: util.GetCurrentTime(),
+		Jti:         jti,
+		ExpireIn:    expireTime.Unix(),
+	}
+	_, err = ormer.Engine.Insert(assertionJti)
+	if err != nil {
+		// a concurrent request has used the same jti
+		existed, err2 := ormer.Engine.Exist(&AssertionJti{Owner: clientId, Name: name})
+		if err2 == nil && existed {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// parseAssertion verifies a JWT assertion issued by the application, see RFC 7523 section 3
+func parseAssertion(application *Application, assertion string, host string) (*jwt.RegisteredClaims, *TokenError, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(assertion, claims, getAssertionKeyFunc(application))
+	if err != nil {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("parse assertion error: %s", err.Error()),
+		}, nil
+	}
+
+	if claims.Issuer != application.ClientId {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "the iss of the assertion is invalid",
+		}, nil
+	}
+
+	isAudienceValid := false
+	for _, audience := range getAssertionAudiences(host) {
+		if claims.VerifyAudience(audience, true) {
+			isAudienceValid = true
+			break
+		}
+	}
+	if !isAudienceValid {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "the aud of the assertion is invalid",
+		}, nil
+	}
+
+	if claims.ExpiresAt == nil || claims.ExpiresAt.After(time.Now().Add(maxAssertionLifetime)) {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: fmt.Sprintf("the exp of the assertion must be within %s", maxAssertionLifetime),
+		}, nil
+	}
+
+	if claims.ID == "" {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "the jti of the assertion is required",
+		}, nil
+	}
+
+	ok, err := checkAssertionJti(application.ClientId, claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "the assertion has already been used",
+		}, nil
+	}
+
+	return claims, nil, nil
+}
+
+// AuthenticateClientAssertion authenticates a client with the private_key_jwt or
+// client_secret_jwt method, see RFC 7523 section 2.2. The client ID may be omitted
+// from the request, as it is also carried by the assertion.
+func AuthenticateClientAssertion(clientId string, clientAssertionType string, clientAssertion string, host string) (*Application, *TokenError, error) {
+	if clientAssertionType != JwtBearerClientAssertionType {
+		return nil, &TokenError{
+			Error:            InvalidClient,
+			ErrorDescription: fmt.Sprintf("client_assertion_type: %s is not supported", clientAssertionType),
+		}, nil
+	}
+
+	if clientId == "" {
+		unverifiedClaims := &jwt.RegisteredClaims{}
+		_, _, err := jwt.NewParser().ParseUnverified(clientAssertion, unverifiedClaims)
+		if err != nil {
+			return nil, &TokenError{
+				Error:            InvalidClient,
+				ErrorDescription: fmt.Sprintf("parse client_assertion error: %s", err.Error()),
+			}, nil
+		}
+		clientId = unverifiedClaims.Subject
+	}
+
+	application, err := GetApplicationByClientId(clientId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if application == nil {
+		return nil, &TokenError{
+			Error:            InvalidClient,
+			ErrorDescription: "client_id is invalid",
+		}, nil
+	}
+
+	claims, tokenError, err := parseAssertion(application, clientAssertion, host)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if tokenError != nil {
+		tokenError.Error = InvalidClient
+		return nil, tokenError, nil
+	}
+
+	if claims.Subject != application.ClientId {
+		return nil, &TokenError{
+			Error:            InvalidClient,
+			ErrorDescription: "the sub of client_assertion is invalid",
+		}, nil
+	}
+
+	return application, nil, nil
+}
+
+// GetJwtBearerToken
+// JWT Bearer Authorization Grant (RFC 7523 section 2.1), the assertion is issued and
+// signed by the application and its subject is a user of the application's organization
+func GetJwtBearerToken(application *Application, clientSecret string, assertion string, scope string, host string) (*Token, *TokenError, error) {
+	// client authentication is optional for this grant, but if the secret is provided, it must be accurate
+	if clientSecret != "" && application.ClientSecret != clientSecret {
+		return nil, &TokenError{
+			Error:            InvalidClient,
+			ErrorDescription: "client_secret is invalid",
+		}, nil
+	}
+
+	if assertion == "" {
+		return nil, &TokenError{
+			Error:            InvalidRequest,
+			ErrorDescription: "assertion is required",
+		}, nil
+	}
+
+	claims, tokenError, err := parseAssertion(application, assertion, host)
+	if tokenError != nil || err != nil {
+		return nil, tokenError, err
+	}
+
+	user, err := getUser(application.Organization, claims.Subject)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil {
+		user, err = getUserById(application.Organization, claims.Subject)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if user == nil || user.IsForbidden || user.IsDeleted {
+		return nil, &TokenError{
+			Error:            InvalidGrant,
+			ErrorDescription: "the sub of the assertion is invalid",
+		}, nil
+	}
+
+	err = ExtendUserWithRolesAndPermissions(user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accessToken, _, tokenName, err := generateJwtToken(application, user, "", scope, host, "")
+	if err != nil {
+		return nil, &TokenError{
+			Error:            EndpointError,
+			ErrorDescription: fmt.Sprintf("generate jwt token error: %s", err.Error()),
+		}, nil
+	}
+
+	token := &Token{
+		Owner:        application.Owner,
+		Name:         tokenName,
+		CreatedTime:  util.GetCurrentTime(),
+		Application:  application.Name,
+		Organization: application.Organization,
+		User:         user.Name,
+		Code:         util.GenerateClientId(),
+		AccessToken:  accessToken,
+		ExpiresIn:    application.ExpireInHours * hourSeconds,
+		Scope:        scope,
+		TokenType:    "Bearer",
+		CodeIsUsed:   true,
+	}
+	_, err = AddToken(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return token, nil, nil
+}
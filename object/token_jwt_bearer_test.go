@@ -0,0 +1,86 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func getTestAssertion(t *testing.T, method jwt.SigningMethod, kid string, key interface{}) string {
+	claims := jwt.RegisteredClaims{
+		Issuer:    "client-id",
+		Subject:   "client-id",
+		Audience:  []string{"https://door.casdoor.com/api/login/oauth/access_token"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		ID:        "jti",
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	assertion, err := token.SignedString(key)
+	assert.NoError(t, err)
+	return assertion
+}
+
+func TestGetAssertionKeyFunc(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	jwks, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &privateKey.PublicKey, KeyID: "key-1", Algorithm: "RS256", Use: "sig"},
+	}})
+	assert.NoError(t, err)
+
+	application := &Application{ClientId: "client-id", ClientSecret: "client-secret", Jwks: string(jwks)}
+
+	scenarios := []struct {
+		description string
+		assertion   string
+		valid       bool
+	}{
+		{"private_key_jwt", getTestAssertion(t, jwt.SigningMethodRS256, "key-1", privateKey), true},
+		{"private_key_jwt without kid", getTestAssertion(t, jwt.SigningMethodRS256, "", privateKey), true},
+		{"private_key_jwt with unknown kid", getTestAssertion(t, jwt.SigningMethodRS256, "key-2", privateKey), false},
+		{"private_key_jwt with unregistered alg", getTestAssertion(t, jwt.SigningMethodRS512, "key-1", privateKey), false},
+		{"client_secret_jwt", getTestAssertion(t, jwt.SigningMethodHS256, "", []byte("client-secret")), true},
+		{"client_secret_jwt with wrong secret", getTestAssertion(t, jwt.SigningMethodHS256, "", []byte("wrong-secret")), false},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.description, func(t *testing.T) {
+			_, err := jwt.ParseWithClaims(scenario.assertion, &jwt.RegisteredClaims{}, getAssertionKeyFunc(application))
+			if scenario.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+
+	application.ClientSecret = ""
+	_, err = jwt.ParseWithClaims(getTestAssertion(t, jwt.SigningMethodHS256, "", []byte("")), &jwt.RegisteredClaims{}, getAssertionKeyFunc(application))
+	assert.Error(t, err, "client_secret_jwt must be rejected when the application has no secret")
+}
@@ -525,8 +525,8 @@ func GetMaskedUser(user *User, isAdminOrSelf bool, errs ...error) (*User, error)
 	}
 
 	if user.ManagedAccounts != nil {
-		for _, manageAccount := range user.ManagedAccounts {
-			manageAccount.Password = "***"
+		for i := range user.ManagedAccounts {
+			user.ManagedAccounts[i].Password = "***"
 		}
 	}
 
@@ -697,6 +697,11 @@ func updateUser(id string, user *User, columns []string) (int64, error) {
 		}
 	}
 
+	if affected != 0 {
+		enqueueUserColumnsProvisioning(oldUser, user, columns)
+		emitUserUpdatedEvent(oldUser, user, columns)
+	}
+
 	return affected, nil
 }
 
@@ -779,6 +784,11 @@ func UpdateUserForAllFields(id string, user *User) (bool, error) {
 		}
 	}
 
+	if affected != 0 {
+		enqueueUserProvisioning(user, ProvisioningActionUpsert, oldUser.Groups, user.Groups)
+		emitUserUpdatedEvent(oldUser, user, nil)
+	}
+
 	return affected != 0, nil
 }
 
@@ -858,6 +868,9 @@ func AddUser(user *User) (bool, error) {
 		if err != nil {
 			return false, fmt.Errorf("ProcessPolicyDifference: %w", err)
 		}
+
+		enqueueUserProvisioning(user, ProvisioningActionUpsert, nil, user.Groups)
+		emitUserEvent(user, EventUserCreated)
 	}
 
 	return affected != 0, nil
@@ -908,6 +921,11 @@ func AddUsers(users []*User) (bool, error) {
 		if err != nil {
 			return false, fmt.Errorf("ProcessPolicyDifference: %w", err)
 		}
+
+		for _, user := range users {
+			enqueueUserProvisioning(user, ProvisioningActionUpsert, nil, user.Groups)
+			emitUserEvent(user, EventUserCreated)
+		}
 	}
 
 	return affected != 0, nil
@@ -952,6 +970,11 @@ func DeleteUser(user *User) (bool, error) {
 		return false, fmt.Errorf("reachablePermissionsByUser: %w", err)
 	}
 
+	deletedUser, err := getUser(user.Owner, user.Name)
+	if err != nil {
+		return false, err
+	}
+
 	affected, err := ormer.Engine.ID(core.PK{user.Owner, user.Name}).Delete(&User{})
 	if err != nil {
 		return false, err
@@ -962,6 +985,16 @@ func DeleteUser(user *User) (bool, error) {
 		if err != nil {
 			return false, fmt.Errorf("ProcessPolicyDifference: %w", err)
 		}
+
+		err = deleteApiTokensByUser(user.Owner, user.Name)
+		if err != nil {
+			return false, err
+		}
+
+		if deletedUser != nil {
+			enqueueUserProvisioning(deletedUser, ProvisioningActionDelete, deletedUser.Groups, nil)
+			emitUserEvent(deletedUser, EventUserDeleted)
+		}
 	}
 
 	return affected != 0, nil
@@ -15,152 +15,340 @@
 package object
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"regexp"
+	"net"
+	"strings"
+	"time"
 
 	"github.com/casdoor/casdoor/util"
 	"github.com/xorm-io/core"
 )
 
 const (
-	TokenUser = "token-user"
+	// ApiTokenPrefix starts the personal access tokens, which are sent as bearer tokens like
+	// cgt_<name>_<secret>
+	ApiTokenPrefix = "cgt_"
+
+	apiTokenRoutePrefix = "/api/"
 )
 
 var (
-	tagExtractionRegexp = regexp.MustCompile("<access-token><access-token-user-id:(.+)>")
-
-	ExtractOwnerIdError = errors.New("could not extract owner id from tag")
+	ErrApiTokenInvalid   = errors.New("the API token is invalid")
+	ErrApiTokenExpired   = errors.New("the API token has expired")
+	ErrApiTokenIpAllowed = errors.New("the API token is not allowed from this IP address")
 )
 
-func MakeTokenUserTag(user *User) string {
-	return fmt.Sprintf("<access-token><access-token-user-id:%s>", user.Id)
+// apiTokenRoutes are the routes which a token can never authorize, so a token cannot mint a
+// token with a wider scope
+var apiTokenRoutes = []string{"/api/get-api-tokens", "/api/add-api-token", "/api/delete-api-token", "/api/recreate-api-token", "/api/get-user-by-api-token"}
+
+// ApiToken is a personal access token of a user. Only the hash of its secret is stored. A token
+// authorizes the requests of the user to the routes and for the organizations of its scope, an
+// empty list allows any of them.
+type ApiToken struct {
+	Owner       string `xorm:"varchar(100) notnull pk" json:"owner"`
+	Name        string `xorm:"varchar(100) notnull pk" json:"name"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+	DisplayName string `xorm:"varchar(100)" json:"displayName"`
+
+	User          string   `xorm:"varchar(100) index" json:"user"`
+	Hash          string   `xorm:"varchar(64)" json:"-"`
+	Routes        []string `xorm:"mediumtext" json:"routes"`
+	Organizations []string `xorm:"mediumtext" json:"organizations"`
+	IpAllowList   []string `xorm:"mediumtext" json:"ipAllowList"`
+	ExpireTime    string   `xorm:"varchar(100)" json:"expireTime"`
+
+	LastUsedTime string `xorm:"varchar(100)" json:"lastUsedTime"`
+	LastUsedIp   string `xorm:"varchar(100)" json:"lastUsedIp"`
+}
+
+// UserApiToken is a token with its secret, which is only returned when it is created or recreated
+type UserApiToken struct {
+	Owner    string `json:"owner"`
+	Name     string `json:"name"`
+	ApiToken string `json:"api_token"`
+}
+
+func (token *ApiToken) GetId() string {
+	return fmt.Sprintf("%s/%s", token.Owner, token.Name)
+}
+
+func (token *ApiToken) GetUserId() string {
+	return util.GetId(token.Owner, token.User)
+}
+
+func getApiTokenHash(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
 }
 
-func ExtractOwnerIdFromTag(tag string) (string, error) {
-	res := tagExtractionRegexp.FindStringSubmatch(tag)
-	if res == nil {
-		return "", ExtractOwnerIdError
+// parseApiToken returns the name and the secret of the token
+func parseApiToken(s string) (string, string, bool) {
+	if !strings.HasPrefix(s, ApiTokenPrefix) {
+		return "", "", false
 	}
 
-	return res[1], nil
+	tokens := strings.SplitN(strings.TrimPrefix(s, ApiTokenPrefix), "_", 2)
+	if len(tokens) != 2 || tokens[0] == "" || tokens[1] == "" {
+		return "", "", false
+	}
+	return tokens[0], tokens[1], true
 }
 
-func MakeTokenUserName(owner *User, accessKey, accessSecret string) string {
-	return fmt.Sprintf("<AT:%s/%s>", owner.Id, util.GenerateId())
+// generateApiTokenSecret replaces the secret of the token, the token with the new secret is returned
+func generateApiTokenSecret(token *ApiToken) *UserApiToken {
+	secret := util.GenerateClientSecret()
+	token.Hash = getApiTokenHash(secret)
+	return &UserApiToken{
+		Owner:    token.Owner,
+		Name:     token.Name,
+		ApiToken: ApiTokenPrefix + token.Name + "_" + secret,
+	}
 }
 
-func separateTokenToKeys(token string) (string, string) {
-	accessKey := string([]byte(token)[:36])
-	accessSecret := string([]byte(token)[36:])
+func isApiTokenIpAllowed(ipAllowList []string, ip string) bool {
+	if len(ipAllowList) == 0 {
+		return true
+	}
+
+	clientIp := net.ParseIP(ip)
+	if clientIp == nil {
+		return false
+	}
+
+	for _, address := range ipAllowList {
+		if !strings.Contains(address, "/") {
+			if allowedIp := net.ParseIP(address); allowedIp != nil && allowedIp.Equal(clientIp) {
+				return true
+			}
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(address)
+		if err == nil && ipNet.Contains(clientIp) {
+			return true
+		}
+	}
 
-	return accessKey, accessSecret
+	return false
 }
 
-func DeleteApiToken(user *User, token string) (bool, error) {
-	tag := fmt.Sprintf("<access-token><access-token-user-id:%s>", user.Id)
+func isApiTokenRouteAllowed(routes []string, urlPath string) bool {
+	if util.InSlice(apiTokenRoutes, urlPath) {
+		return false
+	}
+
+	if len(routes) == 0 {
+		return true
+	}
+
+	for _, route := range routes {
+		if route == urlPath || (strings.HasSuffix(route, "*") && strings.HasPrefix(urlPath, strings.TrimSuffix(route, "*"))) {
+			return true
+		}
+	}
 
-	accessKey, accessSecret := separateTokenToKeys(token)
+	return false
+}
 
-	token_user := &User{
-		AccessKey:    accessKey,
-		AccessSecret: accessSecret,
-		Tag:          tag,
+// IsAllowed returns whether the scope of the token allows the request to the URL path for the
+// objects of the organization, and for the object of the id query if any, which is the one some
+// routes act on. The requests without an organization are only allowed by the tokens without
+// organizations in their scope.
+func (token *ApiToken) IsAllowed(urlPath string, organization string, id string) bool {
+	if !isApiTokenRouteAllowed(token.Routes, urlPath) {
+		return false
 	}
 
-	affected, err := ormer.Engine.Delete(token_user)
+	if len(token.Organizations) == 0 {
+		return true
+	}
 
-	return affected != 0, err
+	if id != "" {
+		idOwner, _, ok := strings.Cut(id, "/")
+		if !ok || !util.InSlice(token.Organizations, idOwner) {
+			return false
+		}
+	}
+
+	return util.InSlice(token.Organizations, organization)
 }
 
-func RecreateApiToken(tokenOwner, tokenUser *User) error {
-	tokenUser.AccessKey = util.GenerateId()
-	tokenUser.AccessSecret = util.GenerateId()
+func checkApiToken(token *ApiToken) error {
+	for _, route := range token.Routes {
+		if !strings.HasPrefix(route, apiTokenRoutePrefix) {
+			return fmt.Errorf("the route: \"%s\" of the API token should start with %s", route, apiTokenRoutePrefix)
+		}
+	}
 
-	affected, err := ormer.Engine.ID(core.PK{tokenUser.Owner, tokenUser.Name}).Update(tokenUser)
-	if err != nil {
-		return err
+	for _, address := range token.IpAllowList {
+		if net.ParseIP(address) == nil {
+			if _, _, err := net.ParseCIDR(address); err != nil {
+				return fmt.Errorf("the address: \"%s\" of the API token is neither an IP address nor a CIDR range", address)
+			}
+		}
 	}
-	if affected == 0 {
-		return errors.New("token not affected")
+
+	if token.ExpireTime != "" {
+		if _, err := time.Parse(time.RFC3339, token.ExpireTime); err != nil {
+			return fmt.Errorf("the expire time: \"%s\" of the API token should be in the RFC 3339 format", token.ExpireTime)
+		}
 	}
 
 	return nil
 }
 
-func GetApiKeyUser(token string) (*User, error) {
-	accessKey, accessSecret := separateTokenToKeys(token)
+func GetApiTokens(owner string, user string) ([]*ApiToken, error) {
+	tokens := []*ApiToken{}
+	err := ormer.Engine.Desc("created_time").Find(&tokens, &ApiToken{Owner: owner, User: user})
+	if err != nil {
+		return tokens, err
+	}
+
+	return tokens, nil
+}
 
-	token_user := &User{
-		AccessKey:    accessKey,
-		AccessSecret: accessSecret,
+func getApiToken(owner string, name string) (*ApiToken, error) {
+	if owner == "" || name == "" {
+		return nil, nil
 	}
 
-	present, err := ormer.Engine.Get(token_user)
+	token := ApiToken{Owner: owner, Name: name}
+	existed, err := ormer.Engine.Get(&token)
 	if err != nil {
-		return nil, err
+		return &token, err
 	}
 
-	if !present {
+	if existed {
+		return &token, nil
+	} else {
 		return nil, nil
 	}
+}
 
-	return token_user, nil
+func GetApiToken(id string) (*ApiToken, error) {
+	owner, name := util.GetOwnerAndNameFromIdNoCheck(id)
+	return getApiToken(owner, name)
 }
 
-func GetApiKeyOwner(token string) (*User, error) {
-	apiKeyUser, err := GetApiKeyUser(token)
-	if err != nil || apiKeyUser == nil {
-		return apiKeyUser, err
+// AddApiToken adds a token of the user, the returned token has the secret
+func AddApiToken(user *User, token *ApiToken) (*UserApiToken, error) {
+	err := checkApiToken(token)
+	if err != nil {
+		return nil, err
 	}
 
-	ownerId, err := ExtractOwnerIdFromTag(apiKeyUser.Tag)
+	token.Owner = user.Owner
+	token.Name = util.GenerateClientId()
+	token.CreatedTime = util.GetCurrentTime()
+	token.User = user.Name
+	token.LastUsedTime, token.LastUsedIp = "", ""
+	userApiToken := generateApiTokenSecret(token)
+
+	_, err = ormer.Engine.Insert(token)
 	if err != nil {
 		return nil, err
 	}
 
-	owner := &User{
-		Id: ownerId,
-	}
+	return userApiToken, nil
+}
 
-	present, err := ormer.Engine.Get(owner)
+// RecreateApiToken replaces the secret of the token, its scope is kept
+func RecreateApiToken(token *ApiToken) (*UserApiToken, error) {
+	userApiToken := generateApiTokenSecret(token)
+
+	affected, err := ormer.Engine.ID(core.PK{token.Owner, token.Name}).Cols("hash").Update(token)
 	if err != nil {
 		return nil, err
 	}
-	if !present {
-		return nil, nil
+	if affected == 0 {
+		return nil, fmt.Errorf("the API token: %s does not exist", token.GetId())
 	}
 
-	return owner, nil
+	return userApiToken, nil
 }
 
-type UserApiToken struct {
-	Owner    string `json:"owner"`
-	ApiToken string `json:"api_token"`
+func DeleteApiToken(token *ApiToken) (bool, error) {
+	affected, err := ormer.Engine.ID(core.PK{token.Owner, token.Name}).Delete(&ApiToken{})
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+func deleteApiTokensByUser(owner string, user string) error {
+	_, err := ormer.Engine.Delete(&ApiToken{Owner: owner, User: user})
+	return err
 }
 
-func MakeUserForToken(owner *User) *User {
-	tokenUser := User{}
-	accessKey := util.GenerateId()
-	accessSecret := util.GenerateId()
+// getApiTokenBySecret returns the token and its user if the token is valid
+func getApiTokenBySecret(s string) (*ApiToken, *User, error) {
+	name, secret, ok := parseApiToken(s)
+	if !ok {
+		return nil, nil, ErrApiTokenInvalid
+	}
+
+	tokens := []*ApiToken{}
+	err := ormer.Engine.Where("name = ?", name).Limit(1).Find(&tokens)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(tokens) == 0 || subtle.ConstantTimeCompare([]byte(tokens[0].Hash), []byte(getApiTokenHash(secret))) != 1 {
+		return nil, nil, ErrApiTokenInvalid
+	}
+	token := tokens[0]
+
+	// the tokens of the forbidden and the soft deleted users are kept but cannot be used
+	user, err := getUser(token.Owner, token.User)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil || user.IsForbidden || user.IsDeleted {
+		return nil, nil, ErrApiTokenInvalid
+	}
+
+	if token.ExpireTime != "" {
+		expireTime, err := time.Parse(time.RFC3339, token.ExpireTime)
+		if err != nil || time.Now().After(expireTime) {
+			return nil, nil, ErrApiTokenExpired
+		}
+	}
 
-	tokenUser = *owner
-	tokenUser.Id = ""
-	tokenUser.AccessKey = accessKey
-	tokenUser.AccessSecret = accessSecret
-	tokenUser.Tag = MakeTokenUserTag(owner)
-	tokenUser.Name = MakeTokenUserName(owner, accessKey, accessSecret)
-	tokenUser.DisplayName = "<API-TOKEN>"
-	tokenUser.CreatedTime = util.GetCurrentTime()
-	tokenUser.UpdatedTime = ""
-	tokenUser.Type = TokenUser
+	return token, user, nil
+}
 
-	return &tokenUser
+// GetApiTokenUser returns the user of the token if the token is valid, nil otherwise
+func GetApiTokenUser(s string) (*User, error) {
+	_, user, err := getApiTokenBySecret(s)
+	if err == ErrApiTokenInvalid || err == ErrApiTokenExpired {
+		return nil, nil
+	}
+	return user, err
 }
 
-func MakeUserApiToken(user *User) UserApiToken {
-	return UserApiToken{
-		Owner:    user.Owner,
-		ApiToken: user.AccessKey + user.AccessSecret,
+// CheckApiToken returns the token if it is valid from the IP address, and records its use
+func CheckApiToken(s string, ip string) (*ApiToken, error) {
+	token, _, err := getApiTokenBySecret(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isApiTokenIpAllowed(token.IpAllowList, ip) {
+		return nil, ErrApiTokenIpAllowed
 	}
+
+	token.LastUsedTime = util.GetCurrentTime()
+	token.LastUsedIp = ip
+	_, err = ormer.Engine.ID(core.PK{token.Owner, token.Name}).Cols("last_used_time", "last_used_ip").Update(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
 }
@@ -0,0 +1,98 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseApiToken(t *testing.T) {
+	name, secret, ok := parseApiToken("cgt_abc_secret_with_underscore")
+	assert.True(t, ok)
+	assert.Equal(t, "abc", name)
+	assert.Equal(t, "secret_with_underscore", secret)
+
+	for _, s := range []string{"", "abc_secret", "cgt_", "cgt_abc", "cgt__secret", "cgt_abc_"} {
+		_, _, ok = parseApiToken(s)
+		assert.False(t, ok, s)
+	}
+}
+
+func TestGenerateApiTokenSecret(t *testing.T) {
+	token := &ApiToken{Owner: "org", Name: "abc"}
+	userApiToken := generateApiTokenSecret(token)
+
+	name, secret, ok := parseApiToken(userApiToken.ApiToken)
+	assert.True(t, ok)
+	assert.Equal(t, "abc", name)
+	assert.Equal(t, getApiTokenHash(secret), token.Hash)
+	assert.NotContains(t, token.Hash, secret)
+}
+
+func TestIsApiTokenIpAllowed(t *testing.T) {
+	assert.True(t, isApiTokenIpAllowed(nil, "10.0.0.1"))
+
+	ipAllowList := []string{"192.168.1.10", "10.0.0.0/8", "2001:db8::/32"}
+	assert.True(t, isApiTokenIpAllowed(ipAllowList, "192.168.1.10"))
+	assert.True(t, isApiTokenIpAllowed(ipAllowList, "10.20.30.40"))
+	assert.True(t, isApiTokenIpAllowed(ipAllowList, "2001:db8::1"))
+	assert.False(t, isApiTokenIpAllowed(ipAllowList, "192.168.1.11"))
+	assert.False(t, isApiTokenIpAllowed(ipAllowList, "not an ip"))
+}
+
+func TestApiTokenIsAllowed(t *testing.T) {
+	token := &ApiToken{}
+	assert.True(t, token.IsAllowed("/api/get-users", "", ""))
+	assert.False(t, token.IsAllowed("/api/add-api-token", "", ""))
+	assert.False(t, token.IsAllowed("/api/get-user-by-api-token", "org", ""))
+
+	token.Routes = []string{"/api/get-*", "/api/update-user"}
+	assert.True(t, token.IsAllowed("/api/get-users", "", ""))
+	assert.True(t, token.IsAllowed("/api/update-user", "", ""))
+	assert.False(t, token.IsAllowed("/api/update-users", "", ""))
+	assert.False(t, token.IsAllowed("/api/get-api-tokens", "", ""))
+
+	token.Organizations = []string{"org"}
+	assert.True(t, token.IsAllowed("/api/get-users", "org", ""))
+	assert.False(t, token.IsAllowed("/api/get-users", "other", ""))
+	assert.False(t, token.IsAllowed("/api/get-users", "", ""))
+
+	// the object of the id query must be in the organizations as well, whatever the owner of the body
+	assert.True(t, token.IsAllowed("/api/update-user", "org", "org/alice"))
+	assert.False(t, token.IsAllowed("/api/update-user", "org", "other/alice"))
+	assert.False(t, token.IsAllowed("/api/update-user", "org", "alice"))
+}
+
+func TestCheckApiToken(t *testing.T) {
+	token := &ApiToken{
+		Routes:      []string{"/api/get-*"},
+		IpAllowList: []string{"10.0.0.1", "10.0.0.0/8"},
+		ExpireTime:  "2030-01-02T15:04:05Z",
+	}
+	assert.Nil(t, checkApiToken(token))
+
+	token.Routes = []string{"get-users"}
+	assert.NotNil(t, checkApiToken(token))
+
+	token.Routes = nil
+	token.IpAllowList = []string{"10.0.0.0/33"}
+	assert.NotNil(t, checkApiToken(token))
+
+	token.IpAllowList = nil
+	token.ExpireTime = "2030-01-02"
+	assert.NotNil(t, checkApiToken(token))
+}
@@ -409,3 +409,35 @@ func getNextPasswordChangeTime(passwordChangeInterval int) time.Time {
 func getIntervalFromdays(days int) time.Duration {
 	return time.Hour * 24 * time.Duration(days)
 }
+
+// userSecretFields are the json names of the user fields holding credentials, they are never
+// published by the claims of the scopes, the LDAP server, the RADIUS replies or the provisioners
+var userSecretFields = []string{"password", "passwordSalt", "hash", "preHash", "accessKey", "accessSecret", "totpSecret", "recoveryCodes", "webauthnCredentials", "multiFactorAuths", "managedAccounts"}
+
+// getUserWithoutSecrets returns a copy of the user whose credentials, the ones of userSecretFields,
+// are cleared, to be sent out of Casdoor
+func getUserWithoutSecrets(user *User) *User {
+	res := *user
+	res.Password, res.PasswordSalt, res.Hash, res.PreHash = "", "", "", ""
+	res.AccessKey, res.AccessSecret, res.TotpSecret = "", "", ""
+	res.RecoveryCodes, res.WebauthnCredentials, res.MultiFactorAuths = nil, nil, nil
+
+	if user.ManagedAccounts != nil {
+		res.ManagedAccounts = make([]ManagedAccount, len(user.ManagedAccounts))
+		for i, managedAccount := range user.ManagedAccounts {
+			managedAccount.Password = ""
+			res.ManagedAccounts[i] = managedAccount
+		}
+	}
+	return &res
+}
+
+// isUserSecretField returns whether the user field, named by its json or its Go name, holds credentials
+func isUserSecretField(field string) bool {
+	for _, secretField := range userSecretFields {
+		if strings.EqualFold(field, secretField) {
+			return true
+		}
+	}
+	return false
+}
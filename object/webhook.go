@@ -40,6 +40,10 @@ type Webhook struct {
 	Events         []string  `xorm:"varchar(1000)" json:"events"`
 	IsUserExtended bool      `json:"isUserExtended"`
 	IsEnabled      bool      `json:"isEnabled"`
+
+	// Secret signs the deliveries with HMAC-SHA256, see signWebhookPayload
+	Secret      string `xorm:"varchar(100)" json:"secret"`
+	MaxAttempts int    `json:"maxAttempts"`
 }
 
 func GetWebhookCount(owner, organization, field, value string) (int64, error) {
@@ -114,10 +118,21 @@ func UpdateWebhook(id string, webhook *Webhook) (bool, error) {
 		return false, err
 	}
 
+	if webhook.GetId() != id {
+		err = renameWebhookDeliveries(id, webhook.GetId())
+		if err != nil {
+			return false, err
+		}
+	}
+
 	return affected != 0, nil
 }
 
 func AddWebhook(webhook *Webhook) (bool, error) {
+	if webhook.Secret == "" {
+		webhook.Secret = util.GenerateClientSecret()
+	}
+
 	affected, err := ormer.Engine.Insert(webhook)
 	if err != nil {
 		return false, err
@@ -132,9 +147,21 @@ func DeleteWebhook(webhook *Webhook) (bool, error) {
 		return false, err
 	}
 
+	err = deleteWebhookDeliveries(webhook.GetId())
+	if err != nil {
+		return false, err
+	}
+
 	return affected != 0, nil
 }
 
 func (p *Webhook) GetId() string {
 	return fmt.Sprintf("%s/%s", p.Owner, p.Name)
 }
+
+func (p *Webhook) getMaxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return webhookDefaultMaxAttempts
+	}
+	return p.MaxAttempts
+}
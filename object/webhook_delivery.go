@@ -0,0 +1,349 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/util"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	WebhookDeliveryStatePending   = "Pending"
+	WebhookDeliveryStateSucceeded = "Succeeded"
+	WebhookDeliveryStateDead      = "Dead"
+)
+
+const (
+	webhookInterval           = 5 * time.Second
+	webhookBatchSize          = 100
+	webhookLease              = 5 * time.Minute
+	webhookRetryDelay         = 30 * time.Second
+	webhookMaxRetryDelay      = time.Hour
+	webhookDefaultMaxAttempts = 10
+	webhookConcurrency        = 10
+)
+
+// WebhookDelivery is an event waiting in the outbox to be sent to a webhook, or already sent. A
+// failed delivery is retried with an exponential backoff until the max attempts of the webhook,
+// then it is dead until it is replayed. The receivers may get an event more than once, and can
+// tell the duplicates by the X-Casdoor-Delivery header.
+type WebhookDelivery struct {
+	Id           int64  `xorm:"pk autoincr" json:"id"`
+	Webhook      string `xorm:"varchar(200) index" json:"webhook"`
+	Organization string `xorm:"varchar(100) index" json:"organization"`
+	CreatedTime  string `xorm:"varchar(100)" json:"createdTime"`
+
+	Event string `xorm:"varchar(100)" json:"event"`
+	// ContentType overrides the content type of the webhook if not empty
+	ContentType string `xorm:"varchar(100)" json:"contentType"`
+	Payload     string `xorm:"mediumtext" json:"payload"`
+
+	State          string `xorm:"varchar(100) index" json:"state"`
+	Attempts       int    `json:"attempts"`
+	NextRunTime    int64  `xorm:"index" json:"nextRunTime"`
+	LastStatusCode int    `json:"lastStatusCode"`
+	LastError      string `xorm:"mediumtext" json:"lastError"`
+	DeliveredTime  string `xorm:"varchar(100)" json:"deliveredTime"`
+}
+
+// WebhookAttempt is the log of a request sent for a delivery
+type WebhookAttempt struct {
+	Id          int64  `xorm:"pk autoincr" json:"id"`
+	Delivery    int64  `xorm:"index" json:"delivery"`
+	Webhook     string `xorm:"varchar(200) index" json:"webhook"`
+	CreatedTime string `xorm:"varchar(100)" json:"createdTime"`
+
+	Url        string `xorm:"varchar(500)" json:"url"`
+	StatusCode int    `json:"statusCode"`
+	Response   string `xorm:"mediumtext" json:"response"`
+	Error      string `xorm:"mediumtext" json:"error"`
+	// Duration is in milliseconds
+	Duration int64 `json:"duration"`
+}
+
+func addWebhookDelivery(webhook *Webhook, event string, contentType string, payload string) error {
+	delivery := &WebhookDelivery{
+		Webhook:      webhook.GetId(),
+		Organization: webhook.Organization,
+		CreatedTime:  util.GetCurrentTime(),
+		Event:        event,
+		ContentType:  contentType,
+		Payload:      payload,
+		State:        WebhookDeliveryStatePending,
+		NextRunTime:  time.Now().Unix(),
+	}
+
+	_, err := ormer.Engine.Insert(delivery)
+	return err
+}
+
+func GetWebhookDeliveryCount(webhook string, state string) (int64, error) {
+	return ormer.Engine.Count(&WebhookDelivery{Webhook: webhook, State: state})
+}
+
+func GetWebhookDeliveries(webhook string, state string, offset, limit int) ([]*WebhookDelivery, error) {
+	deliveries := []*WebhookDelivery{}
+	session := ormer.Engine.Desc("id")
+	if offset != -1 && limit != -1 {
+		session = session.Limit(limit, offset)
+	}
+	err := session.Find(&deliveries, &WebhookDelivery{Webhook: webhook, State: state})
+	if err != nil {
+		return deliveries, err
+	}
+
+	return deliveries, nil
+}
+
+func GetWebhookDelivery(id int64) (*WebhookDelivery, error) {
+	delivery := WebhookDelivery{Id: id}
+	existed, err := ormer.Engine.Get(&delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	if existed {
+		return &delivery, nil
+	} else {
+		return nil, nil
+	}
+}
+
+func GetWebhookAttempts(delivery int64) ([]*WebhookAttempt, error) {
+	attempts := []*WebhookAttempt{}
+	err := ormer.Engine.Asc("id").Find(&attempts, &WebhookAttempt{Delivery: delivery})
+	if err != nil {
+		return attempts, err
+	}
+
+	return attempts, nil
+}
+
+// ReplayWebhookDelivery puts the delivery back in the outbox, whatever its state, to send it again
+func ReplayWebhookDelivery(id int64) (bool, error) {
+	affected, err := ormer.Engine.ID(id).Cols("state", "attempts", "next_run_time").
+		Update(&WebhookDelivery{State: WebhookDeliveryStatePending, NextRunTime: time.Now().Unix()})
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+// ReplayWebhookDeliveries puts the dead deliveries of the webhook back in the outbox
+func ReplayWebhookDeliveries(webhook string) (bool, error) {
+	affected, err := ormer.Engine.Where("webhook = ? and state = ?", webhook, WebhookDeliveryStateDead).
+		Cols("state", "attempts", "next_run_time").
+		Update(&WebhookDelivery{State: WebhookDeliveryStatePending, NextRunTime: time.Now().Unix()})
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+func renameWebhookDeliveries(oldId string, newId string) error {
+	_, err := ormer.Engine.Where("webhook = ?", oldId).Update(&WebhookDelivery{Webhook: newId})
+	if err != nil {
+		return err
+	}
+
+	_, err = ormer.Engine.Where("webhook = ?", oldId).Update(&WebhookAttempt{Webhook: newId})
+	return err
+}
+
+func deleteWebhookDeliveries(webhook string) error {
+	_, err := ormer.Engine.Delete(&WebhookDelivery{Webhook: webhook})
+	if err != nil {
+		return err
+	}
+
+	_, err = ormer.Engine.Delete(&WebhookAttempt{Webhook: webhook})
+	return err
+}
+
+// claim leases the delivery to this instance, it returns false if another instance leased it first
+func (delivery *WebhookDelivery) claim() (bool, error) {
+	nextRunTime := time.Now().Add(webhookLease).Unix()
+	affected, err := ormer.Engine.Where("id = ? and state = ? and next_run_time = ?", delivery.Id, WebhookDeliveryStatePending, delivery.NextRunTime).
+		Cols("next_run_time").Update(&WebhookDelivery{NextRunTime: nextRunTime})
+	if err != nil {
+		return false, err
+	}
+
+	delivery.NextRunTime = nextRunTime
+	return affected != 0, nil
+}
+
+func getWebhookRetryDelay(attempts int) time.Duration {
+	delay := webhookRetryDelay << (attempts - 1)
+	if delay > webhookMaxRetryDelay || delay <= 0 {
+		delay = webhookMaxRetryDelay
+	}
+	return delay
+}
+
+// deliver sends the delivery to the webhook, logs the attempt and saves the new state
+func (delivery *WebhookDelivery) deliver(webhook *Webhook) error {
+	start := time.Now()
+	statusCode, response, sendErr := sendWebhook(webhook, delivery)
+
+	attempt := &WebhookAttempt{
+		Delivery:    delivery.Id,
+		Webhook:     delivery.Webhook,
+		CreatedTime: util.GetCurrentTime(),
+		Url:         webhook.Url,
+		StatusCode:  statusCode,
+		Response:    response,
+		Duration:    time.Since(start).Milliseconds(),
+	}
+	if sendErr != nil {
+		attempt.Error = sendErr.Error()
+	}
+
+	_, err := ormer.Engine.Insert(attempt)
+	if err != nil {
+		return err
+	}
+
+	delivery.Attempts += 1
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = attempt.Error
+	if sendErr == nil {
+		delivery.State = WebhookDeliveryStateSucceeded
+		delivery.DeliveredTime = attempt.CreatedTime
+	} else if delivery.Attempts >= webhook.getMaxAttempts() {
+		delivery.State = WebhookDeliveryStateDead
+	} else {
+		delivery.NextRunTime = time.Now().Add(getWebhookRetryDelay(delivery.Attempts)).Unix()
+	}
+
+	_, err = ormer.Engine.ID(delivery.Id).Cols("state", "attempts", "next_run_time", "last_status_code", "last_error", "delivered_time").Update(delivery)
+	return err
+}
+
+// getDisabledWebhookIds returns the ids of the disabled webhooks, whose deliveries are kept in the
+// outbox until they are enabled again
+func getDisabledWebhookIds() ([]string, error) {
+	webhooks := []*Webhook{}
+	err := ormer.Engine.Cols("owner", "name").Where("is_enabled = ?", false).Find(&webhooks)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for _, webhook := range webhooks {
+		ids = append(ids, webhook.GetId())
+	}
+	return ids, nil
+}
+
+// kill makes the delivery dead without sending it, as its webhook can't be sent to
+func (delivery *WebhookDelivery) kill(reason string) error {
+	delivery.State = WebhookDeliveryStateDead
+	delivery.LastError = reason
+	_, err := ormer.Engine.ID(delivery.Id).Cols("state", "last_error").Update(delivery)
+	return err
+}
+
+// runWebhookDeliveriesOfWebhook sends the due deliveries of a webhook in order
+func runWebhookDeliveriesOfWebhook(webhookId string, deliveries []*WebhookDelivery) error {
+	webhook, err := GetWebhook(webhookId)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		if webhook == nil {
+			err = delivery.kill(fmt.Sprintf("the webhook: %s doesn't exist", webhookId))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		claimed, err := delivery.claim()
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		err = delivery.deliver(webhook)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runWebhookDeliveries sends the due deliveries of the outbox. The deliveries of a webhook are sent
+// in order, and the ones of different webhooks concurrently so that a slow receiver only delays
+// its own deliveries.
+func runWebhookDeliveries() error {
+	disabledIds, err := getDisabledWebhookIds()
+	if err != nil {
+		return err
+	}
+
+	session := ormer.Engine.Where("state = ? and next_run_time <= ?", WebhookDeliveryStatePending, time.Now().Unix())
+	if len(disabledIds) != 0 {
+		session = session.NotIn("webhook", disabledIds)
+	}
+
+	deliveries := []*WebhookDelivery{}
+	err = session.Asc("id").Limit(webhookBatchSize).Find(&deliveries)
+	if err != nil {
+		return err
+	}
+
+	webhookIds := []string{}
+	deliveriesByWebhook := map[string][]*WebhookDelivery{}
+	for _, delivery := range deliveries {
+		if _, ok := deliveriesByWebhook[delivery.Webhook]; !ok {
+			webhookIds = append(webhookIds, delivery.Webhook)
+		}
+		deliveriesByWebhook[delivery.Webhook] = append(deliveriesByWebhook[delivery.Webhook], delivery)
+	}
+
+	var g errgroup.Group
+	g.SetLimit(webhookConcurrency)
+	for _, webhookId := range webhookIds {
+		webhookId := webhookId
+		g.Go(func() error {
+			return runWebhookDeliveriesOfWebhook(webhookId, deliveriesByWebhook[webhookId])
+		})
+	}
+
+	return g.Wait()
+}
+
+// RunWebhookJob sends the deliveries of the webhook outbox in the background
+func RunWebhookJob() {
+	for {
+		err := runWebhookDeliveries()
+		if err != nil {
+			logs.Warning("failed to send the webhook deliveries: %s", err.Error())
+		}
+
+		time.Sleep(webhookInterval)
+	}
+}
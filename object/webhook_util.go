@@ -15,28 +15,71 @@
 package object
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
+)
 
-	"github.com/casdoor/casdoor/util"
+const (
+	webhookRequestTimeout   = 10 * time.Second
+	webhookMaxResponseBytes = 4096
 )
 
-func sendWebhook(webhook *Webhook, record *Record) error {
-	client := &http.Client{}
+var webhookClient = &http.Client{Timeout: webhookRequestTimeout}
 
-	body := strings.NewReader(util.StructToJson(record))
+// signWebhookPayload returns the signature of the payload sent at the timestamp, the receiver
+// computes the hex HMAC-SHA256 of "<timestamp>.<payload>" with the secret of the webhook and
+// compares it to the v1 value of the X-Casdoor-Signature header
+func signWebhookPayload(secret string, timestamp int64, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
 
-	req, err := http.NewRequest(webhook.Method, webhook.Url, body)
+// sendWebhook sends the payload of the delivery to the webhook, it returns the status code and the
+// beginning of the response body, with an error if the delivery failed
+func sendWebhook(webhook *Webhook, delivery *WebhookDelivery) (int, string, error) {
+	req, err := http.NewRequest(webhook.Method, webhook.Url, strings.NewReader(delivery.Payload))
 	if err != nil {
-		return err
+		return 0, "", err
 	}
 
-	req.Header.Set("Content-Type", webhook.ContentType)
+	contentType := webhook.ContentType
+	if delivery.ContentType != "" {
+		contentType = delivery.ContentType
+	}
+	req.Header.Set("Content-Type", contentType)
 
 	for _, header := range webhook.Headers {
 		req.Header.Set(header.Name, header.Value)
 	}
 
-	_, err = client.Do(req)
-	return err
+	req.Header.Set("X-Casdoor-Event", delivery.Event)
+	req.Header.Set("X-Casdoor-Delivery", strconv.FormatInt(delivery.Id, 10))
+	if webhook.Secret != "" {
+		timestamp := time.Now().Unix()
+		req.Header.Set("X-Casdoor-Signature", fmt.Sprintf("t=%d,v1=%s", timestamp, signWebhookPayload(webhook.Secret, timestamp, delivery.Payload)))
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, webhookMaxResponseBytes))
+	if err != nil {
+		return resp.StatusCode, "", err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(body), fmt.Errorf("the webhook returned the status code: %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(body), nil
 }
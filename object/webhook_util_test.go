@@ -0,0 +1,80 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package object
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendWebhook(t *testing.T) {
+	webhook := &Webhook{
+		Method:      "POST",
+		ContentType: "application/json",
+		Headers:     []*Header{{Name: "X-Custom", Value: "custom"}},
+		Secret:      "secret",
+	}
+	delivery := &WebhookDelivery{Id: 42, Event: "login", Payload: `{"action":"login"}`}
+
+	statusCode := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, delivery.Payload, string(body))
+		assert.Equal(t, "custom", r.Header.Get("X-Custom"))
+		assert.Equal(t, "login", r.Header.Get("X-Casdoor-Event"))
+		assert.Equal(t, "42", r.Header.Get("X-Casdoor-Delivery"))
+
+		var timestamp int64
+		var signature string
+		_, err := fmt.Sscanf(strings.Replace(r.Header.Get("X-Casdoor-Signature"), ",", " ", 1), "t=%d v1=%s", &timestamp, &signature)
+		assert.Nil(t, err)
+		assert.Equal(t, signWebhookPayload("secret", timestamp, string(body)), signature)
+
+		w.WriteHeader(statusCode)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	webhook.Url = server.URL
+
+	code, response, err := sendWebhook(webhook, delivery)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", response)
+
+	statusCode = http.StatusServiceUnavailable
+	code, _, err = sendWebhook(webhook, delivery)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	// echo -n '1700000000.{}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163", signWebhookPayload("secret", 1700000000, "{}"))
+	assert.NotEqual(t, signWebhookPayload("secret", 1700000000, "{}"), signWebhookPayload("other", 1700000000, "{}"))
+	assert.NotEqual(t, signWebhookPayload("secret", 1700000000, "{}"), signWebhookPayload("secret", 1700000001, "{}"))
+}
+
+func TestGetWebhookRetryDelay(t *testing.T) {
+	assert.Equal(t, webhookRetryDelay, getWebhookRetryDelay(1))
+	assert.Equal(t, 4*webhookRetryDelay, getWebhookRetryDelay(3))
+	assert.Equal(t, webhookMaxRetryDelay, getWebhookRetryDelay(20))
+	assert.Equal(t, webhookMaxRetryDelay, getWebhookRetryDelay(100))
+}
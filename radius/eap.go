@@ -0,0 +1,329 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package radius
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/casdoor/casdoor/object"
+	"layeh.com/radius"
+	"layeh.com/radius/rfc2865"
+	"layeh.com/radius/rfc2869"
+)
+
+// EAP over RADIUS, see RFC 3579
+const (
+	eapCodeRequest  = 1
+	eapCodeResponse = 2
+	eapCodeSuccess  = 3
+	eapCodeFailure  = 4
+
+	eapTypeIdentity = 1
+	eapTypeNak      = 3
+	eapTypeTtls     = 21
+	eapTypeMschapv2 = 26
+
+	messageAuthenticatorType = 80
+
+	vendorMicrosoft   = 311
+	msMppeSendKeyType = 16
+	msMppeRecvKeyType = 17
+)
+
+type mppeKeys struct {
+	recvKey []byte
+	sendKey []byte
+}
+
+type eapPacket struct {
+	code       byte
+	identifier byte
+	eapType    byte
+	data       []byte
+}
+
+// eapSession is the state of the EAP method negotiated with the peer
+type eapSession struct {
+	lock sync.Mutex
+	// identifier is the identifier of the last request sent to the peer
+	identifier byte
+	eapType    byte
+	mschapv2   *mschapv2Session
+	ttls       *ttlsSession
+}
+
+func parseEapPacket(b []byte) (*eapPacket, error) {
+	if len(b) < 4 {
+		return nil, fmt.Errorf("the EAP packet is too short")
+	}
+
+	length := int(binary.BigEndian.Uint16(b[2:4]))
+	if length < 4 || length > len(b) {
+		return nil, fmt.Errorf("the length of the EAP packet is invalid")
+	}
+
+	packet := &eapPacket{code: b[0], identifier: b[1]}
+	if length > 4 {
+		packet.eapType = b[4]
+		packet.data = b[5:length]
+	}
+	return packet, nil
+}
+
+func (packet *eapPacket) encode() []byte {
+	length := 4
+	if packet.code == eapCodeRequest || packet.code == eapCodeResponse {
+		length += 1 + len(packet.data)
+	}
+
+	b := make([]byte, length)
+	b[0] = packet.code
+	b[1] = packet.identifier
+	binary.BigEndian.PutUint16(b[2:4], uint16(length))
+	if length > 4 {
+		b[4] = packet.eapType
+		copy(b[5:], packet.data)
+	}
+	return b
+}
+
+// verifyMessageAuthenticator checks the Message-Authenticator of a request, which is required
+// with EAP, see RFC 3579 section 3.2
+func verifyMessageAuthenticator(p *radius.Packet) bool {
+	b, err := p.Encode()
+	if err != nil {
+		return false
+	}
+
+	var messageAuthenticator []byte
+	for i := 20; i+2 <= len(b); i += int(b[i+1]) {
+		length := int(b[i+1])
+		if length < 2 || i+length > len(b) {
+			return false
+		}
+
+		if b[i] == messageAuthenticatorType && length == 18 {
+			messageAuthenticator = append([]byte{}, b[i+2:i+18]...)
+			copy(b[i+2:i+18], make([]byte, 16))
+		}
+	}
+
+	if messageAuthenticator == nil {
+		return false
+	}
+
+	mac := hmac.New(md5.New, p.Secret)
+	mac.Write(b)
+	return hmac.Equal(mac.Sum(nil), messageAuthenticator)
+}
+
+// setMessageAuthenticator signs a response, it must be called after all the other attributes
+// are added so that the signed attributes are the ones that are sent
+func setMessageAuthenticator(p *radius.Packet) error {
+	err := rfc2869.MessageAuthenticator_Set(p, make([]byte, 16))
+	if err != nil {
+		return err
+	}
+
+	b, err := p.Encode()
+	if err != nil {
+		return err
+	}
+
+	// the response is signed with the authenticator of the request
+	copy(b[4:20], p.Authenticator[:])
+	mac := hmac.New(md5.New, p.Secret)
+	mac.Write(b)
+	return rfc2869.MessageAuthenticator_Set(p, mac.Sum(nil))
+}
+
+// addMppeKey adds an MS-MPPE-Send-Key or MS-MPPE-Recv-Key attribute, see RFC 2548 section 2.4
+func addMppeKey(p *radius.Packet, keyType byte, key []byte) error {
+	salt := make([]byte, 2)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return err
+	}
+	salt[0] |= 0x80
+
+	encryptedKey, err := radius.NewTunnelPassword(key, salt, p.Secret, p.Authenticator[:])
+	if err != nil {
+		return err
+	}
+
+	value := append([]byte{keyType, byte(2 + len(encryptedKey))}, encryptedKey...)
+	attribute, err := radius.NewVendorSpecific(vendorMicrosoft, value)
+	if err != nil {
+		return err
+	}
+	return rfc2865.VendorSpecific_Add(p, attribute)
+}
+
+func writeEapRequest(w radius.ResponseWriter, r *radius.Request, state string, session *eapSession, eapType byte, data []byte) {
+	session.identifier++
+	session.eapType = eapType
+	eap := &eapPacket{code: eapCodeRequest, identifier: session.identifier, eapType: eapType, data: data}
+
+	res := r.Response(radius.CodeAccessChallenge)
+	err := rfc2869.EAPMessage_Set(res, eap.encode())
+	if err == nil {
+		err = rfc2865.State_SetString(res, state)
+	}
+	if err == nil {
+		err = setMessageAuthenticator(res)
+	}
+	if err != nil {
+		log.Printf("writeEapRequest() error: %s", err.Error())
+		return
+	}
+
+	w.Write(res)
+}
+
+// writeEapResult ends the EAP authentication, user is the authenticated user or nil if the
+// authentication failed. The keys derived by the method are sent to the network access server
+// as the MS-MPPE-Recv-Key and the MS-MPPE-Send-Key.
+func writeEapResult(w radius.ResponseWriter, r *radius.Request, identifier byte, user *object.User, keys *mppeKeys) {
+	code, eapCode := radius.CodeAccessReject, byte(eapCodeFailure)
+	if user != nil {
+		code, eapCode = radius.CodeAccessAccept, eapCodeSuccess
+	}
+
+	res := r.Response(code)
+	eap := &eapPacket{code: eapCode, identifier: identifier}
+	err := rfc2869.EAPMessage_Set(res, eap.encode())
+	if err == nil && user != nil {
+		err = addReplyAttributes(res, user)
+	}
+	if err == nil && user != nil && keys != nil {
+		err = addMppeKey(res, msMppeRecvKeyType, keys.recvKey)
+		if err == nil {
+			err = addMppeKey(res, msMppeSendKeyType, keys.sendKey)
+		}
+	}
+	if err == nil {
+		err = setMessageAuthenticator(res)
+	}
+	if err != nil {
+		log.Printf("writeEapResult() error: %s", err.Error())
+		return
+	}
+
+	w.Write(res)
+}
+
+// startEapMethod sends the first request of the EAP method
+func startEapMethod(w radius.ResponseWriter, r *radius.Request, state string, session *radiusSession, eapType byte) {
+	switch eapType {
+	case eapTypeTtls:
+		ttls, err := newTtlsSession()
+		if err != nil {
+			log.Printf("newTtlsSession() error: %s", err.Error())
+			writeEapResult(w, r, session.eap.identifier, nil, nil)
+			return
+		}
+
+		session.eap.ttls = ttls
+		writeEapRequest(w, r, state, session.eap, eapTypeTtls, []byte{ttlsFlagStart})
+	case eapTypeMschapv2:
+		mschapv2, data, err := newMschapv2Session(session.eap.identifier + 1)
+		if err != nil {
+			log.Printf("newMschapv2Session() error: %s", err.Error())
+			writeEapResult(w, r, session.eap.identifier, nil, nil)
+			return
+		}
+
+		session.eap.mschapv2 = mschapv2
+		writeEapRequest(w, r, state, session.eap, eapTypeMschapv2, data)
+	}
+}
+
+func isEapTypeSupported(eapType byte) bool {
+	switch eapType {
+	case eapTypeTtls:
+		return radiusTlsConfig != nil
+	case eapTypeMschapv2:
+		return true
+	default:
+		return false
+	}
+}
+
+func handleEap(w radius.ResponseWriter, r *radius.Request, organization string) {
+	if !verifyMessageAuthenticator(r.Packet) {
+		log.Printf("handleEap() discarded a request with an invalid Message-Authenticator from %v", r.RemoteAddr)
+		return
+	}
+
+	eap, err := parseEapPacket(rfc2869.EAPMessage_Get(r.Packet))
+	if err != nil || eap.code != eapCodeResponse {
+		writeEapResult(w, r, 0, nil, nil)
+		return
+	}
+
+	state := rfc2865.State_GetString(r.Packet)
+	if eap.eapType == eapTypeIdentity && state == "" {
+		session := &radiusSession{organization: organization, eap: &eapSession{identifier: eap.identifier}}
+		state = addSession(session)
+
+		eapType := byte(eapTypeMschapv2)
+		if isEapTypeSupported(eapTypeTtls) {
+			eapType = eapTypeTtls
+		}
+		startEapMethod(w, r, state, session, eapType)
+		return
+	}
+
+	session := getSession(state, organization)
+	if session == nil || session.eap == nil {
+		writeEapResult(w, r, eap.identifier, nil, nil)
+		return
+	}
+
+	session.eap.lock.Lock()
+	defer session.eap.lock.Unlock()
+
+	if eap.identifier != session.eap.identifier {
+		log.Printf("handleEap() discarded an EAP response with an unexpected identifier from %v", r.RemoteAddr)
+		return
+	}
+
+	switch {
+	case eap.eapType == eapTypeNak:
+		// the peer proposes the methods it supports instead
+		for _, eapType := range eap.data {
+			if eapType != session.eap.eapType && isEapTypeSupported(eapType) {
+				startEapMethod(w, r, state, session, eapType)
+				return
+			}
+		}
+	case eap.eapType != session.eap.eapType:
+	case eap.eapType == eapTypeMschapv2:
+		handleEapMschapv2(w, r, state, session, eap)
+		return
+	case eap.eapType == eapTypeTtls:
+		handleEapTtls(w, r, state, session, eap)
+		return
+	}
+
+	deleteSession(state)
+	writeEapResult(w, r, eap.identifier, nil, nil)
+}
@@ -0,0 +1,164 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package radius
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"log"
+
+	"github.com/casdoor/casdoor/object"
+	"layeh.com/radius"
+)
+
+// EAP-MSCHAPv2, see draft-kamath-pppext-eap-mschapv2
+const (
+	mschapv2OpCodeChallenge = 1
+	mschapv2OpCodeResponse  = 2
+	mschapv2OpCodeSuccess   = 3
+	mschapv2OpCodeFailure   = 4
+
+	mschapv2ServerName = "casgate"
+)
+
+type mschapv2Session struct {
+	id        byte
+	challenge []byte
+	// user and keys are set once the response of the peer is verified
+	user *object.User
+	keys *mppeKeys
+}
+
+func newMschapv2Session(id byte) (*mschapv2Session, []byte, error) {
+	challenge := make([]byte, 16)
+	_, err := rand.Read(challenge)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session := &mschapv2Session{id: id, challenge: challenge}
+	value := append([]byte{byte(len(challenge))}, challenge...)
+	return session, encodeMschapv2Packet(mschapv2OpCodeChallenge, id, append(value, mschapv2ServerName...)), nil
+}
+
+func encodeMschapv2Packet(opCode byte, id byte, data []byte) []byte {
+	b := make([]byte, 4+len(data))
+	b[0] = opCode
+	b[1] = id
+	binary.BigEndian.PutUint16(b[2:4], uint16(len(b)))
+	copy(b[4:], data)
+	return b
+}
+
+// getMschapv2PasswordHash returns the NT hash of the password of the user, which can only be
+// computed for the users whose passwords are stored in plain text
+func getMschapv2PasswordHash(user *object.User, username string) ([]byte, error) {
+	if user == nil || user.IsDeleted || user.IsForbidden {
+		return nil, fmt.Errorf("the user: %s cannot sign in", username)
+	}
+
+	if user.Ldap != "" || user.PasswordType != "plain" {
+		return nil, fmt.Errorf("EAP-MSCHAPv2 requires the password of the user: %s to be stored in plain text", username)
+	}
+
+	return ntPasswordHash(user.Password), nil
+}
+
+func (session *mschapv2Session) verifyResponse(organization string, data []byte) ([]byte, error) {
+	// Value-Size, Peer-Challenge, Reserved, NT-Response, Flags and Name
+	if len(data) < 1+49 || data[0] != 49 {
+		return nil, fmt.Errorf("the MS-CHAPv2 response is invalid")
+	}
+
+	peerChallenge := data[1:17]
+	ntResponse := data[25:49]
+	username := getMschapUsername(string(data[50:]))
+
+	user, err := object.GetUserByFields(organization, username)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := getMschapv2PasswordHash(user, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsMfaEnabled() {
+		return nil, fmt.Errorf("the user: %s has MFA enabled, which is not supported with EAP", username)
+	}
+
+	expectedNtResponse, err := generateNtResponse(session.challenge, peerChallenge, string(data[50:]), passwordHash)
+	if err != nil {
+		return nil, err
+	}
+
+	// the failed responses lock the user out like the failed passwords of PAP and EAP-TTLS
+	err = object.CheckPasswordResponse(user, func() bool {
+		return subtle.ConstantTimeCompare(expectedNtResponse, ntResponse) == 1
+	}, "en")
+	if err != nil {
+		return nil, err
+	}
+
+	masterKey := getMasterKey(passwordHash, ntResponse)
+	session.user = user
+	session.keys = &mppeKeys{
+		recvKey: getAsymmetricStartKey(masterKey, false),
+		sendKey: getAsymmetricStartKey(masterKey, true),
+	}
+
+	authenticatorResponse := generateAuthenticatorResponse(passwordHash, ntResponse, peerChallenge, session.challenge, string(data[50:]))
+	return encodeMschapv2Packet(mschapv2OpCodeSuccess, session.id, []byte(authenticatorResponse+" M=Authentication succeeded")), nil
+}
+
+func handleEapMschapv2(w radius.ResponseWriter, r *radius.Request, state string, session *radiusSession, eap *eapPacket) {
+	mschapv2 := session.eap.mschapv2
+	if mschapv2 == nil || len(eap.data) == 0 {
+		deleteSession(state)
+		writeEapResult(w, r, eap.identifier, nil, nil)
+		return
+	}
+
+	switch eap.data[0] {
+	case mschapv2OpCodeResponse:
+		if len(eap.data) < 4 || eap.data[1] != mschapv2.id {
+			break
+		}
+
+		data, err := mschapv2.verifyResponse(session.organization, eap.data[4:])
+		if err != nil {
+			log.Printf("handleEapMschapv2() error: %s", err.Error())
+			break
+		}
+
+		writeEapRequest(w, r, state, session.eap, eapTypeMschapv2, data)
+		return
+	case mschapv2OpCodeSuccess:
+		// the peer has verified the authenticator response
+		if mschapv2.user == nil {
+			break
+		}
+
+		deleteSession(state)
+		writeEapResult(w, r, eap.identifier, mschapv2.user, mschapv2.keys)
+		return
+	}
+
+	deleteSession(state)
+	writeEapResult(w, r, eap.identifier, nil, nil)
+}
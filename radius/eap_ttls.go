@@ -0,0 +1,367 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package radius
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/casdoor/casdoor/object"
+	"layeh.com/radius"
+)
+
+// EAP-TTLSv0 with PAP as the inner authentication, see RFC 5281
+const (
+	ttlsFlagLengthIncluded = 0x80
+	ttlsFlagMoreFragments  = 0x40
+	ttlsFlagStart          = 0x20
+
+	ttlsFragmentSize = 1000
+	ttlsTimeout      = 10 * time.Second
+
+	diameterAvpFlagVendor = 0x80
+	avpCodeUserName       = 1
+	avpCodeUserPassword   = 2
+)
+
+// eapTlsConn carries the records of the TLS connection of EAP-TTLS in the EAP packets instead
+// of a network connection. The TLS connection runs in its own goroutine, and waits for the
+// records of the peer which are fed by the handler of the RADIUS requests.
+type eapTlsConn struct {
+	lock sync.Mutex
+	cond *sync.Cond
+	in   bytes.Buffer
+	out  bytes.Buffer
+	// waiting is set when the TLS connection has consumed all the records of the peer
+	waiting  bool
+	finished bool
+	closed   bool
+}
+
+type ttlsSession struct {
+	conn    *eapTlsConn
+	tlsConn *tls.Conn
+	// pending is the outgoing data whose fragments have not all been sent yet
+	pending        []byte
+	pendingStarted bool
+
+	// the result of the inner authentication, set when the connection is finished
+	username string
+	password string
+	keys     *mppeKeys
+	err      error
+}
+
+func newEapTlsConn() *eapTlsConn {
+	conn := &eapTlsConn{}
+	conn.cond = sync.NewCond(&conn.lock)
+	return conn
+}
+
+func (conn *eapTlsConn) Read(b []byte) (int, error) {
+	conn.lock.Lock()
+	defer conn.lock.Unlock()
+
+	for conn.in.Len() == 0 && !conn.closed {
+		conn.waiting = true
+		conn.cond.Broadcast()
+		conn.cond.Wait()
+	}
+
+	if conn.closed {
+		return 0, io.EOF
+	}
+	return conn.in.Read(b)
+}
+
+func (conn *eapTlsConn) Write(b []byte) (int, error) {
+	conn.lock.Lock()
+	defer conn.lock.Unlock()
+
+	if conn.closed {
+		return 0, io.ErrClosedPipe
+	}
+	return conn.out.Write(b)
+}
+
+func (conn *eapTlsConn) Close() error {
+	conn.lock.Lock()
+	defer conn.lock.Unlock()
+
+	conn.closed = true
+	conn.cond.Broadcast()
+	return nil
+}
+
+func (conn *eapTlsConn) LocalAddr() net.Addr {
+	return &net.IPAddr{}
+}
+
+func (conn *eapTlsConn) RemoteAddr() net.Addr {
+	return &net.IPAddr{}
+}
+
+func (conn *eapTlsConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (conn *eapTlsConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (conn *eapTlsConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+func (conn *eapTlsConn) feed(b []byte) {
+	conn.lock.Lock()
+	defer conn.lock.Unlock()
+
+	conn.in.Write(b)
+	conn.waiting = false
+	conn.cond.Broadcast()
+}
+
+func (conn *eapTlsConn) finish() {
+	conn.lock.Lock()
+	defer conn.lock.Unlock()
+
+	conn.finished = true
+	conn.cond.Broadcast()
+}
+
+// wait waits until the TLS connection needs more records from the peer or is finished, and
+// returns the data to send to the peer
+func (conn *eapTlsConn) wait() ([]byte, bool, error) {
+	timer := time.AfterFunc(ttlsTimeout, func() {
+		conn.Close()
+	})
+	defer timer.Stop()
+
+	conn.lock.Lock()
+	defer conn.lock.Unlock()
+
+	for !conn.waiting && !conn.finished && !conn.closed {
+		conn.cond.Wait()
+	}
+
+	if conn.closed && !conn.finished {
+		return nil, false, fmt.Errorf("the TLS connection is closed")
+	}
+
+	out := append([]byte{}, conn.out.Bytes()...)
+	conn.out.Reset()
+	return out, conn.finished, nil
+}
+
+func newTtlsSession() (*ttlsSession, error) {
+	if radiusTlsConfig == nil {
+		return nil, fmt.Errorf("no certificate is configured for EAP-TTLS")
+	}
+
+	config := radiusTlsConfig.Clone()
+	// the keying material of EAP-TTLS is only defined for TLS 1.2 and below
+	config.MaxVersion = tls.VersionTLS12
+	config.SessionTicketsDisabled = true
+
+	conn := newEapTlsConn()
+	session := &ttlsSession{conn: conn, tlsConn: tls.Server(conn, config)}
+	go session.run()
+	return session, nil
+}
+
+func (session *ttlsSession) run() {
+	defer session.conn.finish()
+
+	session.err = session.tlsConn.Handshake()
+	if session.err != nil {
+		return
+	}
+
+	b := make([]byte, 4096)
+	n, err := session.tlsConn.Read(b)
+	if err != nil {
+		session.err = err
+		return
+	}
+
+	avps, err := parseDiameterAvps(b[:n])
+	if err != nil {
+		session.err = err
+		return
+	}
+
+	if avps[avpCodeUserPassword] == nil {
+		session.err = fmt.Errorf("only PAP is supported as the inner authentication of EAP-TTLS")
+		return
+	}
+
+	session.username = string(avps[avpCodeUserName])
+	session.password = string(bytes.TrimRight(avps[avpCodeUserPassword], "\x00"))
+
+	state := session.tlsConn.ConnectionState()
+	keyingMaterial, err := state.ExportKeyingMaterial("ttls keying material", nil, 64)
+	if err != nil {
+		// the peer will not be able to use the keys either, but the authentication can succeed
+		log.Printf("ttlsSession.run() failed to export the keying material: %s", err.Error())
+		return
+	}
+	session.keys = &mppeKeys{recvKey: keyingMaterial[:32], sendKey: keyingMaterial[32:]}
+}
+
+func (session *ttlsSession) close() {
+	session.conn.Close()
+}
+
+// parseDiameterAvps returns the values of the AVPs which are not vendor specific by their codes
+func parseDiameterAvps(b []byte) (map[uint32][]byte, error) {
+	avps := map[uint32][]byte{}
+	for len(b) > 0 {
+		if len(b) < 8 {
+			return nil, fmt.Errorf("the AVP is too short")
+		}
+
+		code := binary.BigEndian.Uint32(b[0:4])
+		flags := b[4]
+		length := int(b[5])<<16 | int(b[6])<<8 | int(b[7])
+		headerLength := 8
+		if flags&diameterAvpFlagVendor != 0 {
+			headerLength = 12
+		}
+		if length < headerLength || length > len(b) {
+			return nil, fmt.Errorf("the length of the AVP is invalid")
+		}
+
+		if flags&diameterAvpFlagVendor == 0 {
+			avps[code] = b[headerLength:length]
+		}
+
+		// AVPs are padded to a multiple of 4 bytes
+		length = (length + 3) &^ 3
+		if length > len(b) {
+			length = len(b)
+		}
+		b = b[length:]
+	}
+	return avps, nil
+}
+
+// writeTtlsFragment sends the next fragment of the pending data
+func writeTtlsFragment(w radius.ResponseWriter, r *radius.Request, state string, session *radiusSession) {
+	ttls := session.eap.ttls
+
+	flags := byte(0)
+	header := []byte{}
+	if !ttls.pendingStarted && len(ttls.pending) > ttlsFragmentSize {
+		flags |= ttlsFlagLengthIncluded
+		header = binary.BigEndian.AppendUint32(header, uint32(len(ttls.pending)))
+	}
+
+	fragment := ttls.pending
+	if len(fragment) > ttlsFragmentSize {
+		flags |= ttlsFlagMoreFragments
+		fragment = fragment[:ttlsFragmentSize]
+	}
+
+	ttls.pending = ttls.pending[len(fragment):]
+	ttls.pendingStarted = len(ttls.pending) != 0
+
+	data := append([]byte{flags}, header...)
+	writeEapRequest(w, r, state, session.eap, eapTypeTtls, append(data, fragment...))
+}
+
+func handleEapTtls(w radius.ResponseWriter, r *radius.Request, state string, session *radiusSession, eap *eapPacket) {
+	ttls := session.eap.ttls
+	if ttls == nil || len(eap.data) == 0 {
+		deleteSession(state)
+		writeEapResult(w, r, eap.identifier, nil, nil)
+		return
+	}
+
+	flags := eap.data[0]
+	data := eap.data[1:]
+	if flags&ttlsFlagLengthIncluded != 0 {
+		if len(data) < 4 {
+			deleteSession(state)
+			writeEapResult(w, r, eap.identifier, nil, nil)
+			return
+		}
+		data = data[4:]
+	}
+
+	// the peer acknowledges a fragment of the pending data
+	if len(ttls.pending) != 0 {
+		writeTtlsFragment(w, r, state, session)
+		return
+	}
+
+	if len(data) != 0 {
+		ttls.conn.feed(data)
+	}
+
+	// acknowledge the fragment, the next ones are to come
+	if flags&ttlsFlagMoreFragments != 0 {
+		writeEapRequest(w, r, state, session.eap, eapTypeTtls, []byte{0})
+		return
+	}
+
+	out, finished, err := ttls.conn.wait()
+	if err != nil {
+		log.Printf("handleEapTtls() error: %s", err.Error())
+		deleteSession(state)
+		writeEapResult(w, r, eap.identifier, nil, nil)
+		return
+	}
+
+	if len(out) != 0 {
+		ttls.pending = out
+		writeTtlsFragment(w, r, state, session)
+		return
+	}
+
+	if !finished {
+		writeEapRequest(w, r, state, session.eap, eapTypeTtls, []byte{0})
+		return
+	}
+
+	deleteSession(state)
+	if ttls.err != nil {
+		log.Printf("handleEapTtls() error: %s", ttls.err.Error())
+		writeEapResult(w, r, eap.identifier, nil, nil)
+		return
+	}
+
+	user, err := object.CheckUserPassword(session.organization, ttls.username, ttls.password, "en")
+	if err != nil {
+		log.Printf("handleEapTtls() failed to authenticate the user: %s, err = %s", ttls.username, err.Error())
+		writeEapResult(w, r, eap.identifier, nil, nil)
+		return
+	}
+
+	if user.IsMfaEnabled() {
+		log.Printf("handleEapTtls() the user: %s has MFA enabled, which is not supported with EAP", ttls.username)
+		writeEapResult(w, r, eap.identifier, nil, nil)
+		return
+	}
+
+	writeEapResult(w, r, eap.identifier, user, ttls.keys)
+}
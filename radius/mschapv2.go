@@ -0,0 +1,127 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package radius
+
+import (
+	"crypto/des"
+	"crypto/sha1"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+)
+
+// The MS-CHAPv2 algorithms of RFC 2759, and the derivation of the MPPE keys of RFC 3079
+
+var (
+	authenticatorMagic1 = []byte("Magic server to client signing constant")
+	authenticatorMagic2 = []byte("Pad to make it do more than one iteration")
+
+	masterKeyMagic = []byte("This is the MPPE Master Key")
+	// the receive key of the server
+	startKeyMagic2 = []byte("On the client side, this is the send key; on the server side, it is the receive key.")
+	// the send key of the server
+	startKeyMagic3 = []byte("On the client side, this is the receive key; on the server side, it is the send key.")
+)
+
+func md4Sum(data []byte) []byte {
+	h := md4.New()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func sha1Sum(data ...[]byte) []byte {
+	h := sha1.New()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// getMschapUsername strips the domain of a user name like "DOMAIN\user"
+func getMschapUsername(username string) string {
+	if i := strings.LastIndex(username, "\\"); i != -1 {
+		return username[i+1:]
+	}
+	return username
+}
+
+func ntPasswordHash(password string) []byte {
+	b := []byte{}
+	for _, c := range utf16.Encode([]rune(password)) {
+		b = append(b, byte(c), byte(c>>8))
+	}
+	return md4Sum(b)
+}
+
+func challengeHash(peerChallenge []byte, authenticatorChallenge []byte, username string) []byte {
+	return sha1Sum(peerChallenge, authenticatorChallenge, []byte(getMschapUsername(username)))[:8]
+}
+
+// desKey expands a 7-byte key into a DES key, the parity bits are ignored
+func desKey(key []byte) []byte {
+	return []byte{
+		key[0],
+		key[0]<<7 | key[1]>>1,
+		key[1]<<6 | key[2]>>2,
+		key[2]<<5 | key[3]>>3,
+		key[3]<<4 | key[4]>>4,
+		key[4]<<3 | key[5]>>5,
+		key[5]<<2 | key[6]>>6,
+		key[6] << 1,
+	}
+}
+
+func challengeResponse(challenge []byte, passwordHash []byte) ([]byte, error) {
+	zPasswordHash := make([]byte, 21)
+	copy(zPasswordHash, passwordHash)
+
+	res := make([]byte, 24)
+	for i := 0; i < 3; i++ {
+		block, err := des.NewCipher(desKey(zPasswordHash[i*7 : i*7+7]))
+		if err != nil {
+			return nil, err
+		}
+		block.Encrypt(res[i*8:i*8+8], challenge)
+	}
+	return res, nil
+}
+
+func generateNtResponse(authenticatorChallenge []byte, peerChallenge []byte, username string, passwordHash []byte) ([]byte, error) {
+	return challengeResponse(challengeHash(peerChallenge, authenticatorChallenge, username), passwordHash)
+}
+
+func generateAuthenticatorResponse(passwordHash []byte, ntResponse []byte, peerChallenge []byte, authenticatorChallenge []byte, username string) string {
+	digest := sha1Sum(md4Sum(passwordHash), ntResponse, authenticatorMagic1)
+	digest = sha1Sum(digest, challengeHash(peerChallenge, authenticatorChallenge, username), authenticatorMagic2)
+	return fmt.Sprintf("S=%X", digest)
+}
+
+func getMasterKey(passwordHash []byte, ntResponse []byte) []byte {
+	return sha1Sum(md4Sum(passwordHash), ntResponse, masterKeyMagic)[:16]
+}
+
+// getAsymmetricStartKey returns the send or the receive key of the server
+func getAsymmetricStartKey(masterKey []byte, isSend bool) []byte {
+	magic := startKeyMagic2
+	if isSend {
+		magic = startKeyMagic3
+	}
+
+	shsPad1 := make([]byte, 40)
+	shsPad2 := []byte(strings.Repeat("\xf2", 40))
+	return sha1Sum(masterKey, shsPad1, magic, shsPad2)[:16]
+}
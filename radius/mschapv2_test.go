@@ -0,0 +1,49 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package radius
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// The test vectors of RFC 2759 section 9.2 and RFC 3079 section 3.5.3
+func TestMschapv2(t *testing.T) {
+	authenticatorChallenge := mustDecodeHex("5B5D7C7D7B3F2F3E3C2C602132262628")
+	peerChallenge := mustDecodeHex("21402324255E262A28295F2B3A337C7E")
+	passwordHash := ntPasswordHash("clientPass")
+
+	assert.Equal(t, mustDecodeHex("D02E4386BCE91226"), challengeHash(peerChallenge, authenticatorChallenge, "User"))
+	assert.Equal(t, mustDecodeHex("44EBBA8D5312B8D611474411F56989AE"), passwordHash)
+
+	ntResponse, err := generateNtResponse(authenticatorChallenge, peerChallenge, "DOMAIN\\User", passwordHash)
+	assert.Nil(t, err)
+	assert.Equal(t, mustDecodeHex("82309ECD8D708B5EA08FAA3981CD83544233114A3D85D6DF"), ntResponse)
+
+	authenticatorResponse := generateAuthenticatorResponse(passwordHash, ntResponse, peerChallenge, authenticatorChallenge, "User")
+	assert.Equal(t, "S=407A5589115FD0D6209F510FE9C04566932CDA56", authenticatorResponse)
+
+	assert.Equal(t, mustDecodeHex("FDECE3717A8C838CB388E527AE3CDD31"), getMasterKey(passwordHash, ntResponse))
+}
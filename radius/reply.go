@@ -0,0 +1,106 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package radius
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/casdoor/casdoor/object"
+	"layeh.com/radius"
+	"layeh.com/radius/rfc2865"
+)
+
+func newReplyAttribute(definition *object.RadiusAttributeDefinition, value string) (radius.Attribute, error) {
+	if !definition.IsInteger {
+		return radius.NewString(value)
+	}
+
+	i, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("the value: \"%s\" is not an integer", value)
+	}
+	return radius.NewInteger(uint32(i)), nil
+}
+
+// addReplyAttributes adds the attributes of the RADIUS reply rules of the organization of the
+// user to an Access-Accept. The attributes which cannot be encoded are skipped so that a
+// misconfigured rule does not lock the users out.
+func addReplyAttributes(p *radius.Packet, user *object.User) error {
+	organization, err := object.GetOrganizationByUser(user)
+	if err != nil {
+		return err
+	}
+	if organization == nil || len(organization.RadiusReplyRules) == 0 {
+		return nil
+	}
+
+	attributes, err := object.GetRadiusReplyAttributes(user, organization.RadiusReplyRules)
+	if err != nil {
+		return err
+	}
+
+	for _, attribute := range attributes {
+		definition, ok := object.RadiusAttributeDefinitions[attribute.Name]
+		if !ok {
+			log.Printf("addReplyAttributes() the RADIUS attribute: %s is not supported", attribute.Name)
+			continue
+		}
+
+		value, err := newReplyAttribute(definition, attribute.Value)
+		if err != nil {
+			log.Printf("addReplyAttributes() failed to encode the RADIUS attribute: %s, err = %s", attribute.Name, err.Error())
+			continue
+		}
+
+		if definition.VendorId == 0 {
+			p.Add(radius.Type(definition.Type), value)
+			continue
+		}
+
+		// the vendor id and the header of the vendor attribute are carried in the value of Vendor-Specific
+		if len(value) > 253-4-2 {
+			log.Printf("addReplyAttributes() the value of the RADIUS attribute: %s is too long", attribute.Name)
+			continue
+		}
+
+		vendorValue := append([]byte{definition.Type, byte(2 + len(value))}, value...)
+		vendorAttribute, err := radius.NewVendorSpecific(definition.VendorId, vendorValue)
+		if err != nil {
+			return err
+		}
+
+		err = rfc2865.VendorSpecific_Add(p, vendorAttribute)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeAccessAccept accepts the user of the request with the attributes of the RADIUS reply rules
+func writeAccessAccept(w radius.ResponseWriter, r *radius.Request, user *object.User) {
+	res := r.Response(radius.CodeAccessAccept)
+	err := addReplyAttributes(res, user)
+	if err != nil {
+		log.Printf("writeAccessAccept() error: %s", err.Error())
+		w.Write(r.Response(radius.CodeAccessReject))
+		return
+	}
+
+	w.Write(res)
+}
@@ -15,23 +15,36 @@
 package radius
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
+	"net"
 
 	"github.com/casdoor/casdoor/conf"
 	"github.com/casdoor/casdoor/object"
+	"github.com/casdoor/casdoor/util"
 	"layeh.com/radius"
 	"layeh.com/radius/rfc2865"
 	"layeh.com/radius/rfc2866"
+	"layeh.com/radius/rfc2869"
 )
 
+// radiusTlsConfig is the TLS config of EAP-TTLS, nil if no certificate is configured
+var radiusTlsConfig *tls.Config
+
 // https://support.huawei.com/enterprise/zh/doc/EDOC1000178159/35071f9a#tab_3
 func StartRadiusServer() {
-	secret := conf.GetConfigString("radiusSecret")
+	tlsConfig, err := getRadiusTlsConfig()
+	if err != nil {
+		log.Printf("StartRadiusServer() failed to load the certificate, err = %v", err)
+	}
+	radiusTlsConfig = tlsConfig
+
 	server := radius.PacketServer{
 		Addr:         "0.0.0.0:" + conf.GetConfigString("radiusServerPort"),
 		Handler:      radius.HandlerFunc(handlerRadius),
-		SecretSource: radius.StaticSecretSource([]byte(secret)),
+		SecretSource: &secretSource{defaultSecret: []byte(conf.GetConfigString("radiusSecret"))},
 	}
 	log.Printf("Starting Radius server on %s", server.Addr)
 	if err := server.ListenAndServe(); err != nil {
@@ -39,6 +52,74 @@ func StartRadiusServer() {
 	}
 }
 
+// getRadiusTlsConfig returns the TLS config of EAP-TTLS, or nil if no certificate is configured
+func getRadiusTlsConfig() (*tls.Config, error) {
+	certId := conf.GetConfigString("radiusCertId")
+	if certId == "" {
+		return nil, nil
+	}
+
+	cert, err := object.GetCert(certId)
+	if err != nil {
+		return nil, err
+	}
+	if cert == nil {
+		return nil, fmt.Errorf("the cert: %s does not exist", certId)
+	}
+
+	certificate, err := tls.X509KeyPair([]byte(cert.Certificate), []byte(cert.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{certificate},
+	}, nil
+}
+
+// secretSource returns the secret of the RADIUS client sending the request, and the global
+// secret for the network access servers which are not registered as RADIUS clients
+type secretSource struct {
+	defaultSecret []byte
+}
+
+func (s *secretSource) RADIUSSecret(ctx context.Context, remoteAddr net.Addr) ([]byte, error) {
+	radiusClient, err := getRadiusClient(remoteAddr)
+	if err != nil {
+		log.Printf("RADIUSSecret() error: %v", err)
+		return nil, err
+	}
+
+	if radiusClient != nil {
+		return []byte(radiusClient.Secret), nil
+	}
+	return s.defaultSecret, nil
+}
+
+func getRadiusClient(remoteAddr net.Addr) (*object.RadiusClient, error) {
+	udpAddr, ok := remoteAddr.(*net.UDPAddr)
+	if !ok {
+		return nil, nil
+	}
+
+	return object.GetRadiusClientByIp(udpAddr.IP)
+}
+
+// getOrganization returns the organization bound to the RADIUS client of the request, or the
+// Class attribute of the request for the network access servers using the global secret
+func getOrganization(r *radius.Request) (string, error) {
+	radiusClient, err := getRadiusClient(r.RemoteAddr)
+	if err != nil {
+		return "", err
+	}
+
+	if radiusClient != nil {
+		return radiusClient.Owner, nil
+	}
+	return rfc2865.Class_GetString(r.Packet), nil
+}
+
 func handlerRadius(w radius.ResponseWriter, r *radius.Request) {
 	switch r.Code {
 	case radius.CodeAccessRequest:
@@ -49,32 +130,99 @@ func handlerRadius(w radius.ResponseWriter, r *radius.Request) {
 		log.Printf("radius message, code = %d", r.Code)
 	}
 }
+
 func handleAccessRequest(w radius.ResponseWriter, r *radius.Request) {
 	username := rfc2865.UserName_GetString(r.Packet)
-	password := rfc2865.UserPassword_GetString(r.Packet)
-	organization := rfc2865.Class_GetString(r.Packet)
-	log.Printf("handleAccessRequest() username=%v, org=%v, password=%v", username, organization, password)
+	organization, err := getOrganization(r)
+	if err != nil {
+		log.Printf("handleAccessRequest() error: %v", err)
+		w.Write(r.Response(radius.CodeAccessReject))
+		return
+	}
+
+	log.Printf("handleAccessRequest() username=%v, org=%v", username, organization)
 
 	if organization == "" {
 		w.Write(r.Response(radius.CodeAccessReject))
 		return
 	}
-	_, err := object.CheckUserPassword(organization, username, password, "en")
+
+	if _, err = rfc2869.EAPMessage_Lookup(r.Packet); err == nil {
+		handleEap(w, r, organization)
+		return
+	}
+
+	state := rfc2865.State_GetString(r.Packet)
+	if state != "" {
+		handleMfaChallengeResponse(w, r, state, organization, username)
+		return
+	}
+
+	password := rfc2865.UserPassword_GetString(r.Packet)
+	user, err := object.CheckUserPassword(organization, username, password, "en")
+	if err != nil {
+		w.Write(r.Response(radius.CodeAccessReject))
+		return
+	}
+
+	if !user.IsMfaEnabled() {
+		writeAccessAccept(w, r, user)
+		return
+	}
+
+	// the second factor is the TOTP code of the user, answered to an Access-Challenge
+	if user.TotpSecret == "" {
+		res := r.Response(radius.CodeAccessReject)
+		rfc2865.ReplyMessage_SetString(res, "Only TOTP is supported as the second factor")
+		w.Write(res)
+		return
+	}
+
+	state = addSession(&radiusSession{organization: organization, userId: user.GetId()})
+	res := r.Response(radius.CodeAccessChallenge)
+	rfc2865.State_SetString(res, state)
+	rfc2865.ReplyMessage_SetString(res, "Please enter the code of your authenticator app")
+	w.Write(res)
+}
+
+// handleMfaChallengeResponse checks the TOTP code answered to an Access-Challenge, which is
+// carried by the User-Password attribute
+func handleMfaChallengeResponse(w radius.ResponseWriter, r *radius.Request, state string, organization string, username string) {
+	session := getSession(state, organization)
+	deleteSession(state)
+	if session == nil || session.userId != util.GetId(organization, username) {
+		w.Write(r.Response(radius.CodeAccessReject))
+		return
+	}
+
+	user, err := object.GetUser(session.userId)
+	if err != nil || user == nil {
+		w.Write(r.Response(radius.CodeAccessReject))
+		return
+	}
+
+	passcode := rfc2865.UserPassword_GetString(r.Packet)
+	err = object.GetMfaUtil(object.TotpType, user.GetMfaProps(object.TotpType, false)).Verify(passcode)
 	if err != nil {
+		log.Printf("handleMfaChallengeResponse() the TOTP code of the user: %s is incorrect", session.userId)
 		w.Write(r.Response(radius.CodeAccessReject))
 		return
 	}
 
-	w.Write(r.Response(radius.CodeAccessAccept))
+	writeAccessAccept(w, r, user)
 }
 
 func handleAccountingRequest(w radius.ResponseWriter, r *radius.Request) {
 	statusType := rfc2866.AcctStatusType_Get(r.Packet)
 	username := rfc2865.UserName_GetString(r.Packet)
-	organization := rfc2865.Class_GetString(r.Packet)
+	organization, err := getOrganization(r)
+	if err != nil {
+		log.Printf("handleAccountingRequest() error: %v", err)
+		return
+	}
+
 	log.Printf("handleAccountingRequest() username=%v, org=%v, statusType=%v", username, organization, statusType)
 	w.Write(r.Response(radius.CodeAccountingResponse))
-	var err error
 	defer func() {
 		if err != nil {
 			log.Printf("handleAccountingRequest() failed, err = %v", err)
@@ -83,12 +231,12 @@ func handleAccountingRequest(w radius.ResponseWriter, r *radius.Request) {
 	switch statusType {
 	case rfc2866.AcctStatusType_Value_Start:
 		// Start an accounting session
-		ra := GetAccountingFromRequest(r)
+		ra := GetAccountingFromRequest(r, organization)
 		err = object.AddRadiusAccounting(ra)
 	case rfc2866.AcctStatusType_Value_InterimUpdate, rfc2866.AcctStatusType_Value_Stop:
 		// Interim update to an accounting session | Stop an accounting session
 		var (
-			newRa = GetAccountingFromRequest(r)
+			newRa = GetAccountingFromRequest(r, organization)
 			oldRa *object.RadiusAccounting
 		)
 		oldRa, err = object.GetRadiusAccountingBySessionId(newRa.AcctSessionId)
@@ -0,0 +1,93 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package radius
+
+import (
+	"sync"
+	"time"
+
+	"github.com/casdoor/casdoor/util"
+)
+
+const sessionTimeout = 2 * time.Minute
+
+// radiusSession is the state of an authentication spanning several Access-Request and
+// Access-Challenge round trips, it is identified by the State attribute of the packets
+type radiusSession struct {
+	organization string
+	// userId is the user who has passed the first factor and has to answer the MFA challenge
+	userId     string
+	eap        *eapSession
+	expireTime time.Time
+}
+
+var (
+	sessions     = map[string]*radiusSession{}
+	sessionsLock sync.Mutex
+)
+
+func (session *radiusSession) close() {
+	if session.eap != nil && session.eap.ttls != nil {
+		session.eap.ttls.close()
+	}
+}
+
+// addSession stores the session and returns its state
+func addSession(session *radiusSession) string {
+	sessionsLock.Lock()
+	defer sessionsLock.Unlock()
+
+	now := time.Now()
+	for state, s := range sessions {
+		if now.After(s.expireTime) {
+			s.close()
+			delete(sessions, state)
+		}
+	}
+
+	state := util.GenerateId()
+	session.expireTime = now.Add(sessionTimeout)
+	sessions[state] = session
+	return state
+}
+
+func getSession(state string, organization string) *radiusSession {
+	sessionsLock.Lock()
+	defer sessionsLock.Unlock()
+
+	session, ok := sessions[state]
+	if !ok || session.organization != organization {
+		return nil
+	}
+
+	if time.Now().After(session.expireTime) {
+		session.close()
+		delete(sessions, state)
+		return nil
+	}
+
+	session.expireTime = time.Now().Add(sessionTimeout)
+	return session
+}
+
+func deleteSession(state string) {
+	sessionsLock.Lock()
+	defer sessionsLock.Unlock()
+
+	if session, ok := sessions[state]; ok {
+		session.close()
+		delete(sessions, state)
+	}
+}
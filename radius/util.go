@@ -26,12 +26,11 @@ import (
 	"layeh.com/radius/rfc2869"
 )
 
-func GetAccountingFromRequest(r *radius.Request) *object.RadiusAccounting {
+func GetAccountingFromRequest(r *radius.Request, organization string) *object.RadiusAccounting {
 	acctInputOctets := int(rfc2866.AcctInputOctets_Get(r.Packet))
 	acctInputGigawords := int(rfc2869.AcctInputGigawords_Get(r.Packet))
 	acctOutputOctets := int(rfc2866.AcctOutputOctets_Get(r.Packet))
 	acctOutputGigawords := int(rfc2869.AcctOutputGigawords_Get(r.Packet))
-	organization := rfc2865.Class_GetString(r.Packet)
 	getAcctStartTime := func(sessionTime int) time.Time {
 		m, _ := time.ParseDuration(fmt.Sprintf("-%ds", sessionTime))
 		return time.Now().Add(m)
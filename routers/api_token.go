@@ -0,0 +1,139 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routers
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/beego/beego/context"
+	"github.com/casdoor/casdoor/conf"
+	"github.com/casdoor/casdoor/object"
+)
+
+const apiTokenDataKey = "apiToken"
+
+// trustedProxies are the IP addresses and CIDR ranges of the reverse proxies whose X-Forwarded-For
+// header is used to get the client IP address of the requests authorized by an API token
+var trustedProxies = strings.Split(conf.GetConfigString("trustedProxies"), ",")
+
+// apiTokenSession is the session of a request authorized by an API token. It is never saved, so
+// the session cookie cannot be used to send requests outside of the scope of the token.
+type apiTokenSession struct {
+	sid    string
+	lock   sync.RWMutex
+	values map[interface{}]interface{}
+}
+
+func (s *apiTokenSession) Set(key, value interface{}) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.values[key] = value
+	return nil
+}
+
+func (s *apiTokenSession) Get(key interface{}) interface{} {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.values[key]
+}
+
+func (s *apiTokenSession) Delete(key interface{}) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.values, key)
+	return nil
+}
+
+func (s *apiTokenSession) SessionID() string {
+	return s.sid
+}
+
+func (s *apiTokenSession) SessionRelease(w http.ResponseWriter) {}
+
+func (s *apiTokenSession) Flush() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.values = map[interface{}]interface{}{}
+	return nil
+}
+
+// signinByApiToken signs the user of the API token in for the request only
+func signinByApiToken(ctx *context.Context, accessToken string) {
+	token, err := object.CheckApiToken(accessToken, getApiTokenClientIp(ctx.Request, trustedProxies))
+	if err != nil {
+		responseError(ctx, err.Error())
+		return
+	}
+
+	sid := ""
+	if ctx.Input.CruSession != nil {
+		sid = ctx.Input.CruSession.SessionID()
+	}
+	ctx.Input.CruSession = &apiTokenSession{sid: sid, values: map[interface{}]interface{}{}}
+	ctx.Input.SetData(apiTokenDataKey, token)
+	setSessionUser(ctx, token.GetUserId())
+}
+
+func isTrustedProxy(proxies []string, ip string) bool {
+	clientIp := net.ParseIP(ip)
+	if clientIp == nil {
+		return false
+	}
+
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if !strings.Contains(proxy, "/") {
+			if proxyIp := net.ParseIP(proxy); proxyIp != nil && proxyIp.Equal(clientIp) {
+				return true
+			}
+			continue
+		}
+
+		_, proxyNet, err := net.ParseCIDR(proxy)
+		if err == nil && proxyNet.Contains(clientIp) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getApiTokenClientIp returns the IP address the request is sent from. The X-Forwarded-For header
+// is set by the client, so its entries are only used when they are appended by a trusted proxy.
+func getApiTokenClientIp(req *http.Request, proxies []string) string {
+	ip, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		ip = req.RemoteAddr
+	}
+
+	forwardedIps := strings.Split(req.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwardedIps) - 1; i >= 0 && isTrustedProxy(proxies, ip); i-- {
+		forwardedIp := strings.TrimSpace(forwardedIps[i])
+		if forwardedIp == "" {
+			break
+		}
+		ip = forwardedIp
+	}
+
+	return ip
+}
+
+func getApiToken(ctx *context.Context) *object.ApiToken {
+	token, _ := ctx.Input.GetData(apiTokenDataKey).(*object.ApiToken)
+	return token
+}
@@ -0,0 +1,47 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetApiTokenClientIp(t *testing.T) {
+	newRequest := func(remoteAddr string, forwardedFor string) *http.Request {
+		req, _ := http.NewRequest("GET", "/api/get-account", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		return req
+	}
+
+	assert.Equal(t, "203.0.113.7", getApiTokenClientIp(newRequest("203.0.113.7:51234", ""), nil))
+	assert.Equal(t, "2001:db8::7", getApiTokenClientIp(newRequest("[2001:db8::7]:51234", ""), nil))
+
+	// the header of a client connecting directly is spoofed
+	assert.Equal(t, "203.0.113.7", getApiTokenClientIp(newRequest("203.0.113.7:51234", "10.0.0.1"), nil))
+	assert.Equal(t, "203.0.113.7", getApiTokenClientIp(newRequest("203.0.113.7:51234", "10.0.0.1"), []string{"10.0.0.0/8"}))
+
+	// the proxy appends the address of the client to the header sent by the client
+	proxies := []string{"10.0.0.0/8", " 192.168.1.1"}
+	assert.Equal(t, "203.0.113.7", getApiTokenClientIp(newRequest("10.0.0.2:51234", "203.0.113.7"), proxies))
+	assert.Equal(t, "203.0.113.7", getApiTokenClientIp(newRequest("10.0.0.2:51234", "10.0.0.1, 203.0.113.7"), proxies))
+	assert.Equal(t, "203.0.113.7", getApiTokenClientIp(newRequest("192.168.1.1:51234", "10.0.0.1, 203.0.113.7, 10.0.0.3"), proxies))
+	assert.Equal(t, "10.0.0.2", getApiTokenClientIp(newRequest("10.0.0.2:51234", ""), proxies))
+}
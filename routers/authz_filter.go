@@ -172,6 +172,14 @@ func getUrlPath(urlPath string) string {
 		return "/api/webauthn"
 	}
 
+	if strings.HasPrefix(urlPath, "/scim/") {
+		return "/scim"
+	}
+
+	if strings.HasPrefix(urlPath, "/.well-known/") && strings.HasSuffix(urlPath, "/openid-configuration") {
+		return "/.well-known/openid-configuration"
+	}
+
 	return urlPath
 }
 
@@ -191,6 +199,9 @@ func ApiFilter(ctx *context.Context) {
 	}
 
 	isAllowed := authz.IsAllowed(subOwner, subName, method, urlPath, objOwner, objName, id)
+	if token := getApiToken(ctx); token != nil && !token.IsAllowed(urlPath, objOwner, id) {
+		isAllowed = false
+	}
 
 	result := "deny"
 	if isAllowed {
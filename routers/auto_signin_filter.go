@@ -16,6 +16,7 @@ package routers
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/beego/beego/context"
 	"github.com/casdoor/casdoor/object"
@@ -27,6 +28,19 @@ func AutoSigninFilter(ctx *context.Context) {
 	//	return
 	//}
 
+	// the bearer tokens of the client registration endpoint are initial access tokens and
+	// registration access tokens, they are checked by the endpoint itself
+	if ctx.Request.URL.Path == "/api/login/oauth/register" {
+		return
+	}
+
+	// personal access tokens are only accepted as HTTP Bearer tokens like
+	// "Authorization: Bearer cgt_123_456", so they are not logged with the URL
+	if bearerToken := parseBearerToken(ctx); strings.HasPrefix(bearerToken, object.ApiTokenPrefix) {
+		signinByApiToken(ctx, bearerToken)
+		return
+	}
+
 	// GET parameter like "/page?access_token=123" or
 	// HTTP Bearer token like "Authorization: Bearer 123"
 	accessToken := ctx.Input.Query("accessToken")
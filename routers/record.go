@@ -18,6 +18,7 @@ import (
 	goCtx "context"
 	"encoding/json"
 	"reflect"
+	"strings"
 
 	"github.com/casdoor/casdoor/object"
 	"github.com/casdoor/casdoor/util"
@@ -77,8 +78,16 @@ func LogRecordMessage(bCtx *beeCtx.Context) {
 		record = rb.Build()
 	}
 
+	if token := getApiToken(bCtx); token != nil {
+		record.ApiToken = token.GetId()
+		if record.User == "" {
+			record.Organization, record.User = token.Owner, token.User
+		}
+	}
+
 	if resp, ok := bCtx.Input.Data()["json"]; ok {
-		sensitiveResponseFields := []string{"access_token", "id_token", "refresh_token"}
+		// the secrets of the API tokens are only returned once and never kept, see AddApiToken
+		sensitiveResponseFields := []string{"access_token", "id_token", "refresh_token", "api_token"}
 		sanitizeData(resp, sensitiveResponseFields, "***")
 
 		if jsonResp, err := json.Marshal(resp); err == nil {
@@ -104,31 +113,77 @@ func defaultRecordLog(ctx *beeCtx.Context) *object.Record {
 	return record
 }
 
+// sanitizeData replaces the values of the sensitive fields in the data, which are the string fields
+// of the structs and the string values of the maps named by their Go name or their json name. It
+// descends into the pointers, the interfaces, the slices and the maps, like the data of a Response.
 func sanitizeData(data interface{}, sensitiveResponseFields []string, stringToReplace string) {
-	v := reflect.ValueOf(data)
-	if v.Kind() == reflect.Ptr {
-		v = v.Elem() // dereference if it is a pointer
-	}
+	sanitizeValue(reflect.ValueOf(data), sensitiveResponseFields, stringToReplace)
+}
+
+func isSensitiveField(field reflect.StructField, sensitiveResponseFields []string) bool {
+	jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+	return util.InSlice(sensitiveResponseFields, field.Name) || (jsonName != "" && util.InSlice(sensitiveResponseFields, jsonName))
+}
+
+func sanitizeValue(v reflect.Value, sensitiveResponseFields []string, stringToReplace string) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			sanitizeValue(v.Elem(), sensitiveResponseFields, stringToReplace)
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
 
-	if v.Kind() == reflect.Struct {
+		elem := v.Elem()
+		if elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Map || elem.Kind() == reflect.Slice {
+			sanitizeValue(elem, sensitiveResponseFields, stringToReplace)
+		} else if v.CanSet() {
+			// the value of an interface cannot be modified, it is replaced by a sanitized copy
+			copied := reflect.New(elem.Type()).Elem()
+			copied.Set(elem)
+			sanitizeValue(copied, sensitiveResponseFields, stringToReplace)
+			v.Set(copied)
+		}
+	case reflect.Struct:
 		for i := 0; i < v.NumField(); i++ {
 			field := v.Field(i)
-			fieldType := v.Type().Field(i)
+			if !field.CanSet() {
+				continue
+			}
 
-			// check if the field is a struct and recursively call sanitizeData
-			if field.Kind() == reflect.Struct || (field.Kind() == reflect.Ptr && field.Elem().Kind() == reflect.Struct) {
-				sanitizeData(field.Addr().Interface(), sensitiveResponseFields, stringToReplace)
+			if field.Kind() == reflect.String {
+				if isSensitiveField(v.Type().Field(i), sensitiveResponseFields) {
+					field.SetString(stringToReplace)
+				}
 			} else {
-				// check if the field name or its tag is in the list of sensitive fields
-				for _, sensitiveField := range sensitiveResponseFields {
-					if (fieldType.Name == sensitiveField || fieldType.Tag.Get("json") == sensitiveField) && field.CanSet() {
-						if field.Kind() == reflect.String {
-							field.SetString(stringToReplace)
-						}
-						break
-					}
+				sanitizeValue(field, sensitiveResponseFields, stringToReplace)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			sanitizeValue(v.Index(i), sensitiveResponseFields, stringToReplace)
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+
+		for _, key := range v.MapKeys() {
+			value := v.MapIndex(key)
+			if util.InSlice(sensitiveResponseFields, key.String()) {
+				if value.Kind() == reflect.String || (value.Kind() == reflect.Interface && !value.IsNil() && value.Elem().Kind() == reflect.String) {
+					v.SetMapIndex(key, reflect.ValueOf(stringToReplace).Convert(v.Type().Elem()))
 				}
+				continue
 			}
+
+			// the values of a map are not addressable, they are sanitized in a copy
+			copied := reflect.New(value.Type()).Elem()
+			copied.Set(value)
+			sanitizeValue(copied, sensitiveResponseFields, stringToReplace)
+			v.SetMapIndex(key, copied)
 		}
 	}
 }
@@ -0,0 +1,45 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package routers
+
+import (
+	"testing"
+
+	"github.com/casdoor/casdoor/controllers"
+	"github.com/casdoor/casdoor/object"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeData(t *testing.T) {
+	fields := []string{"access_token", "api_token"}
+
+	// the token is the data of the Response, by pointer or by value
+	token := &object.UserApiToken{Owner: "org", Name: "alice", ApiToken: "cgt_token_secret"}
+	resp := &controllers.Response{Status: "ok", Data: token, Data2: object.UserApiToken{ApiToken: "cgt_other_secret"}}
+	sanitizeData(resp, fields, "***")
+	assert.Equal(t, "***", token.ApiToken)
+	assert.Equal(t, "alice", token.Name)
+	assert.Equal(t, "***", resp.Data2.(object.UserApiToken).ApiToken)
+
+	values := map[string]interface{}{
+		"access_token": "secret",
+		"scope":        "openid",
+		"tokens":       []interface{}{map[string]interface{}{"api_token": "secret"}},
+	}
+	sanitizeData(values, fields, "***")
+	assert.Equal(t, "***", values["access_token"])
+	assert.Equal(t, "openid", values["scope"])
+	assert.Equal(t, "***", values["tokens"].([]interface{})[0].(map[string]interface{})["api_token"])
+}
@@ -60,6 +60,7 @@ func initAPI() {
 	beego.Router("/api/get-saml-login", &controllers.ApiController{}, "GET:GetSamlLogin")
 	beego.Router("/api/acs", &controllers.ApiController{}, "POST:HandleSamlLogin")
 	beego.Router("/api/saml/metadata", &controllers.ApiController{}, "GET:GetSamlMeta")
+	beego.Router("/api/saml/slo", &controllers.ApiController{}, "GET,POST:SamlSingleLogout")
 	beego.Router("/api/webhook", &controllers.ApiController{}, "POST:HandleOfficialAccountEvent")
 	beego.Router("/api/get-webhook-event", &controllers.ApiController{}, "GET:GetWebhookEventType")
 	beego.Router("/api/get-captcha-status", &controllers.ApiController{}, "GET:GetCaptchaStatus")
@@ -86,10 +87,11 @@ func initAPI() {
 	beego.Router("/api/remove-user-from-group", &controllers.ApiController{}, "POST:RemoveUserFromGroup")
 	beego.Router("/api/send-invite", &controllers.ApiController{}, "POST:SendInvite")
 
+	beego.Router("/api/get-api-tokens", &controllers.ApiController{}, "GET:GetApiTokens")
 	beego.Router("/api/add-api-token", &controllers.ApiController{}, "POST:AddApiToken")
 	beego.Router("/api/delete-api-token", &controllers.ApiController{}, "POST:DeleteApiToken")
 	beego.Router("/api/recreate-api-token", &controllers.ApiController{}, "POST:RecreateApiToken")
-	beego.Router("/api/get-user-by-api-token", &controllers.ApiController{}, "GET:GetUserByApiToken")
+	beego.Router("/api/get-user-by-api-token", &controllers.ApiController{}, "POST:GetUserByApiToken")
 
 	beego.Router("/api/get-groups", &controllers.ApiController{}, "GET:GetGroups")
 	beego.Router("/api/get-group", &controllers.ApiController{}, "GET:GetGroup")
@@ -138,6 +140,11 @@ func initAPI() {
 	beego.Router("/api/update-policy", &controllers.ApiController{}, "POST:UpdatePolicy")
 	beego.Router("/api/add-policy", &controllers.ApiController{}, "POST:AddPolicy")
 	beego.Router("/api/remove-policy", &controllers.ApiController{}, "POST:RemovePolicy")
+	beego.Router("/api/get-api-policies", &controllers.ApiController{}, "GET:GetApiPolicies")
+	beego.Router("/api/add-api-policy", &controllers.ApiController{}, "POST:AddApiPolicy")
+	beego.Router("/api/update-api-policy", &controllers.ApiController{}, "POST:UpdateApiPolicy")
+	beego.Router("/api/remove-api-policy", &controllers.ApiController{}, "POST:RemoveApiPolicy")
+	beego.Router("/api/explain-api-policy", &controllers.ApiController{}, "GET:ExplainApiPolicy")
 
 	beego.Router("/api/get-enforcers", &controllers.ApiController{}, "GET:GetEnforcers")
 	beego.Router("/api/get-enforcer", &controllers.ApiController{}, "GET:GetEnforcer")
@@ -196,9 +203,22 @@ func initAPI() {
 	beego.Router("/api/login/oauth/access_token", &controllers.ApiController{}, "POST:GetOAuthToken")
 	beego.Router("/api/login/oauth/refresh_token", &controllers.ApiController{}, "POST:RefreshToken")
 	beego.Router("/api/login/oauth/introspect", &controllers.ApiController{}, "POST:IntrospectToken")
+	beego.Router("/api/login/oauth/revoke", &controllers.ApiController{}, "POST:RevokeToken")
+	beego.Router("/api/login/oauth/par", &controllers.ApiController{}, "POST:PushAuthorizationRequest")
+	beego.Router("/api/login/oauth/device_authorization", &controllers.ApiController{}, "POST:DeviceAuthorization")
+	beego.Router("/api/login/oauth/register", &controllers.ApiController{}, "POST:RegisterClient;GET:GetRegisteredClient;PUT:UpdateRegisteredClient;DELETE:DeleteRegisteredClient")
+	beego.Router("/api/get-device-auth", &controllers.ApiController{}, "GET:GetDeviceAuth")
+	beego.Router("/api/approve-device-auth", &controllers.ApiController{}, "POST:ApproveDeviceAuth")
+	beego.Router("/api/grant-consent", &controllers.ApiController{}, "POST:GrantConsent")
+	beego.Router("/api/get-consents", &controllers.ApiController{}, "GET:GetConsents")
+	beego.Router("/api/revoke-consent", &controllers.ApiController{}, "POST:RevokeConsent")
 	beego.Router("/api/get-records", &controllers.ApiController{}, "GET:GetRecords")
 	beego.Router("/api/get-records-filter", &controllers.ApiController{}, "POST:GetRecordsByFilter")
 	beego.Router("/api/add-record", &controllers.ApiController{}, "POST:AddRecord")
+	beego.Router("/api/get-audit-entries", &controllers.ApiController{}, "GET:GetAuditEntries")
+	beego.Router("/api/verify-audit-chain", &controllers.ApiController{}, "GET:VerifyAuditChain")
+	beego.Router("/api/purge-audit-entries", &controllers.ApiController{}, "POST:PurgeAuditEntries")
+	beego.Router("/api/export-audit-entries", &controllers.ApiController{}, "GET:ExportAuditEntries")
 
 	beego.Router("/api/get-sessions", &controllers.ApiController{}, "GET:GetSessions")
 	beego.Router("/api/get-session", &controllers.ApiController{}, "GET:GetSingleSession")
@@ -212,6 +232,23 @@ func initAPI() {
 	beego.Router("/api/update-webhook", &controllers.ApiController{}, "POST:UpdateWebhook")
 	beego.Router("/api/add-webhook", &controllers.ApiController{}, "POST:AddWebhook")
 	beego.Router("/api/delete-webhook", &controllers.ApiController{}, "POST:DeleteWebhook")
+	beego.Router("/api/get-webhook-deliveries", &controllers.ApiController{}, "GET:GetWebhookDeliveries")
+	beego.Router("/api/get-webhook-attempts", &controllers.ApiController{}, "GET:GetWebhookAttempts")
+	beego.Router("/api/replay-webhook-delivery", &controllers.ApiController{}, "POST:ReplayWebhookDelivery")
+	beego.Router("/api/replay-webhook-deliveries", &controllers.ApiController{}, "POST:ReplayWebhookDeliveries")
+
+	beego.Router("/api/get-initial-access-tokens", &controllers.ApiController{}, "GET:GetInitialAccessTokens")
+	beego.Router("/api/get-initial-access-token", &controllers.ApiController{}, "GET:GetInitialAccessToken")
+	beego.Router("/api/update-initial-access-token", &controllers.ApiController{}, "POST:UpdateInitialAccessToken")
+	beego.Router("/api/add-initial-access-token", &controllers.ApiController{}, "POST:AddInitialAccessToken")
+	beego.Router("/api/delete-initial-access-token", &controllers.ApiController{}, "POST:DeleteInitialAccessToken")
+
+	beego.Router("/api/get-radius-clients", &controllers.ApiController{}, "GET:GetRadiusClients")
+	beego.Router("/api/get-radius-client", &controllers.ApiController{}, "GET:GetRadiusClient")
+	beego.Router("/api/update-radius-client", &controllers.ApiController{}, "POST:UpdateRadiusClient")
+	beego.Router("/api/add-radius-client", &controllers.ApiController{}, "POST:AddRadiusClient")
+	beego.Router("/api/delete-radius-client", &controllers.ApiController{}, "POST:DeleteRadiusClient")
+	beego.Router("/api/test-radius-reply-rules", &controllers.ApiController{}, "POST:TestRadiusReplyRules")
 
 	beego.Router("/api/get-syncers", &controllers.ApiController{}, "GET:GetSyncers")
 	beego.Router("/api/get-syncer", &controllers.ApiController{}, "GET:GetSyncer")
@@ -219,6 +256,17 @@ func initAPI() {
 	beego.Router("/api/add-syncer", &controllers.ApiController{}, "POST:AddSyncer")
 	beego.Router("/api/delete-syncer", &controllers.ApiController{}, "POST:DeleteSyncer")
 	beego.Router("/api/run-syncer", &controllers.ApiController{}, "GET:RunSyncer")
+	beego.Router("/api/dry-run-syncer", &controllers.ApiController{}, "GET:DryRunSyncer")
+
+	beego.Router("/api/get-provisioners", &controllers.ApiController{}, "GET:GetProvisioners")
+	beego.Router("/api/get-provisioner", &controllers.ApiController{}, "GET:GetProvisioner")
+	beego.Router("/api/update-provisioner", &controllers.ApiController{}, "POST:UpdateProvisioner")
+	beego.Router("/api/add-provisioner", &controllers.ApiController{}, "POST:AddProvisioner")
+	beego.Router("/api/delete-provisioner", &controllers.ApiController{}, "POST:DeleteProvisioner")
+	beego.Router("/api/get-provisioner-status", &controllers.ApiController{}, "GET:GetProvisionerStatus")
+	beego.Router("/api/get-provisioning-tasks", &controllers.ApiController{}, "GET:GetProvisioningTasks")
+	beego.Router("/api/retry-provisioning-tasks", &controllers.ApiController{}, "POST:RetryProvisioningTasks")
+	beego.Router("/api/sync-provisioner", &controllers.ApiController{}, "POST:SyncProvisioner")
 
 	beego.Router("/api/get-certs", &controllers.ApiController{}, "GET:GetCerts")
 	beego.Router("/api/get-globle-certs", &controllers.ApiController{}, "GET:GetGlobleCerts")
@@ -278,12 +326,14 @@ func initAPI() {
 
 	beego.Router("/api/get-system-info", &controllers.ApiController{}, "GET:GetSystemInfo")
 	beego.Router("/api/get-version-info", &controllers.ApiController{}, "GET:GetVersionInfo")
+	beego.Router("/api/get-replication-status", &controllers.ApiController{}, "GET:GetReplicationStatus")
 	beego.Router("/api/health", &controllers.ApiController{}, "GET:Health")
 	beego.Router("/api/get-prometheus-info", &controllers.ApiController{}, "GET:GetPrometheusInfo")
 
 	beego.Handler("/api/metrics", promhttp.Handler())
 
 	beego.Router("/.well-known/openid-configuration", &controllers.RootController{}, "GET:GetOidcDiscovery")
+	beego.Router("/.well-known/:application/openid-configuration", &controllers.RootController{}, "GET:GetOidcDiscoveryByApplication")
 	beego.Router("/.well-known/jwks", &controllers.RootController{}, "*:GetJwks")
 
 	beego.Router("/cas/:organization/:application/serviceValidate", &controllers.RootController{}, "GET:CasServiceValidate")
@@ -294,4 +344,14 @@ func initAPI() {
 	beego.Router("/cas/:organization/:application/p3/serviceValidate", &controllers.RootController{}, "GET:CasP3ServiceValidate")
 	beego.Router("/cas/:organization/:application/p3/proxyValidate", &controllers.RootController{}, "GET:CasP3ProxyValidate")
 	beego.Router("/cas/:organization/:application/samlValidate", &controllers.RootController{}, "POST:SamlValidate")
+
+	beego.Router("/scim/v2/:organization/Users", &controllers.RootController{}, "GET:GetScimUsers;POST:AddScimUser")
+	beego.Router("/scim/v2/:organization/Users/:id", &controllers.RootController{}, "GET:GetScimUser;PUT:UpdateScimUser;PATCH:PatchScimUser;DELETE:DeleteScimUser")
+	beego.Router("/scim/v2/:organization/Groups", &controllers.RootController{}, "GET:GetScimGroups;POST:AddScimGroup")
+	beego.Router("/scim/v2/:organization/Groups/:id", &controllers.RootController{}, "GET:GetScimGroup;PUT:UpdateScimGroup;PATCH:PatchScimGroup;DELETE:DeleteScimGroup")
+	beego.Router("/scim/v2/:organization/ServiceProviderConfig", &controllers.RootController{}, "GET:GetScimServiceProviderConfig")
+	beego.Router("/scim/v2/:organization/ResourceTypes", &controllers.RootController{}, "GET:GetScimResourceTypes")
+	beego.Router("/scim/v2/:organization/ResourceTypes/:id", &controllers.RootController{}, "GET:GetScimResourceTypes")
+	beego.Router("/scim/v2/:organization/Schemas", &controllers.RootController{}, "GET:GetScimSchemas")
+	beego.Router("/scim/v2/:organization/Schemas/:id", &controllers.RootController{}, "GET:GetScimSchemas")
 }
@@ -61,13 +61,30 @@ func fastAutoSignin(ctx *context.Context) (string, error) {
 	}
 
 	clientId := ctx.Input.Query("client_id")
-	responseType := ctx.Input.Query("response_type")
-	redirectUri := ctx.Input.Query("redirect_uri")
-	scope := ctx.Input.Query("scope")
-	state := ctx.Input.Query("state")
-	nonce := ""
-	codeChallenge := ""
-	if clientId == "" || responseType != "code" || redirectUri == "" {
+	if clientId == "" {
+		return "", nil
+	}
+
+	requestUri := ctx.Input.Query("request_uri")
+	authorizeRequest, msg, err := object.ResolveAuthorizeRequest(object.NewAuthorizeRequest(clientId, ctx.Input.Query), ctx.Input.Query("request"), requestUri, ctx.Request.Host, getAcceptLanguage(ctx))
+	if err != nil || msg != "" {
+		// let the authorize page show the error
+		return "", err
+	}
+
+	responseType := authorizeRequest.ResponseType
+	redirectUri := authorizeRequest.RedirectUri
+	scope := authorizeRequest.Scope
+	state := authorizeRequest.State
+	nonce := authorizeRequest.Nonce
+	codeChallenge := authorizeRequest.CodeChallenge
+	if responseType != "code" || redirectUri == "" {
+		return "", nil
+	}
+
+	// let the authorize page show the error
+	challengeMethod := authorizeRequest.CodeChallengeMethod
+	if challengeMethod != "S256" && challengeMethod != "null" && challengeMethod != "" {
 		return "", nil
 	}
 
@@ -83,6 +100,23 @@ func fastAutoSignin(ctx *context.Context) (string, error) {
 		return "", nil
 	}
 
+	user, err := object.GetUser(userId)
+	if err != nil {
+		return "", err
+	}
+	if user == nil {
+		return "", nil
+	}
+
+	// let the authorize page ask the user for consent
+	consentRequest, err := object.GetConsentRequest(application, user, scope, authorizeRequest.Prompt)
+	if err != nil {
+		return "", err
+	}
+	if consentRequest != nil {
+		return "", nil
+	}
+
 	code, err := object.GetOAuthCode(userId, clientId, responseType, redirectUri, scope, state, nonce, codeChallenge, ctx.Request.Host, sid, getAcceptLanguage(ctx))
 	if err != nil {
 		return "", err
@@ -90,6 +124,11 @@ func fastAutoSignin(ctx *context.Context) (string, error) {
 		return "", fmt.Errorf(code.Message)
 	}
 
+	err = object.DeletePushedAuthRequest(requestUri)
+	if err != nil {
+		return "", err
+	}
+
 	sep := "?"
 	if strings.Contains(redirectUri, "?") {
 		sep = "&"
@@ -105,7 +144,7 @@ func StaticFilter(ctx *context.Context) {
 		http.ServeContent(ctx.ResponseWriter, ctx.Request, "acme-challenge", time.Now(), strings.NewReader("content"))
 	}
 
-	if strings.HasPrefix(urlPath, "/api/") || strings.HasPrefix(urlPath, "/.well-known/") {
+	if strings.HasPrefix(urlPath, "/api/") || strings.HasPrefix(urlPath, "/.well-known/") || strings.HasPrefix(urlPath, "/scim/") {
 		return
 	}
 	if strings.HasPrefix(urlPath, "/cas") && (strings.HasSuffix(urlPath, "/serviceValidate") || strings.HasSuffix(urlPath, "/proxy") || strings.HasSuffix(urlPath, "/proxyValidate") || strings.HasSuffix(urlPath, "/validate") || strings.HasSuffix(urlPath, "/p3/serviceValidate") || strings.HasSuffix(urlPath, "/p3/proxyValidate") || strings.HasSuffix(urlPath, "/samlValidate")) {
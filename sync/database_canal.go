@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/Masterminds/squirrel"
 	"github.com/go-mysql-org/go-mysql/canal"
 	"github.com/go-mysql-org/go-mysql/mysql"
 	"github.com/go-mysql-org/go-mysql/replication"
@@ -85,7 +86,7 @@ func (db *Database) OnRow(e *canal.RowsEvent) error {
 			}
 			if i%2 == 1 {
 				pkColumnValue := getPkColumnValues(oldColumnValue, e.Table.PKColumns)
-				updateSql, args, err := getUpdateSql(e.Table.Schema, e.Table.Name, columnNames, newColumnValue, pkColumnNames, pkColumnValue)
+				updateSql, args, err := getUpdateSql(e.Table.Schema, e.Table.Name, columnNames, newColumnValue, pkColumnNames, pkColumnValue, squirrel.Question)
 				if err != nil {
 					return err
 				}
@@ -111,7 +112,7 @@ func (db *Database) OnRow(e *canal.RowsEvent) error {
 			}
 
 			pkColumnValue := getPkColumnValues(oldColumnValue, e.Table.PKColumns)
-			deleteSql, args, err := getDeleteSql(e.Table.Schema, e.Table.Name, pkColumnNames, pkColumnValue)
+			deleteSql, args, err := getDeleteSql(e.Table.Schema, e.Table.Name, pkColumnNames, pkColumnValue, squirrel.Question)
 			if err != nil {
 				return err
 			}
@@ -139,7 +140,7 @@ func (db *Database) OnRow(e *canal.RowsEvent) error {
 				}
 			}
 
-			insertSql, args, err := getInsertSql(e.Table.Schema, e.Table.Name, columnNames, newColumnValue)
+			insertSql, args, err := getInsertSql(e.Table.Schema, e.Table.Name, columnNames, newColumnValue, squirrel.Question)
 			if err != nil {
 				return err
 			}
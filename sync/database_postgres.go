@@ -0,0 +1,503 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/beego/beego/logs"
+	_ "github.com/lib/pq"
+)
+
+const (
+	postgresReplicationBatchSize     = 1000
+	postgresReplicationPollInterval  = time.Second
+	postgresReplicationRetryInterval = 10 * time.Second
+)
+
+// postgresTransaction is a transaction of the source database decoded from the replication slot
+type postgresTransaction struct {
+	endLsn     uint64
+	commitTime time.Time
+	origin     string
+	changes    []*pgMessage
+}
+
+// postgresReplication replicates the changes of the tables of a publication of the source database
+// to the target database with the PostgreSQL logical replication. The changes are read from a
+// logical replication slot of the pgoutput plugin, and the slot is only advanced once they have
+// been applied. The transactions are applied with a replication origin, so the changes applied
+// by the replication of the other region are recognized by their origin and not sent back.
+//
+// The user of the source database needs the REPLICATION attribute, and the one of the target
+// database the permission to use the replication origins.
+type postgresReplication struct {
+	sourceDataSourceName string
+	targetDataSourceName string
+	slot                 string
+	tables               []string
+	conflictPolicy       string
+
+	source    *sql.DB
+	target    *sql.DB
+	conn      *sql.Conn
+	relations map[uint32]*pgRelation
+}
+
+func newPostgresReplication(sourceDataSourceName string, targetDataSourceName string, slot string, tables []string, conflictPolicy string) *postgresReplication {
+	return &postgresReplication{
+		sourceDataSourceName: sourceDataSourceName,
+		targetDataSourceName: targetDataSourceName,
+		slot:                 slot,
+		tables:               tables,
+		conflictPolicy:       conflictPolicy,
+		relations:            map[uint32]*pgRelation{},
+	}
+}
+
+func quotePgIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (r *postgresReplication) getPublication() string {
+	return r.slot
+}
+
+// isTableAllowed returns whether the table is in the allow-list, where a table without schema
+// matches the tables of all the schemas. All the tables are allowed if the list is empty.
+func (r *postgresReplication) isTableAllowed(relation *pgRelation) bool {
+	if len(r.tables) == 0 {
+		return true
+	}
+
+	for _, table := range r.tables {
+		if table == relation.Name || table == relation.Namespace+"."+relation.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *postgresReplication) connect() error {
+	var err error
+	r.source, err = sql.Open("postgres", r.sourceDataSourceName)
+	if err != nil {
+		return err
+	}
+
+	r.target, err = sql.Open("postgres", r.targetDataSourceName)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	err = r.source.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_publication WHERE pubname = $1)", r.getPublication()).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		tables := "ALL TABLES"
+		if len(r.tables) != 0 {
+			names := []string{}
+			for _, table := range r.tables {
+				parts := strings.SplitN(table, ".", 2)
+				for i := range parts {
+					parts[i] = quotePgIdentifier(parts[i])
+				}
+				names = append(names, strings.Join(parts, "."))
+			}
+			tables = "TABLE " + strings.Join(names, ", ")
+		}
+
+		_, err = r.source.Exec(fmt.Sprintf("CREATE PUBLICATION %s FOR %s", quotePgIdentifier(r.getPublication()), tables))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = r.source.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_replication_slots WHERE slot_name = $1)", r.slot).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		_, err = r.source.Exec("SELECT pg_create_logical_replication_slot($1, 'pgoutput')", r.slot)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the replication origin is set on the session, so all the changes are applied with the same connection
+	r.conn, err = r.target.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+
+	_, err = r.conn.ExecContext(context.Background(), "SELECT pg_replication_origin_create($1) WHERE pg_replication_origin_oid($1) IS NULL", r.slot)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.conn.ExecContext(context.Background(), "SELECT pg_replication_origin_session_setup($1)", r.slot)
+	return err
+}
+
+func (r *postgresReplication) close() {
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+	}
+	if r.source != nil {
+		r.source.Close()
+		r.source = nil
+	}
+	if r.target != nil {
+		r.target.Close()
+		r.target = nil
+	}
+}
+
+// getTransactions returns the next committed transactions of the slot, without consuming them
+func (r *postgresReplication) getTransactions() ([]*postgresTransaction, error) {
+	rows, err := r.source.Query("SELECT data FROM pg_logical_slot_peek_binary_changes($1, NULL, $2, 'proto_version', '1', 'publication_names', $3)",
+		r.slot, postgresReplicationBatchSize, r.getPublication())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := []*postgresTransaction{}
+	var transaction *postgresTransaction
+	for rows.Next() {
+		var data []byte
+		err = rows.Scan(&data)
+		if err != nil {
+			return nil, err
+		}
+
+		message, err := parsePgMessage(data)
+		if err != nil {
+			return nil, err
+		}
+
+		switch message.Type {
+		case pgMessageBegin:
+			transaction = &postgresTransaction{commitTime: message.CommitTime}
+		case pgMessageCommit:
+			if transaction != nil {
+				transaction.endLsn = message.Lsn
+				transactions = append(transactions, transaction)
+				transaction = nil
+			}
+		case pgMessageOrigin:
+			if transaction != nil {
+				transaction.origin = message.Origin
+			}
+		default:
+			if transaction != nil && message.IsSupported {
+				transaction.changes = append(transaction.changes, message)
+			}
+		}
+	}
+
+	return transactions, rows.Err()
+}
+
+func (r *postgresReplication) advance(lsn uint64) error {
+	_, err := r.source.Exec("SELECT pg_replication_slot_advance($1, $2::pg_lsn)", r.slot, formatPgLsn(lsn))
+	return err
+}
+
+func getPgTupleValues(relation *pgRelation, tuple []pgValue, isKey bool) ([]string, []interface{}) {
+	names := []string{}
+	values := []interface{}{}
+	for i, column := range relation.Columns {
+		if i >= len(tuple) || tuple[i].Kind == pgValueUnchanged || (isKey && !column.IsKey) {
+			continue
+		}
+
+		names = append(names, quotePgIdentifier(column.Name))
+		if tuple[i].Kind == pgValueNull {
+			values = append(values, nil)
+		} else {
+			values = append(values, tuple[i].Data)
+		}
+	}
+	return names, values
+}
+
+// getRow returns the text values of the columns of the row with the key, or nil if there is none
+func (r *postgresReplication) getRow(tx *sql.Tx, relation *pgRelation, keyNames []string, keyValues []interface{}) ([]sql.NullString, error) {
+	columns := []string{}
+	for _, column := range relation.Columns {
+		columns = append(columns, quotePgIdentifier(column.Name)+"::text")
+	}
+
+	query := squirrel.Select(columns...).From(quotePgIdentifier(relation.Namespace) + "." + quotePgIdentifier(relation.Name)).PlaceholderFormat(squirrel.Dollar)
+	for i, name := range keyNames {
+		query = query.Where(squirrel.Eq{name: keyValues[i]})
+	}
+
+	sqlString, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	row := make([]sql.NullString, len(relation.Columns))
+	dest := make([]interface{}, len(row))
+	for i := range row {
+		dest[i] = &row[i]
+	}
+
+	err = tx.QueryRow(sqlString, args...).Scan(dest...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return row, err
+}
+
+// isPgRowChanged returns whether the row differs from the old tuple of the change, which happens
+// when the row has been changed in both databases
+func isPgRowChanged(row []sql.NullString, tuple []pgValue) bool {
+	for i, value := range tuple {
+		if i >= len(row) || value.Kind == pgValueUnchanged {
+			continue
+		}
+
+		if (value.Kind == pgValueNull) != !row[i].Valid || (value.Kind == pgValueText && value.Data != row[i].String) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyChange applies the row event to the target database. A conflict on the primary key, like
+// an inserted row which already exists or a changed row which has been changed or deleted in the
+// target database, is resolved with the conflict policy.
+func (r *postgresReplication) applyChange(tx *sql.Tx, relation *pgRelation, change *pgMessage) error {
+	schema := quotePgIdentifier(relation.Namespace)
+	table := quotePgIdentifier(relation.Name)
+	conflict := &ReplicationConflict{
+		Time:   time.Now().Format(time.RFC3339),
+		Table:  relation.Namespace + "." + relation.Name,
+		Action: map[byte]string{pgMessageInsert: "insert", pgMessageUpdate: "update", pgMessageDelete: "delete"}[change.Type],
+	}
+
+	keyTuple := change.OldTuple
+	if keyTuple == nil {
+		keyTuple = change.NewTuple
+	}
+	keyNames, keyValues := getPgTupleValues(relation, keyTuple, true)
+	if len(keyNames) == 0 {
+		return fmt.Errorf("the table: %s has no primary key", conflict.Table)
+	}
+	for _, value := range keyValues {
+		conflict.Key = append(conflict.Key, fmt.Sprintf("%v", value))
+	}
+
+	row, err := r.getRow(tx, relation, keyNames, keyValues)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case change.Type == pgMessageInsert && row != nil:
+		conflict.Reason = "the row already exists"
+	case change.Type != pgMessageInsert && row == nil:
+		conflict.Reason = "the row does not exist"
+	case change.Type != pgMessageInsert && !change.IsOldKey && change.OldTuple != nil && isPgRowChanged(row, change.OldTuple):
+		conflict.Reason = "the row has been changed"
+	}
+
+	if conflict.Reason != "" {
+		if r.conflictPolicy == ReplicationConflictPolicySkip || (change.Type == pgMessageDelete && row == nil) {
+			conflict.Resolution = "skipped"
+			addReplicationConflict(conflict)
+			return nil
+		}
+
+		conflict.Resolution = "overwritten"
+		addReplicationConflict(conflict)
+	}
+
+	var sqlString string
+	var args []interface{}
+	names, values := getPgTupleValues(relation, change.NewTuple, false)
+	switch {
+	case change.Type == pgMessageDelete:
+		sqlString, args, err = getDeleteSql(schema, table, keyNames, keyValues, squirrel.Dollar)
+	case row == nil:
+		if len(names) != len(relation.Columns) {
+			return fmt.Errorf("the row of the table: %s with the key: %v cannot be inserted without its unchanged values", conflict.Table, conflict.Key)
+		}
+		sqlString, args, err = getInsertSql(schema, table, names, values, squirrel.Dollar)
+	default:
+		sqlString, args, err = getUpdateSql(schema, table, names, values, keyNames, keyValues, squirrel.Dollar)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(sqlString, args...)
+	if err != nil {
+		return err
+	}
+
+	replicationEvents.WithLabelValues(conflict.Table, conflict.Action).Inc()
+	return nil
+}
+
+func (r *postgresReplication) applyTransaction(transaction *postgresTransaction) error {
+	tx, err := r.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// the origin LSN records the progress of the replication in the target database
+	_, err = tx.Exec("SELECT pg_replication_origin_xact_setup($1::pg_lsn, $2)", formatPgLsn(transaction.endLsn), transaction.commitTime)
+	if err != nil {
+		return err
+	}
+
+	events := 0
+	skippedEvents := 0
+	for _, change := range transaction.changes {
+		if change.Type == pgMessageRelation {
+			r.relations[change.Relation.Id] = change.Relation
+			continue
+		}
+
+		relation, ok := r.relations[change.RelationId]
+		if !ok {
+			return fmt.Errorf("the relation: %d of the change is unknown", change.RelationId)
+		}
+
+		if change.Type != pgMessageInsert && change.Type != pgMessageUpdate && change.Type != pgMessageDelete {
+			continue
+		}
+
+		if !r.isTableAllowed(relation) {
+			skippedEvents++
+			continue
+		}
+
+		err = r.applyChange(tx, relation, change)
+		if err != nil {
+			return err
+		}
+		events++
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	updateReplicationStatus(func(status *ReplicationStatus) {
+		status.Transactions++
+		status.Events += int64(events)
+		status.SkippedEvents += int64(skippedEvents)
+	})
+	return nil
+}
+
+// replicate applies the next transactions of the slot and returns how many there were
+func (r *postgresReplication) replicate() (int, error) {
+	transactions, err := r.getTransactions()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, transaction := range transactions {
+		if transaction.origin == "" {
+			err = r.applyTransaction(transaction)
+		} else {
+			// the transaction has been applied by a replication, so it comes from the target database
+			for _, change := range transaction.changes {
+				if change.Type == pgMessageRelation {
+					r.relations[change.Relation.Id] = change.Relation
+				}
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		err = r.advance(transaction.endLsn)
+		if err != nil {
+			return 0, err
+		}
+
+		updateReplicationStatus(func(status *ReplicationStatus) {
+			status.LastLsn = formatPgLsn(transaction.endLsn)
+			status.LastCommitTime = transaction.commitTime.Format(time.RFC3339)
+		})
+	}
+
+	lagSeconds := 0.0
+	if len(transactions) != 0 {
+		lagSeconds = time.Since(transactions[len(transactions)-1].commitTime).Seconds()
+	}
+
+	var lagBytes int64
+	err = r.source.QueryRow("SELECT COALESCE(pg_wal_lsn_diff(pg_current_wal_lsn(), confirmed_flush_lsn), 0)::bigint FROM pg_replication_slots WHERE slot_name = $1", r.slot).Scan(&lagBytes)
+	if err != nil {
+		return 0, err
+	}
+
+	replicationLagBytes.Set(float64(lagBytes))
+	replicationLagSeconds.Set(lagSeconds)
+	updateReplicationStatus(func(status *ReplicationStatus) {
+		status.LagBytes = lagBytes
+		status.LagSeconds = lagSeconds
+	})
+
+	return len(transactions), nil
+}
+
+func (r *postgresReplication) run() {
+	for {
+		err := r.connect()
+		for err == nil {
+			updateReplicationStatus(func(status *ReplicationStatus) {
+				status.IsRunning = true
+			})
+
+			var n int
+			n, err = r.replicate()
+			if err == nil && n == 0 {
+				time.Sleep(postgresReplicationPollInterval)
+			}
+		}
+
+		setReplicationError(err)
+		updateReplicationStatus(func(status *ReplicationStatus) {
+			status.IsRunning = false
+		})
+
+		r.close()
+		// the relations are sent again by the next decoding of the slot
+		r.relations = map[uint32]*pgRelation{}
+		logs.Info("the replication restarts in %s", postgresReplicationRetryInterval)
+		time.Sleep(postgresReplicationRetryInterval)
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// The messages of the pgoutput plugin used by the PostgreSQL logical replication, see
+// https://www.postgresql.org/docs/current/protocol-logicalrep-message-formats.html
+const (
+	pgMessageBegin    = 'B'
+	pgMessageCommit   = 'C'
+	pgMessageOrigin   = 'O'
+	pgMessageRelation = 'R'
+	pgMessageInsert   = 'I'
+	pgMessageUpdate   = 'U'
+	pgMessageDelete   = 'D'
+
+	pgValueNull      = 'n'
+	pgValueUnchanged = 'u'
+	pgValueText      = 't'
+)
+
+var pgEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type pgColumn struct {
+	Name  string
+	IsKey bool
+}
+
+type pgRelation struct {
+	Id        uint32
+	Namespace string
+	Name      string
+	Columns   []*pgColumn
+}
+
+// pgValue is the value of a column in text format. The unchanged TOASTed values are not sent.
+type pgValue struct {
+	Kind byte
+	Data string
+}
+
+type pgMessage struct {
+	Type byte
+
+	// Begin and Commit
+	Lsn        uint64
+	CommitTime time.Time
+
+	// Origin
+	Origin string
+
+	// Relation
+	Relation *pgRelation
+
+	// Insert, Update and Delete. The old tuple of an update is only sent when the key changed,
+	// or always with all the columns if the replica identity of the table is full.
+	RelationId  uint32
+	OldTuple    []pgValue
+	IsOldKey    bool
+	NewTuple    []pgValue
+	IsSupported bool
+}
+
+type pgReader struct {
+	data []byte
+	err  error
+}
+
+func (r *pgReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.data) < n {
+		r.err = fmt.Errorf("the pgoutput message is truncated")
+		return nil
+	}
+
+	res := r.data[:n]
+	r.data = r.data[n:]
+	return res
+}
+
+func (r *pgReader) readByte() byte {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *pgReader) readUint16() uint16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (r *pgReader) readUint32() uint32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *pgReader) readUint64() uint64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+func (r *pgReader) readTime() time.Time {
+	return pgEpoch.Add(time.Duration(int64(r.readUint64())) * time.Microsecond)
+}
+
+func (r *pgReader) readString() string {
+	if r.err != nil {
+		return ""
+	}
+
+	i := bytes.IndexByte(r.data, 0)
+	if i < 0 {
+		r.err = fmt.Errorf("the pgoutput message has an unterminated string")
+		return ""
+	}
+
+	res := string(r.data[:i])
+	r.data = r.data[i+1:]
+	return res
+}
+
+func (r *pgReader) readTuple() []pgValue {
+	n := int(r.readUint16())
+	values := make([]pgValue, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		value := pgValue{Kind: r.readByte()}
+		switch value.Kind {
+		case pgValueNull, pgValueUnchanged:
+		case pgValueText:
+			value.Data = string(r.next(int(r.readUint32())))
+		default:
+			r.err = fmt.Errorf("the pgoutput value kind: %q is not supported", value.Kind)
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// parsePgMessage parses a message of the version 1 of the pgoutput protocol. The messages of the
+// other types are returned as not supported.
+func parsePgMessage(data []byte) (*pgMessage, error) {
+	r := &pgReader{data: data}
+	message := &pgMessage{Type: r.readByte(), IsSupported: true}
+	switch message.Type {
+	case pgMessageBegin:
+		message.Lsn = r.readUint64()
+		message.CommitTime = r.readTime()
+		r.readUint32()
+	case pgMessageCommit:
+		r.readByte()
+		r.readUint64()
+		// the end LSN of the transaction, from which the next one is read
+		message.Lsn = r.readUint64()
+		message.CommitTime = r.readTime()
+	case pgMessageOrigin:
+		message.Lsn = r.readUint64()
+		message.Origin = r.readString()
+	case pgMessageRelation:
+		relation := &pgRelation{Id: r.readUint32(), Namespace: r.readString(), Name: r.readString()}
+		r.readByte()
+		n := int(r.readUint16())
+		for i := 0; i < n && r.err == nil; i++ {
+			flags := r.readByte()
+			relation.Columns = append(relation.Columns, &pgColumn{Name: r.readString(), IsKey: flags&1 != 0})
+			r.readUint32()
+			r.readUint32()
+		}
+		message.Relation = relation
+	case pgMessageInsert:
+		message.RelationId = r.readUint32()
+		if r.readByte() != 'N' && r.err == nil {
+			r.err = fmt.Errorf("the pgoutput insert message has no new tuple")
+		}
+		message.NewTuple = r.readTuple()
+	case pgMessageUpdate, pgMessageDelete:
+		message.RelationId = r.readUint32()
+		kind := r.readByte()
+		if kind == 'K' || kind == 'O' {
+			message.IsOldKey = kind == 'K'
+			message.OldTuple = r.readTuple()
+			if message.Type == pgMessageUpdate {
+				kind = r.readByte()
+			}
+		}
+		if message.Type == pgMessageUpdate {
+			if kind != 'N' && r.err == nil {
+				r.err = fmt.Errorf("the pgoutput update message has no new tuple")
+			}
+			message.NewTuple = r.readTuple()
+		}
+	default:
+		message.IsSupported = false
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	return message, nil
+}
+
+func formatPgLsn(lsn uint64) string {
+	return fmt.Sprintf("%X/%X", uint32(lsn>>32), uint32(lsn))
+}
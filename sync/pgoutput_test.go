@@ -0,0 +1,128 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+type pgWriter []byte
+
+func (w pgWriter) byte(b byte) pgWriter {
+	return append(w, b)
+}
+
+func (w pgWriter) uint16(n uint16) pgWriter {
+	return binary.BigEndian.AppendUint16(w, n)
+}
+
+func (w pgWriter) uint32(n uint32) pgWriter {
+	return binary.BigEndian.AppendUint32(w, n)
+}
+
+func (w pgWriter) uint64(n uint64) pgWriter {
+	return binary.BigEndian.AppendUint64(w, n)
+}
+
+func (w pgWriter) string(s string) pgWriter {
+	return append(append(w, s...), 0)
+}
+
+func (w pgWriter) text(s string) pgWriter {
+	return append(w.byte(pgValueText).uint32(uint32(len(s))), s...)
+}
+
+func TestParsePgMessage(t *testing.T) {
+	commitTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	microseconds := uint64(commitTime.Sub(pgEpoch) / time.Microsecond)
+
+	message, err := parsePgMessage(pgWriter{}.byte(pgMessageBegin).uint64(0x1A0).uint64(microseconds).uint32(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Lsn != 0x1A0 || !message.CommitTime.Equal(commitTime) {
+		t.Errorf("unexpected begin message: %+v", message)
+	}
+
+	message, err = parsePgMessage(pgWriter{}.byte(pgMessageCommit).byte(0).uint64(0x1A0).uint64(0x1B8).uint64(microseconds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Lsn != 0x1B8 || !message.CommitTime.Equal(commitTime) {
+		t.Errorf("unexpected commit message: %+v", message)
+	}
+
+	message, err = parsePgMessage(pgWriter{}.byte(pgMessageRelation).uint32(16384).string("public").string("user").byte('d').uint16(2).
+		byte(1).string("owner").uint32(25).uint32(0xFFFFFFFF).
+		byte(0).string("display_name").uint32(25).uint32(0xFFFFFFFF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	relation := message.Relation
+	if relation.Id != 16384 || relation.Namespace != "public" || relation.Name != "user" || len(relation.Columns) != 2 ||
+		relation.Columns[0].Name != "owner" || !relation.Columns[0].IsKey || relation.Columns[1].IsKey {
+		t.Errorf("unexpected relation message: %+v", relation)
+	}
+
+	message, err = parsePgMessage(pgWriter{}.byte(pgMessageInsert).uint32(16384).byte('N').uint16(2).text("built-in").byte(pgValueNull))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.RelationId != 16384 || len(message.NewTuple) != 2 || message.NewTuple[0].Data != "built-in" || message.NewTuple[1].Kind != pgValueNull {
+		t.Errorf("unexpected insert message: %+v", message)
+	}
+
+	message, err = parsePgMessage(pgWriter{}.byte(pgMessageUpdate).uint32(16384).
+		byte('O').uint16(2).text("built-in").text("Admin").
+		byte('N').uint16(2).text("built-in").byte(pgValueUnchanged))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.IsOldKey || message.OldTuple[1].Data != "Admin" || message.NewTuple[1].Kind != pgValueUnchanged {
+		t.Errorf("unexpected update message: %+v", message)
+	}
+
+	message, err = parsePgMessage(pgWriter{}.byte(pgMessageDelete).uint32(16384).byte('K').uint16(2).text("built-in").byte(pgValueNull))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !message.IsOldKey || len(message.OldTuple) != 2 || message.NewTuple != nil {
+		t.Errorf("unexpected delete message: %+v", message)
+	}
+
+	message, err = parsePgMessage(pgWriter{}.byte('Y').uint32(16384))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.IsSupported {
+		t.Errorf("the type message should not be supported")
+	}
+
+	_, err = parsePgMessage(pgWriter{}.byte(pgMessageInsert).uint32(16384).byte('N').uint16(1).byte(pgValueText).uint32(10))
+	if err == nil {
+		t.Errorf("the truncated message should fail")
+	}
+}
+
+func TestFormatPgLsn(t *testing.T) {
+	if lsn := formatPgLsn(0x16B3748); lsn != "0/16B3748" {
+		t.Errorf("got %s", lsn)
+	}
+	if lsn := formatPgLsn(0x1_0000_00A0); lsn != "1/A0" {
+		t.Errorf("got %s", lsn)
+	}
+}
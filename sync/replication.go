@@ -0,0 +1,192 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/logs"
+	"github.com/casdoor/casdoor/conf"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	ReplicationModePostgres = "postgres"
+
+	ReplicationConflictPolicyOverwrite = "overwrite"
+	ReplicationConflictPolicySkip      = "skip"
+
+	replicationMaxRecentConflicts = 100
+)
+
+var (
+	replicationEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "casdoor_replication_events_total",
+		Help: "The row events applied by the database replication",
+	}, []string{"table", "action"})
+
+	replicationConflicts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "casdoor_replication_conflicts_total",
+		Help: "The primary key conflicts found by the database replication",
+	}, []string{"table", "action"})
+
+	replicationLagBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "casdoor_replication_lag_bytes",
+		Help: "The WAL of the source database not replicated yet, in bytes",
+	})
+
+	replicationLagSeconds = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "casdoor_replication_lag_seconds",
+		Help: "The time since the commit of the last replicated transaction, 0 once the replication has caught up",
+	})
+)
+
+type ReplicationConflict struct {
+	Time       string   `json:"time"`
+	Table      string   `json:"table"`
+	Action     string   `json:"action"`
+	Key        []string `json:"key"`
+	Reason     string   `json:"reason"`
+	Resolution string   `json:"resolution"`
+}
+
+type ReplicationStatus struct {
+	Mode            string                 `json:"mode"`
+	Slot            string                 `json:"slot"`
+	Tables          []string               `json:"tables"`
+	ConflictPolicy  string                 `json:"conflictPolicy"`
+	IsRunning       bool                   `json:"isRunning"`
+	LastError       string                 `json:"lastError"`
+	LastErrorTime   string                 `json:"lastErrorTime"`
+	LastCommitTime  string                 `json:"lastCommitTime"`
+	LastLsn         string                 `json:"lastLsn"`
+	LagBytes        int64                  `json:"lagBytes"`
+	LagSeconds      float64                `json:"lagSeconds"`
+	Transactions    int64                  `json:"transactions"`
+	Events          int64                  `json:"events"`
+	SkippedEvents   int64                  `json:"skippedEvents"`
+	Conflicts       int64                  `json:"conflicts"`
+	RecentConflicts []*ReplicationConflict `json:"recentConflicts"`
+}
+
+var (
+	replicationStatus      *ReplicationStatus
+	replicationStatusMutex sync.RWMutex
+)
+
+func updateReplicationStatus(f func(status *ReplicationStatus)) {
+	replicationStatusMutex.Lock()
+	defer replicationStatusMutex.Unlock()
+	f(replicationStatus)
+}
+
+// GetReplicationStatus returns the status of the database replication, or nil if it is disabled
+func GetReplicationStatus() *ReplicationStatus {
+	replicationStatusMutex.RLock()
+	defer replicationStatusMutex.RUnlock()
+
+	if replicationStatus == nil {
+		return nil
+	}
+
+	status := *replicationStatus
+	status.RecentConflicts = append([]*ReplicationConflict{}, replicationStatus.RecentConflicts...)
+	return &status
+}
+
+func addReplicationConflict(conflict *ReplicationConflict) {
+	logs.Warning("replication conflict on %s %s %v: %s, %s", conflict.Action, conflict.Table, conflict.Key, conflict.Reason, conflict.Resolution)
+	replicationConflicts.WithLabelValues(conflict.Table, conflict.Action).Inc()
+
+	updateReplicationStatus(func(status *ReplicationStatus) {
+		status.Conflicts++
+		status.RecentConflicts = append(status.RecentConflicts, conflict)
+		if len(status.RecentConflicts) > replicationMaxRecentConflicts {
+			status.RecentConflicts = status.RecentConflicts[1:]
+		}
+	})
+}
+
+func setReplicationError(err error) {
+	logs.Error("replication error: %s", err.Error())
+	updateReplicationStatus(func(status *ReplicationStatus) {
+		status.LastError = err.Error()
+		status.LastErrorTime = time.Now().Format(time.RFC3339)
+	})
+}
+
+func getReplicationTables() []string {
+	tables := []string{}
+	for _, table := range strings.Split(conf.GetConfigString("replicationTables"), ",") {
+		table = strings.TrimSpace(table)
+		if table != "" {
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
+
+// StartReplication replicates the changes of the peer database of the replicationDataSourceName
+// to the database of Casdoor, it returns at once if the replicationMode is not set. Each region
+// of a multi-master deployment replicates the changes of the other one.
+func StartReplication() {
+	mode := conf.GetConfigString("replicationMode")
+	if mode == "" {
+		return
+	}
+
+	conflictPolicy := conf.GetConfigString("replicationConflictPolicy")
+	if conflictPolicy == "" {
+		conflictPolicy = ReplicationConflictPolicyOverwrite
+	}
+
+	slot := conf.GetConfigString("replicationSlot")
+	if slot == "" {
+		slot = "casdoor_replication"
+	}
+
+	tables := getReplicationTables()
+	replicationStatusMutex.Lock()
+	replicationStatus = &ReplicationStatus{
+		Mode:            mode,
+		Slot:            slot,
+		Tables:          tables,
+		ConflictPolicy:  conflictPolicy,
+		RecentConflicts: []*ReplicationConflict{},
+	}
+	replicationStatusMutex.Unlock()
+
+	if conflictPolicy != ReplicationConflictPolicyOverwrite && conflictPolicy != ReplicationConflictPolicySkip {
+		setReplicationError(fmt.Errorf("the replication conflict policy: \"%s\" is not supported", conflictPolicy))
+		return
+	}
+
+	switch mode {
+	case ReplicationModePostgres:
+		if conf.GetConfigString("driverName") != "postgres" {
+			setReplicationError(fmt.Errorf("the postgres replication needs the postgres driver"))
+			return
+		}
+
+		replication := newPostgresReplication(conf.GetConfigString("replicationDataSourceName"), conf.GetConfigRealDataSourceName("postgres"), slot, tables, conflictPolicy)
+		replication.run()
+	default:
+		setReplicationError(fmt.Errorf("the replication mode: \"%s\" is not supported", mode))
+	}
+}
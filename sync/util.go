@@ -23,8 +23,8 @@ import (
 	"github.com/xorm-io/xorm"
 )
 
-func getUpdateSql(schemaName string, tableName string, columnNames []string, newColumnVal []interface{}, pkColumnNames []string, pkColumnValue []interface{}) (string, []interface{}, error) {
-	updateSql := squirrel.Update(schemaName + "." + tableName)
+func getUpdateSql(schemaName string, tableName string, columnNames []string, newColumnVal []interface{}, pkColumnNames []string, pkColumnValue []interface{}, placeholder squirrel.PlaceholderFormat) (string, []interface{}, error) {
+	updateSql := squirrel.Update(schemaName + "." + tableName).PlaceholderFormat(placeholder)
 	for i, columnName := range columnNames {
 		updateSql = updateSql.Set(columnName, newColumnVal[i])
 	}
@@ -41,14 +41,14 @@ func getUpdateSql(schemaName string, tableName string, columnNames []string, new
 	return sql, args, nil
 }
 
-func getInsertSql(schemaName string, tableName string, columnNames []string, columnValue []interface{}) (string, []interface{}, error) {
-	insertSql := squirrel.Insert(schemaName + "." + tableName).Columns(columnNames...).Values(columnValue...)
+func getInsertSql(schemaName string, tableName string, columnNames []string, columnValue []interface{}, placeholder squirrel.PlaceholderFormat) (string, []interface{}, error) {
+	insertSql := squirrel.Insert(schemaName + "." + tableName).Columns(columnNames...).Values(columnValue...).PlaceholderFormat(placeholder)
 
 	return insertSql.ToSql()
 }
 
-func getDeleteSql(schemaName string, tableName string, pkColumnNames []string, pkColumnValue []interface{}) (string, []interface{}, error) {
-	deleteSql := squirrel.Delete(schemaName + "." + tableName)
+func getDeleteSql(schemaName string, tableName string, pkColumnNames []string, pkColumnValue []interface{}, placeholder squirrel.PlaceholderFormat) (string, []interface{}, error) {
+	deleteSql := squirrel.Delete(schemaName + "." + tableName).PlaceholderFormat(placeholder)
 
 	for i, columnName := range pkColumnNames {
 		deleteSql = deleteSql.Where(squirrel.Eq{columnName: pkColumnValue[i]})
@@ -17,6 +17,7 @@ package util
 import (
 	"bytes"
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -187,6 +188,11 @@ func GetMd5Hash(text string) string {
 	return hex.EncodeToString(hash[:])
 }
 
+func GetSha256Hash(text string) string {
+	hash := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(hash[:])
+}
+
 func IsStringsEmpty(strs ...string) bool {
 	for _, str := range strs {
 		if len(str) == 0 {
@@ -294,14 +294,16 @@ class App extends Component {
           });
           clearWeb3AuthToken();
           Setting.showMessage("success", i18next.t("application:Logged out successfully"));
-          const redirectUri = res.data2;
-          if (redirectUri !== null && redirectUri !== undefined && redirectUri !== "") {
-            Setting.goToLink(redirectUri);
-          } else if (owner !== "built-in") {
-            Setting.goToLink(`${window.location.origin}/login/${owner}`);
-          } else {
-            Setting.goToLinkSoft(this, "/");
-          }
+          Setting.frontChannelLogout(res.frontChannelLogoutUrls).then(() => {
+            const redirectUri = res.data2;
+            if (redirectUri !== null && redirectUri !== undefined && redirectUri !== "") {
+              Setting.goToLink(redirectUri);
+            } else if (owner !== "built-in") {
+              Setting.goToLink(`${window.location.origin}/login/${owner}`);
+            } else {
+              Setting.goToLinkSoft(this, "/");
+            }
+          });
         } else {
           Setting.showMessage("error", `Failed to log out: ${res.msg}`);
         }
@@ -26,6 +26,7 @@ import SignupPage from "./auth/SignupPage";
 import LoginPage from "./auth/LoginPage";
 import i18next from "i18next";
 import UrlTable from "./table/UrlTable";
+import ScopeTable from "./table/ScopeTable";
 import ProviderTable from "./table/ProviderTable";
 import SigninTable from "./table/SigninTable";
 import SignupTable from "./table/SignupTable";
@@ -354,6 +355,26 @@ class ApplicationEditPage extends React.Component {
             }} />
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:JWKS URI"), i18next.t("application:JWKS URI - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input prefix={<LinkOutlined />} value={this.state.application.jwksUri} onChange={e => {
+              this.updateApplicationField("jwksUri", e.target.value);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:JWKS"), i18next.t("application:JWKS - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input.TextArea rows={4} value={this.state.application.jwks} onChange={e => {
+              this.updateApplicationField("jwks", e.target.value);
+            }} />
+          </Col>
+        </Row>
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
             {Setting.getLabel(i18next.t("general:Cert"), i18next.t("general:Cert - Tooltip"))} :
@@ -378,6 +399,26 @@ class ApplicationEditPage extends React.Component {
             />
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:Front-channel logout URI"), i18next.t("application:Front-channel logout URI - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input prefix={<LinkOutlined />} value={this.state.application.frontChannelLogoutUri} onChange={e => {
+              this.updateApplicationField("frontChannelLogoutUri", e.target.value);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:Back-channel logout URI"), i18next.t("application:Back-channel logout URI - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Input prefix={<LinkOutlined />} value={this.state.application.backChannelLogoutUri} onChange={e => {
+              this.updateApplicationField("backChannelLogoutUri", e.target.value);
+            }} />
+          </Col>
+        </Row>
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
             {Setting.getLabel(i18next.t("application:Token format"), i18next.t("application:Token format - Tooltip"))} :
@@ -388,6 +429,18 @@ class ApplicationEditPage extends React.Component {
             />
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:Scopes"), i18next.t("application:Scopes - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <ScopeTable
+              title={i18next.t("application:Scopes")}
+              table={this.state.application.scopes}
+              onUpdateTable={(value) => {this.updateApplicationField("scopes", value);}}
+            />
+          </Col>
+        </Row>
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
             {Setting.getLabel(i18next.t("application:Token expire"), i18next.t("application:Token expire - Tooltip"))} :
@@ -648,11 +701,46 @@ class ApplicationEditPage extends React.Component {
                   {id: "token", name: "Token"},
                   {id: "id_token", name: "ID Token"},
                   {id: "refresh_token", name: "Refresh Token"},
+                  {id: "urn:ietf:params:oauth:grant-type:device_code", name: "Device Code"},
+                  {id: "urn:ietf:params:oauth:grant-type:token-exchange", name: "Token Exchange"},
+                  {id: "urn:ietf:params:oauth:grant-type:jwt-bearer", name: "JWT Bearer"},
                 ].map((item, index) => <Option key={index} value={item.id}>{item.name}</Option>)
               }
             </Select>
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:Token exchange allow list"), i18next.t("application:Token exchange allow list - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} mode="tags" style={{width: "100%"}}
+              value={this.state.application.tokenExchangeAllowList ?? []}
+              onChange={(value => {
+                this.updateApplicationField("tokenExchangeAllowList", value);
+              })} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 19 : 2}>
+            {Setting.getLabel(i18next.t("application:Require PAR"), i18next.t("application:Require PAR - Tooltip"))} :
+          </Col>
+          <Col span={1} >
+            <Switch checked={this.state.application.requirePushedAuthorizationRequests} onChange={checked => {
+              this.updateApplicationField("requirePushedAuthorizationRequests", checked);
+            }} />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 19 : 2}>
+            {Setting.getLabel(i18next.t("application:Require consent"), i18next.t("application:Require consent - Tooltip"))} :
+          </Col>
+          <Col span={1} >
+            <Switch checked={this.state.application.requireConsent} onChange={checked => {
+              this.updateApplicationField("requireConsent", checked);
+            }} />
+          </Col>
+        </Row>
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
             {Setting.getLabel(i18next.t("application:SAML reply URL"), i18next.t("application:Redirect URL (Assertion Consumer Service POST Binding URL) - Tooltip"))} :
@@ -673,6 +761,68 @@ class ApplicationEditPage extends React.Component {
             }} />
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:SAML SP metadata"), i18next.t("application:SAML SP metadata - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <div style={{height: "200px"}} >
+              <CodeMirror
+                value={this.state.application.samlSpMetadata}
+                options={{mode: "xml", theme: "default"}}
+                onBeforeChange={(editor, data, value) => {
+                  this.updateApplicationField("samlSpMetadata", value);
+                }}
+              />
+            </div>
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:SAML NameID format"), i18next.t("application:SAML NameID format - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} style={{width: "100%"}} value={this.state.application.samlNameIdFormat ?? ""} onChange={(value => {this.updateApplicationField("samlNameIdFormat", value);})}
+              options={[
+                {label: i18next.t("application:Requested by the SP"), value: ""},
+                {label: "unspecified", value: "urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified"},
+                {label: "emailAddress", value: "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"},
+                {label: "persistent", value: "urn:oasis:names:tc:SAML:2.0:nameid-format:persistent"},
+                {label: "transient", value: "urn:oasis:names:tc:SAML:2.0:nameid-format:transient"},
+              ].map((item) => Setting.getOption(item.label, item.value))}
+            />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:SAML signing mode"), i18next.t("application:SAML signing mode - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} style={{width: "100%"}} value={this.state.application.samlSigningMode || "Response"} onChange={(value => {this.updateApplicationField("samlSigningMode", value);})}
+              options={["Response", "Assertion", "Both"].map((item) => Setting.getOption(item, item))}
+            />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("application:SAML signature algorithm"), i18next.t("application:SAML signature algorithm - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} style={{width: "100%"}} value={this.state.application.samlSignatureAlgorithm || "RSA-SHA1"} onChange={(value => {this.updateApplicationField("samlSignatureAlgorithm", value);})}
+              options={["RSA-SHA1", "RSA-SHA256", "RSA-SHA512"].map((item) => Setting.getOption(item, item))}
+            />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 19 : 2}>
+            {Setting.getLabel(i18next.t("application:Enable SAML assertion encryption"), i18next.t("application:Enable SAML assertion encryption - Tooltip"))} :
+          </Col>
+          <Col span={1} >
+            <Switch checked={this.state.application.enableSamlAssertionEncryption} onChange={checked => {
+              this.updateApplicationField("enableSamlAssertionEncryption", checked);
+            }} />
+          </Col>
+        </Row>
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
             {Setting.getLabel(i18next.t("application:SAML metadata"), i18next.t("application:SAML metadata - Tooltip"))} :
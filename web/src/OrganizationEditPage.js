@@ -25,6 +25,8 @@ import LdapTable from "./table/LdapTable";
 import AccountTable from "./table/AccountTable";
 import ThemeEditor from "./common/theme/ThemeEditor";
 import MfaTable from "./table/MfaTable";
+import LdapSchemaTable from "./table/LdapSchemaTable";
+import RadiusReplyRuleTable from "./table/RadiusReplyRuleTable";
 
 const {Option} = Select;
 
@@ -369,6 +371,16 @@ class OrganizationEditPage extends React.Component {
             }} />
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("organization:Audit retention days"), i18next.t("organization:Audit retention days - Tooltip"))} :
+          </Col>
+          <Col span={4} >
+            <InputNumber min={0} value={this.state.organization.auditRetentionDays} onChange={value => {
+              this.updateOrganizationField("auditRetentionDays", value);
+            }} />
+          </Col>
+        </Row>
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 19 : 2}>
             {Setting.getLabel(i18next.t("organization:Soft deletion"), i18next.t("organization:Soft deletion - Tooltip"))} :
@@ -453,6 +465,42 @@ class OrganizationEditPage extends React.Component {
             />
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("organization:LDAP object classes"), i18next.t("organization:LDAP object classes - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} mode="tags" style={{width: "100%"}} placeholder="top, person, organizationalPerson, inetOrgPerson, posixAccount" value={this.state.organization.ldapObjectClasses ?? []} onChange={(value => {this.updateOrganizationField("ldapObjectClasses", value);})}>
+              {
+                this.state.organization.ldapObjectClasses?.map((item, index) => <Option key={index} value={item}>{item}</Option>)
+              }
+            </Select>
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("organization:LDAP attributes"), i18next.t("organization:LDAP attributes - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <LdapSchemaTable
+              title={i18next.t("organization:LDAP attributes")}
+              table={this.state.organization.ldapAttributes ?? []}
+              onUpdateTable={(value) => {this.updateOrganizationField("ldapAttributes", value);}}
+            />
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("organization:RADIUS reply rules"), i18next.t("organization:RADIUS reply rules - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <RadiusReplyRuleTable
+              title={i18next.t("organization:RADIUS reply rules")}
+              table={this.state.organization.radiusReplyRules ?? []}
+              onUpdateTable={(value) => {this.updateOrganizationField("radiusReplyRules", value);}}
+            />
+          </Col>
+        </Row>
       </Card>
     );
   }
@@ -711,6 +711,25 @@ export function goToLink(link) {
   window.location.href = link;
 }
 
+// Load the front-channel logout URLs returned by /api/logout in hidden iframes, so that the
+// applications can clear their sessions before the page navigates away
+export function frontChannelLogout(urls) {
+  if (!urls || urls.length === 0) {
+    return Promise.resolve();
+  }
+
+  const loads = urls.map(url => new Promise(resolve => {
+    const iframe = document.createElement("iframe");
+    iframe.style.display = "none";
+    iframe.onload = resolve;
+    iframe.onerror = resolve;
+    iframe.src = url;
+    document.body.appendChild(iframe);
+  }));
+  const timeout = new Promise(resolve => setTimeout(resolve, 5000));
+  return Promise.race([Promise.all(loads), timeout]);
+}
+
 export function goToLinkSoft(ths, link) {
   if (link.startsWith("http")) {
     openLink(link);
@@ -13,7 +13,7 @@
 // limitations under the License.
 
 import React from "react";
-import {Button, Card, Col, Input, InputNumber, Row, Select, Switch} from "antd";
+import {Button, Card, Col, Input, InputNumber, Row, Select, Switch, Table} from "antd";
 import {LinkOutlined} from "@ant-design/icons";
 import * as SyncerBackend from "./backend/SyncerBackend";
 import * as OrganizationBackend from "./backend/OrganizationBackend";
@@ -36,6 +36,7 @@ class SyncerEditPage extends React.Component {
       syncerName: props.match.params.syncerName,
       syncer: null,
       organizations: [],
+      report: null,
       mode: props.location.mode !== undefined ? props.location.mode : "edit",
     };
   }
@@ -90,6 +91,71 @@ class SyncerEditPage extends React.Component {
     });
   }
 
+  dryRunSyncer() {
+    SyncerBackend.dryRunSyncer(this.state.syncer.owner, this.state.syncerName)
+      .then((res) => {
+        if (res.status === "ok") {
+          this.setState({
+            report: res.data,
+          });
+        } else {
+          Setting.showMessage("error", res.msg);
+        }
+      })
+      .catch(error => {
+        Setting.showMessage("error", `${i18next.t("general:Failed to connect to server")}: ${error}`);
+      });
+  }
+
+  renderReport() {
+    const report = this.state.report;
+    const columns = [
+      {
+        title: i18next.t("syncer:Action"),
+        dataIndex: "action",
+        key: "action",
+        width: "120px",
+      },
+      {
+        title: i18next.t("syncer:Target"),
+        dataIndex: "target",
+        key: "target",
+        width: "120px",
+      },
+      {
+        title: i18next.t("syncer:Key"),
+        dataIndex: "key",
+        key: "key",
+        width: "200px",
+      },
+      {
+        title: i18next.t("general:User"),
+        dataIndex: "user",
+        key: "user",
+        width: "200px",
+      },
+      {
+        title: i18next.t("syncer:Fields"),
+        dataIndex: "fields",
+        key: "fields",
+        render: (text, record, index) => {
+          const fields = (text || []).join(", ");
+          return record.isConflict ? `${fields} (${i18next.t("syncer:Conflict")})` : fields;
+        },
+      },
+    ];
+
+    return (
+      <Table rowKey={(record, index) => index} columns={columns} dataSource={report.changes} size="middle" bordered pagination={{pageSize: 100}}
+        title={() => (
+          <div>
+            {`${i18next.t("syncer:Dry run report")}: ${report.sourceCount} ${i18next.t("syncer:rows read")}, ${report.createCount} ${i18next.t("syncer:creates")}, ${report.updateCount} ${i18next.t("syncer:updates")}, ${report.deleteCount} ${i18next.t("syncer:deletes")}, ${report.conflictCount} ${i18next.t("syncer:conflicts")}`}
+          </div>
+        )}
+      />
+    );
+  }
+
   getSyncerTableColumns(syncer) {
     switch (syncer.type) {
     case "Keycloak":
@@ -189,6 +255,7 @@ class SyncerEditPage extends React.Component {
           <Button onClick={() => this.submitSyncerEdit(false)}>{i18next.t("general:Save")}</Button>
           <Button style={{marginLeft: "20px"}} type="primary" onClick={() => this.submitSyncerEdit(true)}>{i18next.t("general:Save & Exit")}</Button>
           {this.state.mode === "add" ? <Button style={{marginLeft: "20px"}} onClick={() => this.deleteSyncer()}>{i18next.t("general:Cancel")}</Button> : null}
+          {this.state.mode === "add" ? null : <Button style={{marginLeft: "20px"}} onClick={() => this.dryRunSyncer()}>{i18next.t("syncer:Dry run")}</Button>}
         </div>
       } style={(Setting.isMobile()) ? {margin: "5px"} : {}} type="inner">
         <Row style={{marginTop: "10px"}} >
@@ -385,6 +452,88 @@ class SyncerEditPage extends React.Component {
             }} />
           </Col>
         </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("syncer:Sync mode"), i18next.t("syncer:Sync mode - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} style={{width: "100%"}} value={this.state.syncer.syncMode || "Full"} onChange={(value => {this.updateSyncerField("syncMode", value);})}>
+              {
+                [
+                  {id: "Full", name: i18next.t("syncer:Full")},
+                  {id: "Incremental", name: i18next.t("syncer:Incremental")},
+                  {id: "Change log", name: i18next.t("syncer:Change log")},
+                ].map((item, index) => <Option key={index} value={item.id}>{item.name}</Option>)
+              }
+            </Select>
+          </Col>
+        </Row>
+        {
+          this.state.syncer.syncMode !== "Change log" ? null : (
+            <React.Fragment>
+              <Row style={{marginTop: "20px"}} >
+                <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                  {Setting.getLabel(i18next.t("syncer:Change log table"), i18next.t("syncer:Change log table - Tooltip"))} :
+                </Col>
+                <Col span={22} >
+                  <Input value={this.state.syncer.changeLogTable} onChange={e => {
+                    this.updateSyncerField("changeLogTable", e.target.value);
+                  }} />
+                </Col>
+              </Row>
+              <Row style={{marginTop: "20px"}} >
+                <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                  {Setting.getLabel(i18next.t("syncer:Change log key column"), i18next.t("syncer:Change log key column - Tooltip"))} :
+                </Col>
+                <Col span={22} >
+                  <Input value={this.state.syncer.changeLogKeyColumn} onChange={e => {
+                    this.updateSyncerField("changeLogKeyColumn", e.target.value);
+                  }} />
+                </Col>
+              </Row>
+            </React.Fragment>
+          )
+        }
+        {
+          !this.state.syncer.syncMode || this.state.syncer.syncMode === "Full" ? null : (
+            <Row style={{marginTop: "20px"}} >
+              <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+                {Setting.getLabel(i18next.t("syncer:Change column"), i18next.t("syncer:Change column - Tooltip"))} :
+              </Col>
+              <Col span={22} >
+                <Input value={this.state.syncer.changeColumn} onChange={e => {
+                  this.updateSyncerField("changeColumn", e.target.value);
+                }} />
+              </Col>
+            </Row>
+          )
+        }
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
+            {Setting.getLabel(i18next.t("syncer:Conflict policy"), i18next.t("syncer:Conflict policy - Tooltip"))} :
+          </Col>
+          <Col span={22} >
+            <Select virtual={false} style={{width: "100%"}} value={this.state.syncer.conflictPolicy || "Source"} onChange={(value => {this.updateSyncerField("conflictPolicy", value);})}>
+              {
+                [
+                  {id: "Source", name: i18next.t("syncer:Source wins")},
+                  {id: "Local", name: i18next.t("syncer:Local wins")},
+                  {id: "Field", name: i18next.t("syncer:Field level")},
+                ].map((item, index) => <Option key={index} value={item.id}>{item.name}</Option>)
+              }
+            </Select>
+          </Col>
+        </Row>
+        <Row style={{marginTop: "20px"}} >
+          <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 19 : 2}>
+            {Setting.getLabel(i18next.t("syncer:Sync deletions"), i18next.t("syncer:Sync deletions - Tooltip"))} :
+          </Col>
+          <Col span={1} >
+            <Switch checked={this.state.syncer.syncDeletions} onChange={checked => {
+              this.updateSyncerField("syncDeletions", checked);
+            }} />
+          </Col>
+        </Row>
         <Row style={{marginTop: "20px"}} >
           <Col style={{marginTop: "5px"}} span={(Setting.isMobile()) ? 22 : 2}>
             {Setting.getLabel(i18next.t("syncer:Error text"), i18next.t("syncer:Error text - Tooltip"))} :
@@ -470,6 +619,9 @@ class SyncerEditPage extends React.Component {
         {
           this.state.syncer !== null ? this.renderSyncer() : null
         }
+        {
+          this.state.report !== null ? <div style={{marginTop: "20px"}}>{this.renderReport()}</div> : null
+        }
         <div style={{marginTop: "20px", marginLeft: "40px"}}>
           <Button size="large" onClick={() => this.submitSyncerEdit(false)}>{i18next.t("general:Save")}</Button>
           <Button style={{marginLeft: "20px"}} type="primary" size="large" onClick={() => this.submitSyncerEdit(true)}>{i18next.t("general:Save & Exit")}</Button>
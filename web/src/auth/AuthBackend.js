@@ -57,7 +57,17 @@ export function oAuthParamsToQuery(oAuthParams) {
   }
 
   // code
-  return `?clientId=${oAuthParams.clientId}&responseType=${oAuthParams.responseType}&redirectUri=${encodeURIComponent(oAuthParams.redirectUri)}&type=${oAuthParams.type}&scope=${oAuthParams.scope}&state=${oAuthParams.state}&nonce=${oAuthParams.nonce}&code_challenge_method=${oAuthParams.challengeMethod}&code_challenge=${oAuthParams.codeChallenge}`;
+  let query = `?clientId=${oAuthParams.clientId}&responseType=${oAuthParams.responseType}&redirectUri=${encodeURIComponent(oAuthParams.redirectUri)}&type=${oAuthParams.type}&scope=${oAuthParams.scope}&state=${oAuthParams.state}&nonce=${oAuthParams.nonce}&code_challenge_method=${oAuthParams.challengeMethod}&code_challenge=${oAuthParams.codeChallenge}`;
+  if (oAuthParams.request) {
+    query += `&request=${encodeURIComponent(oAuthParams.request)}`;
+  }
+  if (oAuthParams.requestUri) {
+    query += `&requestUri=${encodeURIComponent(oAuthParams.requestUri)}`;
+  }
+  if (oAuthParams.prompt) {
+    query += `&prompt=${encodeURIComponent(oAuthParams.prompt)}`;
+  }
+  return query;
 }
 
 export function getApplicationLogin(params) {
@@ -82,6 +92,37 @@ export function login(values, oAuthParams) {
   }).then(res => res.json());
 }
 
+export function getDeviceAuth(userCode) {
+  return fetch(`${authConfig.serverUrl}/api/get-device-auth?userCode=${encodeURIComponent(userCode)}`, {
+    method: "GET",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function approveDeviceAuth(userCode, approved) {
+  return fetch(`${authConfig.serverUrl}/api/approve-device-auth?userCode=${encodeURIComponent(userCode)}&approved=${approved}`, {
+    method: "POST",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
+export function grantConsent(values, oAuthParams) {
+  return fetch(`${authConfig.serverUrl}/api/grant-consent${oAuthParamsToQuery(oAuthParams)}`, {
+    method: "POST",
+    credentials: "include",
+    body: JSON.stringify(values),
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
+
 export function loginCas(values, params) {
   return fetch(`${authConfig.serverUrl}/api/login?service=${params.service}`, {
     method: "POST",
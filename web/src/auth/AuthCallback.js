@@ -21,6 +21,7 @@ import {authConfig} from "./Auth";
 import * as Setting from "../Setting";
 import i18next from "i18next";
 import RedirectForm from "../common/RedirectForm";
+import {ConsentForm, NextConsent} from "./ConsentForm";
 
 class AuthCallback extends React.Component {
   constructor(props) {
@@ -31,16 +32,20 @@ class AuthCallback extends React.Component {
       samlResponse: "",
       relayState: "",
       redirectUrl: "",
+      consent: null,
     };
   }
 
+  getInnerQuery() {
+    const params = new URLSearchParams(this.props.location.search);
+    const state = params.get("state");
+    return Util.getQueryParamsFromState(state);
+  }
+
   getInnerParams() {
     // For example, for Casbin-OA, realRedirectUri = "http://localhost:9000/login"
     // realRedirectUrl = "http://localhost:9000"
-    const params = new URLSearchParams(this.props.location.search);
-    const state = params.get("state");
-    const queryString = Util.getQueryParamsFromState(state);
-    return new URLSearchParams(queryString);
+    return new URLSearchParams(this.getInnerQuery());
   }
 
   getResponseType() {
@@ -121,6 +126,8 @@ class AuthCallback extends React.Component {
       provider: providerName,
       code: code,
       samlRequest: samlRequest,
+      relayState: innerParams.get("RelayState"),
+      samlQuery: this.getInnerQuery(),
       // state: innerParams.get("state"),
       state: applicationName,
       redirectUri: redirectUri,
@@ -156,6 +163,13 @@ class AuthCallback extends React.Component {
     AuthBackend.login(body, oAuthParams)
       .then((res) => {
         if (res.status === "ok") {
+          if (res.data === NextConsent) {
+            this.setState({
+              consent: {consentRequest: res.data2, values: body, oAuthParams: oAuthParams},
+            });
+            return;
+          }
+
           const responseType = this.getResponseType();
           if (responseType === "login") {
             Setting.showMessage("success", "Logged in successfully");
@@ -194,11 +208,38 @@ class AuthCallback extends React.Component {
       });
   }
 
+  onConsentGranted(res) {
+    const responseType = this.getResponseType();
+    const oAuthParams = this.state.consent.oAuthParams;
+    const concatChar = oAuthParams?.redirectUri?.includes("?") ? "&" : "?";
+    if (responseType === "code") {
+      Setting.goToLink(`${oAuthParams.redirectUri}${concatChar}code=${res.data}&state=${oAuthParams.state}`);
+    } else {
+      Setting.goToLink(`${oAuthParams.redirectUri}${concatChar}${responseType}=${res.data}&state=${oAuthParams.state}&token_type=bearer`);
+    }
+  }
+
   render() {
     if (this.state.samlResponse !== "") {
       return <RedirectForm samlResponse={this.state.samlResponse} redirectUrl={this.state.redirectUrl} relayState={this.state.relayState} />;
     }
 
+    if (this.state.consent !== null) {
+      return (
+        <div style={{display: "flex", justifyContent: "center", alignItems: "center", paddingTop: "10%"}}>
+          <div style={{width: 400, textAlign: "center"}}>
+            <ConsentForm
+              consentRequest={this.state.consent.consentRequest}
+              values={this.state.consent.values}
+              oAuthParams={this.state.consent.oAuthParams}
+              onSuccess={(res) => this.onConsentGranted(res)}
+              onFail={(res) => this.setState({consent: null, msg: res.msg})}
+            />
+          </div>
+        </div>
+      );
+    }
+
     return (
       <div style={{display: "flex", justifyContent: "center", alignItems: "center"}}>
         {
@@ -0,0 +1,83 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import React, {useState} from "react";
+import i18next from "i18next";
+import {Button, List, Space, Tag} from "antd";
+import * as AuthBackend from "./AuthBackend";
+import * as Setting from "../Setting";
+
+export const NextConsent = "NextConsent";
+
+export function ConsentForm({consentRequest, values, oAuthParams, onSuccess, onFail}) {
+  const [loading, setLoading] = useState(false);
+  const application = consentRequest.application;
+
+  const grantConsent = () => {
+    setLoading(true);
+
+    // the credentials of the login form are not needed again, the user is signed in already
+    AuthBackend.grantConsent({application: values.application, type: values.type, autoSignin: values.autoSignin}, oAuthParams)
+      .then((res) => {
+        if (res.status === "ok") {
+          onSuccess(res);
+        } else {
+          onFail(res);
+        }
+      }).finally(() => setLoading(false));
+  };
+
+  const denyConsent = () => {
+    // the error is returned to the client the same way as the authorization response, see RFC 6749 section 4.1.2.1
+    const concatChar = oAuthParams.responseType === "code" ? (oAuthParams.redirectUri.includes("?") ? "&" : "?") : "#";
+    Setting.goToLink(`${oAuthParams.redirectUri}${concatChar}error=access_denied&state=${oAuthParams.state}`);
+  };
+
+  return (
+    <React.Fragment>
+      <h1>{i18next.t("login:Authorize")}</h1>
+      <p>
+        <b>{application.displayName || application.name}</b> {i18next.t("login:is requesting access to your account")}
+      </p>
+      {
+        consentRequest.scopes.length === 0 ? null : (
+          <List
+            size="small"
+            bordered
+            style={{marginBottom: "20px", textAlign: "left"}}
+            dataSource={consentRequest.scopes}
+            renderItem={scope => (
+              <List.Item>
+                {scope}
+                {
+                  consentRequest.grantedScopes.includes(scope) ? null : (
+                    <Tag color="blue" style={{marginLeft: "10px"}}>{i18next.t("login:New")}</Tag>
+                  )
+                }
+              </List.Item>
+            )}
+          />
+        )
+      }
+      <Space>
+        <Button type="primary" loading={loading} onClick={grantConsent}>
+          {i18next.t("login:Allow")}
+        </Button>
+        <Button danger disabled={loading} onClick={denyConsent}>
+          {i18next.t("login:Deny")}
+        </Button>
+      </Space>
+    </React.Fragment>
+  );
+}
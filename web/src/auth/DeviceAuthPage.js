@@ -0,0 +1,128 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import React from "react";
+import {Button, Card, Input, Result, Space} from "antd";
+import {withRouter} from "react-router-dom";
+import i18next from "i18next";
+import * as AuthBackend from "./AuthBackend";
+import * as Setting from "../Setting";
+
+class DeviceAuthPage extends React.Component {
+  constructor(props) {
+    super(props);
+    this.state = {
+      classes: props,
+      userCode: props.match?.params?.userCode ?? "",
+      deviceAuth: null,
+      application: null,
+      result: null,
+    };
+  }
+
+  UNSAFE_componentWillMount() {
+    if (this.state.userCode !== "") {
+      this.getDeviceAuth(this.state.userCode);
+    }
+  }
+
+  getDeviceAuth(userCode) {
+    AuthBackend.getDeviceAuth(userCode)
+      .then((res) => {
+        if (res.status === "ok") {
+          this.setState({
+            deviceAuth: res.data,
+            application: res.data2,
+          });
+        } else {
+          Setting.showMessage("error", res.msg);
+        }
+      });
+  }
+
+  approveDeviceAuth(approved) {
+    AuthBackend.approveDeviceAuth(this.state.deviceAuth.userCode, approved)
+      .then((res) => {
+        if (res.status === "ok") {
+          this.setState({
+            result: approved ? "approved" : "denied",
+          });
+        } else {
+          Setting.showMessage("error", res.msg);
+        }
+      });
+  }
+
+  renderUserCodeInput() {
+    return (
+      <Card title={i18next.t("login:Connect a device")} style={{width: 400}}>
+        <p>{i18next.t("login:Enter the code displayed on your device")}</p>
+        <Space.Compact style={{width: "100%"}}>
+          <Input value={this.state.userCode} placeholder="XXXX-XXXX" onChange={e => {
+            this.setState({userCode: e.target.value});
+          }} onPressEnter={() => this.getDeviceAuth(this.state.userCode)} />
+          <Button type="primary" onClick={() => this.getDeviceAuth(this.state.userCode)}>
+            {i18next.t("general:Next")}
+          </Button>
+        </Space.Compact>
+      </Card>
+    );
+  }
+
+  renderConsent() {
+    const application = this.state.application;
+    return (
+      <Card title={i18next.t("login:Connect a device")} style={{width: 400}}>
+        <p>
+          {i18next.t("login:The device is requesting access to")} <b>{application?.displayName || this.state.deviceAuth.application}</b>
+        </p>
+        <p>{i18next.t("general:Code")}: <b>{this.state.deviceAuth.userCode}</b></p>
+        {
+          this.state.deviceAuth.scope === "" ? null : (
+            <p>{i18next.t("provider:Scope")}: {this.state.deviceAuth.scope}</p>
+          )
+        }
+        <Space>
+          <Button type="primary" onClick={() => this.approveDeviceAuth(true)}>
+            {i18next.t("login:Allow")}
+          </Button>
+          <Button danger onClick={() => this.approveDeviceAuth(false)}>
+            {i18next.t("login:Deny")}
+          </Button>
+        </Space>
+      </Card>
+    );
+  }
+
+  render() {
+    if (this.state.result !== null) {
+      return (
+        <Result
+          style={{display: "flex", flex: "1 1 0%", justifyContent: "center", flexDirection: "column"}}
+          status={this.state.result === "approved" ? "success" : "warning"}
+          title={this.state.result === "approved" ? i18next.t("login:Device connected") : i18next.t("login:Device access denied")}
+          subTitle={i18next.t("login:You can close this page and return to your device")}
+        />
+      );
+    }
+
+    return (
+      <div style={{display: "flex", flex: "1", justifyContent: "center", alignItems: "center"}}>
+        {this.state.deviceAuth === null ? this.renderUserCodeInput() : this.renderConsent()}
+      </div>
+    );
+  }
+}
+
+export default withRouter(DeviceAuthPage);
@@ -35,6 +35,7 @@ import {CaptchaModal, CaptchaRule} from "../common/modal/CaptchaModal";
 import RedirectForm from "../common/RedirectForm";
 import {MfaAuthVerifyForm, NextMfa, RequiredMfa} from "./mfa/MfaAuthVerifyForm";
 import {ChangePasswordForm, NextChangePasswordForm} from "./ChangePasswordForm";
+import {ConsentForm, NextConsent} from "./ConsentForm";
 
 import {GoogleOneTapLoginVirtualButton} from "./GoogleLoginButton";
 import LdapSelect from "../common/select/LdapSelect";
@@ -151,6 +152,9 @@ class LoginPage extends React.Component {
       .then((res) => {
         if (res.status === "ok") {
           const application = res.data;
+          if (loginParams?.request || loginParams?.requestUri) {
+            Util.setOAuthGetParameters(res.data2);
+          }
           this.onUpdateApplication(application);
         } else {
           this.onUpdateApplication(null);
@@ -274,6 +278,7 @@ class LoginPage extends React.Component {
       values["samlRequest"] = oAuthParams.samlRequest;
       values["type"] = "saml";
       values["relayState"] = oAuthParams.relayState;
+      values["samlQuery"] = oAuthParams.samlQuery;
     }
   }
 
@@ -390,6 +395,30 @@ class LoginPage extends React.Component {
       AuthBackend.login(values, oAuthParams)
         .then((res) => {
           const callback = (res) => {
+            if (res.data === NextConsent) {
+              this.setState({
+                getVerifyTotp: undefined,
+                getChangePasswordForm: undefined,
+                getConsentForm: () => {
+                  return (
+                    <ConsentForm
+                      consentRequest={res.data2}
+                      values={values}
+                      oAuthParams={oAuthParams}
+                      onSuccess={(res) => {
+                        this.setState({getConsentForm: undefined});
+                        callback(res);
+                      }}
+                      onFail={(res) => {
+                        Setting.showMessage("error", `${i18next.t("application:Failed to sign in")}: ${res.msg}`);
+                      }}
+                    />
+                  );
+                },
+              });
+              return;
+            }
+
             const responseType = values["type"];
 
             if (responseType === "login") {
@@ -981,6 +1010,8 @@ class LoginPage extends React.Component {
       return this.state.getVerifyTotp();
     } else if (this.state.getChangePasswordForm !== undefined) {
       return this.state.getChangePasswordForm();
+    } else if (this.state.getConsentForm !== undefined) {
+      return this.state.getConsentForm();
     } else {
       return (
         <React.Fragment>
@@ -139,7 +139,12 @@ export function getOAuthGetParameters(params) {
   const codeChallenge = getRefinedValue(queries.get("code_challenge"));
   const samlRequest = getRefinedValue(queries.get("SAMLRequest"));
   const relayState = getRefinedValue(queries.get("RelayState"));
+  // the signature of the HTTP-Redirect binding is verified against the query string as received
+  const samlQuery = (params !== undefined) ? "" : window.location.search;
   const noRedirect = getRefinedValue(queries.get("noRedirect"));
+  const request = getRefinedValue(queries.get("request"));
+  const requestUri = getRefinedValue(queries.get("request_uri"));
+  const prompt = getRefinedValue(queries.get("prompt"));
 
   if (clientId === "" && samlRequest === "") {
     // login
@@ -157,12 +162,31 @@ export function getOAuthGetParameters(params) {
       codeChallenge: codeChallenge,
       samlRequest: samlRequest,
       relayState: relayState,
+      samlQuery: samlQuery,
       noRedirect: noRedirect,
+      request: request,
+      requestUri: requestUri,
+      prompt: prompt,
       type: "code",
     };
   }
 }
 
+// The parameters of a request object or a pushed authorization request are resolved by the backend,
+// they replace the ones in the URL so that the rest of the flow can read them with getOAuthGetParameters()
+export function setOAuthGetParameters(authorizeRequest) {
+  const queries = new URLSearchParams(window.location.search);
+  queries.set("response_type", authorizeRequest.responseType);
+  queries.set("redirect_uri", authorizeRequest.redirectUri);
+  queries.set("scope", authorizeRequest.scope);
+  queries.set("state", authorizeRequest.state);
+  queries.set("nonce", authorizeRequest.nonce);
+  queries.set("code_challenge_method", authorizeRequest.codeChallengeMethod);
+  queries.set("code_challenge", authorizeRequest.codeChallenge);
+  queries.set("prompt", authorizeRequest.prompt);
+  window.history.replaceState(null, "", `${window.location.pathname}?${queries.toString()}`);
+}
+
 export function getStateFromQueryParams(applicationName, providerName, method, isShortState) {
   let query = window.location.search;
   query = `${query}&application=${encodeURIComponent(applicationName)}&provider=${encodeURIComponent(providerName)}&method=${method}`;
@@ -79,3 +79,13 @@ export function runSyncer(owner, name) {
     },
   }).then(res => res.json());
 }
+
+export function dryRunSyncer(owner, name) {
+  return fetch(`${Setting.ServerUrl}/api/dry-run-syncer?id=${owner}/${encodeURIComponent(name)}`, {
+    method: "GET",
+    credentials: "include",
+    headers: {
+      "Accept-Language": Setting.getAcceptLanguage(),
+    },
+  }).then(res => res.json());
+}
@@ -0,0 +1,172 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import React from "react";
+import {DeleteOutlined} from "@ant-design/icons";
+import {Button, Col, Input, Row, Select, Table, Tooltip} from "antd";
+import * as Setting from "../Setting";
+import i18next from "i18next";
+
+const {Option} = Select;
+
+const matches = ["Any", "Role", "Group", "Property"];
+
+const matchPlaceholders = {
+  "Role": "network-admin",
+  "Group": "engineering",
+  "Property": "department=sales",
+};
+
+const attributes = [
+  "Service-Type", "Filter-Id", "Reply-Message", "Class", "Session-Timeout", "Idle-Timeout",
+  "Tunnel-Type", "Tunnel-Medium-Type", "Tunnel-Private-Group-Id",
+  "Cisco-AVPair",
+  "Juniper-Local-User-Name", "Juniper-Allow-Commands", "Juniper-Deny-Commands",
+  "Fortinet-Group-Name", "Fortinet-Vdom-Name", "Fortinet-Access-Profile",
+];
+
+class RadiusReplyRuleTable extends React.Component {
+  constructor(props) {
+    super(props);
+    this.state = {
+      classes: props,
+    };
+  }
+
+  updateTable(table) {
+    this.props.onUpdateTable(table);
+  }
+
+  updateField(table, index, key, value) {
+    table[index][key] = value;
+    this.updateTable(table);
+  }
+
+  addRow(table) {
+    if (table === undefined || table === null) {
+      table = [];
+    }
+    const row = {match: "Any", matchValue: "", attribute: "Filter-Id", value: ""};
+    table = Setting.addRow(table, row);
+    this.updateTable(table);
+  }
+
+  deleteRow(table, i) {
+    table = Setting.deleteRow(table, i);
+    this.updateTable(table);
+  }
+
+  renderTable(table) {
+    const columns = [
+      {
+        title: i18next.t("organization:Match"),
+        dataIndex: "match",
+        key: "match",
+        width: "150px",
+        render: (text, record, index) => {
+          return (
+            <Select virtual={false} style={{width: "100%"}} value={text} onChange={value => {
+              this.updateField(table, index, "match", value);
+            }}>
+              {
+                matches.map((match) => <Option key={match} value={match}>{match}</Option>)
+              }
+            </Select>
+          );
+        },
+      },
+      {
+        title: i18next.t("organization:Match value"),
+        dataIndex: "matchValue",
+        key: "matchValue",
+        width: "200px",
+        render: (text, record, index) => {
+          return (
+            <Input value={text} disabled={record.match === "Any"} placeholder={matchPlaceholders[record.match]} onChange={e => {
+              this.updateField(table, index, "matchValue", e.target.value);
+            }} />
+          );
+        },
+      },
+      {
+        title: i18next.t("ldap:Attribute"),
+        dataIndex: "attribute",
+        key: "attribute",
+        width: "250px",
+        render: (text, record, index) => {
+          return (
+            <Select virtual={false} showSearch style={{width: "100%"}} value={text} onChange={value => {
+              this.updateField(table, index, "attribute", value);
+            }}>
+              {
+                attributes.map((attribute) => <Option key={attribute} value={attribute}>{attribute}</Option>)
+              }
+            </Select>
+          );
+        },
+      },
+      {
+        title: i18next.t("webhook:Value"),
+        dataIndex: "value",
+        key: "value",
+        render: (text, record, index) => {
+          return (
+            <Input value={text} placeholder="${properties.vlan}" onChange={e => {
+              this.updateField(table, index, "value", e.target.value);
+            }} />
+          );
+        },
+      },
+      {
+        title: i18next.t("general:Action"),
+        key: "action",
+        width: "50px",
+        render: (text, record, index) => {
+          return (
+            <Tooltip placement="topLeft" title={i18next.t("general:Delete")}>
+              <Button icon={<DeleteOutlined />} size="small" onClick={() => this.deleteRow(table, index)} />
+            </Tooltip>
+          );
+        },
+      },
+    ];
+
+    return (
+      <Table rowKey={(record, index) => index} columns={columns} dataSource={table} size="middle" bordered pagination={false}
+        title={() => (
+          <div>
+            {this.props.title}&nbsp;&nbsp;&nbsp;&nbsp;
+            <Button style={{marginRight: "5px"}} type="primary" size="small" onClick={() => this.addRow(table)}>{i18next.t("general:Add")}</Button>
+          </div>
+        )}
+      />
+    );
+  }
+
+  render() {
+    return (
+      <div>
+        <Row style={{marginTop: "20px"}} >
+          <Col span={24}>
+            {
+              this.renderTable(this.props.table)
+            }
+          </Col>
+        </Row>
+      </div>
+    );
+  }
+}
+
+export default RadiusReplyRuleTable;
@@ -0,0 +1,145 @@
+// Copyright 2024 The Casgate Authors. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import React from "react";
+import {DeleteOutlined} from "@ant-design/icons";
+import {Button, Input, Select, Table, Tooltip} from "antd";
+import * as Setting from "../Setting";
+import i18next from "i18next";
+
+const {Option} = Select;
+
+const sources = ["User", "Property", "Roles", "Groups", "Static"];
+
+class ScopeClaimTable extends React.Component {
+  constructor(props) {
+    super(props);
+    this.state = {
+      classes: props,
+    };
+  }
+
+  updateTable(table) {
+    this.props.onUpdateTable(table);
+  }
+
+  updateField(table, index, key, value) {
+    table[index][key] = value;
+    this.updateTable(table);
+  }
+
+  addRow(table) {
+    if (table === undefined || table === null) {
+      table = [];
+    }
+    const row = {name: `claim-${table.length}`, source: "User", value: "", transform: ""};
+    table = Setting.addRow(table, row);
+    this.updateTable(table);
+  }
+
+  deleteRow(table, i) {
+    table = Setting.deleteRow(table, i);
+    this.updateTable(table);
+  }
+
+  render() {
+    const table = this.props.table;
+    const columns = [
+      {
+        title: i18next.t("general:Name"),
+        dataIndex: "name",
+        key: "name",
+        width: "200px",
+        render: (text, record, index) => {
+          return (
+            <Input value={text} onChange={e => {
+              this.updateField(table, index, "name", e.target.value);
+            }} />
+          );
+        },
+      },
+      {
+        title: i18next.t("application:Source"),
+        dataIndex: "source",
+        key: "source",
+        width: "150px",
+        render: (text, record, index) => {
+          return (
+            <Select virtual={false} style={{width: "100%"}} value={text} onChange={value => {
+              this.updateField(table, index, "source", value);
+            }}>
+              {
+                sources.map((source) => <Option key={source} value={source}>{source}</Option>)
+              }
+            </Select>
+          );
+        },
+      },
+      {
+        title: i18next.t("webhook:Value"),
+        dataIndex: "value",
+        key: "value",
+        width: "200px",
+        render: (text, record, index) => {
+          if (record.source === "Roles" || record.source === "Groups") {
+            return null;
+          }
+
+          return (
+            <Input value={text} onChange={e => {
+              this.updateField(table, index, "value", e.target.value);
+            }} />
+          );
+        },
+      },
+      {
+        title: i18next.t("application:Transform"),
+        dataIndex: "transform",
+        key: "transform",
+        render: (text, record, index) => {
+          return (
+            <Input value={text} placeholder="lower(value)" onChange={e => {
+              this.updateField(table, index, "transform", e.target.value);
+            }} />
+          );
+        },
+      },
+      {
+        title: i18next.t("general:Action"),
+        key: "action",
+        width: "50px",
+        render: (text, record, index) => {
+          return (
+            <Tooltip placement="topLeft" title={i18next.t("general:Delete")}>
+              <Button icon={<DeleteOutlined />} size="small" onClick={() => this.deleteRow(table, index)} />
+            </Tooltip>
+          );
+        },
+      },
+    ];
+
+    return (
+      <Table rowKey={(record, index) => index} columns={columns} dataSource={table} size="small" bordered pagination={false}
+        title={() => (
+          <div>
+            {i18next.t("application:Claims")}&nbsp;&nbsp;&nbsp;&nbsp;
+            <Button style={{marginRight: "5px"}} type="primary" size="small" onClick={() => this.addRow(table)}>{i18next.t("general:Add")}</Button>
+          </div>
+        )}
+      />
+    );
+  }
+}
+
+export default ScopeClaimTable;